@@ -0,0 +1,196 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// viewer is an io.Writer watching a session's output (see Server.Watch and Session.TeeOutput), or input (see
+// Session.TeeInput).
+type viewer struct {
+	w      io.Writer
+	err    error         // set if writing to w failed
+	failed chan struct{} // closed once writing to w has failed
+}
+
+// Watch copies the output of the session identified by 'sessionID' (see Session.ID) to 'viewer' as it's written (but
+// not commands), so an operator can watch it live, e.g. an attacker's session in a honeypot. It blocks until the
+// session ends, returning nil, or writing to 'viewer' fails, returning the error.
+//
+// Writes to the client wait on 'viewer', like they do on Session.TeeOutput's writers, so a slow viewer slows the
+// session down.
+func (server *Server) Watch(sessionID string, viewer io.Writer) error {
+	return server.attach(sessionID, viewer, nil)
+}
+
+// Attach is like Watch, but also sends the data read from 'viewer' to the session as if the client had sent it, so
+// an operator can take over the session (like tmux attach). It also returns once reading from 'viewer' fails,
+// returning nil for io.EOF. If the session ends first, reading from 'viewer' carries on in the background until it
+// fails, so it should be closed once Attach returns.
+func (server *Server) Attach(sessionID string, viewer io.ReadWriter) error {
+	return server.attach(sessionID, viewer, viewer)
+}
+
+// attach watches the session identified by 'sessionID' with 'w', sending it the input from 'r' (if set).
+func (server *Server) attach(sessionID string, w io.Writer, r io.Reader) error {
+	server.handlesMu.Lock()
+	session, ok := server.sessions[sessionID]
+	server.handlesMu.Unlock()
+
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	v := session.addViewer(w)
+	defer session.removeViewer(v)
+
+	input := make(chan error, 1)
+	if r != nil {
+		go func() {
+			input <- session.injectFrom(r)
+		}()
+	}
+
+	select {
+	case <-session.Context().Done():
+		return nil
+	case <-v.failed:
+		return v.err
+	case err := <-input:
+		return err
+	}
+}
+
+// addViewer starts copying the session's output to 'w'.
+func (s *Session) addViewer(w io.Writer) *viewer {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	v := &viewer{w: w, failed: make(chan struct{})}
+	s.viewers = append(s.viewers, v)
+
+	return v
+}
+
+// removeViewer stops copying the session's output to 'v'.
+func (s *Session) removeViewer(v *viewer) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.viewers = slices.DeleteFunc(s.viewers, func(other *viewer) bool { return other == v })
+}
+
+// writeViewers copies 'data' to the session's viewers, dropping any that fail. s.writeMu must be held.
+func (s *Session) writeViewers(data []byte) {
+	s.viewers = writeTo(s.viewers, data)
+}
+
+// writeTo copies 'data' to 'viewers', returning those that didn't fail.
+func writeTo(viewers []*viewer, data []byte) []*viewer {
+	return slices.DeleteFunc(viewers, func(v *viewer) bool {
+		if _, err := v.w.Write(data); err != nil {
+			v.err = err
+			close(v.failed)
+
+			return true
+		}
+
+		return false
+	})
+}
+
+// injectFrom sends the data read from 'r' to the session as the client's input, until reading fails, returning nil for
+// io.EOF.
+func (s *Session) injectFrom(r io.Reader) error {
+	var buffer [1024]byte
+
+	for {
+		n, err := r.Read(buffer[:])
+		if n > 0 {
+			s.inject(buffer[:n])
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// inject queues 'data' to be read as the client's input, interrupting any read blocked waiting on the client.
+func (s *Session) inject(data []byte) {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	s.injected = append(s.injected, data...)
+
+	if s.Conn != nil {
+		s.interrupting = true
+		_ = s.Conn.SetReadDeadline(time.Unix(1, 0))
+	}
+}
+
+// takeInjected returns (and clears) the input queued by inject.
+func (s *Session) takeInjected() []byte {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	injected := s.injected
+	s.injected = nil
+
+	return injected
+}
+
+// readClient reads data from the client, returning early with any input injected by attached viewers meanwhile, or
+// once 'ctx' is done.
+func (s *Session) readClient(ctx context.Context, data []byte) (n int, err error) {
+	for {
+		if injected := s.takeInjected(); len(injected) > 0 {
+			return s.readInjected(data, injected), nil
+		}
+
+		n, err = s.reader.Read(data)
+		if err == nil || !s.interrupted(err) {
+			return n, err
+		}
+
+		// The read deadline may also have been set because 'ctx' is done.
+		if err = ctx.Err(); n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// readInjected copies as much of 'injected' into 'data' as fits, keeping the rest for the next Read.
+func (s *Session) readInjected(data []byte, injected []byte) int {
+	n := copy(data, injected)
+	s.pending = append(s.pending, injected[n:]...)
+
+	return n
+}
+
+// interrupted reports whether 'err' is from inject interrupting a read, restoring the read deadline it replaced if so.
+func (s *Session) interrupted(err error) bool {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	if !s.interrupting {
+		return false
+	}
+
+	s.interrupting = false
+	s.restoreReadDeadline()
+
+	return true
+}
@@ -0,0 +1,216 @@
+package telnet
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestServer_Attach(t *testing.T) {
+	server, client, id := serveRepeater(t)
+
+	viewer, operator := net.Pipe()
+	defer operator.Close()
+
+	attached := make(chan error, 1)
+	go func() {
+		attached <- server.Attach(id, viewer)
+	}()
+
+	// The operator's input reaches the handler as if the client had sent it, and the output reaches both.
+	if _, err := operator.Write([]byte("apple\r\n")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	operatorReader := bufio.NewReader(operator)
+	clientReader := bufio.NewReader(NewConn(client))
+
+	for i, reader := range []*bufio.Reader{operatorReader, clientReader} {
+		if expected, actual := "You said: apple\r\n", readString(t, reader); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+	}
+
+	// The client can still type too.
+	if _, err := client.Write([]byte("banana\r\n")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for i, reader := range []*bufio.Reader{operatorReader, clientReader} {
+		if expected, actual := "You said: banana\r\n", readString(t, reader); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+	}
+
+	// Attach returns once the session ends.
+	_ = client.Close()
+
+	select {
+	case err := <-attached:
+		if err != nil {
+			t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected Attach to return once the session ended, but it didn't.")
+	}
+}
+
+func TestServer_Watch(t *testing.T) {
+	server, client, id := serveRepeater(t)
+
+	viewer, operator := net.Pipe()
+
+	watched := make(chan error, 1)
+	go func() {
+		watched <- server.Watch(id, viewer)
+	}()
+
+	// Wait for the viewer to be added, so it sees the output.
+	server.handlesMu.Lock()
+	session := server.sessions[id]
+	server.handlesMu.Unlock()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		session.writeMu.Lock()
+		viewers := len(session.viewers)
+		session.writeMu.Unlock()
+
+		if viewers > 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the viewer to be added, but it never was.")
+		}
+	}
+
+	if _, err := client.Write([]byte("apple\r\n")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "You said: apple\r\n", readString(t, bufio.NewReader(operator)); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	// Watch returns once writing to the viewer fails.
+	_ = operator.Close()
+
+	if _, err := client.Write([]byte("banana\r\n")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if err := <-watched; !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected the viewer's error, but actually got %v.", err)
+	}
+
+	if err := server.Watch("192.0.2.1:1", viewer); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected the error %v, but actually got %v.", ErrSessionNotFound, err)
+	}
+}
+
+func TestServer_AttachKeepsDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	ids := make(chan string, 1)
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
+
+	server := NewServer(WithHandler(func(session *Session) {
+		_ = session.SetReadDeadline(time.Now().Add(time.Second))
+		ids <- session.ID()
+
+		line, _ := session.ReadLine()
+		lines <- line
+
+		// Interrupting the read for the operator's input leaves the handler's deadline in place.
+		_, err := session.ReadLine()
+		errs <- err
+	}), WithoutGoAhead())
+
+	go server.Serve(listener)
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer client.Close()
+
+	viewer, operator := net.Pipe()
+	defer operator.Close()
+
+	go func() {
+		_ = server.Attach(<-ids, viewer)
+	}()
+
+	go func() {
+		_, _ = operator.Write([]byte("apple\r\n"))
+		_, _ = io.Copy(io.Discard, operator)
+	}()
+
+	if expected, actual := "apple", <-lines; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("Expected the error %v, but actually got %v.", os.ErrDeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the read to time out at the handler's deadline, but it didn't.")
+	}
+}
+
+// serveRepeater serves a session repeating each line the client sends, returning the server, and a client connected
+// to it once its session has started, along with the session's ID.
+func serveRepeater(t *testing.T) (*Server, net.Conn, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	ids := make(chan string, 1)
+
+	server := NewServer(
+		WithHandler(func(session *Session) {
+			ids <- session.ID()
+			_ = session.WriteLine("Welcome")
+
+			for {
+				line, err := session.ReadLine()
+				if err != nil {
+					return
+				}
+
+				if err = session.WriteLine("You said: ", line); err != nil {
+					return
+				}
+			}
+		}),
+		WithoutGoAhead(),
+	)
+
+	go server.Serve(listener)
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	// Skip the server's negotiation and welcome, which come once the session has started.
+	expect(t, client, append([]byte{IAC, WONT, SGA}, "Welcome\r\n"...))
+
+	return server, client, <-ids
+}
@@ -0,0 +1,47 @@
+package telnet
+
+// StartBinaryWindow negotiates BINARY both ways, so 8-bit data (e.g. an XMODEM or ZMODEM transfer relayed to a
+// serial console) passes through the session untouched until EndBinaryWindow is called: newlines aren't translated
+// either way, and only IAC is still escaped by doubling it. Each direction is transparent once the client has agreed,
+// which AwaitOption(ctx, BINARY, true) waits for.
+func (s *Session) StartBinaryWindow() error {
+	return s.negotiateBinary(true)
+}
+
+// EndBinaryWindow negotiates the end of BINARY both ways (see StartBinaryWindow), returning the session to NVT text.
+func (s *Session) EndBinaryWindow() error {
+	return s.negotiateBinary(false)
+}
+
+// negotiateBinary offers (or stops offering) to transmit in BINARY, and asks the client to do the same, unless that's
+// already been done.
+func (s *Session) negotiateBinary(enable bool) error {
+	will, do := WILL, DO
+	if !enable {
+		will, do = WONT, DONT
+	}
+
+	if s.options.offered(BINARY) != enable {
+		if _, err := s.WriteCommand(IAC, will, BINARY); err != nil {
+			return err
+		}
+	}
+
+	if s.options.requested(BINARY) != enable {
+		if _, err := s.WriteCommand(IAC, do, BINARY); err != nil {
+			return err
+		}
+	}
+
+	return s.Flush()
+}
+
+// receivedBinaryOption makes the session read what the client transmits as binary data while it's in BINARY mode.
+func (s *Session) receivedBinaryOption() {
+	binary := s.options.remote(BINARY)
+	if binary != s.reader.binary {
+		s.reader.binary = binary
+		s.reader.cr = false
+		s.readCR = false
+	}
+}
@@ -0,0 +1,47 @@
+package telnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSession_BinaryWindow(t *testing.T) {
+	session, client := newTestSession(t, NewServer(WithParseMode(ParseStrict), WithNewlinePolicy(NewlineCRLF)))
+
+	go func() {
+		if err := session.StartBinaryWindow(); err != nil {
+			t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}()
+
+	expect(t, client, []byte{IAC, WILL, BINARY, IAC, DO, BINARY})
+	go client.Write([]byte{IAC, DO, BINARY, IAC, WILL, BINARY})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := session.AwaitOption(ctx, BINARY, true); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// Data isn't interpreted either way, apart from IAC being doubled.
+	go client.Write([]byte{'\r', 'x', IAC, IAC, IAC, 1})
+
+	buffer := make([]byte, 5)
+	if _, err := io.ReadFull(session, buffer); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := string([]byte{'\r', 'x', IAC, IAC, 1}), string(buffer); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	go func() {
+		_, _ = session.Write([]byte{'a', '\n', IAC})
+		_ = session.EndBinaryWindow()
+	}()
+
+	expect(t, client, []byte{'a', '\n', IAC, IAC, IAC, WONT, BINARY, IAC, DONT, BINARY})
+}
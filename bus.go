@@ -0,0 +1,105 @@
+package telnet
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionEventType is the kind of a SessionEvent.
+type SessionEventType int
+
+const (
+	// SessionStarted is published once a session has been set up (after the initial negotiation, and the Enricher),
+	// just before the handler is called.
+	SessionStarted SessionEventType = iota + 1
+
+	// SessionHijacked is published when the handler takes over the session's connection (see Session.Hijack).
+	SessionHijacked
+
+	// SessionEnded is published once the handler has returned, and the session's output has been flushed.
+	SessionEnded
+)
+
+type (
+	// SessionEvent is something that happened to a session served by a Server, as passed to its subscribers (see
+	// Server.Subscribe).
+	SessionEvent struct {
+		Type    SessionEventType
+		Time    time.Time
+		Session *Session
+	}
+
+	// eventBus passes a server's SessionEvents on to its subscribers. The zero value has none.
+	eventBus struct {
+		mu          sync.Mutex
+		subscribers []*subscriber // replaced rather than modified, so publish can use it without holding mu
+	}
+
+	// subscriber is a function subscribed to an eventBus; it's a pointer so it can be told apart when unsubscribing.
+	subscriber struct {
+		f func(SessionEvent)
+	}
+)
+
+// String returns the name of the event type, e.g. "started".
+func (t SessionEventType) String() string {
+	switch t {
+	case SessionStarted:
+		return "started"
+	case SessionHijacked:
+		return "hijacked"
+	case SessionEnded:
+		return "ended"
+	default:
+		return "unknown"
+	}
+}
+
+// Subscribe calls 'f' with the events of each session the server serves (see SessionEventType), e.g. to keep metrics,
+// record sessions or keep a registry of them, until the returned function is called. Events are delivered in order,
+// on the session's goroutine, so 'f' should return quickly (handing slow work to another goroutine), and must be safe
+// to call from several sessions at once.
+func (server *Server) Subscribe(f func(SessionEvent)) (unsubscribe func()) {
+	return server.events.subscribe(f)
+}
+
+// subscribe adds 'f' to the bus's subscribers, returning the function removing it again.
+func (b *eventBus) subscribe(f func(SessionEvent)) func() {
+	sub := &subscriber{f: f}
+
+	b.mu.Lock()
+	b.subscribers = append(slices.Clip(b.subscribers), sub)
+	b.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			b.subscribers = slices.DeleteFunc(slices.Clone(b.subscribers), func(s *subscriber) bool { return s == sub })
+		})
+	}
+}
+
+// publish passes an event of type 't' for 'session' to the bus's subscribers, if it has any. A nil bus has none.
+func (b *eventBus) publish(t SessionEventType, session *Session) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	subscribers := b.subscribers
+	b.mu.Unlock()
+
+	if len(subscribers) == 0 {
+		return
+	}
+
+	event := SessionEvent{Type: t, Time: session.Clock().Now(), Session: session}
+	for _, sub := range subscribers {
+		sub.f(event)
+	}
+}
@@ -0,0 +1,54 @@
+package telnet
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestServer_Subscribe(t *testing.T) {
+	server := NewServer(WithHandler(func(session *Session) {
+		if conn, _, err := session.Hijack(); err == nil {
+			_ = conn.Close()
+		}
+	}), WithInitialNegotiation())
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+
+	serve := func() {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+
+		server.ServeConn(serverConn)
+	}
+
+	unsubscribe := server.Subscribe(func(event SessionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if event.Session == nil || event.Time.IsZero() {
+			t.Errorf("Expected the event to have its session and time, but actually got %+v.", event)
+		}
+
+		events = append(events, event.Type.String())
+	})
+
+	serve()
+
+	if expected := []string{"started", "hijacked", "ended"}; !slices.Equal(expected, events) {
+		t.Errorf("Expected %q, but actually got %q.", expected, events)
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	serve()
+
+	if expected := 3; len(events) != expected {
+		t.Errorf("Expected %d events after unsubscribing, but actually got %d.", expected, len(events))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
 	"strings"
 )
@@ -27,9 +28,35 @@ type (
 		CallTELNET(context.Context, io.Writer, io.Reader)
 	}
 
+	// A SessionCaller is like a Caller, but is passed a ClientSession, giving it the connection's line helpers and
+	// negotiation too.
+	SessionCaller interface {
+		CallTELNETSession(session *ClientSession)
+	}
+
+	// ClientSession is the client end of a TELNET connection, as passed to a SessionCaller. Its Conn reads the server's
+	// data with TELNET commands filtered out, and escapes the data written to it. The server's negotiation can be
+	// answered with Conn.OnOption and Conn.WriteCommand. Like Session, it's an Endpoint.
+	ClientSession struct {
+		*Conn
+		ctx    context.Context
+		cancel context.CancelFunc
+	}
+
 	Client struct {
 		Caller Caller
 		Logger *slog.Logger
+
+		// SessionCaller, if set, is called with the connection instead of Caller.
+		SessionCaller SessionCaller
+
+		// ConnContext, if set, returns the context passed to the Caller derived from 'ctx', e.g. to attach values for
+		// it, like WithConnContext does for servers. It's called before ConnCallback.
+		ConnContext func(ctx context.Context, conn net.Conn) context.Context
+
+		// ConnCallback, if set, wraps the connection before the Caller runs (e.g. to collect metrics or throttle it),
+		// like Server.ConnCallback.
+		ConnCallback func(ctx context.Context, conn net.Conn) net.Conn
 	}
 )
 
@@ -41,14 +68,40 @@ func NewClient(caller Caller, logger *slog.Logger) *Client {
 	return &Client{Caller: caller, Logger: logger}
 }
 
+// NewSessionClient returns a Client calling 'caller' with a ClientSession for each connection.
+func NewSessionClient(caller SessionCaller, logger *slog.Logger) *Client {
+	client := NewClient(nil, logger)
+	client.SessionCaller = caller
+
+	return client
+}
+
 func (client *Client) Call(conn *Conn) error {
 	caller := client.Caller
-	if caller == nil {
+	if caller == nil && client.SessionCaller == nil {
 		client.Logger.Debug("defaulted caller to EchoCaller")
 		caller = EchoCaller
 	}
 
-	caller.CallTELNET(context.Background(), conn.writer, conn.reader)
+	ctx := context.Background()
+	if client.ConnContext != nil {
+		if ctx = client.ConnContext(ctx, conn.conn); ctx == nil {
+			panic("telnet: ConnContext returned nil")
+		}
+	}
+
+	if client.ConnCallback != nil {
+		conn.wrap(client.ConnCallback(ctx, conn.conn))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if client.SessionCaller != nil {
+		client.SessionCaller.CallTELNETSession(&ClientSession{Conn: conn, ctx: ctx, cancel: cancel})
+	} else {
+		caller.CallTELNET(ctx, conn.writer, conn.reader)
+	}
 
 	// TODO: should this be closed here? Seems irresponsible to not leave it up to the caller
 	conn.Close()
@@ -67,6 +120,16 @@ func DialAndCall(srvAddr string, caller Caller) error {
 	return client.Call(conn)
 }
 
+// DialAndCallSession is like DialAndCall, for a SessionCaller.
+func DialAndCallSession(srvAddr string, caller SessionCaller) error {
+	conn, err := Dial("", srvAddr)
+	if err != nil {
+		return err
+	}
+
+	return NewSessionClient(caller, nil).Call(conn)
+}
+
 func DialAndCallTLS(srvAddr string, caller Caller, tlsConfig *tls.Config) error {
 	conn, err := DialTLS("", srvAddr, tlsConfig)
 	if err != nil {
@@ -86,6 +149,50 @@ func (f CallerFunc) CallTELNET(ctx context.Context, w io.Writer, r io.Reader) {
 	f(ctx, w, r)
 }
 
+// The SessionCallerFunc type is an adapter to allow the use of ordinary functions as TELNET session callers.
+type SessionCallerFunc func(session *ClientSession)
+
+// CallTELNETSession calls f(session).
+func (f SessionCallerFunc) CallTELNETSession(session *ClientSession) {
+	f(session)
+}
+
+// Context returns the connection's context (see Client.ConnContext), which is done once the SessionCaller returns or
+// closes the session.
+func (s *ClientSession) Context() context.Context {
+	return s.ctx
+}
+
+// Close closes the connection, and cancels the session's context.
+func (s *ClientSession) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	return s.Conn.Close()
+}
+
+// ReadLine reads a line from the server, without its CR LF. It blocks until the server ends the line, so it doesn't
+// suit reading prompts.
+func (s *ClientSession) ReadLine() (string, error) {
+	return ReadLine(s)
+}
+
+// WriteLine writes 'text' to the server as a line, ending it with CR LF.
+func (s *ClientSession) WriteLine(text ...string) error {
+	return WriteLine(s, text...)
+}
+
+// WriteString writes 'text' to the server as is.
+func (s *ClientSession) WriteString(text ...string) error {
+	return WriteString(s, text...)
+}
+
+// Writef writes the formatted text to the server as is.
+func (s *ClientSession) Writef(format string, args ...any) error {
+	return Writef(s, format, args...)
+}
+
 // EchoCaller is a simple TELNET client which sends to the server any data it gets from os.Stdin
 // as TELNET data, and writes any TELNET data it receives from the server to os.Stdout.
 var EchoCaller CallerFunc = func(ctx context.Context, w io.Writer, r io.Reader) {
@@ -117,7 +224,7 @@ var EchoCaller CallerFunc = func(ctx context.Context, w io.Writer, r io.Reader)
 			clientLine = strings.TrimSuffix(clientLine, "\n") + "\r\n"
 		}
 
-		if err = WriteLine(w, clientLine); err != nil {
+		if err = WriteString(w, clientLine); err != nil {
 			fmt.Printf("Failed to write to server: %v\n", err)
 			return
 		}
@@ -0,0 +1,257 @@
+package telnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+type (
+	// contextKey is the key of the value tests attach with ConnContext.
+	contextKey struct{}
+
+	// countingConn counts the bytes read from a connection.
+	countingConn struct {
+		net.Conn
+		read *atomic.Int64
+	}
+)
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+
+	return n, err
+}
+
+func TestClient_ConnCallback(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		_, _ = server.Write([]byte("apple\r\n"))
+		_ = server.Close()
+	}()
+
+	var read atomic.Int64
+	var value any
+	var line string
+
+	client := NewClient(CallerFunc(func(ctx context.Context, w io.Writer, r io.Reader) {
+		value = ctx.Value(contextKey{})
+		line, _ = ReadLine(r)
+	}), nil)
+
+	client.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		return context.WithValue(ctx, contextKey{}, conn.RemoteAddr().Network())
+	}
+
+	client.ConnCallback = func(ctx context.Context, conn net.Conn) net.Conn {
+		if ctx.Value(contextKey{}) == nil {
+			t.Errorf("Expected ConnContext to have been called before ConnCallback, but it wasn't.")
+		}
+
+		return countingConn{Conn: conn, read: &read}
+	}
+
+	if err := client.Call(NewConn(conn)); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "pipe", value; expected != actual {
+		t.Errorf("Expected the context value %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := "apple", line; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := int64(len("apple\r\n")), read.Load(); expected != actual {
+		t.Errorf("Expected %d bytes read through the callback's connection, but actually got %d.", expected, actual)
+	}
+}
+
+func TestClient_ConnCallbackKeepsConn(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		_, _ = server.Write([]byte{IAC, WILL, ECHO})
+		_, _ = server.Write([]byte("apple\r\n"))
+		_ = server.Close()
+	}()
+
+	var read atomic.Int64
+	var option, verb byte
+	var line string
+
+	// The Conn's OnOption handler still fires once the callback has wrapped its connection.
+	telnetConn := NewConn(conn)
+	telnetConn.OnOption(func(v byte, o byte) {
+		verb, option = v, o
+	})
+
+	client := NewClient(CallerFunc(func(ctx context.Context, w io.Writer, r io.Reader) {
+		line, _ = ReadLine(r)
+	}), nil)
+
+	client.ConnCallback = func(ctx context.Context, conn net.Conn) net.Conn {
+		return countingConn{Conn: conn, read: &read}
+	}
+
+	if err := client.Call(telnetConn); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "apple", line; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if verb != WILL || option != ECHO {
+		t.Errorf("Expected the OnOption handler to get WILL ECHO, but actually got %d %d.", verb, option)
+	}
+
+	if expected, actual := int64(3+len("apple\r\n")), read.Load(); expected != actual {
+		t.Errorf("Expected %d bytes read through the callback's connection, but actually got %d.", expected, actual)
+	}
+}
+
+func TestClientSession_Context(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	var session *ClientSession
+
+	client := NewSessionClient(SessionCallerFunc(func(s *ClientSession) {
+		session = s
+
+		if err := s.Context().Err(); err != nil {
+			t.Errorf("Did not expect the context to be done while the caller runs, but it was: %v.", err)
+		}
+	}), nil)
+
+	if err := client.Call(NewConn(conn)); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	select {
+	case <-session.Context().Done():
+	default:
+		t.Errorf("Expected the context to be done once Call returned, but it wasn't.")
+	}
+
+	// Closing the session also cancels its context.
+	client = NewSessionClient(SessionCallerFunc(func(s *ClientSession) {
+		_ = s.Close()
+
+		if s.Context().Err() == nil {
+			t.Errorf("Expected the context to be done once the session was closed, but it wasn't.")
+		}
+	}), nil)
+
+	server, conn = net.Pipe()
+	defer server.Close()
+
+	if err := client.Call(NewConn(conn)); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+}
+
+func TestConn_LocalEcho(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	client := NewConn(conn)
+	defer client.Close()
+
+	var changes []bool
+	client.OnEcho(func(localEcho bool) {
+		changes = append(changes, localEcho)
+	})
+
+	var options [][2]byte
+	client.OnOption(func(verb byte, option byte) {
+		options = append(options, [2]byte{verb, option})
+	})
+
+	if !client.LocalEcho() {
+		t.Fatal("Expected the client to echo locally before any negotiation, but it didn't.")
+	}
+
+	// Repeating an offer doesn't change anything, nor does negotiating other options.
+	go server.Write([]byte{IAC, WILL, ECHO, IAC, WILL, ECHO, IAC, WILL, SGA, 'a'})
+
+	buffer := make([]byte, 1)
+	if _, err := client.Read(buffer); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if client.LocalEcho() {
+		t.Error("Expected the client not to echo locally once the server offered to, but it did.")
+	}
+
+	go server.Write([]byte{IAC, WONT, ECHO, 'b'})
+
+	if _, err := client.Read(buffer); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if !client.LocalEcho() {
+		t.Error("Expected the client to echo locally once the server stopped echoing, but it didn't.")
+	}
+
+	if expected, actual := []bool{false, true}, changes; len(actual) != 2 || expected[0] != actual[0] || expected[1] != actual[1] {
+		t.Errorf("Expected %v, but actually got %v.", expected, actual)
+	}
+
+	if expected, actual := 4, len(options); expected != actual {
+		t.Errorf("Expected %d commands to be passed to OnOption, but actually got %d.", expected, actual)
+	}
+}
+
+func TestClient_SessionCaller(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		defer server.Close()
+
+		_, _ = server.Write([]byte{IAC, WILL, ECHO})
+		if !expect(t, server, []byte{IAC, DO, ECHO}) {
+			return
+		}
+
+		_, _ = server.Write([]byte("Welcome!\r\n"))
+		expect(t, server, []byte("hello\r\n"))
+	}()
+
+	var line string
+	var localEcho bool
+
+	client := NewSessionClient(SessionCallerFunc(func(session *ClientSession) {
+		session.OnOption(func(verb byte, option byte) {
+			if verb == WILL && option == ECHO {
+				_, _ = session.WriteCommand(IAC, DO, ECHO)
+			}
+		})
+
+		line, _ = session.ReadLine()
+		localEcho = session.LocalEcho()
+		_ = session.WriteLine("hello")
+		_, _ = io.ReadAll(session)
+	}), nil)
+
+	if err := client.Call(NewConn(conn)); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := "Welcome!"; expected != line {
+		t.Errorf("Expected %q, but actually got %q.", expected, line)
+	}
+
+	if localEcho {
+		t.Errorf("Expected the client not to echo locally once the server offered to, but it did.")
+	}
+}
@@ -0,0 +1,155 @@
+package telnet
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RealClock is the Clock telling the system's time, used unless another is configured.
+var RealClock Clock = realClock{}
+
+type (
+	// Clock tells the time and schedules timers, for everything time-based the server does (such as timeouts and
+	// delays), so tests can substitute a fake clock (e.g. telnettest.Clock) instead of waiting in real time.
+	// Connection deadlines (such as those set by WithWriteTimeout) always use the system's time.
+	Clock interface {
+		Now() time.Time
+
+		// NewTimer returns a Timer sending the time on its channel after 'd'.
+		NewTimer(d time.Duration) Timer
+
+		// AfterFunc returns a Timer calling 'f' after 'd'. The Timer's channel is nil.
+		AfterFunc(d time.Duration, f func()) Timer
+	}
+
+	// Timer is a timer scheduled by a Clock, like time.Timer.
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+		Reset(d time.Duration) bool
+	}
+
+	realClock struct{}
+
+	realTimer struct {
+		*time.Timer
+	}
+
+	// clockKey is the key a session context's Clock is stored under.
+	clockKey struct{}
+
+	// clockContext is a context cancelled with context.DeadlineExceeded by a Clock other than RealClock.
+	clockContext struct {
+		context.Context
+		done chan struct{}
+
+		mu  sync.Mutex
+		err error
+	}
+)
+
+// ContextClock returns the Clock of the session 'ctx' belongs to, or RealClock if it has none.
+func ContextClock(ctx context.Context) Clock {
+	if ctx != nil {
+		if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+			return clock
+		}
+	}
+
+	return RealClock
+}
+
+// WithTimeout is like context.WithTimeout, timing out by the context's Clock (see ContextClock). Contexts timed by a
+// Clock other than RealClock don't report a deadline.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	clock := ContextClock(parent)
+	if clock == RealClock {
+		return context.WithTimeout(parent, timeout)
+	}
+
+	ctx := &clockContext{Context: parent, done: make(chan struct{})}
+
+	timer := clock.AfterFunc(timeout, func() { ctx.cancel(context.DeadlineExceeded) })
+	stop := context.AfterFunc(parent, func() { ctx.cancel(parent.Err()) })
+
+	return ctx, func() {
+		timer.Stop()
+		stop()
+		ctx.cancel(context.Canceled)
+	}
+}
+
+// Sleep waits for 'delay' by the context's Clock (see ContextClock), returning early with the context's error if it's
+// done first.
+func Sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := ContextClock(ctx).NewTimer(delay)
+	defer timer.Stop()
+
+	if ctx == nil {
+		<-timer.C()
+		return nil
+	}
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withClock returns a copy of 'ctx' carrying 'clock', unless it's RealClock.
+func withClock(ctx context.Context, clock Clock) context.Context {
+	if clock == nil || clock == RealClock {
+		return ctx
+	}
+
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (c *clockContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c *clockContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *clockContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// cancel sets the context's error, unless it's already done.
+func (c *clockContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
@@ -0,0 +1,241 @@
+package telnet
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Option configures a Server built by NewServer.
+type Option func(server *Server)
+
+// NewServer returns a Server configured by 'opts', logging to slog.Default() unless WithLogger is given. Options are
+// applied in order, so later ones win. The exported fields they set (Addr, Handler, TLSConfig, Timeout and
+// ConnCallback) can still be changed on the Server until it's served; the rest can only be set by options.
+func NewServer(opts ...Option) *Server {
+	server := &Server{logger: slog.Default()}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	return server
+}
+
+// WithAddr sets the TCP address the server listens on with ListenAndServe or ListenAndServeTLS (see Server.Addr).
+func WithAddr(addr string) Option {
+	return func(server *Server) {
+		server.Addr = addr
+	}
+}
+
+// WithHandler sets the handler each session is served by (see Server.Handler).
+func WithHandler(handler HandlerFunc) Option {
+	return func(server *Server) {
+		server.Handler = handler
+	}
+}
+
+// WithLogger sets the logger the server logs to (see Server.SetLogger).
+func WithLogger(logger *slog.Logger) Option {
+	return func(server *Server) {
+		server.logger = logger
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used by ListenAndServeTLS (see Server.TLSConfig).
+func WithTLSConfig(config *tls.Config) Option {
+	return func(server *Server) {
+		server.TLSConfig = config
+	}
+}
+
+// WithSessionTimeout ends each session once it's lasted 'timeout' (see Server.Timeout).
+func WithSessionTimeout(timeout time.Duration) Option {
+	return func(server *Server) {
+		server.Timeout = timeout
+	}
+}
+
+// WithWriteTimeout bounds how long a single write to a client may block for (e.g. when the client stops reading),
+// after which the write fails. Writes only time out when the session context is done without it.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(server *Server) {
+		server.writeTimeout = timeout
+	}
+}
+
+// WithTCPOptions tunes each TCP connection accepted, before the function set by WithConnContext is called (see
+// TCPOptions).
+func WithTCPOptions(options TCPOptions) Option {
+	return func(server *Server) {
+		server.tcp = options
+	}
+}
+
+// WithBufferSizes sets the size of each session's input and output buffers; 4096 bytes if either is 0. Buffered
+// output is flushed whenever the handler reads, calls Session.Flush, or returns.
+func WithBufferSizes(read int, write int) Option {
+	return func(server *Server) {
+		server.readBufferSize = read
+		server.writeBufferSize = write
+	}
+}
+
+// WithNewlinePolicy sets the newline translation applied to data written to sessions; NewlineRaw without it.
+func WithNewlinePolicy(policy NewlinePolicy) Option {
+	return func(server *Server) {
+		server.newlinePolicy = policy
+	}
+}
+
+// WithParseMode sets how sessions handle data from clients that don't follow the TELNET protocol.
+func WithParseMode(mode ParseMode) Option {
+	return func(server *Server) {
+		server.parseMode = mode
+	}
+}
+
+// WithAYTResponse sets the reply to clients checking whether the server is still there (IAC AYT);
+// DefaultAYTResponse without it.
+func WithAYTResponse(response string) Option {
+	return func(server *Server) {
+		server.aytResponse = response
+	}
+}
+
+// WithForwardAYT stops sessions from answering IAC AYT themselves, leaving it to the handler, which receives it from
+// Session.Signals (e.g. to answer monitoring probes only while a backend is healthy).
+func WithForwardAYT() Option {
+	return func(server *Server) {
+		server.forwardAYT = true
+	}
+}
+
+// WithoutGoAhead stops sessions from sending IAC GA before each ReadLine when SGA hasn't been negotiated. Most modern
+// clients ignore GA, but strictly conforming ones rely on it to know when to transmit.
+func WithoutGoAhead() Option {
+	return func(server *Server) {
+		server.disableGoAhead = true
+	}
+}
+
+// WithLatency delays each byte written to clients by 'latency', so output trickles out like it would from a slow
+// device. If 'baudRate' isn't 0, each byte is delayed further, like a serial line of that speed would (e.g. 9600),
+// assuming 10 bits per byte.
+func WithLatency(latency Latency, baudRate int) Option {
+	return func(server *Server) {
+		server.byteLatency = latency
+		server.baudRate = baudRate
+	}
+}
+
+// WithClock sets the Clock timing the Timeout and delays of sessions (see Session.Clock); RealClock without it.
+func WithClock(clock Clock) Option {
+	return func(server *Server) {
+		server.clock = clock
+	}
+}
+
+// WithConnContext sets a function returning the context for a new connection derived from 'ctx', e.g. to attach
+// values for the handler to read from Session.Context. It's called before Server.ConnCallback.
+func WithConnContext(f func(ctx context.Context, conn net.Conn) context.Context) Option {
+	return func(server *Server) {
+		server.connContext = f
+	}
+}
+
+// WithConnCallback sets the function wrapping each connection before it's handled (see Server.ConnCallback).
+func WithConnCallback(f func(ctx context.Context, conn net.Conn) net.Conn) Option {
+	return func(server *Server) {
+		server.ConnCallback = f
+	}
+}
+
+// WithOutputQueue queues up to 'size' bytes of each session's output to be written to the client in the background,
+// so writes (e.g. broadcasting to every session) don't wait on a slow client until the queue is full. 'policy' then
+// decides what happens.
+func WithOutputQueue(size int, policy OverflowPolicy) Option {
+	return func(server *Server) {
+		server.outputQueueSize = size
+		server.overflowPolicy = policy
+	}
+}
+
+// WithMaxSubnegotiationSize sets the longest subnegotiation (e.g. a terminal type) a client may send, after which
+// reading from its session fails with a fatal ProtocolError, so a malicious client can't keep the session reading one
+// forever; 8192 bytes without it.
+func WithMaxSubnegotiationSize(size int) Option {
+	return func(server *Server) {
+		server.maxSubnegotiationSize = size
+	}
+}
+
+// WithNegotiationLimits limits the negotiations (WILL, WONT, DO, DONT and subnegotiations) each client may send to
+// 'rate' per second and 'limit' per session, so a client can't keep its session busy answering a flood of them.
+// Either limit may be 0 for none. 'policy' decides what happens beyond them.
+func WithNegotiationLimits(rate int, limit int, policy FloodPolicy) Option {
+	return func(server *Server) {
+		server.negotiationRate = rate
+		server.negotiationLimit = limit
+		server.floodPolicy = policy
+	}
+}
+
+// WithInitialNegotiation sets the negotiation sent to each client as it connects, before the handler is called, e.g.
+// to offer ECHO and ask for the client's window size (DO NAWS) up front; IAC WONT SGA without it. Called without any,
+// nothing at all is sent, so the server doesn't reveal itself as a TELNET server until the handler does (e.g. for a
+// honeypot). Replies are processed as the session reads from the client, as if the handler had sent them (e.g. with
+// RequestWindowSize).
+func WithInitialNegotiation(negotiation ...Negotiation) Option {
+	return func(server *Server) {
+		if negotiation == nil {
+			negotiation = []Negotiation{}
+		}
+
+		server.initialNegotiation = negotiation
+	}
+}
+
+// WithInputMode sets how clients of new sessions send their input: a line at a time (InputLine, the default), or a
+// character at a time (InputChar), with the session echoing it (see Session.SetInputMode).
+func WithInputMode(mode InputMode) Option {
+	return func(server *Server) {
+		server.inputMode = mode
+	}
+}
+
+// WithEnricher sets the Enricher looking up each client's IP address (e.g. its location, see the geoip package) as it
+// connects, before the handler is called, for it to read with Session.Enrichment.
+func WithEnricher(enricher Enricher) Option {
+	return func(server *Server) {
+		server.enricher = enricher
+	}
+}
+
+// WithPanicHandler sets the function called when a handler panics, with the session (whose connection is still open,
+// e.g. to write a crash message to the client), the value passed to panic, and the goroutine's stack trace. It may
+// panic itself (e.g. re-panic in development) to crash the program. Panics are logged without it.
+func WithPanicHandler(handler func(session *Session, recovered any, stack []byte)) Option {
+	return func(server *Server) {
+		server.panicHandler = handler
+	}
+}
+
+// WithoutPanicRecovery lets panics in handlers crash the program, rather than only ending the session, e.g. so bugs
+// can't go unnoticed in development. The function set by WithPanicHandler isn't called.
+func WithoutPanicRecovery() Option {
+	return func(server *Server) {
+		server.disablePanicRecovery = true
+	}
+}
+
+// WithLogSampling limits what the server logs about each session (and what handlers log with Session.Logger) to a
+// sample, e.g. the first 10 records, then 1 in 100; everything is logged without it.
+func WithLogSampling(sampling LogSampling) Option {
+	return func(server *Server) {
+		server.logSampling = sampling
+	}
+}
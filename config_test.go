@@ -0,0 +1,76 @@
+package telnet
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNewServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(nil, nil))
+
+	server := NewServer(
+		WithAddr(":2323"),
+		WithHandler(EchoHandler),
+		WithLogger(logger),
+		WithSessionTimeout(time.Minute),
+		WithWriteTimeout(time.Second),
+		WithTCPOptions(TCPOptions{KeepAlive: time.Minute, Linger: -1}),
+		WithBufferSizes(512, 1024),
+		WithNewlinePolicy(NewlineCRLF),
+		WithParseMode(ParseStrict),
+		WithAYTResponse("yes"),
+		WithForwardAYT(),
+		WithoutGoAhead(),
+		WithLatency(Latency{Fixed: time.Millisecond}, 9600),
+		WithMaxSubnegotiationSize(1024),
+		WithNegotiationLimits(10, 100, FloodDisconnect),
+		WithPanicHandler(func(*Session, any, []byte) {}),
+		WithoutPanicRecovery(),
+		WithLogSampling(LogSampling{First: 10, Every: 100}),
+	)
+
+	tests := []struct {
+		expected any
+		actual   any
+	}{
+		{expected: ":2323", actual: server.Addr},
+		{expected: logger, actual: server.logger},
+		{expected: time.Minute, actual: server.Timeout},
+		{expected: time.Second, actual: server.writeTimeout},
+		{expected: TCPOptions{KeepAlive: time.Minute, Linger: -1}, actual: server.tcp},
+		{expected: 512, actual: server.readBufferSize},
+		{expected: 1024, actual: server.writeBufferSize},
+		{expected: NewlineCRLF, actual: server.newlinePolicy},
+		{expected: ParseStrict, actual: server.parseMode},
+		{expected: "yes", actual: server.aytResponse},
+		{expected: true, actual: server.forwardAYT},
+		{expected: true, actual: server.disableGoAhead},
+		{expected: Latency{Fixed: time.Millisecond}, actual: server.byteLatency},
+		{expected: 9600, actual: server.baudRate},
+		{expected: 1024, actual: server.maxSubnegotiationSize},
+		{expected: 10, actual: server.negotiationRate},
+		{expected: 100, actual: server.negotiationLimit},
+		{expected: FloodDisconnect, actual: server.floodPolicy},
+		{expected: true, actual: server.disablePanicRecovery},
+		{expected: LogSampling{First: 10, Every: 100}, actual: server.logSampling},
+	}
+
+	for i, test := range tests {
+		if test.expected != test.actual {
+			t.Errorf("For test #%d, expected %v, but actually got %v.", i, test.expected, test.actual)
+		}
+	}
+
+	if server.Handler == nil {
+		t.Error("Expected the handler to be set, but it wasn't.")
+	}
+
+	if server.panicHandler == nil {
+		t.Error("Expected the panic handler to be set, but it wasn't.")
+	}
+
+	if expected, actual := slog.Default(), NewServer().logger; expected != actual {
+		t.Errorf("Expected the default logger, but actually got %v.", actual)
+	}
+}
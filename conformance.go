@@ -0,0 +1,372 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// conformanceQuiet is how long RunConformanceTests waits for a server to send more, before taking it's done
+	// answering.
+	conformanceQuiet = 500 * time.Millisecond
+
+	// conformanceExchangeLimit bounds how long RunConformanceTests reads a server's answer, in case it never goes
+	// quiet.
+	conformanceExchangeLimit = 10 * time.Second
+
+	// unassignedOption is an option no server supports, as it isn't assigned to anything.
+	unassignedOption byte = 200
+)
+
+type (
+	// Violation is a breach of the TELNET protocol found by RunConformanceTests.
+	Violation struct {
+		// Check is the name of the check that failed, e.g. "refuses unknown options".
+		Check string
+
+		// RFC is the specification broken, e.g. "RFC 855".
+		RFC string
+
+		// Detail describes what the server did wrong.
+		Detail string
+	}
+
+	// conformanceCheck is a check made by RunConformanceTests, returning what the server did wrong, if anything.
+	conformanceCheck struct {
+		name string
+		rfc  string
+		run  func(conn *conformanceConn) string
+	}
+
+	// conformanceConn is a connection to a server being checked by RunConformanceTests. It reads the server's output
+	// raw, rather than as a Conn would, so nothing answers its negotiation but the checks.
+	conformanceConn struct {
+		net.Conn
+
+		quiet   time.Duration
+		pending []byte // the start of a command, split across reads
+	}
+
+	// conformanceReply is what a server sent a conformanceConn in an exchange.
+	conformanceReply struct {
+		negotiations []Negotiation
+		data         []byte
+		malformed    []byte // the bytes following IAC that aren't TELNET commands
+		err          error
+	}
+)
+
+// conformanceChecks are the checks RunConformanceTests makes, in order.
+var conformanceChecks = []conformanceCheck{
+	{name: "sends well-formed commands", rfc: "RFC 854", run: checkWellFormed},
+	{name: "refuses unknown options (DO)", rfc: "RFC 855", run: checkRefusesDo},
+	{name: "refuses unknown options (WILL)", rfc: "RFC 855", run: checkRefusesWill},
+	{name: "doesn't acknowledge modes it's already in", rfc: "RFC 854", run: checkNoAcknowledgement},
+	{name: "doesn't repeat answered negotiation", rfc: "RFC 854", run: checkNoLoop},
+	{name: "ignores subnegotiation of disabled options", rfc: "RFC 855", run: checkIgnoresSubnegotiation},
+	{name: "answers are-you-there", rfc: "RFC 854", run: checkAreYouThere},
+}
+
+// RunConformanceTests checks the TELNET server at 'addr' (host and port) against the RFC 854 and RFC 855 rules for
+// negotiating options: that it refuses options it doesn't support, doesn't acknowledge requests for modes it's already
+// in, doesn't repeat negotiation that's been answered, and so on. It's as useful against this package's Server (and
+// handlers that negotiate themselves) as against devices being emulated, though sessions only answer negotiation while
+// their handler reads (as EchoHandler does). Each check connects afresh, and waits for the server to go quiet before
+// taking it's answered, so the whole run takes several seconds.
+//
+// It returns the violations found, or an error if it couldn't connect to the server, or 'ctx' is done.
+func RunConformanceTests(ctx context.Context, addr string) ([]Violation, error) {
+	return runConformanceTests(ctx, addr, conformanceQuiet)
+}
+
+// String describes the violation, e.g. "refuses unknown options (DO): ... (RFC 855)".
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Check, v.Detail, v.RFC)
+}
+
+// runConformanceTests is RunConformanceTests, taking the server to have answered after 'quiet'.
+func runConformanceTests(ctx context.Context, addr string, quiet time.Duration) ([]Violation, error) {
+	var (
+		dialer     net.Dialer
+		violations []Violation
+	)
+
+	for _, check := range conformanceChecks {
+		rawConn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return violations, err
+		}
+
+		stop := context.AfterFunc(ctx, func() {
+			_ = rawConn.Close()
+		})
+
+		if detail := check.run(&conformanceConn{Conn: rawConn, quiet: quiet}); detail != "" {
+			violations = append(violations, Violation{Check: check.name, RFC: check.rfc, Detail: detail})
+		}
+
+		stop()
+		_ = rawConn.Close()
+
+		if err = ctx.Err(); err != nil {
+			return violations, err
+		}
+	}
+
+	return violations, nil
+}
+
+// checkWellFormed checks that the server only sends TELNET commands after IAC.
+func checkWellFormed(conn *conformanceConn) string {
+	reply := conn.exchange()
+	if reply.err != nil {
+		return reply.failed()
+	}
+
+	if len(reply.malformed) > 0 {
+		return fmt.Sprintf("sent IAC followed by %d, which isn't a TELNET command", reply.malformed[0])
+	}
+
+	return ""
+}
+
+// checkRefusesDo checks that the server answers a request to enable an option it can't support with WONT.
+func checkRefusesDo(conn *conformanceConn) string {
+	return checkRefuses(conn, DO, WONT)
+}
+
+// checkRefusesWill checks that the server answers an offer to enable an option it can't support with DONT.
+func checkRefusesWill(conn *conformanceConn) string {
+	return checkRefuses(conn, WILL, DONT)
+}
+
+// checkRefuses checks that the server answers 'verb' for an unassigned option with 'refusal'.
+func checkRefuses(conn *conformanceConn, verb byte, refusal byte) string {
+	if reply := conn.exchange(); reply.err != nil {
+		return reply.failed()
+	}
+
+	reply := conn.exchange(IAC, verb, unassignedOption)
+	if reply.err != nil {
+		return reply.failed()
+	}
+
+	for _, negotiation := range reply.negotiations {
+		if negotiation.Option != unassignedOption {
+			continue
+		}
+
+		if negotiation.Verb != refusal {
+			return fmt.Sprintf("answered IAC %s %d with IAC %s %[2]d, agreeing to an option that doesn't exist", verbName(verb), unassignedOption, verbName(negotiation.Verb))
+		}
+
+		return ""
+	}
+
+	return fmt.Sprintf("didn't answer IAC %s %d with IAC %s %[2]d", verbName(verb), unassignedOption, verbName(refusal))
+}
+
+// checkNoAcknowledgement checks that the server doesn't answer requests to disable options that are already disabled.
+func checkNoAcknowledgement(conn *conformanceConn) string {
+	if reply := conn.exchange(); reply.err != nil {
+		return reply.failed()
+	}
+
+	reply := conn.exchange(IAC, WONT, unassignedOption, IAC, DONT, unassignedOption)
+	if reply.err != nil {
+		return reply.failed()
+	}
+
+	for _, negotiation := range reply.negotiations {
+		if negotiation.Option == unassignedOption {
+			return fmt.Sprintf("answered IAC WONT %d and IAC DONT %[1]d with IAC %s %[1]d, though the option was never enabled", unassignedOption, verbName(negotiation.Verb))
+		}
+	}
+
+	return ""
+}
+
+// checkNoLoop answers the server's initial negotiation, agreeing to the options it offers and refusing those it asks
+// for, and checks that it doesn't negotiate them again.
+func checkNoLoop(conn *conformanceConn) string {
+	initial := conn.exchange()
+	if initial.err != nil {
+		return initial.failed()
+	}
+
+	answered := make(map[Negotiation]bool)
+
+	var answers []byte
+	for _, negotiation := range initial.negotiations {
+		switch negotiation.Verb {
+		case WILL:
+			answers = append(answers, IAC, DO, negotiation.Option)
+		case DO:
+			answers = append(answers, IAC, WONT, negotiation.Option)
+		default:
+			continue
+		}
+
+		answered[negotiation] = true
+	}
+
+	if len(answers) == 0 {
+		return ""
+	}
+
+	reply := conn.exchange(answers...)
+	if reply.err != nil {
+		return reply.failed()
+	}
+
+	for _, negotiation := range reply.negotiations {
+		if answered[negotiation] {
+			return fmt.Sprintf("sent IAC %s %d again after the client answered it", verbName(negotiation.Verb), negotiation.Option)
+		}
+	}
+
+	return ""
+}
+
+// checkIgnoresSubnegotiation checks that the server carries on after a subnegotiation for an option that isn't
+// enabled, rather than closing the connection.
+func checkIgnoresSubnegotiation(conn *conformanceConn) string {
+	if reply := conn.exchange(); reply.err != nil {
+		return reply.failed()
+	}
+
+	if reply := conn.exchange(IAC, SB, unassignedOption, 1, 2, 3, IAC, SE); reply.err != nil {
+		return fmt.Sprintf("closed the connection after IAC SB %d ... IAC SE for an option that isn't enabled (%v)", unassignedOption, reply.err)
+	}
+
+	return ""
+}
+
+// checkAreYouThere checks that the server answers IAC AYT with something visible.
+func checkAreYouThere(conn *conformanceConn) string {
+	if reply := conn.exchange(); reply.err != nil {
+		return reply.failed()
+	}
+
+	reply := conn.exchange(IAC, AYT)
+	if reply.err != nil {
+		return reply.failed()
+	}
+
+	if len(bytes.TrimSpace(reply.data)) == 0 {
+		return "didn't answer IAC AYT"
+	}
+
+	return ""
+}
+
+// exchange sends 'data' to the server (if any), and returns what the server sends until it goes quiet.
+func (c *conformanceConn) exchange(data ...byte) conformanceReply {
+	var reply conformanceReply
+
+	if len(data) > 0 {
+		if _, err := c.Write(data); err != nil {
+			reply.err = err
+			return reply
+		}
+	}
+
+	deadline := time.Now().Add(conformanceExchangeLimit)
+	buffer := make([]byte, 1024)
+
+	for time.Now().Before(deadline) {
+		_ = c.SetReadDeadline(time.Now().Add(c.quiet))
+
+		n, err := c.Read(buffer)
+		c.pending = append(c.pending, buffer[:n]...)
+		c.parse(&reply)
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				reply.err = err
+			}
+
+			break
+		}
+	}
+
+	return reply
+}
+
+// parse moves what the connection has read from the server into 'reply', keeping any incomplete command for the next
+// read.
+func (c *conformanceConn) parse(reply *conformanceReply) {
+	b := c.pending
+
+parsing:
+	for len(b) > 0 {
+		if b[0] != IAC {
+			end := bytes.IndexByte(b, IAC)
+			if end < 0 {
+				end = len(b)
+			}
+
+			reply.data = append(reply.data, b[:end]...)
+			b = b[end:]
+
+			continue
+		}
+
+		if len(b) < 2 {
+			break
+		}
+
+		switch command := b[1]; {
+		case command == IAC:
+			reply.data = append(reply.data, IAC)
+			b = b[2:]
+		case command >= WILL:
+			if len(b) < 3 {
+				break parsing
+			}
+
+			reply.negotiations = append(reply.negotiations, Negotiation{Verb: command, Option: b[2]})
+			b = b[3:]
+		case command == SB:
+			end := bytes.Index(b, []byte{IAC, SE})
+			if end < 0 {
+				break parsing
+			}
+
+			b = b[end+2:]
+		case command >= SE:
+			b = b[2:]
+		default:
+			reply.malformed = append(reply.malformed, command)
+			b = b[2:]
+		}
+	}
+
+	c.pending = append(c.pending[:0], b...)
+}
+
+// failed describes the error that ended the exchange.
+func (r conformanceReply) failed() string {
+	return fmt.Sprintf("connection failed: %v", r.err)
+}
+
+// verbName returns the name of the negotiation verb 'verb', e.g. "WILL".
+func verbName(verb byte) string {
+	switch verb {
+	case WILL:
+		return "WILL"
+	case WONT:
+		return "WONT"
+	case DO:
+		return "DO"
+	case DONT:
+		return "DONT"
+	default:
+		return fmt.Sprint(verb)
+	}
+}
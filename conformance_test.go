@@ -0,0 +1,106 @@
+package telnet
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRunConformanceTests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	server := NewServer(WithHandler(EchoHandler), WithInitialNegotiation(
+		Negotiation{Verb: WILL, Option: ECHO},
+		Negotiation{Verb: DO, Option: NAWS},
+	))
+
+	go server.Serve(listener)
+	defer server.Shutdown()
+
+	violations, err := runConformanceTests(context.Background(), listener.Addr().String(), 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// Sessions don't answer negotiation for options they weren't asked about, leaving them disabled without refusing.
+	known := map[string]bool{"refuses unknown options (DO)": true, "refuses unknown options (WILL)": true}
+
+	for _, violation := range violations {
+		if !known[violation.Check] {
+			t.Errorf("Did not expect a violation, but actually got one: %v.", violation)
+		}
+	}
+}
+
+func TestRunConformanceTests_Violations(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	// Serve a broken server: it sends a command that doesn't exist, agrees to everything it's asked for (again and
+	// again), acknowledges refusals, ignores offers and AYT, and hangs up on subnegotiation.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				if _, err := conn.Write([]byte{IAC, WILL, ECHO, IAC, 7}); err != nil {
+					return
+				}
+
+				buffer := make([]byte, 64)
+				for {
+					n, err := conn.Read(buffer)
+					if err != nil {
+						return
+					}
+
+					for i := 0; i+2 < n; i++ {
+						if buffer[i] != IAC {
+							continue
+						}
+
+						switch buffer[i+1] {
+						case SB:
+							return
+						case DO:
+							_, _ = conn.Write([]byte{IAC, WILL, buffer[i+2]})
+						case DONT, WONT:
+							_, _ = conn.Write([]byte{IAC, WONT, buffer[i+2]})
+						}
+					}
+				}
+			}()
+		}
+	}()
+
+	violations, err := runConformanceTests(context.Background(), listener.Addr().String(), 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := len(conformanceChecks), len(violations); expected != actual {
+		t.Fatalf("Expected %d violations, but actually got %d: %v.", expected, actual, violations)
+	}
+
+	for i, violation := range violations {
+		if expected, actual := conformanceChecks[i].name, violation.Check; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+	}
+
+	if expected, actual := "sends well-formed commands: sent IAC followed by 7, which isn't a TELNET command (RFC 854)", violations[0].String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
@@ -1,60 +1,73 @@
 package telnet
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/binary"
+	"io"
 	"net"
+	"sync"
 )
 
 type Conn struct {
-	conn   net.Conn
-	reader *reader
-	writer *writer
-}
+	conn    net.Conn
+	reader  *reader
+	writer  *writer
+	options options
+
+	mu         sync.Mutex
+	serverEcho bool                         // set while the server has offered to echo (IAC WILL ECHO)
+	onOption   func(verb byte, option byte) // set by OnOption
+	onEcho     func(localEcho bool)         // set by OnEcho
+	window     windowSize                   // the window size last sent with SendWindowSize
+	environ    map[string]string            // the variables to send the server if it asks (see AdvertiseEnvironment)
 
-// TODO: implement timeout for dialing
+	onSubnegotiation func(option byte, payload []byte) // set by OnSubnegotiation
+}
 
 // Dial makes an unsecured TELNET client connection to the specified address.
-// If no address is supplied, it'll default to localhost.
+// If no address is supplied, it'll default to localhost. Use a Dialer for timeouts or a custom resolver.
 func Dial(protocol, addr string) (*Conn, error) {
-	if protocol == "" {
-		protocol = "tcp"
-	}
-	if addr == "" {
-		addr = "127.0.0.1:telnet"
-	}
-
-	conn, err := net.Dial(protocol, addr)
-	if err != nil {
-		return nil, err
-	}
-
-	return &Conn{
-		conn:   conn,
-		reader: newReader(conn),
-		writer: newWriter(conn),
-	}, nil
+	return (&Dialer{}).Dial(protocol, addr)
 }
 
 // DialTLS makes a secure TELNETS client connection to the specified address.
-// If no address is supplied, it'll default to localhost.
+// If no address is supplied, it'll default to localhost. Use a Dialer for timeouts or a custom resolver.
 func DialTLS(protocol, addr string, tlsConfig *tls.Config) (*Conn, error) {
-	if protocol == "" {
-		protocol = "tcp"
-	}
-	if addr == "" {
-		addr = "127.0.0.1:telnets"
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
 	}
 
-	conn, err := tls.Dial(protocol, addr, tlsConfig)
-	if err != nil {
-		return nil, err
-	}
+	return (&Dialer{TLSConfig: tlsConfig}).Dial(protocol, addr)
+}
 
-	return &Conn{
+// NewConn makes a TELNET client connection over an already established connection, e.g. one dialled through a proxy
+// or held in memory.
+func NewConn(conn net.Conn) *Conn {
+	c := &Conn{
 		conn:   conn,
 		reader: newReader(conn),
 		writer: newWriter(conn),
-	}, nil
+	}
+	c.reader.onOption = c.receivedOption
+	c.reader.onSubnegotiation = c.receivedSubnegotiation
+
+	return c
+}
+
+// wrap swaps 'conn' (e.g. the connection returned by Client.ConnCallback) in for the connection c reads from and writes
+// to, keeping its handlers, negotiated options and any input it has already buffered.
+func (c *Conn) wrap(conn net.Conn) {
+	var r io.Reader = conn
+	if pending := c.reader.buffered.Buffered(); pending > 0 {
+		buffered, _ := c.reader.buffered.Peek(pending)
+		r = io.MultiReader(bytes.NewReader(bytes.Clone(buffered)), conn)
+	}
+
+	c.conn = conn
+	c.reader.reader = r
+	c.reader.buffered.Reset(r)
+	c.writer.writer = conn
 }
 
 // Close closes the client connection.
@@ -62,6 +75,143 @@ func (c *Conn) Close() error {
 	return c.conn.Close()
 }
 
+// CloseWrite shuts down the writing side of the connection, so the server reads EOF, while the server's output can
+// still be read. It returns ErrCloseWriteUnsupported if the underlying connection can't be half-closed.
+func (c *Conn) CloseWrite() error {
+	return closeWrite(c.conn)
+}
+
+// OnOption sets 'f' to be called with each WILL, WONT, DO or DONT command the server sends, as it's read, e.g. to
+// answer the server's negotiations. The commands are filtered out of the data read either way.
+func (c *Conn) OnOption(f func(verb byte, option byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onOption = f
+}
+
+// LocalEcho reports whether the client should echo the characters the user types itself: it shouldn't while the
+// server has offered to echo them (IAC WILL ECHO), e.g. when it's prompting for a password it won't echo at all. It's
+// up to the caller to answer the server's offer (see OnOption).
+func (c *Conn) LocalEcho() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return !c.serverEcho
+}
+
+// OnEcho sets 'f' to be called whenever LocalEcho changes, as the server's negotiation is read, e.g. to switch the
+// user's terminal in and out of raw mode.
+func (c *Conn) OnEcho(f func(localEcho bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onEcho = f
+}
+
+// WriteCommand writes a TELNET command to the server without escaping it, e.g. IAC DO ECHO to answer the server's
+// offer to echo. Negotiation sent this way is tracked by LocalOption and RemoteOption.
+func (c *Conn) WriteCommand(command byte, option byte, action byte) (n int, err error) {
+	n, err = WriteCommand(c.writer, command, option, action)
+	if err == nil && command == IAC {
+		c.options.sent(option, action)
+	}
+
+	return n, err
+}
+
+// LocalOption reports whether the client has agreed with the server to perform 'option' (e.g. NAWS, once the server
+// has answered IAC WILL NAWS with DO, or the other way around).
+func (c *Conn) LocalOption(option byte) bool {
+	return c.options.local(option)
+}
+
+// RemoteOption reports whether the server has agreed with the client to perform 'option' (e.g. ECHO, once the client
+// has answered IAC WILL ECHO with DO, using WriteCommand).
+func (c *Conn) RemoteOption(option byte) bool {
+	return c.options.remote(option)
+}
+
+// SendWindowSize sends the server the client's window size, in characters (RFC 1073), once NAWS has been agreed (see
+// LocalOption).
+func (c *Conn) SendWindowSize(width int, height int) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(width))
+	payload = binary.BigEndian.AppendUint16(payload, uint16(height))
+
+	if _, err := c.writer.Write(append(commandSignature(), appendSubnegotiation(nil, NAWS, payload)...)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.window.width, c.window.height = width, height
+	c.window.received = true
+	c.mu.Unlock()
+
+	return nil
+}
+
+// WindowSize returns the window size last sent with SendWindowSize, and whether one has been sent.
+func (c *Conn) WindowSize() (width int, height int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.window.width, c.window.height, c.window.received
+}
+
+// receivedOption tracks the server's negotiation, agrees to send the environment if it's asked for and advertised,
+// then passes the command on to OnOption's function.
+func (c *Conn) receivedOption(verb byte, option byte) {
+	c.options.received(verb, option)
+
+	c.mu.Lock()
+	onOption := c.onOption
+	advertise := c.environ != nil && verb == DO && (option == NEWENVIRON || option == OLDENVIRON)
+
+	var onEcho func(localEcho bool)
+	if option == ECHO && (verb == WILL || verb == WONT) && c.serverEcho != (verb == WILL) {
+		c.serverEcho = verb == WILL
+		onEcho = c.onEcho
+	}
+	c.mu.Unlock()
+
+	if advertise && !c.options.offered(option) {
+		_, _ = c.WriteCommand(IAC, WILL, option)
+	}
+
+	if onEcho != nil {
+		onEcho(verb == WONT)
+	}
+
+	if onOption != nil {
+		onOption(verb, option)
+	}
+}
+
+// OnSubnegotiation sets 'f' to be called with the unescaped payload of each subnegotiation the server sends, as it's
+// read. The payload is only valid until 'f' returns.
+func (c *Conn) OnSubnegotiation(f func(option byte, payload []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onSubnegotiation = f
+}
+
+// receivedSubnegotiation answers the server asking for the advertised environment, then passes the subnegotiation
+// on to OnSubnegotiation's function.
+func (c *Conn) receivedSubnegotiation(option byte, payload []byte) {
+	c.mu.Lock()
+	vars, onSubnegotiation := c.environ, c.onSubnegotiation
+	c.mu.Unlock()
+
+	if vars != nil && (option == NEWENVIRON || option == OLDENVIRON) && len(payload) > 0 && payload[0] == SEND && c.options.offered(option) {
+		_, _ = c.writer.Write(append(commandSignature(), appendSubnegotiation(nil, option, environmentReply(option, vars, payload[1:]))...))
+	}
+
+	if onSubnegotiation != nil {
+		onSubnegotiation(option, payload)
+	}
+}
+
 // Read reads bytes from the server into p.
 func (c *Conn) Read(p []byte) (int, error) {
 	return c.reader.Read(p)
@@ -81,3 +231,12 @@ func (c *Conn) LocalAddr() net.Addr {
 func (c *Conn) RemoteAddr() net.Addr {
 	return c.conn.RemoteAddr()
 }
+
+// closeWrite half-closes 'conn', if it supports it.
+func closeWrite(conn net.Conn) error {
+	if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+		return closer.CloseWrite()
+	}
+
+	return ErrCloseWriteUnsupported
+}
@@ -0,0 +1,262 @@
+package telnet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// defaultFallbackDelay is how long a Dialer waits for its first address family before trying the other, unless
+// configured otherwise. It's the delay RFC 6555 recommends, as net.Dialer uses.
+const defaultFallbackDelay = 300 * time.Millisecond
+
+type (
+	// Dialer makes TELNET (or TELNETS) client connections, with more control than Dial and DialTLS. Hosts resolving to
+	// both IPv4 and IPv6 addresses are dialled dual-stack ("Happy Eyeballs", RFC 6555): if the first address family
+	// hasn't connected within FallbackDelay, the other is raced against it, so targets with broken AAAA records (common
+	// on embedded devices) don't hang the dial. The zero value is ready to use.
+	Dialer struct {
+		// Timeout bounds the whole dial, including resolving the host and any TLS handshake. There's no limit if 0.
+		Timeout time.Duration
+
+		// AttemptTimeout bounds each address tried, so one that doesn't answer is given up on for the next. There's no
+		// limit (other than Timeout) if 0.
+		AttemptTimeout time.Duration
+
+		// FallbackDelay is how long to wait for the first address family to connect before racing the other; 300ms if
+		// 0. Dual-stack dialing is disabled if it's negative, trying every address in turn.
+		FallbackDelay time.Duration
+
+		// Resolver looks up hosts' addresses; net.DefaultResolver if nil.
+		Resolver *net.Resolver
+
+		// TLSConfig, if set, makes the Dialer connect with TELNETS. Its ServerName defaults to the dialled host.
+		TLSConfig *tls.Config
+
+		// TCP tunes the connection once it's made (see TCPOptions).
+		TCP TCPOptions
+	}
+
+	// dialFunc dials a single address, like net.Dialer.DialContext.
+	dialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+	// dialResult is the outcome of dialling one address family.
+	dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+)
+
+// Dial connects to 'addr' on the named network ("tcp" if empty); if no address is supplied, it'll default to
+// localhost.
+func (d *Dialer) Dial(network string, addr string) (*Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext is like Dial, giving up once 'ctx' is done.
+func (d *Dialer) DialContext(ctx context.Context, network string, addr string) (*Conn, error) {
+	if network == "" {
+		network = "tcp"
+	}
+
+	if addr == "" {
+		addr = "127.0.0.1:telnet"
+		if d.TLSConfig != nil {
+			addr = "127.0.0.1:telnets"
+		}
+	}
+
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+
+	conn, err := d.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.TCP.apply(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if d.TLSConfig != nil {
+		config := d.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName = addr
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				config.ServerName = host
+			}
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		conn = tlsConn
+	}
+
+	return NewConn(conn), nil
+}
+
+// dial connects to 'addr', resolving its host with the Dialer's Resolver and dialling its addresses.
+func (d *Dialer) dial(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Resolver: d.Resolver, FallbackDelay: -1}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || !strings.HasPrefix(network, "tcp") {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return d.dialAddrs(ctx, network, port, []netip.Addr{ip}, dialer.DialContext)
+	}
+
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ipNetwork := "ip" + strings.TrimPrefix(network, "tcp")
+
+	ips, err := resolver.LookupNetIP(ctx, ipNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.dialAddrs(ctx, network, port, ips, dialer.DialContext)
+}
+
+// dialAddrs connects to the first of 'ips' that answers on 'port' with 'dial'. The addresses of the first one's family
+// are tried in turn, racing those of the other family after FallbackDelay (or as soon as the first family fails).
+func (d *Dialer) dialAddrs(ctx context.Context, network string, port string, ips []netip.Addr, dial dialFunc) (net.Conn, error) {
+	if len(ips) == 0 {
+		return nil, &net.AddrError{Err: "no addresses to dial", Addr: port}
+	}
+
+	var primaries, fallbacks []netip.Addr
+	for _, ip := range ips {
+		if ip = ip.Unmap(); ip.Is4() == ips[0].Unmap().Is4() {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+
+	if d.FallbackDelay < 0 || len(fallbacks) == 0 {
+		return d.dialSerial(ctx, network, port, append(primaries, fallbacks...), dial)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	start := func(ips []netip.Addr, primary bool) {
+		go func() {
+			conn, err := d.dialSerial(ctx, network, port, ips, dial)
+			results <- dialResult{conn: conn, err: err, primary: primary}
+		}()
+	}
+
+	start(primaries, true)
+
+	delay := d.FallbackDelay
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+
+	fallback := time.NewTimer(delay)
+	defer fallback.Stop()
+
+	var primaryErr error
+	pending, fellBack := 1, false
+
+	for {
+		select {
+		case <-fallback.C:
+			if fellBack {
+				continue
+			}
+
+			start(fallbacks, false)
+			pending, fellBack = pending+1, true
+		case result := <-results:
+			pending--
+
+			if result.err == nil {
+				// Close the losing connection, if the other family connects too before it notices it's cancelled.
+				if pending > 0 {
+					go func() {
+						if result := <-results; result.conn != nil {
+							_ = result.conn.Close()
+						}
+					}()
+				}
+
+				return result.conn, nil
+			}
+
+			if result.primary {
+				primaryErr = result.err
+			}
+
+			if !fellBack {
+				fallback.Stop()
+				start(fallbacks, false)
+				pending, fellBack = pending+1, true
+			}
+
+			if pending == 0 {
+				if primaryErr == nil {
+					primaryErr = result.err
+				}
+
+				return nil, primaryErr
+			}
+		}
+	}
+}
+
+// dialSerial tries each of 'ips' on 'port' in turn, each for up to AttemptTimeout, returning the first connection, or
+// the first error if none connect.
+func (d *Dialer) dialSerial(ctx context.Context, network string, port string, ips []netip.Addr, dial dialFunc) (net.Conn, error) {
+	var firstErr error
+
+	for _, ip := range ips {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if d.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, d.AttemptTimeout)
+		}
+
+		conn, err := dial(attemptCtx, network, net.JoinHostPort(ip.String(), port))
+		cancel()
+
+		if err == nil {
+			return conn, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = errors.New("no addresses to dial")
+	}
+
+	return nil, firstErr
+}
@@ -0,0 +1,132 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialer_DialAddrs(t *testing.T) {
+	errRefused := errors.New("connection refused")
+
+	tests := []struct {
+		Dialer   Dialer
+		IPs      []string
+		Hanging  []string // addresses that never answer
+		Refusing []string // addresses that refuse connections
+		Expected string   // the address connected to, if any
+	}{
+		// A broken IPv6 address falls back to IPv4.
+		{Dialer: Dialer{FallbackDelay: 10 * time.Millisecond}, IPs: []string{"2001:db8::1", "192.0.2.1"}, Hanging: []string{"[2001:db8::1]:23"}, Expected: "192.0.2.1:23"},
+		// A refused IPv6 address falls back immediately, rather than after FallbackDelay.
+		{Dialer: Dialer{FallbackDelay: time.Hour}, IPs: []string{"2001:db8::1", "192.0.2.1"}, Refusing: []string{"[2001:db8::1]:23"}, Expected: "192.0.2.1:23"},
+		// Addresses that don't answer are given up on after AttemptTimeout.
+		{Dialer: Dialer{FallbackDelay: -1, AttemptTimeout: 10 * time.Millisecond}, IPs: []string{"192.0.2.1", "192.0.2.2"}, Hanging: []string{"192.0.2.1:23"}, Expected: "192.0.2.2:23"},
+		{Dialer: Dialer{}, IPs: []string{"192.0.2.1"}, Expected: "192.0.2.1:23"},
+		{Dialer: Dialer{}, IPs: []string{"2001:db8::1", "192.0.2.1"}, Refusing: []string{"[2001:db8::1]:23", "192.0.2.1:23"}},
+	}
+
+	for testNumber, test := range tests {
+		ips := make([]netip.Addr, len(test.IPs))
+		for i, ip := range test.IPs {
+			ips[i] = netip.MustParseAddr(ip)
+		}
+
+		dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+			for _, hanging := range test.Hanging {
+				if address == hanging {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			}
+
+			for _, refusing := range test.Refusing {
+				if address == refusing {
+					return nil, errRefused
+				}
+			}
+
+			return fakeConn{address: address}, nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		conn, err := test.Dialer.dialAddrs(ctx, "tcp", "23", ips, dial)
+		cancel()
+
+		if test.Expected == "" {
+			if !errors.Is(err, errRefused) {
+				t.Errorf("For test #%d, expected %v, but actually got: (%T) %v.", testNumber, errRefused, err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected, actual := test.Expected, conn.RemoteAddr().String(); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestDialer_Dial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	server := NewServer(WithHandler(func(session *Session) {
+		_ = session.WriteLine("Welcome!")
+	}), WithInitialNegotiation())
+
+	go server.Serve(listener)
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// localhost may resolve to ::1 first, which isn't listening, so the dial falls back to IPv4.
+	dialer := &Dialer{Timeout: 5 * time.Second, Resolver: &net.Resolver{PreferGo: true}}
+
+	conn, err := dialer.Dial("", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	line, err := ReadLine(conn)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := "Welcome!"; !strings.HasPrefix(line, expected) {
+		t.Errorf("Expected %q, but actually got %q.", expected, line)
+	}
+}
+
+// fakeConn is a net.Conn to 'address', for testing dialling.
+type fakeConn struct {
+	net.Conn
+	address string
+}
+
+func (c fakeConn) RemoteAddr() net.Addr {
+	return fakeAddr(c.address)
+}
+
+// fakeAddr is a net.Addr for fakeConn.
+type fakeAddr string
+
+func (a fakeAddr) Network() string {
+	return "tcp"
+}
+
+func (a fakeAddr) String() string {
+	return string(a)
+}
@@ -0,0 +1,32 @@
+package telnet
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// unsentBytes returns how many bytes written to 'conn' the OS has yet to send (or have acknowledged by the client).
+func unsentBytes(conn *net.TCPConn) (int, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		unsent   int32
+		ioctlErr syscall.Errno
+	)
+
+	if err = rawConn.Control(func(fd uintptr) {
+		_, _, ioctlErr = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCOUTQ, uintptr(unsafe.Pointer(&unsent)))
+	}); err != nil {
+		return 0, err
+	}
+
+	if ioctlErr != 0 {
+		return 0, ioctlErr
+	}
+
+	return int(unsent), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package telnet
+
+import (
+	"errors"
+	"net"
+)
+
+// unsentBytes returns how many bytes written to 'conn' the OS has yet to send. Only Linux can tell.
+func unsentBytes(conn *net.TCPConn) (int, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,241 @@
+package telnet
+
+import (
+	"io"
+	"strings"
+)
+
+// Control characters handled by the line editor.
+const (
+	ctrlC     byte = 3
+	ctrlD     byte = 4
+	backspace byte = 8
+	tab       byte = 9
+	ctrlU     byte = 21
+	escape    byte = 27
+	del       byte = 127
+)
+
+// LineEditor configures Session.EditLine.
+type LineEditor struct {
+	// Complete returns the possible completions for the last word of 'line' when the client presses Tab. Each
+	// completion is the full word, not just the missing suffix.
+	Complete func(line string) []string
+
+	// History returns previously entered lines, oldest first, which the client can recall with the up and down arrows.
+	History func() []string
+
+	// Secret stops the line being echoed (e.g. for passwords). Tab completion and history recall are disabled.
+	Secret bool
+}
+
+// EditLine writes 'prompt' and reads a line from the client with server-side line editing: the client is switched to
+// character-at-a-time mode (by offering to ECHO and suppress go-ahead), and the session echoes input itself,
+// handling backspace, Ctrl-U (erase line), Ctrl-C (discard line), Ctrl-D (EOF on an empty line), Tab completion and
+// arrow key history recall. The editor may be nil.
+func (s *Session) EditLine(prompt string, editor *LineEditor) (string, error) {
+	if editor == nil {
+		editor = &LineEditor{}
+	}
+
+	if err := s.enableCharacterMode(); err != nil {
+		return "", err
+	}
+
+	if err := s.WriteString(prompt); err != nil {
+		return "", err
+	}
+
+	var history []string
+	if editor.History != nil && !editor.Secret {
+		history = editor.History()
+	}
+
+	var line []byte
+	recalled := len(history)
+
+	// replace swaps the current line for 'text' on the client's screen.
+	replace := func(text string) error {
+		erase := strings.Repeat("\b \b", len(line))
+		line = append(line[:0], text...)
+
+		if editor.Secret {
+			return nil
+		}
+
+		return s.WriteString(erase, text)
+	}
+
+	var buffer [1]byte
+	p := buffer[:]
+
+	for {
+		n, err := s.Read(p)
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			continue
+		}
+
+		value := p[0]
+
+		// Clients end lines with CR LF or CR NUL; ignore whatever follows the CR that ended the previous line.
+		if s.editedCR {
+			s.editedCR = false
+			if value == NL || value == NUL {
+				continue
+			}
+		}
+
+		switch value {
+		case CR, NL:
+			s.editedCR = value == CR
+			if err = s.WriteLine(); err != nil {
+				return "", err
+			}
+
+			s.count(statLines, 1)
+			return string(line), nil
+		case backspace, del:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				if editor.Secret {
+					continue
+				}
+
+				if err = s.WriteString("\b \b"); err != nil {
+					return "", err
+				}
+			}
+		case ctrlU:
+			if err = replace(""); err != nil {
+				return "", err
+			}
+		case ctrlC:
+			if err = s.WriteLine("^C"); err != nil {
+				return "", err
+			}
+
+			return "", nil
+		case ctrlD:
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+		case tab:
+			if editor.Complete == nil || editor.Secret {
+				continue
+			}
+
+			if err = s.complete(prompt, &line, editor.Complete(string(line))); err != nil {
+				return "", err
+			}
+		case escape:
+			direction, err := s.readEscapeSequence()
+			if err != nil {
+				return "", err
+			}
+
+			switch direction {
+			case 'A':
+				if recalled > 0 {
+					recalled--
+					err = replace(history[recalled])
+				}
+			case 'B':
+				if recalled < len(history)-1 {
+					recalled++
+					err = replace(history[recalled])
+				} else if recalled == len(history)-1 {
+					recalled++
+					err = replace("")
+				}
+			}
+
+			if err != nil {
+				return "", err
+			}
+		default:
+			// Ignore any other control characters.
+			if value < ' ' {
+				continue
+			}
+
+			line = append(line, value)
+			if editor.Secret {
+				continue
+			}
+
+			if _, err = s.Write(p); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// complete applies Tab completion to 'line'. A single candidate completes the word; multiple candidates complete
+// their common prefix, or are listed if there's nothing more to complete.
+func (s *Session) complete(prompt string, line *[]byte, candidates []string) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	word := string(*line)
+	if i := strings.LastIndexByte(word, ' '); i >= 0 {
+		word = word[i+1:]
+	}
+
+	prefix := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	if len(candidates) == 1 && !strings.HasSuffix(prefix, "/") {
+		prefix += " "
+	}
+
+	if suffix, ok := strings.CutPrefix(prefix, word); ok && suffix != "" {
+		*line = append(*line, suffix...)
+		return s.WriteString(suffix)
+	}
+
+	return s.WriteString("\r\n", strings.Join(candidates, "  "), "\r\n", prompt, string(*line))
+}
+
+// readEscapeSequence reads the rest of an ANSI escape sequence, returning its final byte (e.g. 'A' for up arrow).
+func (s *Session) readEscapeSequence() (byte, error) {
+	var buffer [1]byte
+	p := buffer[:]
+
+	for i := 0; ; i++ {
+		if _, err := io.ReadFull(s, p); err != nil {
+			return 0, err
+		}
+
+		// Sequences start with ESC [ or ESC O, followed by parameters, then a final byte in the range @ to ~.
+		if i == 0 && (p[0] == '[' || p[0] == 'O') {
+			continue
+		}
+
+		if p[0] >= '@' && p[0] <= '~' {
+			return p[0], nil
+		}
+	}
+}
+
+// enableCharacterMode offers to echo and suppress go-ahead, which switches most clients to character-at-a-time mode.
+func (s *Session) enableCharacterMode() error {
+	for _, option := range []byte{ECHO, SGA} {
+		if s.options.offered(option) {
+			continue
+		}
+
+		if _, err := s.WriteCommand(IAC, WILL, option); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestSession_EditLine(t *testing.T) {
+	editor := &LineEditor{
+		Complete: func(line string) []string {
+			var candidates []string
+			for _, candidate := range []string{"apple", "apricot", "banana"} {
+				if bytes.HasPrefix([]byte(candidate), []byte(line)) {
+					candidates = append(candidates, candidate)
+				}
+			}
+
+			return candidates
+		},
+		History: func() []string {
+			return []string{"first", "second"}
+		},
+	}
+
+	tests := []struct {
+		Bytes    []byte
+		Expected string
+	}{
+		{
+			Bytes:    []byte("apple\r\n"),
+			Expected: "apple",
+		},
+		{
+			Bytes:    []byte("apx\x7fple\r\x00"),
+			Expected: "apple",
+		},
+		{
+			Bytes:    []byte("b\t\r\n"),
+			Expected: "banana ",
+		},
+		{
+			Bytes:    []byte("apr\t\r\n"),
+			Expected: "apricot ",
+		},
+		{
+			Bytes:    []byte("junk\x15ok\n"),
+			Expected: "ok",
+		},
+		{
+			Bytes:    []byte("\x1b[A\x1b[A\r\n"),
+			Expected: "first",
+		},
+		{
+			Bytes:    []byte("\x1b[A\x1b[A\x1b[B\r\n"),
+			Expected: "second",
+		},
+		{
+			Bytes:    []byte("\x1b[A\x1b[B\r\n"),
+			Expected: "",
+		},
+	}
+
+	for testNumber, test := range tests {
+		session := &Session{
+			ctx:    context.Background(),
+			reader: newReader(bytes.NewReader(test.Bytes)),
+			writer: newWriter(io.Discard),
+		}
+
+		line, err := session.EditLine("$ ", editor)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected, actual := test.Expected, line; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+			continue
+		}
+	}
+}
+
+func TestSession_EditLineEcho(t *testing.T) {
+	var output bytes.Buffer
+
+	session := &Session{
+		ctx:    context.Background(),
+		reader: newReader(bytes.NewReader([]byte("ab\x7fc\r\n"))),
+		writer: newWriter(&output),
+	}
+
+	if _, err := session.EditLine("$ ", nil); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := string([]byte{IAC, WILL, ECHO, IAC, WILL, SGA}) + "$ ab\b \bc\r\n"
+	if actual := output.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestSession_EditLineSecret(t *testing.T) {
+	var output bytes.Buffer
+
+	session := &Session{
+		ctx:    context.Background(),
+		reader: newReader(bytes.NewReader([]byte("ab\x7fc\t\r\n"))),
+		writer: newWriter(&output),
+	}
+
+	line, err := session.EditLine("Password: ", &LineEditor{Secret: true})
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "ac", line; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	expected := string([]byte{IAC, WILL, ECHO, IAC, WILL, SGA}) + "Password: \r\n"
+	if actual := output.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestSession_EditLineThenReadLine(t *testing.T) {
+	tests := []struct {
+		Bytes    []byte
+		Expected string
+	}{
+		{Bytes: []byte("secret\r\nls\r\n"), Expected: "ls"},
+		{Bytes: []byte("secret\r\x00ls\r\n"), Expected: "ls"},
+		{Bytes: []byte("secret\rls\r\n"), Expected: "ls"},
+		{Bytes: []byte("secret\n\r\n"), Expected: ""},
+	}
+
+	for testNumber, test := range tests {
+		session := &Session{
+			ctx:    context.Background(),
+			reader: newReader(bytes.NewReader(test.Bytes)),
+			writer: newWriter(io.Discard),
+		}
+
+		if _, err := session.EditLine("Password: ", &LineEditor{Secret: true}); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		line, err := session.ReadLine()
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected, actual := test.Expected, line; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
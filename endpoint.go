@@ -0,0 +1,38 @@
+package telnet
+
+import (
+	"context"
+	"io"
+)
+
+// Endpoint is what both ends of a TELNET connection have in common: the server's Session, and the client's
+// ClientSession. Helpers (e.g. expect scripts) written against it work the same on either end.
+type Endpoint interface {
+	io.ReadWriter
+
+	// Context is done once the connection is finished with.
+	Context() context.Context
+
+	// ReadLine reads a line from the other end, without its line ending.
+	ReadLine() (string, error)
+
+	// WriteLine, WriteString and Writef write text to the other end: a line ending with CR LF, or the text as is.
+	WriteLine(text ...string) error
+	WriteString(text ...string) error
+	Writef(format string, args ...any) error
+
+	// WriteCommand writes a TELNET command without escaping it, e.g. IAC WILL ECHO.
+	WriteCommand(command byte, option byte, action byte) (n int, err error)
+
+	// LocalOption and RemoteOption report whether this end, or the other, has agreed to perform an option.
+	LocalOption(option byte) bool
+	RemoteOption(option byte) bool
+
+	// WindowSize returns the client's window size (see RFC 1073), if it's sent one.
+	WindowSize() (width int, height int, ok bool)
+}
+
+var (
+	_ Endpoint = (*Session)(nil)
+	_ Endpoint = (*ClientSession)(nil)
+)
@@ -0,0 +1,91 @@
+package telnet
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+// endpointState describes an Endpoint's view of the connection, for comparing both ends.
+type endpointState struct {
+	LocalEcho  bool
+	RemoteEcho bool
+	LocalNAWS  bool
+	RemoteNAWS bool
+	Width      int
+	Height     int
+}
+
+func stateOf(endpoint Endpoint) endpointState {
+	width, height, _ := endpoint.WindowSize()
+
+	return endpointState{
+		LocalEcho:  endpoint.LocalOption(ECHO),
+		RemoteEcho: endpoint.RemoteOption(ECHO),
+		LocalNAWS:  endpoint.LocalOption(NAWS),
+		RemoteNAWS: endpoint.RemoteOption(NAWS),
+		Width:      width,
+		Height:     height,
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	serverState := make(chan endpointState, 1)
+
+	server := NewServer(WithHandler(func(session *Session) {
+		_, _ = session.WriteCommand(IAC, WILL, ECHO)
+		_ = session.RequestWindowSize()
+		_ = session.WriteLine("ready")
+
+		if line, err := session.ReadLine(); err != nil || line != "apple" {
+			t.Errorf("Expected %q, but actually got %q (%v).", "apple", line, err)
+		}
+
+		serverState <- stateOf(session)
+	}))
+
+	go server.Serve(listener)
+
+	conn, err := Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	conn.OnOption(func(verb byte, option byte) {
+		switch {
+		case verb == WILL && option == ECHO:
+			_, _ = conn.WriteCommand(IAC, DO, ECHO)
+		case verb == DO && option == NAWS:
+			_, _ = conn.WriteCommand(IAC, WILL, NAWS)
+			_ = conn.SendWindowSize(80, 24)
+		}
+	})
+
+	client := &ClientSession{Conn: conn, ctx: context.Background()}
+
+	if line, err := client.ReadLine(); err != nil || line != "ready" {
+		t.Fatalf("Expected %q, but actually got %q (%v).", "ready", line, err)
+	}
+
+	if err = client.WriteLine("apple"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// Each end sees the same options, from its own side.
+	expected := endpointState{LocalEcho: true, RemoteNAWS: true, Width: 80, Height: 24}
+	if actual := <-serverState; expected != actual {
+		t.Errorf("Expected the server to see %+v, but actually got %+v.", expected, actual)
+	}
+
+	expected = endpointState{RemoteEcho: true, LocalNAWS: true, Width: 80, Height: 24}
+	if actual := stateOf(client); expected != actual {
+		t.Errorf("Expected the client to see %+v, but actually got %+v.", expected, actual)
+	}
+}
@@ -0,0 +1,88 @@
+package telnet
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// enrichTimeout bounds how long a Server's Enricher has to look up each client.
+const enrichTimeout = 2 * time.Second
+
+type (
+	// Enrichment describes a client's IP address, as looked up by an Enricher. Fields the Enricher doesn't know about
+	// are left empty.
+	Enrichment struct {
+		// Country is the ISO 3166-1 code of the country the address is in, e.g. "NL".
+		Country string `json:"country,omitempty"`
+
+		// City is the (English) name of the city the address is in.
+		City string `json:"city,omitempty"`
+
+		// Latitude and Longitude are roughly where the address is.
+		Latitude  float64 `json:"latitude,omitempty"`
+		Longitude float64 `json:"longitude,omitempty"`
+
+		// ASN is the number of the autonomous system announcing the address, and ASOrg the organisation running it.
+		ASN   uint   `json:"asn,omitempty"`
+		ASOrg string `json:"asOrg,omitempty"`
+	}
+
+	// Enricher looks up details of a client's IP address (such as its location, or the network it's from) when it
+	// connects (see WithEnricher).
+	Enricher interface {
+		Enrich(ctx context.Context, ip net.IP) (Enrichment, error)
+	}
+
+	// The EnricherFunc type is an adapter to allow the use of ordinary functions as an Enricher.
+	EnricherFunc func(ctx context.Context, ip net.IP) (Enrichment, error)
+
+	// enrichmentKey is the key a session's Enrichment is attached under.
+	enrichmentKey struct{}
+)
+
+// Enrich calls f(ctx, ip).
+func (f EnricherFunc) Enrich(ctx context.Context, ip net.IP) (Enrichment, error) {
+	return f(ctx, ip)
+}
+
+// Enrichment returns what the server's Enricher found out about the client's IP address, if it found anything.
+func (s *Session) Enrichment() (Enrichment, bool) {
+	return Value[Enrichment](s, enrichmentKey{})
+}
+
+// enrich looks up the session's client with the server's Enricher, attaching the result to the session.
+func (server *Server) enrich(session *Session) {
+	ip := addrIP(session.RemoteAddr())
+	if ip == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(session.Context(), enrichTimeout)
+	defer cancel()
+
+	enrichment, err := server.enricher.Enrich(ctx, ip)
+	if err != nil {
+		session.logger.Debug("failed to enrich telnet connection", "from", session.RemoteAddr().String(), "err", err)
+		return
+	}
+
+	session.Set(enrichmentKey{}, enrichment)
+}
+
+// addrIP returns the IP address of 'addr', or nil if it doesn't have one.
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case nil:
+		return nil
+	case *net.TCPAddr:
+		return addr.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			host = addr.String()
+		}
+
+		return net.ParseIP(host)
+	}
+}
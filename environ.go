@@ -0,0 +1,246 @@
+package telnet
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// NEWENVIRON is the TELNET option clients use to send their environment variables (RFC 1572).
+const NEWENVIRON byte = 39
+
+// OLDENVIRON is the TELNET option older clients use to send their environment variables (RFC 1408), which NEWENVIRON
+// replaced.
+const OLDENVIRON byte = 36
+
+// NEW-ENVIRON subnegotiation commands, in addition to IS and SEND.
+const (
+	INFO byte = 2
+)
+
+// NEW-ENVIRON variable types.
+const (
+	VAR     byte = 0
+	VALUE   byte = 1
+	ESC     byte = 2
+	USERVAR byte = 3
+)
+
+// wellKnownVariables are the environment variables RFC 1572 defines, which clients send as VAR rather than USERVAR.
+var wellKnownVariables = map[string]bool{"USER": true, "JOB": true, "ACCT": true, "PRINTER": true, "SYSTEMTYPE": true, "DISPLAY": true}
+
+// environment tracks a NEW-ENVIRON negotiation with the client.
+type environment struct {
+	vars map[string]string
+	done bool
+}
+
+// RequestEnvironment asks the client to send its environment variables. Replies are processed as the session reads
+// from the client; use AwaitEnvironment to wait for them.
+func (s *Session) RequestEnvironment() error {
+	if s.options.requested(NEWENVIRON) {
+		return nil
+	}
+
+	_, err := s.WriteCommand(IAC, DO, NEWENVIRON)
+	return err
+}
+
+// AwaitEnvironment requests the client's environment variables (if they haven't been already), and waits until the
+// client has sent them, refused, or 'ctx' is done. Any data the client sends in the meantime is kept for the next
+// Read.
+func (s *Session) AwaitEnvironment(ctx context.Context) (map[string]string, error) {
+	if err := s.RequestEnvironment(); err != nil {
+		return nil, err
+	}
+
+	if err := s.await(ctx, func() bool { return s.environ.done }); err != nil {
+		return s.Environment(), err
+	}
+
+	return s.Environment(), nil
+}
+
+// Environment returns a copy of the environment variables the client has sent so far, including any it has updated
+// since. Well-known (VAR) and user-defined (USERVAR) variables share the same namespace.
+func (s *Session) Environment() map[string]string {
+	vars := make(map[string]string, len(s.environ.vars))
+	for name, value := range s.environ.vars {
+		vars[name] = value
+	}
+
+	return vars
+}
+
+// receivedEnvironmentOption handles the client's response to DO NEW-ENVIRON.
+func (s *Session) receivedEnvironmentOption(verb byte) {
+	if !s.options.requested(NEWENVIRON) || s.environ.done {
+		return
+	}
+
+	switch verb {
+	case WILL:
+		// An empty SEND asks for all the client's variables.
+		if _, err := s.Write(append(commandSignature(), IAC, SB, NEWENVIRON, SEND, IAC, SE)); err != nil {
+			s.environ.done = true
+			return
+		}
+
+		_ = s.Flush()
+	case WONT:
+		s.environ.done = true
+	}
+}
+
+// receivedEnvironment handles a NEW-ENVIRON IS or INFO subnegotiation from the client.
+//
+// The payload is a list of variables, each a VAR or USERVAR byte followed by the variable's name, and optionally a
+// VALUE byte followed by its value. An undefined variable has no VALUE; an empty one has an empty VALUE. ESC quotes
+// the byte after it.
+func (s *Session) receivedEnvironment(payload []byte) {
+	if len(payload) == 0 || (payload[0] != IS && payload[0] != INFO) {
+		return
+	}
+
+	if payload[0] == IS {
+		s.environ.done = true
+	}
+
+	if s.environ.vars == nil {
+		s.environ.vars = make(map[string]string)
+	}
+
+	var name, value []byte
+	var inValue, hasName bool
+
+	flush := func() {
+		if !hasName {
+			return
+		}
+
+		if inValue {
+			s.environ.vars[string(name)] = string(value)
+		} else {
+			delete(s.environ.vars, string(name))
+		}
+	}
+
+	for i := 1; i < len(payload); i++ {
+		switch b := payload[i]; b {
+		case VAR, USERVAR:
+			flush()
+			name, value = name[:0], value[:0]
+			inValue, hasName = false, true
+		case VALUE:
+			inValue = true
+		default:
+			if b == ESC && i+1 < len(payload) {
+				i++
+				b = payload[i]
+			}
+
+			if inValue {
+				value = append(value, b)
+			} else {
+				name = append(name, b)
+			}
+		}
+	}
+
+	flush()
+}
+
+// AdvertiseEnvironment sets environment variables for the client to send the server if it asks for them with
+// NEW-ENVIRON (RFC 1572) or OLD-ENVIRON (RFC 1408), e.g. {"USER": "root"} as telnet -l does, so the server can skip
+// prompting for the username. The server's IAC DO for either option is answered with WILL, and its requests with the
+// variables it asks for (all of them if it doesn't name any). OnOption's function still sees the server's commands,
+// but shouldn't answer these.
+func (c *Conn) AdvertiseEnvironment(vars map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.environ = maps.Clone(vars)
+	if c.environ == nil {
+		c.environ = make(map[string]string)
+	}
+}
+
+// environmentReply returns the IS subnegotiation payload answering a NEW-ENVIRON or OLD-ENVIRON (as 'option' says)
+// SEND for the variables named in 'request' with those in 'vars'. Variables asked for that aren't in 'vars' are sent
+// undefined.
+//
+// Many OLD-ENVIRON implementations swapped the codes of VAR and VALUE (RFC 1571), so they're swapped back if the
+// server names the variables it wants with VALUE.
+func environmentReply(option byte, vars map[string]string, request []byte) []byte {
+	varCode, valueCode := VAR, VALUE
+	if option == OLDENVIRON && len(request) > 0 && request[0] == VALUE {
+		varCode, valueCode = VALUE, VAR
+	}
+
+	reply := []byte{IS}
+	add := func(name string) {
+		code := USERVAR
+		if wellKnownVariables[name] {
+			code = varCode
+		}
+
+		reply = appendEscapedEnvironment(append(reply, code), name)
+		if value, ok := vars[name]; ok {
+			reply = appendEscapedEnvironment(append(reply, valueCode), value)
+		}
+	}
+
+	all := make([]string, 0, len(vars))
+	for name := range vars {
+		all = append(all, name)
+	}
+	slices.Sort(all)
+	if len(request) == 0 {
+		for _, name := range all {
+			add(name)
+		}
+
+		return reply
+	}
+
+	// Each variable asked for is introduced by its type; an empty name asks for every variable of that type.
+	for i := 0; i < len(request); {
+		code := request[i]
+		i++
+
+		var name []byte
+		for ; i < len(request) && request[i] != varCode && request[i] != USERVAR; i++ {
+			if request[i] == ESC && i+1 < len(request) {
+				i++
+			}
+
+			name = append(name, request[i])
+		}
+
+		if len(name) > 0 {
+			add(string(name))
+			continue
+		}
+
+		for _, name := range all {
+			if wellKnownVariables[name] == (code == varCode) {
+				add(name)
+			}
+		}
+	}
+
+	return reply
+}
+
+// appendEscapedEnvironment appends 'text' to 'payload', escaping the bytes NEW-ENVIRON uses as codes with ESC.
+func appendEscapedEnvironment(payload []byte, text string) []byte {
+	for _, b := range []byte(text) {
+		if b <= USERVAR {
+			payload = append(payload, ESC)
+		}
+
+		payload = append(payload, b)
+	}
+
+	return payload
+}
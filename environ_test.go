@@ -0,0 +1,133 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSession_AwaitEnvironment(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	go func() {
+		if !expect(t, client, []byte{IAC, DO, NEWENVIRON}) {
+			return
+		}
+
+		client.Write([]byte{IAC, WILL, NEWENVIRON})
+
+		if !expect(t, client, []byte{IAC, SB, NEWENVIRON, SEND, IAC, SE}) {
+			return
+		}
+
+		client.Write([]byte{
+			IAC, SB, NEWENVIRON, IS,
+			VAR, 'U', 'S', 'E', 'R', VALUE, 'r', 'o', 'o', 't',
+			VAR, 'D', 'I', 'S', 'P', 'L', 'A', 'Y',
+			USERVAR, 'E', 'S', 'C', VALUE, 'a', ESC, VAR, 'b',
+			USERVAR, 'E', 'M', 'P', 'T', 'Y', VALUE,
+			IAC, SE,
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	vars, err := session.AwaitEnvironment(ctx)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := map[string]string{"USER": "root", "ESC": "a\x00b", "EMPTY": ""}
+	if !reflect.DeepEqual(expected, vars) {
+		t.Errorf("Expected %q, but actually got %q.", expected, vars)
+	}
+
+	// INFO updates variables after the initial IS.
+	session.receivedEnvironment([]byte{INFO, VAR, 'U', 'S', 'E', 'R', VALUE, 'a', 'd', 'm', 'i', 'n', USERVAR, 'E', 'M', 'P', 'T', 'Y'})
+
+	expected = map[string]string{"USER": "admin", "ESC": "a\x00b"}
+	if actual := session.Environment(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestConn_AdvertiseEnvironment(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	server := NewServer(WithHandler(func(session *Session) {
+		ctx, cancel := context.WithTimeout(session.Context(), 5*time.Second)
+		defer cancel()
+
+		vars, err := session.AwaitEnvironment(ctx)
+		_ = session.WriteLine(fmt.Sprint(vars, err))
+	}), WithInitialNegotiation(), WithoutGoAhead())
+
+	go server.Serve(listener)
+	defer server.Shutdown()
+
+	conn, err := Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	conn.AdvertiseEnvironment(map[string]string{"USER": "root", "LANG": "C"})
+
+	line, err := ReadLine(conn)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := "map[LANG:C USER:root] <nil>"; expected != line {
+		t.Errorf("Expected %q, but actually got %q.", expected, line)
+	}
+
+	if !conn.LocalOption(NEWENVIRON) {
+		t.Errorf("Expected NEW-ENVIRON to be enabled, but it wasn't.")
+	}
+}
+
+func TestEnvironmentReply(t *testing.T) {
+	vars := map[string]string{"USER": "root", "LANG": "C", "ODD": "a\x01b"}
+
+	tests := []struct {
+		Option   byte
+		Request  []byte
+		Expected []byte
+	}{
+		{
+			Option:   NEWENVIRON,
+			Expected: []byte("\x00\x03LANG\x01C\x03ODD\x01a\x02\x01b\x00USER\x01root"),
+		},
+		{
+			Option:   NEWENVIRON,
+			Request:  []byte("\x00USER\x03MISSING"),
+			Expected: []byte("\x00\x00USER\x01root\x03MISSING"),
+		},
+		{
+			Option:   NEWENVIRON,
+			Request:  []byte{USERVAR},
+			Expected: []byte("\x00\x03LANG\x01C\x03ODD\x01a\x02\x01b"),
+		},
+		{
+			// An OLD-ENVIRON server with VAR and VALUE swapped is answered in kind.
+			Option:   OLDENVIRON,
+			Request:  []byte("\x01USER"),
+			Expected: []byte("\x00\x01USER\x00root"),
+		},
+	}
+
+	for testNumber, test := range tests {
+		if actual := environmentReply(test.Option, vars, test.Request); !bytes.Equal(test.Expected, actual) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.Expected, actual)
+		}
+	}
+}
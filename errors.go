@@ -0,0 +1,85 @@
+package telnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrProtocol is matched by every ProtocolError, so callers can check for malformed TELNET data using errors.Is.
+var ErrProtocol = errors.New("telnet: protocol error")
+
+// ErrHijacked is returned by a Session's methods once its connection has been taken over with Hijack.
+var ErrHijacked = errors.New("telnet: connection has been hijacked")
+
+// ErrSessionClosed is returned by a Session's reads once its context is done, e.g. because the server is shutting down
+// or Server.Timeout has passed. It matches net.ErrClosed, as the connection is closed too.
+var ErrSessionClosed = fmt.Errorf("telnet: session closed: %w", net.ErrClosed)
+
+// ErrCloseWriteUnsupported is returned by CloseWrite when the underlying connection can't be half-closed (i.e. it has
+// no CloseWrite method, like *net.TCPConn and *tls.Conn do).
+var ErrCloseWriteUnsupported = errors.New("telnet: connection doesn't support half-closing")
+
+// ErrNoBackends is returned by BackendPool.Dial when none of the pool's backends can be reached.
+var ErrNoBackends = errors.New("telnet: no backend available")
+
+// ErrSessionNotFound is returned by Server.Attach and Server.Watch when the server has no session with the given ID.
+var ErrSessionNotFound = errors.New("telnet: session not found")
+
+// ErrNotListening is returned by Server.ListenerFile and Server.Upgrade when the server isn't serving a listener.
+var ErrNotListening = errors.New("telnet: server isn't listening")
+
+// ErrNotReady is returned by Server.UpgradeAndWait when the process it started exits without calling Ready.
+var ErrNotReady = errors.New("telnet: new process exited before it was ready")
+
+// ErrOutputOverflow is returned by a Session's writes once its output queue overflowed with OverflowDisconnect.
+var ErrOutputOverflow = errors.New("telnet: output queue overflowed")
+
+// ProtocolError describes TELNET data from the peer that doesn't follow the protocol.
+type ProtocolError struct {
+	// Expected describes what should have been sent instead.
+	Expected string
+
+	// Offset is the position of Byte within the stream, counting from 0.
+	Offset int64
+
+	// Byte is the offending byte.
+	Byte byte
+
+	// Fatal reports whether the stream can't be recovered. If false, the offending sequence has been skipped and
+	// reading may continue.
+	Fatal bool
+}
+
+func (e *ProtocolError) Error() string {
+	severity := "recoverable"
+	if e.Fatal {
+		severity = "fatal"
+	}
+
+	return fmt.Sprintf("telnet: %s protocol error at offset %d: got byte %d, expected %s", severity, e.Offset, e.Byte, e.Expected)
+}
+
+// Is reports whether 'target' is ErrProtocol.
+func (e *ProtocolError) Is(target error) bool {
+	return target == ErrProtocol
+}
+
+// ErrOptionRefused is returned by Session.AwaitOption when the client answers a negotiation by refusing it.
+var ErrOptionRefused = errors.New("telnet: option refused")
+
+// ErrTransferTooLarge is returned by Session.SendFile and Session.ReceiveFile when a file exceeds
+// TransferOptions.MaxSize.
+var ErrTransferTooLarge = errors.New("telnet: file transfer too large")
+
+// ErrInterrupted is the cause of a context returned by Session.WithInterrupt being cancelled when the client
+// interrupts (e.g. with Ctrl-C).
+var ErrInterrupted = errors.New("telnet: interrupted by the client")
+
+// ErrSuspended is the cause of a context returned by Session.WithJobControl being cancelled when the client suspends
+// the command (with Ctrl-Z).
+var ErrSuspended = errors.New("telnet: suspended by the client")
+
+// ErrNegotiationFlood is returned by a Session's reads once its client exceeded its negotiation limits with
+// FloodDisconnect.
+var ErrNegotiationFlood = errors.New("telnet: negotiation flood")
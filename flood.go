@@ -0,0 +1,86 @@
+package telnet
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// FloodIgnore ignores the client's negotiation beyond the limits: it's still read, but not acted on.
+	FloodIgnore FloodPolicy = iota
+
+	// FloodThrottle stops reading from the client for the rest of the second once it's sent the negotiation rate's
+	// worth of negotiations, and ignores its negotiation beyond the negotiation limit.
+	FloodThrottle
+
+	// FloodDisconnect ends the session, failing reads with ErrNegotiationFlood.
+	FloodDisconnect
+)
+
+type (
+	// FloodPolicy decides what happens when a client exceeds a session's negotiation limits (see
+	// WithNegotiationLimits).
+	FloodPolicy int
+
+	// floodGuard counts the negotiation a client sends, applying its policy once the client exceeds the limits.
+	floodGuard struct {
+		ctx    context.Context
+		cancel context.CancelFunc // ends the session, for FloodDisconnect
+		rate   int                // negotiations allowed per second, if set
+		limit  int                // negotiations allowed per session, if set
+		policy FloodPolicy
+
+		total    int
+		window   time.Time // the start of the current second
+		inWindow int       // the negotiations counted since window
+	}
+)
+
+// allow counts a negotiation from the client, reporting whether it should be acted on, or an error if the session
+// should end.
+func (g *floodGuard) allow() (bool, error) {
+	g.total++
+	if g.limit > 0 && g.total > g.limit {
+		if g.policy == FloodDisconnect {
+			return g.disconnect()
+		}
+
+		return false, nil
+	}
+
+	if g.rate <= 0 {
+		return true, nil
+	}
+
+	now := ContextClock(g.ctx).Now()
+	if now.Sub(g.window) >= time.Second {
+		g.window, g.inWindow = now, 0
+	}
+
+	if g.inWindow++; g.inWindow <= g.rate {
+		return true, nil
+	}
+
+	switch g.policy {
+	case FloodThrottle:
+		if err := Sleep(g.ctx, g.window.Add(time.Second).Sub(now)); err != nil {
+			return false, err
+		}
+
+		g.window, g.inWindow = g.window.Add(time.Second), 1
+
+		return true, nil
+	case FloodDisconnect:
+		return g.disconnect()
+	default:
+		return false, nil
+	}
+}
+
+func (g *floodGuard) disconnect() (bool, error) {
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	return false, ErrNegotiationFlood
+}
@@ -0,0 +1,106 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// steppingClock is a Clock whose timers fire straight away, moving its time forward as if they'd been waited for.
+type steppingClock struct {
+	now time.Time
+}
+
+func (c *steppingClock) Now() time.Time {
+	return c.now
+}
+
+func (c *steppingClock) NewTimer(d time.Duration) Timer {
+	c.now = c.now.Add(d)
+
+	fired := make(chan time.Time, 1)
+	fired <- c.now
+
+	return steppingTimer(fired)
+}
+
+func (c *steppingClock) AfterFunc(d time.Duration, f func()) Timer {
+	return steppingTimer(nil)
+}
+
+type steppingTimer chan time.Time
+
+func (t steppingTimer) C() <-chan time.Time        { return t }
+func (t steppingTimer) Stop() bool                 { return false }
+func (t steppingTimer) Reset(d time.Duration) bool { return false }
+
+func TestSession_NegotiationLimits(t *testing.T) {
+	flood := bytes.Repeat([]byte{IAC, DO, ECHO}, 5)
+
+	tests := []struct {
+		Server   *Server
+		Received int
+		Err      error
+	}{
+		{Server: NewServer(WithNegotiationLimits(2, 0, FloodIgnore)), Received: 2},
+		{Server: NewServer(WithNegotiationLimits(0, 3, FloodIgnore)), Received: 3},
+		{Server: NewServer(WithNegotiationLimits(0, 3, FloodThrottle)), Received: 3},
+		{Server: NewServer(WithNegotiationLimits(2, 0, FloodDisconnect)), Received: 2, Err: ErrNegotiationFlood},
+		{Server: NewServer(), Received: 5},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, test.Server)
+
+		var received int
+		session.reader.onOption = func(verb byte, option byte) {
+			received++
+		}
+
+		go client.Write(append(flood, 'a'))
+
+		buffer := make([]byte, 1)
+		_, err := session.Read(buffer)
+
+		if !errors.Is(err, test.Err) {
+			t.Errorf("For test #%d, expected %v, but actually got: (%T) %v.", testNumber, test.Err, err, err)
+		}
+
+		if test.Err != nil && session.Context().Err() == nil {
+			t.Errorf("For test #%d, expected the session to be ended, but it wasn't.", testNumber)
+		}
+
+		if expected, actual := test.Received, received; expected != actual {
+			t.Errorf("For test #%d, expected %d negotiations to be acted on, but actually got %d.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestFloodGuard_Throttle(t *testing.T) {
+	clock := &steppingClock{now: time.Unix(0, 0)}
+	guard := &floodGuard{ctx: withClock(context.Background(), clock), rate: 2, policy: FloodThrottle}
+
+	telnetReader := newReader(bytes.NewReader(append(bytes.Repeat([]byte{IAC, WILL, NAWS}, 5), 'a')))
+	telnetReader.allowNegotiation = guard.allow
+
+	var received int
+	telnetReader.onOption = func(verb byte, option byte) {
+		received++
+	}
+
+	if _, err := io.ReadAll(telnetReader); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// Every negotiation is acted on, but reading waits a second after every 2.
+	if expected, actual := 5, received; expected != actual {
+		t.Errorf("Expected %d negotiations to be acted on, but actually got %d.", expected, actual)
+	}
+
+	if expected, actual := 2*time.Second, clock.now.Sub(time.Unix(0, 0)); expected != actual {
+		t.Errorf("Expected reading to wait %v, but actually waited %v.", expected, actual)
+	}
+}
@@ -0,0 +1,10 @@
+// Package geoip looks up where TELNET clients connect from in MaxMind databases (GeoLite2 or GeoIP2), as a
+// telnet.Enricher:
+//
+//	enricher, err := geoip.Open("GeoLite2-City.mmdb", "GeoLite2-ASN.mmdb")
+//	server := telnet.NewServer(telnet.WithHandler(handler), telnet.WithEnricher(enricher))
+//
+// It's a module of its own, so the database reader isn't a dependency of programs that don't use it:
+//
+//	go get github.com/globalcyberalliance/telnet-go/geoip
+package geoip
@@ -0,0 +1,105 @@
+package geoip
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type (
+	// Enricher is a telnet.Enricher looking up clients' locations in a City database, and their networks in an ASN
+	// database.
+	Enricher struct {
+		city *maxminddb.Reader
+		asn  *maxminddb.Reader
+	}
+
+	// cityRecord is the part of a City (or Country) database record the Enricher uses.
+	cityRecord struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		City struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			Longitude float64 `maxminddb:"longitude"`
+		} `maxminddb:"location"`
+	}
+
+	// asnRecord is an ASN database record.
+	asnRecord struct {
+		Number       uint   `maxminddb:"autonomous_system_number"`
+		Organization string `maxminddb:"autonomous_system_organization"`
+	}
+)
+
+var _ telnet.Enricher = (*Enricher)(nil)
+
+// Open returns an Enricher using the City (or Country) database at 'cityPath', and the ASN database at 'asnPath'.
+// Either may be empty, to skip those details.
+func Open(cityPath string, asnPath string) (*Enricher, error) {
+	var enricher Enricher
+	var err error
+
+	if cityPath != "" {
+		if enricher.city, err = maxminddb.Open(cityPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if asnPath != "" {
+		if enricher.asn, err = maxminddb.Open(asnPath); err != nil {
+			_ = enricher.Close()
+			return nil, err
+		}
+	}
+
+	return &enricher, nil
+}
+
+// Enrich looks up 'ip' in the Enricher's databases.
+func (e *Enricher) Enrich(_ context.Context, ip net.IP) (telnet.Enrichment, error) {
+	var enrichment telnet.Enrichment
+
+	if e.city != nil {
+		var record cityRecord
+		if err := e.city.Lookup(ip, &record); err != nil {
+			return enrichment, err
+		}
+
+		enrichment.Country = record.Country.ISOCode
+		enrichment.City = record.City.Names["en"]
+		enrichment.Latitude = record.Location.Latitude
+		enrichment.Longitude = record.Location.Longitude
+	}
+
+	if e.asn != nil {
+		var record asnRecord
+		if err := e.asn.Lookup(ip, &record); err != nil {
+			return enrichment, err
+		}
+
+		enrichment.ASN = record.Number
+		enrichment.ASOrg = record.Organization
+	}
+
+	return enrichment, nil
+}
+
+// Close closes the Enricher's databases.
+func (e *Enricher) Close() error {
+	var errs []error
+
+	for _, reader := range []*maxminddb.Reader{e.city, e.asn} {
+		if reader != nil {
+			errs = append(errs, reader.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
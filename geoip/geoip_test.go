@@ -0,0 +1,204 @@
+package geoip
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+func TestEnricher(t *testing.T) {
+	_, network, _ := net.ParseCIDR("192.0.2.0/24")
+
+	cityPath := writeDatabase(t, "GeoLite2-City", network, map[string]any{
+		"country":  map[string]any{"iso_code": "IE"},
+		"city":     map[string]any{"names": map[string]any{"en": "Dublin", "ga": "Baile Átha Cliath"}},
+		"location": map[string]any{"latitude": 53.3498, "longitude": -6.2603},
+	})
+
+	asnPath := writeDatabase(t, "GeoLite2-ASN", network, map[string]any{
+		"autonomous_system_number":       uint32(64496),
+		"autonomous_system_organization": "Example Networks",
+	})
+
+	tests := []struct {
+		CityPath string
+		ASNPath  string
+		IP       string
+		Expected telnet.Enrichment
+	}{
+		{
+			CityPath: cityPath,
+			ASNPath:  asnPath,
+			IP:       "192.0.2.1",
+			Expected: telnet.Enrichment{
+				Country:   "IE",
+				City:      "Dublin",
+				Latitude:  53.3498,
+				Longitude: -6.2603,
+				ASN:       64496,
+				ASOrg:     "Example Networks",
+			},
+		},
+		{
+			// Either database can be skipped.
+			ASNPath:  asnPath,
+			IP:       "192.0.2.1",
+			Expected: telnet.Enrichment{ASN: 64496, ASOrg: "Example Networks"},
+		},
+		{
+			// Addresses the databases don't cover aren't an error, but there's nothing to say about them.
+			CityPath: cityPath,
+			ASNPath:  asnPath,
+			IP:       "198.51.100.1",
+		},
+	}
+
+	for testNumber, test := range tests {
+		enricher, err := Open(test.CityPath, test.ASNPath)
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		actual, err := enricher.Enrich(context.Background(), net.ParseIP(test.IP))
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected := test.Expected; expected != actual {
+			t.Errorf("For test #%d, expected %+v, but actually got %+v.", testNumber, expected, actual)
+		}
+
+		if err = enricher.Close(); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"), ""); err == nil {
+		t.Error("Expected an error opening a missing database, but did not actually get one.")
+	}
+}
+
+// writeDatabase writes a MaxMind DB (see https://maxmind.github.io/MaxMind-DB/) of 'databaseType' to a temporary
+// file, holding 'record' for the IPv4 'network', and returns its path.
+func writeDatabase(t *testing.T, databaseType string, network *net.IPNet, record map[string]any) string {
+	t.Helper()
+
+	// The search tree has a node for each bit of the network's prefix, each pointing to the next, or (for the last) to
+	// the record. The other branches lead nowhere, which is marked by the number of nodes.
+	prefix, _ := network.Mask.Size()
+	ip := binary.BigEndian.Uint32(network.IP.To4())
+	nodeCount := uint32(prefix)
+
+	var database []byte
+	for node := uint32(0); node < nodeCount; node++ {
+		next := node + 1
+		if next == nodeCount {
+			next = nodeCount + 16 // the record, at the start of the data section
+		}
+
+		records := [2]uint32{nodeCount, nodeCount}
+		records[ip>>(31-node)&1] = next
+
+		for _, r := range records {
+			database = append(database, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+
+	database = append(database, make([]byte, 16)...)
+	database = append(database, encode(record)...)
+	database = append(database, "\xab\xcd\xefMaxMind.com"...)
+	database = append(database, encode(map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               databaseType,
+		"description":                 map[string]any{"en": "Test " + databaseType},
+		"ip_version":                  uint16(4),
+		"languages":                   []any{"en"},
+		"node_count":                  nodeCount,
+		"record_size":                 uint16(24),
+	})...)
+
+	path := filepath.Join(t.TempDir(), databaseType+".mmdb")
+	if err := os.WriteFile(path, database, 0o600); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	return path
+}
+
+// encode encodes 'value' in the MaxMind DB data section format.
+func encode(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(control(3, 8), math.Float64bits(v))
+	case uint16:
+		return encodeUint(5, uint64(v))
+	case uint32:
+		return encodeUint(6, uint64(v))
+	case uint64:
+		return encodeUint(9, v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		encoded := control(7, len(v))
+		for _, key := range keys {
+			encoded = append(append(encoded, encode(key)...), encode(v[key])...)
+		}
+
+		return encoded
+	case []any:
+		encoded := control(11, len(v))
+		for _, element := range v {
+			encoded = append(encoded, encode(element)...)
+		}
+
+		return encoded
+	default:
+		panic("geoip: can't encode a value of this type")
+	}
+}
+
+// encodeUint encodes the unsigned integer 'value' of 'dataType' in as few bytes as it takes.
+func encodeUint(dataType byte, value uint64) []byte {
+	var encoded []byte
+	for ; value > 0; value >>= 8 {
+		encoded = append([]byte{byte(value)}, encoded...)
+	}
+
+	return append(control(dataType, len(encoded)), encoded...)
+}
+
+// control returns the control byte(s) starting a field of 'dataType' and 'size', which must be under 285.
+func control(dataType byte, size int) []byte {
+	var first byte
+	var extra []byte
+
+	if dataType <= 7 {
+		first = dataType << 5
+	} else {
+		extra = append(extra, dataType-7)
+	}
+
+	if size < 29 {
+		first |= byte(size)
+	} else {
+		first |= 29
+		extra = append(extra, byte(size-29))
+	}
+
+	return append([]byte{first}, extra...)
+}
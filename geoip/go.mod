@@ -0,0 +1,12 @@
+module github.com/globalcyberalliance/telnet-go/geoip
+
+go 1.22.2
+
+require (
+	github.com/globalcyberalliance/telnet-go v0.0.0-00010101000000-000000000000
+	github.com/oschwald/maxminddb-golang v1.13.1
+)
+
+require golang.org/x/sys v0.28.0 // indirect
+
+replace github.com/globalcyberalliance/telnet-go => ../
@@ -1,3 +1,10 @@
 module github.com/globalcyberalliance/telnet-go
 
 go 1.22.2
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0 // indirect
+)
@@ -0,0 +1,100 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+)
+
+// ctrlZ is the control character suspending a command (see Session.WithJobControl).
+const ctrlZ byte = 26
+
+// interrupter cancels a context returned by Session.WithInterrupt (or WithJobControl).
+type interrupter struct {
+	cancel context.CancelCauseFunc
+}
+
+// WithInterrupt returns a copy of 'parent' that's cancelled with the cause ErrInterrupted (see context.Cause) when the
+// client interrupts: it sends IAC IP or IAC BRK, or presses Ctrl-C in character-at-a-time mode. This lets the handler
+// abort a long-running command like a real device would.
+//
+// Until the returned CancelFunc is called, the session reads from the client in the background to see the interrupt,
+// keeping anything else it sends for the next Read, so the handler mustn't read from the session meanwhile. The
+// CancelFunc waits for the background read to stop.
+func (s *Session) WithInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	return s.withInterrupt(parent, false)
+}
+
+// WithJobControl is like WithInterrupt, but the returned context is also cancelled, with the cause ErrSuspended, when
+// the client presses Ctrl-Z to suspend the command (like a shell with job control would stop it).
+func (s *Session) WithJobControl(parent context.Context) (context.Context, context.CancelFunc) {
+	return s.withInterrupt(parent, true)
+}
+
+// withInterrupt implements WithInterrupt, and WithJobControl if 'suspend' is set.
+func (s *Session) withInterrupt(parent context.Context, suspend bool) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	watcher := &interrupter{cancel: cancel}
+
+	s.interruptMu.Lock()
+	s.interrupters = append(s.interrupters, watcher)
+	s.interruptMu.Unlock()
+
+	controls := string(ctrlC)
+	if suspend {
+		controls += string(ctrlZ)
+	}
+
+	watching, stop := context.WithCancel(ctx)
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		from := len(s.pending)
+		_ = s.await(watching, func() bool {
+			// Ctrl-C is the interrupt itself (and Ctrl-Z the suspension), so it's dropped from the input.
+			if i := bytes.IndexAny(s.pending[from:], controls); i >= 0 {
+				control := s.pending[from+i]
+				s.pending = append(s.pending[:from+i], s.pending[from+i+1:]...)
+
+				if control == ctrlZ {
+					watcher.cancel(ErrSuspended)
+				} else {
+					s.interrupt()
+				}
+
+				return true
+			}
+
+			from = len(s.pending)
+
+			return false
+		})
+	}()
+
+	return ctx, func() {
+		stop()
+		<-finished
+		cancel(context.Canceled)
+
+		s.interruptMu.Lock()
+		defer s.interruptMu.Unlock()
+
+		for i, registered := range s.interrupters {
+			if registered == watcher {
+				s.interrupters = append(s.interrupters[:i], s.interrupters[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// interrupt cancels the contexts returned by WithInterrupt, as the client has interrupted.
+func (s *Session) interrupt() {
+	s.interruptMu.Lock()
+	defer s.interruptMu.Unlock()
+
+	for _, watcher := range s.interrupters {
+		watcher.cancel(ErrInterrupted)
+	}
+}
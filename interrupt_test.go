@@ -0,0 +1,114 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSession_WithInterrupt(t *testing.T) {
+	tests := []struct {
+		Input     []byte
+		Interrupt bool
+	}{
+		{Input: []byte{'a', IAC, IP}, Interrupt: true},
+		{Input: []byte{'a', IAC, BRK}, Interrupt: true},
+		{Input: []byte{'a', ctrlC}, Interrupt: true},
+		{Input: []byte{'a'}},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, &Server{})
+
+		ctx, cancel := session.WithInterrupt(context.Background())
+
+		go client.Write(append(test.Input, 'b'))
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if interrupted := errors.Is(context.Cause(ctx), ErrInterrupted); test.Interrupt != interrupted {
+			t.Errorf("For test #%d, expected the context to be interrupted (%t), but actually got: %v.", testNumber, test.Interrupt, context.Cause(ctx))
+		}
+
+		cancel()
+
+		// The rest of the client's input is kept for the next read.
+		buffer := make([]byte, 1)
+		if _, err := session.Read(buffer); err != nil || buffer[0] != 'a' {
+			t.Errorf("For test #%d, expected %q, but actually got %q (%v).", testNumber, "a", buffer, err)
+		}
+
+		if _, err := session.Read(buffer); err != nil || buffer[0] != 'b' {
+			t.Errorf("For test #%d, expected %q, but actually got %q (%v).", testNumber, "b", buffer, err)
+		}
+	}
+}
+
+func TestSession_WithInterruptThenRead(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	// Reading after an interrupt mustn't fail on a deadline left over from interrupting the background read.
+	for i := 0; i < 20; i++ {
+		ctx, cancel := session.WithInterrupt(context.Background())
+
+		if _, err := client.Write([]byte{IAC, IP}); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		<-ctx.Done()
+		cancel()
+
+		go client.Write([]byte("ls"))
+
+		data := make([]byte, 2)
+		if _, err := io.ReadFull(session, data); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		if expected, actual := "ls", string(data); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+	}
+}
+
+func TestSession_WithJobControl(t *testing.T) {
+	tests := []struct {
+		Input    []byte
+		Expected error
+	}{
+		{Input: []byte{'a', ctrlZ}, Expected: ErrSuspended},
+		{Input: []byte{'a', ctrlC}, Expected: ErrInterrupted},
+		{Input: []byte{'a', IAC, IP}, Expected: ErrInterrupted},
+		{Input: []byte{'a'}, Expected: context.Canceled},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, &Server{})
+
+		ctx, cancel := session.WithJobControl(context.Background())
+
+		go client.Write(append(test.Input, 'b'))
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+
+		if actual := context.Cause(ctx); !errors.Is(actual, test.Expected) {
+			t.Errorf("For test #%d, expected %v, but actually got %v.", testNumber, test.Expected, actual)
+		}
+
+		// The rest of the client's input is kept for the next read, without the Ctrl-Z.
+		data := make([]byte, 2)
+		if _, err := io.ReadFull(session, data); err != nil || string(data) != "ab" {
+			t.Errorf("For test #%d, expected %q, but actually got %q (%v).", testNumber, "ab", data, err)
+		}
+	}
+}
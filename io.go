@@ -1,8 +1,10 @@
 package telnet
 
 import (
+	"context"
 	"errors"
 	"io"
+	"time"
 )
 
 // LongWrite attempts to write the bytes from 'p' to the writer 'w', handling
@@ -20,3 +22,101 @@ func LongWrite(w io.Writer, p []byte) (int64, error) {
 	}
 	return numWritten, nil
 }
+
+// LongWriteContext behaves like LongWrite, but gives up once 'ctx' is done, returning ctx.Err().
+//
+// If 'w' supports write deadlines (such as a net.Conn), the deadline of 'ctx' is applied to the write, and a write
+// that's blocked on a stalled peer is interrupted as soon as 'ctx' is cancelled. The write deadline is cleared again
+// before returning.
+func LongWriteContext(ctx context.Context, w io.Writer, p []byte) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if conn, ok := w.(writeDeadliner); ok {
+		if deadline, ok := ctx.Deadline(); ok {
+			if err := conn.SetWriteDeadline(deadline); err != nil {
+				return 0, err
+			}
+		}
+
+		defer interruptOnDone(ctx, conn.SetWriteDeadline)()
+	}
+
+	var numWritten int64
+	for len(p) > 0 {
+		n, err := w.Write(p)
+		numWritten += int64(n)
+		if err != nil && !errors.Is(err, io.ErrShortWrite) {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return numWritten, ctxErr
+			}
+
+			// The write deadline may fire a moment before the context notices its own deadline has passed.
+			if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+				return numWritten, context.DeadlineExceeded
+			}
+
+			return numWritten, err
+		}
+
+		p = p[n:]
+
+		if err = ctx.Err(); err != nil && len(p) > 0 {
+			return numWritten, err
+		}
+	}
+	return numWritten, nil
+}
+
+// writeDeadliner is implemented by writers supporting write deadlines, such as net.Conn.
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// timeoutWriter applies a fresh write deadline before every write, so a peer that stops reading (e.g. with a zero
+// TCP window) can't block a writer forever. Once 'ctx' (if set) is done, writes fail with ctx.Err(), rather than
+// replace the deadline in the past set to interrupt them (see Session.watch).
+type timeoutWriter struct {
+	ctx     context.Context
+	conn    io.Writer
+	timeout time.Duration
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	if conn, ok := w.conn.(writeDeadliner); ok && w.timeout > 0 {
+		if err := conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	// Checked after setting the deadline, in case it replaced the one set as the context ended.
+	if w.ctx != nil {
+		if err := w.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return w.conn.Write(p)
+}
+
+// interruptOnDone sets a deadline in the past with 'setDeadline' once 'ctx' is done, interrupting a blocked read or
+// write, until the returned function is called, which clears the deadline. If 'ctx' is done meanwhile, it waits for
+// the deadline to be set first, so it's never left set.
+func interruptOnDone(ctx context.Context, setDeadline func(time.Time) error) (stop func()) {
+	interrupted := make(chan struct{})
+
+	stopInterrupt := context.AfterFunc(ctx, func() {
+		defer close(interrupted)
+
+		_ = setDeadline(time.Unix(1, 0))
+	})
+
+	return func() {
+		if !stopInterrupt() {
+			<-interrupted
+		}
+
+		_ = setDeadline(time.Time{})
+	}
+}
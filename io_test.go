@@ -0,0 +1,77 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLongWriteContext_Cancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	// Nobody reads from the other end of the pipe, so this blocks until the context is cancelled.
+	n, err := LongWriteContext(ctx, server, []byte("apple"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", context.Canceled, err, err)
+	}
+
+	if n != 0 {
+		t.Errorf("Expected 0 bytes to be written, but actually got %d.", n)
+	}
+}
+
+func TestLongWriteContext_Deadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := LongWriteContext(ctx, server, []byte("apple")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", context.DeadlineExceeded, err, err)
+	}
+}
+
+func TestTimeoutWriter(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	w := &timeoutWriter{conn: server, timeout: 10 * time.Millisecond}
+
+	var netErr net.Error
+	if _, err := w.Write([]byte("apple")); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected a timeout error, but actually got: (%T) %v.", err, err)
+	}
+}
+
+func TestLongWriteContext_DeadlineBeforeContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// The write deadline fires, but the context hasn't noticed its own deadline has passed yet.
+	ctx := &lateContext{Context: context.Background(), deadline: time.Now().Add(10 * time.Millisecond)}
+
+	if _, err := LongWriteContext(ctx, server, []byte("apple")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", context.DeadlineExceeded, err, err)
+	}
+}
+
+// lateContext has a deadline, but is never done.
+type lateContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *lateContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
@@ -0,0 +1,66 @@
+package telnet
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"time"
+)
+
+// pacedChunkSize is the number of bytes a pacedWriter writes at a time, so output trickles out rather than arriving
+// in bursts after long pauses.
+const pacedChunkSize = 16
+
+type (
+	// Latency is a delay with random jitter, used to make an emulated device respond less suspiciously quickly.
+	Latency struct {
+		// Fixed is always waited.
+		Fixed time.Duration
+
+		// Jitter is the most extra time randomly waited on top of Fixed.
+		Jitter time.Duration
+	}
+
+	// pacedWriter delays every byte written to 'writer', emulating a slow device or link.
+	pacedWriter struct {
+		ctx     context.Context // optional; cancels the delays when done
+		writer  io.Writer
+		perByte Latency
+	}
+)
+
+// Duration returns a delay: Fixed plus a random duration up to Jitter.
+func (l Latency) Duration() time.Duration {
+	if l.Jitter <= 0 {
+		return l.Fixed
+	}
+
+	return l.Fixed + rand.N(l.Jitter)
+}
+
+// Sleep waits for a delay (see Duration) by the context's Clock, returning early with the context's error if 'ctx' is
+// done first.
+func (l Latency) Sleep(ctx context.Context) error {
+	return Sleep(ctx, l.Duration())
+}
+
+func (w *pacedWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p[:min(len(p), pacedChunkSize)]
+
+		if err = Sleep(w.ctx, time.Duration(len(chunk))*w.perByte.Duration()); err != nil {
+			return n, err
+		}
+
+		written, err := w.writer.Write(chunk)
+		n += written
+
+		if err != nil {
+			return n, err
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatency_Duration(t *testing.T) {
+	tests := []Latency{
+		{},
+		{Fixed: time.Millisecond},
+		{Jitter: time.Millisecond},
+		{Fixed: time.Second, Jitter: time.Millisecond},
+	}
+
+	for testNumber, test := range tests {
+		for i := 0; i < 100; i++ {
+			if actual := test.Duration(); actual < test.Fixed || actual > test.Fixed+test.Jitter {
+				t.Errorf("For test #%d, expected a duration between %v and %v, but actually got %v.", testNumber, test.Fixed, test.Fixed+test.Jitter, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestPacedWriter(t *testing.T) {
+	var output bytes.Buffer
+
+	data := bytes.Repeat([]byte("a"), 100)
+	writer := &pacedWriter{ctx: context.Background(), writer: &output, perByte: Latency{Fixed: 100 * time.Microsecond}}
+
+	start := time.Now()
+
+	n, err := writer.Write(data)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Expected the write to take at least %v, but actually took %v.", 10*time.Millisecond, elapsed)
+	}
+
+	if n != len(data) || !bytes.Equal(data, output.Bytes()) {
+		t.Errorf("Expected %d bytes to be written, but actually got %d (%q).", len(data), n, output.Bytes())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer = &pacedWriter{ctx: ctx, writer: &output, perByte: Latency{Fixed: time.Hour}}
+	if n, err = writer.Write(data); err != context.Canceled || n != 0 {
+		t.Errorf("Expected %v after 0 bytes, but actually got: (%T) %v after %d.", context.Canceled, err, err, n)
+	}
+}
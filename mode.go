@@ -0,0 +1,76 @@
+package telnet
+
+const (
+	// InputLine has the client edit and echo lines itself, sending each once ENTER is pressed: the NVT default, and
+	// what most clients do unless the server negotiates otherwise.
+	InputLine InputMode = iota
+
+	// InputChar has the client send each key as it's pressed, without echoing it (by offering to ECHO and suppress
+	// go-ahead), so the session echoes it instead. ReadLine then edits lines like EditLine, and Read returns keys as
+	// they're pressed.
+	InputChar
+)
+
+// InputMode is how a client sends its input to the session (see Session.SetInputMode).
+type InputMode int
+
+// InputMode returns how the client is sending its input (see SetInputMode).
+func (s *Session) InputMode() InputMode {
+	return s.inputMode
+}
+
+// SetInputMode negotiates with the client to send its input according to 'mode', and changes how the session reads it
+// to match. Sessions start in the server's input mode (see WithInputMode); EditLine and Page switch the client to
+// character-at-a-time input while they run, without changing the session's mode.
+//
+// In InputChar mode, clients end lines with CR LF or CR NUL as in InputLine mode, but Read returns ENTER as a single CR,
+// so each key press can be handled as it's read.
+func (s *Session) SetInputMode(mode InputMode) error {
+	switch mode {
+	case InputChar:
+		if err := s.enableCharacterMode(); err != nil {
+			return err
+		}
+
+		// LINEMODE clients edit lines locally, so stop them.
+		if s.options.remote(LINEMODE) {
+			if _, err := s.WriteCommand(IAC, DONT, LINEMODE); err != nil {
+				return err
+			}
+		}
+	case InputLine:
+		for _, option := range []byte{ECHO, SGA} {
+			if !s.options.offered(option) {
+				continue
+			}
+
+			if _, err := s.WriteCommand(IAC, WONT, option); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.inputMode = mode
+	s.readCR = false
+
+	return nil
+}
+
+// collapseEnter drops the LF or NUL clients send after the CR of ENTER from 'data' in place (including when it comes
+// in the next read), returning the length left.
+func (s *Session) collapseEnter(data []byte) int {
+	n := 0
+
+	for _, value := range data {
+		if s.readCR && (value == NL || value == NUL) {
+			s.readCR = false
+			continue
+		}
+
+		s.readCR = value == CR
+		data[n] = value
+		n++
+	}
+
+	return n
+}
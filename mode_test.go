@@ -0,0 +1,68 @@
+package telnet
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSession_SetInputMode(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	output := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(client)
+		output <- data
+	}()
+
+	if err := session.SetInputMode(InputChar); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	go client.Write([]byte("ab\b\x7fcd\r\x00e\r\nf"))
+
+	// The line is edited and echoed by the session.
+	line, err := session.ReadLine()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "cd", line; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	// Keys are read as they're pressed, with ENTER as a single CR.
+	var keys []byte
+	buffer := make([]byte, 1)
+
+	for len(keys) < 3 {
+		n, err := session.Read(buffer)
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		keys = append(keys, buffer[:n]...)
+	}
+
+	if expected, actual := "e\rf", string(keys); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if err = session.SetInputMode(InputLine); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := InputLine, session.InputMode(); expected != actual {
+		t.Errorf("Expected %v, but actually got %v.", expected, actual)
+	}
+
+	if err = session.Flush(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	session.Conn.Close()
+
+	expected := string([]byte{IAC, WILL, ECHO, IAC, WILL, SGA}) + "ab\b \b\b \bcd\r\n" + string([]byte{IAC, WONT, ECHO, IAC, WONT, SGA})
+	if actual := string(<-output); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
@@ -0,0 +1,128 @@
+package telnet
+
+import "sync"
+
+type (
+	// Negotiation is a negotiation command sent to a client, e.g. Negotiation{WILL, ECHO} for IAC WILL ECHO.
+	Negotiation struct {
+		Verb   byte // WILL, WONT, DO or DONT
+		Option byte
+	}
+
+	// options tracks which TELNET options have been agreed by both ends of a connection.
+	options struct {
+		states     [256]optionState
+		negotiated bool // whether the peer has sent any negotiation, i.e. it speaks TELNET
+		mu         sync.Mutex
+	}
+
+	// optionState records the last negotiation sent and received for a single option.
+	optionState struct {
+		weWill   bool // We've offered to perform the option.
+		theyDo   bool // They've asked us to perform the option.
+		theyWill bool // They've offered to perform the option.
+		weDo     bool // We've asked them to perform the option.
+
+		willPending bool // We've sent WILL or WONT, and they haven't answered with DO or DONT yet.
+		doPending   bool // We've sent DO or DONT, and they haven't answered with WILL or WONT yet.
+		askedRemote bool // The last negotiation we sent was DO or DONT, rather than WILL or WONT.
+	}
+)
+
+// sent records a negotiation command we've sent to the peer.
+func (o *options) sent(verb byte, option byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := &o.states[option]
+	switch verb {
+	case WILL, WONT:
+		state.weWill = verb == WILL
+		state.willPending = true
+		state.askedRemote = false
+	case DO, DONT:
+		state.weDo = verb == DO
+		state.doPending = true
+		state.askedRemote = true
+	}
+}
+
+// received records a negotiation command the peer has sent to us.
+func (o *options) received(verb byte, option byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.negotiated = true
+
+	state := &o.states[option]
+	switch verb {
+	case WILL, WONT:
+		state.theyWill = verb == WILL
+		state.doPending = false
+	case DO, DONT:
+		state.theyDo = verb == DO
+		state.willPending = false
+	}
+}
+
+// local reports whether we've agreed to perform 'option'.
+func (o *options) local(option byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.states[option].weWill && o.states[option].theyDo
+}
+
+// remote reports whether the peer has agreed to perform 'option'.
+func (o *options) remote(option byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.states[option].theyWill && o.states[option].weDo
+}
+
+// offered reports whether we've offered to perform 'option', regardless of whether the peer has agreed.
+func (o *options) offered(option byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.states[option].weWill
+}
+
+// requested reports whether we've asked the peer to perform 'option', regardless of whether it has agreed.
+func (o *options) requested(option byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.states[option].weDo
+}
+
+// answered reports whether the peer has answered the last negotiation we sent for 'option', and if so, whether the
+// option is now enabled on the side it negotiated.
+func (o *options) answered(option byte) (answered bool, enabled bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.states[option]
+	if state.askedRemote {
+		return !state.doPending, state.theyWill && state.weDo
+	}
+
+	return !state.willPending, state.weWill && state.theyDo
+}
+
+// pending reports whether the peer has yet to answer a negotiation we've sent for 'option', on either side.
+func (o *options) pending(option byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.states[option].willPending || o.states[option].doPending
+}
+
+// peerNegotiated reports whether the peer has sent any negotiation, so will answer ours.
+func (o *options) peerNegotiated() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.negotiated
+}
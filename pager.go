@@ -0,0 +1,123 @@
+package telnet
+
+import (
+	"strings"
+)
+
+const (
+	// DefaultPageHeight is the number of lines per page, including the prompt, when the client's window height isn't
+	// known.
+	DefaultPageHeight = 24
+
+	// DefaultMorePrompt is shown after each page of output, unless configured otherwise.
+	DefaultMorePrompt = "--More--"
+)
+
+// Pager configures how Session.Page breaks output into pages.
+type Pager struct {
+	// Height is the number of lines per page, including the prompt line. If unset, the client's window height is used
+	// (see WindowSize), or DefaultPageHeight if it hasn't sent one. A negative height disables paging.
+	Height int
+
+	// Prompt is shown after each page; DefaultMorePrompt if empty.
+	Prompt string
+}
+
+// Page writes 'output' a page at a time, showing a prompt after each page and waiting for a key press: space shows
+// the next page, Enter the next line, and q (or any other key) discards the rest of the output. The client is switched
+// to character-at-a-time mode, like EditLine. The pager may be nil.
+func (s *Session) Page(output string, pager *Pager) error {
+	if pager == nil {
+		pager = &Pager{}
+	}
+
+	height := pager.Height
+	if height == 0 {
+		if _, windowHeight, ok := s.WindowSize(); ok && windowHeight > 1 {
+			height = windowHeight
+		} else {
+			height = DefaultPageHeight
+		}
+	}
+
+	lines := strings.SplitAfter(output, "\n")
+	if height < 2 || len(lines) < height {
+		return s.WriteString(output)
+	}
+
+	if err := s.enableCharacterMode(); err != nil {
+		return err
+	}
+
+	prompt := pager.Prompt
+	if prompt == "" {
+		prompt = DefaultMorePrompt
+	}
+
+	// Leave room for the prompt on the last line of the window.
+	count := height - 1
+
+	for {
+		count = min(count, len(lines))
+		if err := s.WriteString(strings.Join(lines[:count], "")); err != nil {
+			return err
+		}
+
+		if lines = lines[count:]; len(lines) == 0 || (len(lines) == 1 && lines[0] == "") {
+			return nil
+		}
+
+		if err := s.WriteString(prompt); err != nil {
+			return err
+		}
+
+		key, err := s.readKey()
+		if err != nil {
+			return err
+		}
+
+		erase := strings.Repeat("\b", len(prompt))
+		if err = s.WriteString(erase, strings.Repeat(" ", len(prompt)), erase); err != nil {
+			return err
+		}
+
+		switch key {
+		case ' ':
+			count = height - 1
+		case CR, NL:
+			count = 1
+		default:
+			return nil
+		}
+	}
+}
+
+// readKey reads a single key press from the client, skipping the LF or NUL clients send after CR.
+func (s *Session) readKey() (byte, error) {
+	var buffer [1]byte
+	p := buffer[:]
+
+	for {
+		n, err := s.Read(p)
+		if err != nil {
+			return 0, err
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		value := p[0]
+
+		if s.editedCR {
+			s.editedCR = false
+			if value == NL || value == NUL {
+				continue
+			}
+		}
+
+		s.editedCR = value == CR
+
+		return value, nil
+	}
+}
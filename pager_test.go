@@ -0,0 +1,66 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSession_Page(t *testing.T) {
+	characterMode := string([]byte{IAC, WILL, ECHO, IAC, WILL, SGA})
+	erase := strings.Repeat("\b", 8) + strings.Repeat(" ", 8) + strings.Repeat("\b", 8)
+	output := "1\r\n2\r\n3\r\n4\r\n5\r\n"
+
+	tests := []struct {
+		Pager    *Pager
+		Window   windowSize
+		Keys     string
+		Expected string
+	}{
+		{
+			Pager:    &Pager{Height: 10},
+			Expected: output,
+		},
+		{
+			Pager:    &Pager{Height: -1},
+			Expected: output,
+		},
+		{
+			Pager:    &Pager{Height: 3},
+			Keys:     " \r\n",
+			Expected: characterMode + "1\r\n2\r\n--More--" + erase + "3\r\n4\r\n--More--" + erase + "5\r\n",
+		},
+		{
+			Pager:    &Pager{Height: 3, Prompt: "--Next--"},
+			Keys:     "q",
+			Expected: characterMode + "1\r\n2\r\n--Next--" + erase,
+		},
+		{
+			Pager:    nil,
+			Window:   windowSize{width: 80, height: 4, received: true},
+			Keys:     "\r\x00\r\x00",
+			Expected: characterMode + "1\r\n2\r\n3\r\n--More--" + erase + "4\r\n--More--" + erase + "5\r\n",
+		},
+	}
+
+	for testNumber, test := range tests {
+		var actual bytes.Buffer
+
+		session := &Session{
+			ctx:    context.Background(),
+			reader: newReader(strings.NewReader(test.Keys)),
+			writer: newWriter(&actual),
+			window: test.Window,
+		}
+
+		if err := session.Page(output, test.Pager); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected := test.Expected; expected != actual.String() {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual.String())
+		}
+	}
+}
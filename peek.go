@@ -0,0 +1,76 @@
+package telnet
+
+import (
+	"context"
+	"time"
+)
+
+// Buffered returns the number of bytes of input the session has received but not yet read, which the next reads
+// return without waiting on the client, e.g. to tell whether the user has typed ahead (and skip an animation). The
+// TELNET commands received are processed as they're reached, as they are by Read.
+func (s *Session) Buffered() int {
+	s.bufferInput()
+
+	return len(s.pending)
+}
+
+// Peek returns the next 'n' bytes of input without consuming them, so they're still returned by the next reads, e.g.
+// to look ahead while parsing escape sequences. Like Read, it flushes buffered output first, and waits for the client
+// to send them; if reading fails first, it returns the bytes it has with the error. The bytes are only valid until the
+// next read.
+func (s *Session) Peek(n int) ([]byte, error) {
+	if s.ctx.Err() != nil {
+		return nil, ErrSessionClosed
+	}
+
+	if err := s.Flush(); err != nil {
+		return nil, s.closedErr(err)
+	}
+
+	s.bufferInput()
+
+	for len(s.pending) < n {
+		data := make([]byte, max(n-len(s.pending), defaultBufferSize))
+		buffered := len(s.pending)
+
+		read, err := s.readClient(context.Background(), data)
+
+		// Injected input that didn't fit in 'data' was kept in pending, which must come after what did.
+		s.pending = append(s.pending[:buffered], append(data[:read], s.pending[buffered:]...)...)
+
+		if err != nil {
+			return s.pending, s.closedErr(err)
+		}
+	}
+
+	return s.pending[:n], nil
+}
+
+// bufferInput decodes the input the session has already received (and any injected by viewers) into pending, without
+// waiting on the client. A TELNET sequence that's only partly been received is left until the rest is.
+func (s *Session) bufferInput() {
+	s.pending = append(s.pending, s.takeInjected()...)
+
+	if s.reader == nil || s.reader.buffered.Buffered() == 0 || s.Conn == nil || s.hijacked.Load() {
+		return
+	}
+
+	// Hold off inject interrupting reads with a deadline of its own, while this one's set.
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	// Reading from the connection fails at once, rather than waiting, if the rest of a sequence is needed.
+	_ = s.Conn.SetReadDeadline(time.Unix(1, 0))
+
+	defer s.restoreReadDeadline()
+
+	data := make([]byte, s.reader.buffered.Buffered())
+	for s.reader.buffered.Buffered() > 0 {
+		n, err := s.reader.pump(data)
+		s.pending = append(s.pending, data[:n]...)
+
+		if err != nil {
+			return
+		}
+	}
+}
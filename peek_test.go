@@ -0,0 +1,68 @@
+package telnet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSession_BufferedAndPeek(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	// The last IAC is the start of an escaped IAC that's only partly been sent.
+	go client.Write([]byte{'h', 'e', 'l', 'l', 'o', IAC, IAC, 'x', IAC})
+
+	data := make([]byte, 2)
+	if _, err := session.Read(data); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := 5, session.Buffered(); expected != actual {
+		t.Fatalf("Expected %d, but actually got %d.", expected, actual)
+	}
+
+	go client.Write([]byte{IAC, 'y', 'z'})
+
+	peeked, err := session.Peek(8)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := []byte{'l', 'l', 'o', IAC, 'x', IAC, 'y', 'z'}
+	if !bytes.Equal(expected, peeked) {
+		t.Fatalf("Expected %q, but actually got %q.", expected, peeked)
+	}
+
+	// Peeking doesn't consume the input.
+	data = make([]byte, 16)
+	n, err := session.Read(data)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if !bytes.Equal(expected, data[:n]) {
+		t.Fatalf("Expected %q, but actually got %q.", expected, data[:n])
+	}
+
+	if expected, actual := 0, session.Buffered(); expected != actual {
+		t.Fatalf("Expected %d, but actually got %d.", expected, actual)
+	}
+}
+
+func TestSession_PeekInjected(t *testing.T) {
+	session, _ := newTestSession(t, &Server{})
+
+	session.inject([]byte("typed"))
+
+	if expected, actual := 5, session.Buffered(); expected != actual {
+		t.Fatalf("Expected %d, but actually got %d.", expected, actual)
+	}
+
+	peeked, err := session.Peek(3)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "typ", string(peeked); expected != actual {
+		t.Fatalf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
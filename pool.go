@@ -0,0 +1,297 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHealthTimeout bounds each health probe of a BackendPool, unless PoolOptions.HealthTimeout is set.
+const defaultHealthTimeout = 5 * time.Second
+
+const (
+	// RoundRobin spreads sessions evenly across a BackendPool's backends, taking turns.
+	RoundRobin BalanceStrategy = iota
+
+	// LeastConnections sends each session to the BackendPool's backend with the fewest sessions relayed to it.
+	LeastConnections
+)
+
+type (
+	// BalanceStrategy picks which of a BackendPool's backends is tried first for each session.
+	BalanceStrategy int
+
+	// PoolOptions configures a BackendPool.
+	PoolOptions struct {
+		// Strategy picks the backend tried first for each session; RoundRobin by default.
+		Strategy BalanceStrategy
+
+		// HealthInterval is how often each backend is probed, taking those failing out of rotation until they pass
+		// again. Probes are disabled if it's 0.
+		HealthInterval time.Duration
+
+		// HealthTimeout bounds each probe, including waiting for Banner; 5 seconds if unset.
+		HealthTimeout time.Duration
+
+		// Banner, if set, must appear in a backend's output (e.g. its login prompt) for it to pass a probe. Otherwise,
+		// being able to connect is enough.
+		Banner string
+
+		// Dial, if set, connects to backends instead of dialling TCP, e.g. (&tls.Dialer{}).DialContext.
+		Dial func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+		// Logger logs backends failing (and passing again); slog.Default() if unset.
+		Logger *slog.Logger
+	}
+
+	// BackendPool balances connections across a set of backend TELNET servers, probing their health. Use it with
+	// ProxyPoolHandler, and Close it once done.
+	BackendPool struct {
+		opts     PoolOptions
+		logger   *slog.Logger
+		backends []*poolBackend
+
+		mu   sync.Mutex // guards next, and each backend's state
+		next int
+
+		done      chan struct{}
+		closeOnce sync.Once
+	}
+
+	// poolBackend is a BackendPool's backend server.
+	poolBackend struct {
+		addr    string
+		healthy bool
+		conns   int
+	}
+
+	// poolConn is a connection to a BackendPool's backend, released back to the pool once closed.
+	poolConn struct {
+		net.Conn
+		release sync.Once
+		pool    *BackendPool
+		backend *poolBackend
+	}
+)
+
+// NewBackendPool returns a BackendPool of the backends at 'addrs', all of which are assumed healthy until probed. If
+// opts.HealthInterval is set, they're probed straight away, then periodically until Close is called.
+func NewBackendPool(addrs []string, opts PoolOptions) *BackendPool {
+	if opts.Dial == nil {
+		opts.Dial = (&net.Dialer{Timeout: defaultProxyDialTimeout}).DialContext
+	}
+
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = defaultHealthTimeout
+	}
+
+	pool := &BackendPool{
+		opts:   opts,
+		logger: opts.Logger,
+		done:   make(chan struct{}),
+	}
+
+	if pool.logger == nil {
+		pool.logger = slog.Default()
+	}
+
+	for _, addr := range addrs {
+		pool.backends = append(pool.backends, &poolBackend{addr: addr, healthy: true})
+	}
+
+	if opts.HealthInterval > 0 {
+		go pool.probeLoop()
+	}
+
+	return pool
+}
+
+// Close stops probing the pool's backends. Connections already made carry on until they're closed.
+func (p *BackendPool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+
+	return nil
+}
+
+// Dial connects to one of the pool's backends, chosen by its strategy, failing over to the others in turn if it can't
+// be reached. Backends failing their probes are only tried if every backend is. The connection counts towards its
+// backend's sessions (see LeastConnections) until it's closed.
+func (p *BackendPool) Dial(ctx context.Context) (net.Conn, error) {
+	var lastErr error
+
+	for _, backend := range p.candidates() {
+		conn, err := p.opts.Dial(ctx, "tcp", backend.addr)
+		if err != nil {
+			lastErr = err
+
+			// A backend we can't connect to is down, but without probes there's no telling when it's back up.
+			if p.opts.HealthInterval > 0 {
+				p.setHealthy(backend, err)
+			}
+
+			if ctx.Err() != nil {
+				break
+			}
+
+			continue
+		}
+
+		p.mu.Lock()
+		backend.conns++
+		p.mu.Unlock()
+
+		return &poolConn{Conn: conn, pool: p, backend: backend}, nil
+	}
+
+	if lastErr == nil {
+		return nil, ErrNoBackends
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrNoBackends, lastErr)
+}
+
+// candidates returns the backends to try, in the order the pool's strategy prefers.
+func (p *BackendPool) candidates() []*poolBackend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var candidates []*poolBackend
+	for _, backend := range p.backends {
+		if backend.healthy {
+			candidates = append(candidates, backend)
+		}
+	}
+
+	// Better to try the backends failing their probes than turn everyone away.
+	if len(candidates) == 0 {
+		candidates = slices.Clone(p.backends)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Take turns, so equally loaded backends are still spread across.
+	start := p.next % len(candidates)
+	p.next++
+	candidates = slices.Concat(candidates[start:], candidates[:start])
+
+	if p.opts.Strategy == LeastConnections {
+		slices.SortStableFunc(candidates, func(a *poolBackend, b *poolBackend) int {
+			return a.conns - b.conns
+		})
+	}
+
+	return candidates
+}
+
+// probeLoop probes every backend each health interval, until the pool is closed.
+func (p *BackendPool) probeLoop() {
+	ticker := time.NewTicker(p.opts.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		var group sync.WaitGroup
+
+		for _, backend := range p.backends {
+			group.Add(1)
+
+			go func() {
+				defer group.Done()
+				p.setHealthy(backend, p.probe(backend.addr))
+			}()
+		}
+
+		group.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// probe connects to the backend at 'addr', returning an error if it can't, or if it doesn't send the pool's banner in
+// time.
+func (p *BackendPool) probe(addr string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.HealthTimeout)
+	defer cancel()
+
+	conn, err := p.opts.Dial(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if p.opts.Banner == "" {
+		return nil
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(p.opts.HealthTimeout)); err != nil {
+		return err
+	}
+
+	// Read through the backend's negotiation, looking for the banner in its output.
+	reader := NewConn(conn)
+
+	var output strings.Builder
+	var buffer [512]byte
+
+	for !strings.Contains(output.String(), p.opts.Banner) {
+		n, err := reader.Read(buffer[:])
+		output.Write(buffer[:n])
+
+		// Skip any commands the backend sends that we don't understand.
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) && !protocolErr.Fatal {
+			continue
+		}
+
+		if err != nil && !strings.Contains(output.String(), p.opts.Banner) {
+			return fmt.Errorf("didn't receive the banner: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setHealthy marks 'backend' as failing if 'err' is set, or healthy otherwise, logging any change.
+func (p *BackendPool) setHealthy(backend *poolBackend, err error) {
+	p.mu.Lock()
+	changed := backend.healthy != (err == nil)
+	backend.healthy = err == nil
+	p.mu.Unlock()
+
+	switch {
+	case !changed:
+	case err != nil:
+		p.logger.Warn("telnet backend is unhealthy", "addr", backend.addr, "err", err)
+	default:
+		p.logger.Info("telnet backend is healthy again", "addr", backend.addr)
+	}
+}
+
+// Close closes the connection, releasing it from its backend's sessions.
+func (c *poolConn) Close() error {
+	c.release.Do(func() {
+		c.pool.mu.Lock()
+		c.backend.conns--
+		c.pool.mu.Unlock()
+	})
+
+	return c.Conn.Close()
+}
+
+// CloseWrite half-closes the connection, if the underlying connection supports it.
+func (c *poolConn) CloseWrite() error {
+	return closeWrite(c.Conn)
+}
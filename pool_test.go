@@ -0,0 +1,153 @@
+package telnet
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBackendPool_Dial(t *testing.T) {
+	apple, banana := serveName(t, "apple"), serveName(t, "banana")
+
+	tests := []struct {
+		strategy BalanceStrategy
+		close    []int // the connections to close, after each is made
+		expected []string
+	}{
+		{strategy: RoundRobin, expected: []string{"apple", "banana", "apple", "banana"}},
+		{strategy: RoundRobin, close: []int{1}, expected: []string{"apple", "banana", "apple"}},
+		{strategy: LeastConnections, close: []int{1}, expected: []string{"apple", "banana", "banana"}},
+		{strategy: LeastConnections, close: []int{0}, expected: []string{"apple", "banana", "apple"}},
+	}
+
+	for i, test := range tests {
+		pool := NewBackendPool([]string{apple, banana}, PoolOptions{Strategy: test.strategy})
+
+		for j, expected := range test.expected {
+			conn, err := pool.Dial(context.Background())
+			if err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+			defer conn.Close()
+
+			if actual := readString(t, bufio.NewReader(NewConn(conn))); expected+"\r\n" != actual {
+				t.Errorf("For test #%d, connection #%d, expected %q, but actually got %q.", i, j, expected+"\r\n", actual)
+			}
+
+			for _, k := range test.close {
+				if k == j {
+					_ = conn.Close()
+				}
+			}
+		}
+
+		_ = pool.Close()
+	}
+}
+
+func TestBackendPool_Failover(t *testing.T) {
+	apple := serveName(t, "apple")
+
+	pool := NewBackendPool([]string{deadAddr(t), apple}, PoolOptions{})
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Dial(context.Background())
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if expected, actual := "apple\r\n", readString(t, bufio.NewReader(NewConn(conn))); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+
+		_ = conn.Close()
+	}
+
+	pool = NewBackendPool([]string{deadAddr(t)}, PoolOptions{})
+	defer pool.Close()
+
+	if _, err := pool.Dial(context.Background()); !errors.Is(err, ErrNoBackends) {
+		t.Errorf("Expected the error %v, but actually got %v.", ErrNoBackends, err)
+	}
+}
+
+func TestBackendPool_HealthChecks(t *testing.T) {
+	apple, banana := serveName(t, "apple"), serveName(t, "banana")
+
+	pool := NewBackendPool([]string{apple, banana}, PoolOptions{
+		HealthInterval: 10 * time.Millisecond,
+		HealthTimeout:  time.Second,
+		Banner:         "banana",
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	defer pool.Close()
+
+	// Wait for apple to fail its probe, as it doesn't send the banner.
+	for deadline := time.Now().Add(5 * time.Second); len(pool.candidates()) != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected apple to fail its health check, but it never did.")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Dial(context.Background())
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if expected, actual := "banana\r\n", readString(t, bufio.NewReader(NewConn(conn))); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+
+		_ = conn.Close()
+	}
+}
+
+func TestProxyPoolHandler(t *testing.T) {
+	pool := NewBackendPool([]string{deadAddr(t), serveName(t, "apple")}, PoolOptions{})
+	defer pool.Close()
+
+	addr := serveTest(t, ProxyPoolHandler(pool, ProxyOptions{Transparent: true}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	if expected, actual := "apple\r\n", readString(t, bufio.NewReader(NewConn(conn))); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+// serveName serves a backend sending 'name' to each client, returning its address.
+func serveName(t *testing.T, name string) string {
+	t.Helper()
+
+	return serveTest(t, func(session *Session) {
+		_ = session.WriteLine(name)
+	})
+}
+
+// deadAddr returns an address nothing's listening on.
+func deadAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	return addr
+}
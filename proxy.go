@@ -0,0 +1,361 @@
+package telnet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultProxyDialTimeout bounds connecting to a ProxyHandler's backend, unless ProxyOptions.Dial is set.
+	defaultProxyDialTimeout = 10 * time.Second
+
+	// proxyNegotiationTimeout bounds how long a ProxyHandler waits for the client's window size and terminal type,
+	// before connecting to the backend.
+	proxyNegotiationTimeout = 2 * time.Second
+)
+
+var (
+	// proxyLocalOptions are the options a ProxyHandler agrees to perform when the backend asks (DO).
+	proxyLocalOptions = map[byte]bool{NAWS: true, TTYPE: true, SGA: true, BINARY: true}
+
+	// proxyRemoteOptions are the options a ProxyHandler agrees to the backend performing when it offers (WILL).
+	proxyRemoteOptions = map[byte]bool{ECHO: true, SGA: true, BINARY: true}
+)
+
+type (
+	// ProxyOptions configures a ProxyHandler.
+	ProxyOptions struct {
+		// Transparent relays everything between the client and the backend as is, including negotiation, so they
+		// negotiate with each other directly. Otherwise, the proxy negotiates with each separately, relaying data,
+		// and passing the client's window size and terminal type on to the backend (and the backend's ECHO and SGA on
+		// to the client). Only the latter lets the session's output be copied with Session.TeeOutput.
+		Transparent bool
+
+		// Dial, if set, connects to the backend instead of dialling TCP, e.g. (&tls.Dialer{}).DialContext.
+		Dial func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+		// Allow, if set, is called before connecting to the backend (e.g. to check the client's address, or log
+		// them in), ending the session if it returns false.
+		Allow func(session *Session) bool
+
+		// Logger logs failures to reach the backend; slog.Default() if unset.
+		Logger *slog.Logger
+	}
+
+	// proxy relays a session to a backend TELNET server, negotiating with each separately.
+	proxy struct {
+		session      *Session
+		conn         net.Conn
+		backend      *Conn
+		terminalType string
+
+		mu      sync.Mutex // serialises writes to the backend, and guards the window size
+		options options    // negotiated with the backend, as its client
+		width   int
+		height  int
+		sized   bool
+	}
+)
+
+// ProxyHandler returns a HandlerFunc relaying each session to the TELNET server at 'backendAddr', e.g. to terminate
+// TLS (see ListenAndServeTLS), log sessions, or control access in front of devices that only speak plain TELNET.
+func ProxyHandler(backendAddr string, opts ProxyOptions) HandlerFunc {
+	dial := opts.Dial
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: defaultProxyDialTimeout}).DialContext
+	}
+
+	return proxyHandler(func(ctx context.Context) (net.Conn, error) {
+		return dial(ctx, "tcp", backendAddr)
+	}, opts)
+}
+
+// ProxyPoolHandler returns a HandlerFunc relaying each session to a backend TELNET server from 'pool', failing over to
+// the next if one can't be reached, e.g. to front a farm of console servers. opts.Dial is ignored, in favour of the
+// pool's.
+func ProxyPoolHandler(pool *BackendPool, opts ProxyOptions) HandlerFunc {
+	return proxyHandler(pool.Dial, opts)
+}
+
+// proxyHandler returns a HandlerFunc relaying each session to the backend connected to by 'connect'.
+func proxyHandler(connect func(ctx context.Context) (net.Conn, error), opts ProxyOptions) HandlerFunc {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(session *Session) {
+		if opts.Allow != nil && !opts.Allow(session) {
+			return
+		}
+
+		var p *proxy
+		if !opts.Transparent {
+			p = newProxy(session)
+		}
+
+		conn, err := connect(session.Context())
+		if err != nil {
+			logger.Error("failed to connect to telnet backend", "from", session.RemoteAddr().String(), "err", err)
+			_ = session.WriteLine("Connection to the remote host failed.")
+
+			return
+		}
+		defer conn.Close()
+
+		if p == nil {
+			relayTransparent(session, conn)
+			return
+		}
+
+		p.run(conn)
+	}
+}
+
+// newProxy returns a proxy for 'session', having found out about the client's terminal, in case the backend asks.
+func newProxy(session *Session) *proxy {
+	p := &proxy{session: session}
+
+	ctx, cancel := WithTimeout(session.Context(), proxyNegotiationTimeout)
+	defer cancel()
+
+	p.width, p.height, _ = session.AwaitWindowSize(ctx)
+	_, _, p.sized = session.WindowSize()
+
+	if types, _ := session.AwaitTerminalTypes(ctx); len(types) > 0 {
+		p.terminalType = types[0]
+	}
+
+	return p
+}
+
+// relayTransparent takes over the session's connection, and relays it to 'backend' as is, until the backend closes.
+func relayTransparent(session *Session, backend net.Conn) {
+	conn, input, err := session.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	relayed := make(chan struct{})
+	go func() {
+		defer close(relayed)
+
+		_, _ = io.Copy(conn, backend)
+
+		// The backend ending the session ends the client's too.
+		_ = conn.Close()
+	}()
+
+	_, _ = io.Copy(backend, input)
+
+	// Let the backend finish sending its output, now the client's done.
+	if err = closeWrite(backend); err != nil {
+		_ = backend.Close()
+	}
+
+	<-relayed
+}
+
+// run relays the session's data to and from the backend over 'conn', until either closes.
+func (p *proxy) run(conn net.Conn) {
+	p.conn = conn
+	p.backend = NewConn(conn)
+	p.backend.OnOption(p.receivedOption)
+	p.backend.OnSubnegotiation(p.receivedSubnegotiation)
+
+	ctx, cancel := context.WithCancel(p.session.Context())
+	defer cancel()
+
+	p.session.OnWindowSize(p.resize)
+	defer p.session.OnWindowSize(nil)
+
+	relayed := make(chan struct{})
+	go func() {
+		defer close(relayed)
+		defer cancel()
+
+		p.relayBackend()
+	}()
+
+	p.relayClient(ctx)
+	_ = p.conn.Close()
+	<-relayed
+}
+
+// relayClient sends the client's input to the backend, until either closes or 'ctx' is done.
+func (p *proxy) relayClient(ctx context.Context) {
+	var buffer [1024]byte
+
+	for {
+		n, err := p.session.ReadContext(ctx, buffer[:])
+		if n > 0 {
+			p.mu.Lock()
+			_, writeErr := p.backend.Write(buffer[:n])
+			p.mu.Unlock()
+
+			if writeErr != nil {
+				return
+			}
+		}
+
+		// Skip any commands the client sends that we don't understand.
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) && !protocolErr.Fatal {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// relayBackend sends the backend's output to the client, until either closes.
+func (p *proxy) relayBackend() {
+	var buffer [4096]byte
+
+	for {
+		n, err := p.backend.Read(buffer[:])
+		if n > 0 {
+			if _, err := p.session.Write(buffer[:n]); err != nil {
+				return
+			}
+
+			if err := p.session.Flush(); err != nil {
+				return
+			}
+		}
+
+		// Skip any commands the backend sends that we don't understand.
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) && !protocolErr.Fatal {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// receivedOption answers a negotiation from the backend, only replying when it changes what's been agreed, so
+// negotiations can't loop (RFC 1143). Changes to ECHO and SGA are passed on to the client, so it echoes and buffers
+// input as the backend expects.
+func (p *proxy) receivedOption(verb byte, option byte) {
+	p.options.received(verb, option)
+
+	switch verb {
+	case DO:
+		if !proxyLocalOptions[option] {
+			p.writeCommand(WONT, option)
+			return
+		}
+
+		if p.options.offered(option) {
+			return
+		}
+
+		p.writeCommand(WILL, option)
+
+		if option == NAWS {
+			p.mu.Lock()
+			if p.sized {
+				p.writeWindowSize()
+			}
+			p.mu.Unlock()
+		}
+	case DONT:
+		if p.options.offered(option) {
+			p.writeCommand(WONT, option)
+		}
+	case WILL:
+		if !proxyRemoteOptions[option] {
+			p.writeCommand(DONT, option)
+			return
+		}
+
+		if !p.options.requested(option) {
+			p.writeCommand(DO, option)
+			p.mirror(WILL, option)
+		}
+	case WONT:
+		if p.options.requested(option) {
+			p.writeCommand(DONT, option)
+			p.mirror(WONT, option)
+		}
+	}
+}
+
+// receivedSubnegotiation answers the backend asking for the terminal type, with the client's.
+func (p *proxy) receivedSubnegotiation(option byte, payload []byte) {
+	if option != TTYPE || len(payload) == 0 || payload[0] != SEND || p.terminalType == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, _ = p.conn.Write(appendSubnegotiation(nil, TTYPE, append([]byte{IS}, p.terminalType...)))
+}
+
+// resize passes a new window size from the client on to the backend, if it's asked for it.
+func (p *proxy) resize(width int, height int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.width, p.height, p.sized = width, height, true
+
+	if p.options.local(NAWS) {
+		p.writeWindowSize()
+	}
+}
+
+// mirror passes the backend's offer to perform (or stop performing) ECHO or SGA on to the client.
+func (p *proxy) mirror(verb byte, option byte) {
+	if option != ECHO && option != SGA {
+		return
+	}
+
+	if _, err := p.session.WriteCommand(IAC, verb, option); err == nil {
+		_ = p.session.Flush()
+	}
+}
+
+// writeCommand sends IAC 'verb' 'option' to the backend.
+func (p *proxy) writeCommand(verb byte, option byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.conn.Write([]byte{IAC, verb, option}); err == nil {
+		p.options.sent(verb, option)
+	}
+}
+
+// writeWindowSize sends the client's window size to the backend. p.mu must be held.
+func (p *proxy) writeWindowSize() {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(p.width))
+	payload = binary.BigEndian.AppendUint16(payload, uint16(p.height))
+
+	_, _ = p.conn.Write(appendSubnegotiation(nil, NAWS, payload))
+}
+
+// appendSubnegotiation appends IAC SB 'option' 'payload' IAC SE to 'command', escaping any IAC bytes in 'payload'.
+func appendSubnegotiation(command []byte, option byte, payload []byte) []byte {
+	command = append(command, IAC, SB, option)
+
+	for _, b := range payload {
+		command = append(command, b)
+		if b == IAC {
+			command = append(command, IAC)
+		}
+	}
+
+	return append(command, IAC, SE)
+}
@@ -0,0 +1,128 @@
+package telnet
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+)
+
+func TestProxyHandler(t *testing.T) {
+	backend := serveTest(t, func(session *Session) {
+		if _, err := session.WriteCommand(IAC, WILL, ECHO); err != nil {
+			return
+		}
+
+		if err := session.WriteLine("Welcome"); err != nil {
+			return
+		}
+
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+
+		_ = session.WriteLine("You said: ", line)
+	})
+
+	tests := []struct {
+		name string
+		opts ProxyOptions
+	}{
+		{name: "negotiating", opts: ProxyOptions{}},
+		{name: "transparent", opts: ProxyOptions{Transparent: true}},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr := serveTest(t, ProxyHandler(backend, test.opts))
+
+			raw, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+			defer raw.Close()
+
+			offers := make(chan byte, 8)
+
+			client := NewConn(raw)
+			client.OnOption(func(verb byte, option byte) {
+				switch verb {
+				case DO:
+					// Refuse the proxy's requests, so it doesn't wait for them.
+					_, _ = raw.Write([]byte{IAC, WONT, option})
+				case WILL:
+					offers <- option
+				}
+			})
+
+			reader := bufio.NewReader(client)
+
+			if expected, actual := "Welcome\r\n", readString(t, reader); expected != actual {
+				t.Fatalf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+			}
+
+			select {
+			case option := <-offers:
+				if option != ECHO {
+					t.Errorf("For test #%d, expected WILL %d, but actually got WILL %d.", i, ECHO, option)
+				}
+			default:
+				t.Errorf("For test #%d, expected the backend's WILL ECHO to reach the client.", i)
+			}
+
+			if _, err = client.Write([]byte("hello\r\n")); err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+
+			if expected, actual := "You said: hello\r\n", readString(t, reader); expected != actual {
+				t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_DialFailure(t *testing.T) {
+	addr := serveTest(t, ProxyHandler(deadAddr(t), ProxyOptions{Transparent: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}))
+
+	raw, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer raw.Close()
+
+	if expected, actual := "Connection to the remote host failed.\r\n", readString(t, bufio.NewReader(NewConn(raw))); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+// serveTest serves 'handler' over TCP until the test ends, returning the server's address.
+func serveTest(t *testing.T, handler HandlerFunc) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &Server{Handler: handler}
+	server.SetLogger(slog.Default())
+
+	go server.Serve(listener)
+
+	return listener.Addr().String()
+}
+
+// readString reads a line from 'reader', including its end of line.
+func readString(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	return line
+}
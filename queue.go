@@ -0,0 +1,151 @@
+package telnet
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+const (
+	// OverflowBlock makes writes wait for room once a session's output queue is full, as if there were no queue.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest queued output to make room, so the client misses it (possibly partway
+	// through a command or escape sequence).
+	OverflowDropOldest
+
+	// OverflowDisconnect ends the session, failing the write with ErrOutputOverflow.
+	OverflowDisconnect
+)
+
+type (
+	// OverflowPolicy decides what happens when a session's output queue is full (see WithOutputQueue).
+	OverflowPolicy int
+
+	// outputQueue queues the data written to it, writing it to 'writer' in the background (see run), so writers don't
+	// wait on a slow client until the queue is full.
+	outputQueue struct {
+		ctx    context.Context
+		cancel context.CancelFunc // ends the session, for OverflowDisconnect
+		writer io.Writer
+		size   int
+		policy OverflowPolicy
+
+		mu      sync.Mutex
+		changed *sync.Cond // broadcast whenever chunks, busy or err change, or ctx is done
+		chunks  [][]byte
+		queued  int  // the number of bytes in chunks
+		busy    bool // set while run is writing a chunk
+		err     error
+	}
+)
+
+func newOutputQueue(ctx context.Context, cancel context.CancelFunc, writer io.Writer, size int, policy OverflowPolicy) *outputQueue {
+	q := &outputQueue{ctx: ctx, cancel: cancel, writer: writer, size: size, policy: policy}
+	q.changed = sync.NewCond(&q.mu)
+
+	context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		q.changed.Broadcast()
+	})
+
+	return q
+}
+
+// Write queues a copy of 'p', applying the queue's overflow policy if there isn't room. A chunk larger than the whole
+// queue is still accepted once the queue is empty.
+func (q *outputQueue) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.queued > 0 && q.queued+len(p) > q.size {
+		if err := q.failed(); err != nil {
+			return 0, err
+		}
+
+		switch q.policy {
+		case OverflowDropOldest:
+			q.queued -= len(q.chunks[0])
+			q.chunks = q.chunks[1:]
+		case OverflowDisconnect:
+			q.err = ErrOutputOverflow
+			q.cancel()
+			q.changed.Broadcast()
+
+			return 0, q.err
+		default:
+			q.changed.Wait()
+		}
+	}
+
+	if err := q.failed(); err != nil {
+		return 0, err
+	}
+
+	q.chunks = append(q.chunks, append([]byte(nil), p...))
+	q.queued += len(p)
+	q.changed.Broadcast()
+
+	return len(p), nil
+}
+
+// run writes the queued data to the queue's writer, until writing fails or the queue's context is done.
+func (q *outputQueue) run() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		for len(q.chunks) == 0 && q.failed() == nil {
+			q.changed.Wait()
+		}
+
+		if q.failed() != nil {
+			return
+		}
+
+		chunk := q.chunks[0]
+		q.chunks = q.chunks[1:]
+		q.queued -= len(chunk)
+		q.busy = true
+		q.mu.Unlock()
+
+		_, err := LongWrite(q.writer, chunk)
+
+		q.mu.Lock()
+		q.busy = false
+
+		if err != nil && q.err == nil {
+			q.err = err
+		}
+
+		q.changed.Broadcast()
+	}
+}
+
+// drain waits until everything queued has been written, returning an error if writing it fails, or the queue's
+// context is done first.
+func (q *outputQueue) drain() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.chunks) > 0 || q.busy {
+		if err := q.failed(); err != nil {
+			return err
+		}
+
+		q.changed.Wait()
+	}
+
+	return q.err
+}
+
+// failed returns the error writing failed with, or the context's error once it's done. q.mu must be held.
+func (q *outputQueue) failed() error {
+	if q.err != nil {
+		return q.err
+	}
+
+	return q.ctx.Err()
+}
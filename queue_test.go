@@ -0,0 +1,157 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutputQueue_Overflow(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		expected string
+		err      error
+	}{
+		{policy: OverflowDropOldest, expected: "cdef"},
+		{policy: OverflowDisconnect, err: ErrOutputOverflow},
+	}
+
+	for i, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var output syncBuffer
+		queue := newOutputQueue(ctx, cancel, &output, 4, test.policy)
+
+		// Nothing's written until the queue runs, so the queue fills up.
+		var err error
+		for _, chunk := range []string{"ab", "cd", "ef"} {
+			if _, err = queue.Write([]byte(chunk)); err != nil {
+				break
+			}
+		}
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("For test #%d, expected the error %v, but actually got %v.", i, test.err, err)
+		}
+
+		if test.err != nil {
+			if ctx.Err() == nil {
+				t.Errorf("For test #%d, expected the session to be ended, but it wasn't.", i)
+			}
+
+			continue
+		}
+
+		go queue.run()
+
+		if err = queue.drain(); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		if expected, actual := test.expected, output.String(); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", i, expected, actual)
+		}
+
+		cancel()
+	}
+}
+
+func TestOutputQueue_Block(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var output syncBuffer
+	queue := newOutputQueue(ctx, cancel, &output, 4, OverflowBlock)
+
+	if _, err := queue.Write([]byte("abcd")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := queue.Write([]byte("ef"))
+		written <- err
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("Expected the write to wait for room in the queue, but it didn't.")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	go queue.run()
+
+	if err := <-written; err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if err := queue.drain(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "abcdef", output.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestServer_OutputQueueSize(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	// The session's output is all delivered once the handler returns, even though it was queued.
+	server := NewServer(
+		WithHandler(func(session *Session) {
+			for i := 0; i < 100; i++ {
+				_ = session.WriteLine(strings.Repeat("x", 78))
+				_ = session.Flush()
+			}
+		}),
+		WithOutputQueue(1024, OverflowBlock),
+	)
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	output, err := io.ReadAll(NewConn(conn))
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := strings.Repeat(strings.Repeat("x", 78)+"\r\n", 100), string(output); expected != actual {
+		t.Errorf("Expected %d bytes of output, but actually got %d.", len(expected), len(actual))
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.String()
+}
@@ -3,17 +3,30 @@ package telnet
 import (
 	"bufio"
 	"bytes"
-	"errors"
+	"fmt"
 	"io"
 )
 
 const (
+	NUL      byte = 0
+	BINARY   byte = 0
 	ECHO     byte = 1
 	SGA      byte = 3
+	TM       byte = 6  // Timing mark.
 	NL       byte = 10 // New line.
 	CR       byte = 13 // Carriage return.
+	TTYPE    byte = 24 // Terminal type.
 	LINEMODE byte = 34
 	SE       byte = 240
+	NOP      byte = 241 // No operation.
+	DM       byte = 242 // Data mark.
+	BRK      byte = 243 // Break.
+	IP       byte = 244 // Interrupt process.
+	AO       byte = 245 // Abort output.
+	AYT      byte = 246 // Are you there.
+	EC       byte = 247 // Erase character.
+	EL       byte = 248 // Erase line.
+	GA       byte = 249 // Go ahead.
 	SB       byte = 250
 	WILL     byte = 251
 	WONT     byte = 252
@@ -22,6 +35,15 @@ const (
 	IAC      byte = 255
 )
 
+// maxSubnegotiationSize caps the payload of a subnegotiation the reader keeps, so a peer can't exhaust memory by
+// never ending one. The rest of the payload is discarded, unless the reader has a limit of its own (see
+// WithMaxSubnegotiationSize), which it also is by default for sessions.
+const maxSubnegotiationSize = 8192
+
+// maxEmptyReads is the number of reads in a row ReadLine allows to return no data and no error, before giving up with
+// io.ErrNoProgress.
+const maxEmptyReads = 100
+
 // reader handles un-escaping data according to the TELNET protocol.
 //
 // In the TELNET protocol, byte value 255 (IAC, "interpret as command") is used to indicate commands.
@@ -46,8 +68,55 @@ const (
 type reader struct {
 	buffered *bufio.Reader
 	reader   io.Reader
+	offset   int64 // The number of bytes consumed from the stream so far.
+	mode     ParseMode
+	cr       bool // Set in strict mode when the last data byte was a CR, so the next byte must be LF or NUL.
+	binary   bool // Set while the peer is transmitting in BINARY mode; the stream is parsed as in ParseLiteral.
+
+	// onOption is called for every WILL, WONT, DO or DONT command received.
+	onOption func(verb byte, option byte)
+
+	// onSubnegotiation is called with the unescaped payload of every subnegotiation received. The payload is only
+	// valid until the next call to Read.
+	onSubnegotiation func(option byte, payload []byte)
+	subnegotiation   []byte
+	subnegotiating   bool // Set while a subnegotiation is partly read, so reading it carries on if a read was interrupted.
+	subnegotiated    int  // The length of the subnegotiation being read, including bytes beyond maxSubnegotiationSize.
+
+	// maxSubnegotiation, if set, is the longest subnegotiation (including its option byte) the peer may send before
+	// reading fails with a fatal ProtocolError, which every later read returns too (see failed).
+	maxSubnegotiation int
+	failed            error
+
+	// allowNegotiation, if set, is called for every negotiation or subnegotiation received, before it's passed on,
+	// reporting whether to pass it on, or an error to fail reading with (see failed).
+	allowNegotiation func() (bool, error)
+
+	// onCommand is called for every single byte command (NOP, DM, BRK, IP, AO, AYT, EC, EL and GA) received.
+	onCommand func(command byte)
 }
 
+// ParseMode controls how the reader reacts to data that doesn't follow the TELNET protocol.
+type ParseMode int
+
+const (
+	// ParseDefault returns a recoverable ProtocolError for unknown commands, skipping them.
+	ParseDefault ParseMode = iota
+
+	// ParseLenient silently skips unknown commands, keeping sessions with non-conforming clients (such as IoT devices
+	// and scanners) alive.
+	ParseLenient
+
+	// ParseLiteral silently treats unknown commands as literal data, passing both the IAC and the byte after it
+	// through to the handler.
+	ParseLiteral
+
+	// ParseStrict enforces RFC 854: CR must be followed by LF or NUL (which is stripped), IAC must be doubled in data,
+	// and subnegotiations must be correctly framed. Violations are reported as detailed ProtocolErrors; broken
+	// escaping and framing are fatal.
+	ParseStrict
+)
+
 // newReader creates a new DataReader reading from 'r'.
 func newReader(r io.Reader) *reader {
 	return &reader{
@@ -56,73 +125,100 @@ func newReader(r io.Reader) *reader {
 	}
 }
 
+// newReaderSize creates a new DataReader reading from 'r', whose buffer has at least the specified size.
+func newReaderSize(r io.Reader, size int) *reader {
+	return &reader{
+		buffered: bufio.NewReaderSize(r, size),
+		reader:   r,
+	}
+}
+
 // Read reads the Telnet data stream, and parses Telnet-specific data.
+//
+// Runs of plain data are located with bytes.IndexByte and copied in bulk; only IAC sequences are parsed byte by byte.
 func (r *reader) Read(data []byte) (n int, err error) {
+	return r.read(data, false)
+}
+
+// pump behaves like Read, but also returns (possibly without any data) once a command has been processed and nothing
+// else is buffered. This lets callers waiting on negotiation replies see them without waiting for user data.
+func (r *reader) pump(data []byte) (n int, err error) {
+	return r.read(data, true)
+}
+
+func (r *reader) read(data []byte, returnOnCommand bool) (n int, err error) {
+	if r.failed != nil {
+		return 0, r.failed
+	}
+
+	var commands int
+
 	for len(data) > 0 {
-		if n > 0 && r.buffered.Buffered() < 1 {
+		if (n > 0 || (returnOnCommand && commands > 0)) && r.buffered.Buffered() < 1 {
 			break
 		}
 
-		b, err := r.buffered.ReadByte()
-		if err != nil {
+		// Block until there's at least 1 byte to process, then work on everything that's been buffered.
+		if _, err = r.buffered.Peek(1); err != nil {
 			return n, err
 		}
 
-		if b == IAC {
-			var peeked []byte
+		if r.cr {
+			if err = r.readAfterCR(); err != nil {
+				return n, err
+			}
 
-			peeked, err = r.buffered.Peek(1)
-			if err != nil {
+			continue
+		}
+
+		if r.subnegotiating {
+			if err = r.readSubnegotiation(); err != nil {
 				return n, err
 			}
 
-			switch peeked[0] {
-			case WILL, WONT, DO, DONT:
-				if _, err = r.buffered.Discard(2); err != nil {
-					return n, err
-				}
-			case IAC:
-				data[0] = IAC
-				n++
-				data = data[1:]
+			commands++
 
-				if _, err = r.buffered.Discard(1); err != nil {
-					return n, err
-				}
-			case SB:
-				for {
-					b2, err := r.buffered.ReadByte()
-					if err != nil {
-						return n, err
-					}
-
-					if b2 == IAC {
-						peeked, err = r.buffered.Peek(1)
-						if err != nil {
-							return n, err
-						}
-
-						if peeked[0] == IAC || peeked[0] == SE {
-							if _, err = r.buffered.Discard(1); err != nil {
-								return n, err
-							}
-
-							if peeked[0] == SE {
-								break
-							}
-						}
-					}
-				}
-			case SE:
-				if _, err = r.buffered.Discard(1); err != nil {
-					return n, err
+			continue
+		}
+
+		chunk, _ := r.buffered.Peek(r.buffered.Buffered())
+
+		if i := bytes.IndexByte(chunk, IAC); i != 0 {
+			if i > 0 {
+				chunk = chunk[:i]
+			}
+
+			// In strict mode, stop after a CR so the byte following it can be validated.
+			if r.parseMode() == ParseStrict {
+				if j := bytes.IndexByte(chunk, CR); j >= 0 {
+					chunk = chunk[:j+1]
 				}
-			default:
-				// If we're here, it's not following the telnet protocol.
-				return n, errors.New("corrupted")
 			}
-		} else {
-			data[0] = b
+
+			copied := copy(data, chunk)
+			n += copied
+			data = data[copied:]
+
+			if _, err = r.discard(copied); err != nil {
+				return n, err
+			}
+
+			if r.parseMode() == ParseStrict && copied == len(chunk) && chunk[copied-1] == CR {
+				r.cr = true
+			}
+
+			continue
+		}
+
+		value, isData, err := r.readCommand()
+		if err != nil {
+			return n, err
+		}
+
+		commands++
+
+		if isData {
+			data[0] = value
 			n++
 			data = data[1:]
 		}
@@ -131,6 +227,211 @@ func (r *reader) Read(data []byte) (n int, err error) {
 	return n, nil
 }
 
+// readAfterCR validates the byte following a CR in strict mode, stripping it if it's a NUL.
+func (r *reader) readAfterCR() error {
+	peeked, err := r.buffered.Peek(1)
+	if err != nil {
+		return err
+	}
+
+	r.cr = false
+
+	switch peeked[0] {
+	case NL:
+		return nil
+	case NUL:
+		_, err = r.discard(1)
+		return err
+	}
+
+	return &ProtocolError{Offset: r.offset, Byte: peeked[0], Expected: "LF or NUL after CR"}
+}
+
+// readCommand consumes the IAC sequence at the head of the buffer. If the sequence is an escaped IAC, the data byte it
+// represents is returned with isData set.
+func (r *reader) readCommand() (value byte, isData bool, err error) {
+	peeked, err := r.buffered.Peek(2)
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch peeked[1] {
+	case WILL, WONT, DO, DONT:
+		if peeked, err = r.buffered.Peek(3); err != nil {
+			return 0, false, err
+		}
+
+		verb, option := peeked[1], peeked[2]
+
+		if _, err = r.discard(3); err != nil {
+			return 0, false, err
+		}
+
+		if allowed, err := r.allow(); err != nil {
+			return 0, false, err
+		} else if allowed && r.onOption != nil {
+			r.onOption(verb, option)
+		}
+	case IAC:
+		if _, err = r.discard(2); err != nil {
+			return 0, false, err
+		}
+
+		return IAC, true, nil
+	case SB:
+		if _, err = r.discard(2); err != nil {
+			return 0, false, err
+		}
+
+		r.subnegotiation = r.subnegotiation[:0]
+		r.subnegotiating, r.subnegotiated = true, 0
+
+		return 0, false, r.readSubnegotiation()
+	case NOP, DM, BRK, IP, AO, AYT, EC, EL, GA:
+		command := peeked[1]
+
+		if _, err = r.discard(2); err != nil {
+			return 0, false, err
+		}
+
+		if r.onCommand != nil {
+			r.onCommand(command)
+		}
+	case SE:
+		if r.parseMode() == ParseStrict {
+			return 0, false, &ProtocolError{Offset: r.offset + 1, Byte: SE, Expected: "IAC SE only after IAC SB", Fatal: true}
+		}
+
+		if _, err = r.discard(2); err != nil {
+			return 0, false, err
+		}
+	default:
+		// If we're here, it's not following the telnet protocol.
+		switch r.parseMode() {
+		case ParseLenient:
+			_, err = r.discard(2)
+			return 0, false, err
+		case ParseLiteral:
+			_, err = r.discard(1)
+			return IAC, err == nil, err
+		}
+
+		// In strict mode, this is most likely an unescaped IAC in the data, so the stream can't be trusted any further.
+		if r.parseMode() == ParseStrict {
+			return 0, false, &ProtocolError{Offset: r.offset + 1, Byte: peeked[1], Expected: "a command, or IAC to escape IAC in data", Fatal: true}
+		}
+
+		// Skip the unknown command so reading can resume.
+		protocolErr := &ProtocolError{Offset: r.offset + 1, Byte: peeked[1], Expected: "a command"}
+		if _, err = r.discard(2); err != nil {
+			return 0, false, err
+		}
+
+		return 0, false, protocolErr
+	}
+
+	return 0, false, nil
+}
+
+// readSubnegotiation reads the rest of the subnegotiation being received, up to IAC SE, and passes it on. Bytes are only
+// consumed once they've been parsed, so if reading fails before IAC SE (e.g. as Server.Attach interrupted it), what's
+// been read is kept, and the next call carries on from there.
+func (r *reader) readSubnegotiation() error {
+	for {
+		peeked, err := r.buffered.Peek(1)
+		if err != nil {
+			return err
+		}
+
+		b := peeked[0]
+
+		if b == IAC {
+			if peeked, err = r.buffered.Peek(2); err != nil {
+				return err
+			}
+
+			if peeked[1] == SE {
+				if _, err = r.discard(2); err != nil {
+					return err
+				}
+
+				break
+			}
+
+			if peeked[1] != IAC {
+				if r.parseMode() == ParseStrict {
+					return &ProtocolError{Offset: r.offset + 1, Byte: peeked[1], Expected: "IAC or SE after IAC within a subnegotiation", Fatal: true}
+				}
+
+				// Skip the stray IAC, and carry on from the byte after it.
+				if _, err = r.discard(1); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if _, err = r.discard(2); err != nil {
+				return err
+			}
+		} else if _, err = r.discard(1); err != nil {
+			return err
+		}
+
+		if r.subnegotiated++; r.maxSubnegotiation > 0 && r.subnegotiated > r.maxSubnegotiation {
+			r.failed = &ProtocolError{Offset: r.offset - 1, Byte: b, Expected: fmt.Sprintf("IAC SE within %d bytes of IAC SB", r.maxSubnegotiation), Fatal: true}
+			return r.failed
+		}
+
+		// The option byte comes first, followed by the payload.
+		if len(r.subnegotiation) <= maxSubnegotiationSize {
+			r.subnegotiation = append(r.subnegotiation, b)
+		}
+	}
+
+	r.subnegotiating = false
+
+	if allowed, err := r.allow(); err != nil {
+		return err
+	} else if allowed && r.onSubnegotiation != nil && len(r.subnegotiation) > 0 {
+		r.onSubnegotiation(r.subnegotiation[0], r.subnegotiation[1:])
+	}
+
+	return nil
+}
+
+// parseMode returns the parse mode in effect. Data the peer transmits in BINARY mode isn't NVT text, so CRs aren't
+// checked, and unknown commands are most likely unescaped IAC bytes, so they're passed through as data.
+func (r *reader) parseMode() ParseMode {
+	if r.binary {
+		return ParseLiteral
+	}
+
+	return r.mode
+}
+
+// allow reports whether to pass on the negotiation just received (see allowNegotiation).
+func (r *reader) allow() (bool, error) {
+	if r.allowNegotiation == nil {
+		return true, nil
+	}
+
+	allowed, err := r.allowNegotiation()
+	if err != nil {
+		r.failed = err
+	}
+
+	return allowed, err
+}
+
+// discard skips the next 'n' bytes of the stream.
+func (r *reader) discard(n int) (int, error) {
+	discarded, err := r.buffered.Discard(n)
+	r.offset += int64(discarded)
+
+	return discarded, err
+}
+
 // ReadLine is a helper function to read a line from the Telnet client.
 //
 // This doesn't really work for reading from servers, as servers may not finish a line with a \r or \n (e.g. an auth
@@ -140,14 +441,20 @@ func ReadLine(reader io.Reader) (string, error) {
 	var buffer [1]byte
 	p := buffer[:]
 
-	for {
+	for empty := 0; ; {
 		n, err := reader.Read(p)
 		if n <= 0 && err == nil {
+			if empty++; empty >= maxEmptyReads {
+				return "", io.ErrNoProgress
+			}
+
 			continue
 		} else if n <= 0 && err != nil {
 			return "", err
 		}
 
+		empty = 0
+
 		line.WriteByte(p[0])
 
 		if p[0] == NL {
@@ -0,0 +1,99 @@
+package telnet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func BenchmarkReader_Read(b *testing.B) {
+	benchmarks := []struct {
+		Name   string
+		Data   []byte
+		Buffer int
+	}{
+		{
+			Name:   "Interactive",
+			Data:   []byte("uname -a\r\n"),
+			Buffer: 1,
+		},
+		{
+			Name:   "Plain",
+			Data:   bytes.Repeat([]byte("apple banana cherry "), 50),
+			Buffer: 4096,
+		},
+		{
+			Name:   "IACDense",
+			Data:   bytes.Repeat([]byte{IAC, IAC}, 1024),
+			Buffer: 4096,
+		},
+		{
+			Name:   "Negotiation",
+			Data:   bytes.Repeat([]byte{IAC, DO, ECHO, IAC, SB, 24, 1, IAC, SE, 'a'}, 100),
+			Buffer: 4096,
+		},
+		{
+			Name:   "Bulk",
+			Data:   bytes.Repeat([]byte("apple banana cherry\r\n"), 64*1024/21),
+			Buffer: 32 * 1024,
+		},
+		{
+			Name:   "BulkBinary",
+			Data:   benchmarkEscape(benchmarkBinaryData(64 * 1024)),
+			Buffer: 32 * 1024,
+		},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.Name, func(b *testing.B) {
+			source := bytes.NewReader(benchmark.Data)
+			telnetReader := newReader(source)
+			p := make([]byte, benchmark.Buffer)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(benchmark.Data)))
+
+			for i := 0; i < b.N; i++ {
+				source.Reset(benchmark.Data)
+				telnetReader.buffered.Reset(source)
+
+				for {
+					if _, err := telnetReader.Read(p); err != nil {
+						if err == io.EOF {
+							break
+						}
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReadLine(b *testing.B) {
+	data := bytes.Repeat([]byte("apple banana cherry\r\n"), 100)
+	source := bytes.NewReader(data)
+	telnetReader := newReader(source)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		source.Reset(data)
+		telnetReader.buffered.Reset(source)
+
+		for {
+			if _, err := ReadLine(telnetReader); err != nil {
+				if err == io.EOF {
+					break
+				}
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkEscape doubles every IAC in 'data', as the writer would.
+func benchmarkEscape(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{IAC}, []byte{IAC, IAC})
+}
@@ -2,7 +2,11 @@ package telnet
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -306,3 +310,450 @@ func TestReader_Read(t *testing.T) {
 		}
 	}
 }
+
+func TestReader_ReadBulk(t *testing.T) {
+	var escaped, expected bytes.Buffer
+
+	for i := 0; i < 10000; i++ {
+		value := byte(i)
+		expected.WriteByte(value)
+		escaped.WriteByte(value)
+
+		if value == IAC {
+			escaped.WriteByte(IAC)
+		}
+
+		// Sprinkle in some commands, which shouldn't make it through.
+		if i%997 == 0 {
+			escaped.Write([]byte{IAC, DO, ECHO, IAC, SB, 24, 1, IAC, SE})
+		}
+	}
+
+	// Deliberately use a buffer that doesn't divide the data evenly.
+	telnetReader := newReader(&escaped)
+	var actual bytes.Buffer
+	p := make([]byte, 333)
+
+	for {
+		n, err := telnetReader.Read(p)
+		actual.Write(p[:n])
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}
+
+	if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+		t.Errorf("Expected %d unescaped bytes to match, but they didn't (got %d bytes).", expected.Len(), actual.Len())
+	}
+}
+
+func TestReader_ReadProtocolError(t *testing.T) {
+	telnetReader := newReader(bytes.NewReader([]byte{'a', 'b', IAC, 1, 'c'}))
+
+	buffer := make([]byte, 8)
+	n, err := telnetReader.Read(buffer)
+
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) || !errors.Is(err, ErrProtocol) {
+		t.Fatalf("Expected a protocol error, but actually got: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "ab", string(buffer[:n]); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := int64(3), protocolErr.Offset; expected != actual {
+		t.Errorf("Expected offset %d, but actually got %d.", expected, actual)
+	}
+
+	if expected, actual := byte(1), protocolErr.Byte; expected != actual {
+		t.Errorf("Expected byte %d, but actually got %d.", expected, actual)
+	}
+
+	if protocolErr.Fatal {
+		t.Errorf("Expected the error to be recoverable.")
+	}
+
+	// The unknown command should have been skipped.
+	n, err = telnetReader.Read(buffer)
+	if err != nil && err != io.EOF {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "c", string(buffer[:n]); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestReader_ReadParseMode(t *testing.T) {
+	tests := []struct {
+		Mode     ParseMode
+		Bytes    []byte
+		Expected []byte
+	}{
+		{
+			Mode:     ParseLenient,
+			Bytes:    []byte{'a', IAC, 1, 'b', IAC, IAC, 'c'},
+			Expected: []byte{'a', 'b', IAC, 'c'},
+		},
+		{
+			Mode:     ParseLiteral,
+			Bytes:    []byte{'a', IAC, 1, 'b', IAC, IAC, 'c'},
+			Expected: []byte{'a', IAC, 1, 'b', IAC, 'c'},
+		},
+		{
+			Mode:     ParseLiteral,
+			Bytes:    []byte{IAC, 1, IAC, DO, ECHO, IAC, 2},
+			Expected: []byte{IAC, 1, IAC, 2},
+		},
+	}
+
+	for testNumber, test := range tests {
+		telnetReader := newReader(bytes.NewReader(test.Bytes))
+		telnetReader.mode = test.Mode
+
+		actual, err := io.ReadAll(telnetReader)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected := string(test.Expected); expected != string(actual) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, string(actual))
+			continue
+		}
+	}
+}
+
+func TestReader_ReadStrict(t *testing.T) {
+	tests := []struct {
+		Bytes    []byte
+		Expected []byte
+		Offset   int64
+		Fatal    bool
+		Error    bool
+	}{
+		{
+			Bytes:    []byte("apple\r\nbanana\r\x00cherry"),
+			Expected: []byte("apple\r\nbanana\rcherry"),
+		},
+		{
+			Bytes:    []byte("apple\rbanana"),
+			Expected: []byte("apple\r"),
+			Offset:   6,
+			Error:    true,
+		},
+		{
+			Bytes:    []byte{'a', IAC, 'b'},
+			Expected: []byte("a"),
+			Offset:   2,
+			Fatal:    true,
+			Error:    true,
+		},
+		{
+			Bytes:    []byte{'a', IAC, SB, 24, IAC, 'b', IAC, SE},
+			Expected: []byte("a"),
+			Offset:   5,
+			Fatal:    true,
+			Error:    true,
+		},
+		{
+			Bytes:    []byte{'a', IAC, SE},
+			Expected: []byte("a"),
+			Offset:   2,
+			Fatal:    true,
+			Error:    true,
+		},
+	}
+
+	for testNumber, test := range tests {
+		telnetReader := newReader(bytes.NewReader(test.Bytes))
+		telnetReader.mode = ParseStrict
+
+		actual, err := io.ReadAll(telnetReader)
+
+		if expected := string(test.Expected); expected != string(actual) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, string(actual))
+			continue
+		}
+
+		if !test.Error {
+			if err != nil {
+				t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			}
+			continue
+		}
+
+		var protocolErr *ProtocolError
+		if !errors.As(err, &protocolErr) {
+			t.Errorf("For test #%d, expected a protocol error, but actually got: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if protocolErr.Offset != test.Offset || protocolErr.Fatal != test.Fatal {
+			t.Errorf("For test #%d, expected offset %d (fatal: %t), but actually got %d (fatal: %t).", testNumber, test.Offset, test.Fatal, protocolErr.Offset, protocolErr.Fatal)
+			continue
+		}
+	}
+}
+
+func FuzzReaderRead(f *testing.F) {
+	f.Add([]byte("root\r\n"), uint8(4))
+	f.Add([]byte{'a', IAC, IAC, 'b', IAC, DO, ECHO, IAC, SB, NAWS, 0, 80, 0, 24, IAC, SE, IAC, AYT}, uint8(1))
+	f.Add([]byte{IAC, SB, TTYPE, IS, 'x', IAC, 'y', IAC, IAC, IAC, SE, 'c', '\r', 0, IAC, 1}, uint8(3))
+
+	f.Fuzz(func(t *testing.T, data []byte, size uint8) {
+		for _, mode := range []ParseMode{ParseDefault, ParseLenient, ParseLiteral, ParseStrict} {
+			telnetReader := newReader(bytes.NewReader(data))
+			telnetReader.mode = mode
+
+			buffer := make([]byte, int(size)%16+1)
+			var read int
+
+			for {
+				offset := telnetReader.offset
+
+				n, err := telnetReader.Read(buffer)
+				read += n
+
+				// Literal mode passes the IAC of unknown commands through as data, so it never outputs more than the
+				// input either.
+				if read > len(data) {
+					t.Fatalf("For mode %d, expected at most %d bytes, but actually read %d.", mode, len(data), read)
+				}
+
+				var protocolErr *ProtocolError
+				if errors.As(err, &protocolErr) && !protocolErr.Fatal {
+					continue
+				} else if err != nil {
+					break
+				}
+
+				if telnetReader.offset == offset {
+					t.Fatalf("For mode %d, expected Read to consume input or return an error, but it did neither at offset %d.", mode, offset)
+				}
+			}
+
+			if telnetReader.offset > int64(len(data)) {
+				t.Fatalf("For mode %d, expected to consume at most %d bytes, but actually consumed %d.", mode, len(data), telnetReader.offset)
+			}
+		}
+	})
+}
+
+func FuzzSubnegotiation(f *testing.F) {
+	f.Add(NAWS, []byte{0, 80, 0, 24})
+	f.Add(TTYPE, []byte("\x00XTERM-256COLOR"))
+	f.Add(TTYPE, []byte("\x00MTTS 2825"))
+	f.Add(NEWENVIRON, []byte("\x00\x00USER\x01root\x03DISPLAY\x02\x01\x02:0"))
+	f.Add(NEWENVIRON, []byte{INFO, USERVAR, 'T', VALUE, IAC, 'x'})
+
+	f.Fuzz(func(t *testing.T, option byte, payload []byte) {
+		escaped := append([]byte{IAC, SB}, bytes.ReplaceAll(append([]byte{option}, payload...), []byte{IAC}, []byte{IAC, IAC})...)
+		escaped = append(escaped, IAC, SE, 'z')
+
+		session := &Session{
+			ctx:    context.Background(),
+			reader: newReader(bytes.NewReader(escaped)),
+			writer: newWriter(io.Discard),
+		}
+		session.options.sent(DO, NAWS)
+		session.options.sent(DO, TTYPE)
+		session.options.sent(DO, NEWENVIRON)
+
+		var received [][]byte
+		session.reader.onOption = session.receivedOption
+		session.reader.onSubnegotiation = func(option byte, payload []byte) {
+			received = append(received, append([]byte{option}, payload...))
+			session.receivedSubnegotiation(option, payload)
+		}
+
+		data, err := io.ReadAll(session)
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if expected, actual := "z", string(data); expected != actual {
+			t.Errorf("Expected %q after the subnegotiation, but actually got %q.", expected, actual)
+		}
+
+		if len(payload) > maxSubnegotiationSize {
+			payload = payload[:maxSubnegotiationSize]
+		}
+
+		if expected := append([]byte{option}, payload...); len(received) != 1 || !bytes.Equal(expected, received[0]) {
+			t.Errorf("Expected the subnegotiation %v, but actually got %v.", expected, received)
+		}
+	})
+}
+
+func FuzzReadLine(f *testing.F) {
+	f.Add([]byte("root\r\nadmin\r\n"))
+	f.Add([]byte("enable\r\x00\nshow\n"))
+	f.Add([]byte{'a', IAC, IP, 'b', '\r', '\n', IAC, IAC, '\n'})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		telnetReader := newReader(bytes.NewReader(data))
+
+		// Each line consumes at least its LF.
+		for lines := 0; ; lines++ {
+			if lines > len(data) {
+				t.Fatalf("Expected at most %d lines, but actually read more.", len(data))
+			}
+
+			line, err := ReadLine(telnetReader)
+			if err != nil {
+				if !errors.Is(err, io.EOF) && !errors.Is(err, ErrProtocol) {
+					t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+				}
+
+				break
+			}
+
+			if strings.HasSuffix(line, "\r\n") {
+				t.Fatalf("Expected the CR LF to be removed from %q.", line)
+			}
+		}
+	})
+}
+
+// emptyReader returns no data and no error, forever.
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) {
+	return 0, nil
+}
+
+func TestReadLine_NoProgress(t *testing.T) {
+	if _, err := ReadLine(emptyReader{}); !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("Expected io.ErrNoProgress, but actually got: (%T) %v.", err, err)
+	}
+}
+
+func TestReader_ReadLongSubnegotiation(t *testing.T) {
+	data := append([]byte{IAC, SB, TTYPE}, bytes.Repeat([]byte{'x'}, 3*maxSubnegotiationSize)...)
+	data = append(data, IAC, SE, 'a')
+
+	var length int
+
+	telnetReader := newReader(bytes.NewReader(data))
+	telnetReader.onSubnegotiation = func(option byte, payload []byte) {
+		length = len(payload)
+	}
+
+	actual, err := io.ReadAll(telnetReader)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := "a"; expected != string(actual) {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected := maxSubnegotiationSize; expected != length {
+		t.Errorf("Expected the payload to be cut to %d bytes, but actually got %d.", expected, length)
+	}
+}
+
+// interruptedReader returns its chunks from successive reads, failing the read for each nil chunk with
+// os.ErrDeadlineExceeded, as if it had been interrupted.
+type interruptedReader [][]byte
+
+func (r *interruptedReader) Read(p []byte) (int, error) {
+	if len(*r) == 0 {
+		return 0, io.EOF
+	}
+
+	chunk := (*r)[0]
+	*r = (*r)[1:]
+
+	if chunk == nil {
+		return 0, os.ErrDeadlineExceeded
+	}
+
+	return copy(p, chunk), nil
+}
+
+func TestReader_InterruptedSubnegotiation(t *testing.T) {
+	tests := []interruptedReader{
+		{{IAC, SB, TTYPE, 0, 'x'}, nil, {'t', 'e', 'r', 'm', IAC, SE, 'a'}},
+		{{IAC, SB, TTYPE, 0, 'x', IAC}, nil, {IAC, 't', 'e', 'r', 'm', IAC, SE, 'a'}},
+		{{IAC, SB, TTYPE, 0, 'x', IAC}, nil, {SE, 'a'}},
+	}
+	payloads := []string{"\x00xterm", "\x00x\xffterm", "\x00x"}
+
+	for testNumber, test := range tests {
+		var payload []byte
+
+		telnetReader := newReader(&test)
+		telnetReader.onSubnegotiation = func(option byte, p []byte) {
+			payload = append([]byte(nil), p...)
+		}
+
+		buffer := make([]byte, 8)
+
+		// Reading carries on with the subnegotiation, rather than losing the part read before the interruption.
+		if _, err := telnetReader.Read(buffer); !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("For test #%d, expected the error %v, but actually got %v.", testNumber, os.ErrDeadlineExceeded, err)
+		}
+
+		actual, err := io.ReadAll(telnetReader)
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected := "a"; expected != string(actual) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		if expected := payloads[testNumber]; expected != string(payload) {
+			t.Errorf("For test #%d, expected the payload %q, but actually got %q.", testNumber, expected, payload)
+		}
+	}
+}
+
+func TestReader_SubnegotiationLimit(t *testing.T) {
+	data := append([]byte{IAC, SB, TTYPE}, bytes.Repeat([]byte{'x'}, 32)...)
+	data = append(data, IAC, SE, 'a')
+
+	telnetReader := newReader(bytes.NewReader(data))
+	telnetReader.maxSubnegotiation = 16
+
+	buffer := make([]byte, 8)
+
+	_, err := telnetReader.Read(buffer)
+
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) || !protocolErr.Fatal {
+		t.Fatalf("Expected a fatal protocol error, but actually got: (%T) %v.", err, err)
+	}
+
+	if expected, actual := int64(18), protocolErr.Offset; expected != actual {
+		t.Errorf("Expected the error at offset %d, but actually got %d.", expected, actual)
+	}
+
+	// The rest of the stream isn't read.
+	if _, err = telnetReader.Read(buffer); err != protocolErr {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", protocolErr, err, err)
+	}
+
+	// Subnegotiations up to the limit are read as usual.
+	data = append([]byte{IAC, SB, TTYPE}, bytes.Repeat([]byte{'x'}, 15)...)
+	data = append(data, IAC, SE, 'a')
+
+	telnetReader = newReader(bytes.NewReader(data))
+	telnetReader.maxSubnegotiation = 16
+
+	actual, err := io.ReadAll(telnetReader)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := "a"; expected != string(actual) {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
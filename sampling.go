@@ -0,0 +1,66 @@
+package telnet
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+type (
+	// LogSampling limits how much each session logs (see WithLogSampling), so a flood of connections (e.g. from
+	// scanners) can't drown everything else out, or make debug logging unusable. The zero value logs everything.
+	LogSampling struct {
+		// First is how many records each session logs before sampling starts.
+		First int
+
+		// Every samples the records each session logs after the First, logging 1 in Every. None are logged if it's 0.
+		Every int
+	}
+
+	// samplingHandler is a slog.Handler logging a sample of the records it handles (see LogSampling), for a session.
+	samplingHandler struct {
+		slog.Handler
+
+		sampling LogSampling
+		count    *atomic.Int64 // the records handled so far, shared with the handlers derived from this one
+	}
+)
+
+// sessionLogger returns the logger for a new session: the server's logger, sampled as configured by LogSampling.
+func (server *Server) sessionLogger() *slog.Logger {
+	logger := server.serverLogger()
+
+	if server.logSampling == (LogSampling{}) {
+		return logger
+	}
+
+	return slog.New(&samplingHandler{Handler: logger.Handler(), sampling: server.logSampling, count: new(atomic.Int64)})
+}
+
+// sampled reports whether the record numbered 'n' (from 0) should be logged.
+func (s LogSampling) sampled(n int64) bool {
+	if n < int64(s.First) {
+		return true
+	}
+
+	return s.Every > 0 && (n-int64(s.First))%int64(s.Every) == 0
+}
+
+// Handle logs 'record' if it's sampled. Records below the logger's level aren't counted, as they never reach it.
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if !h.sampling.sampled(h.count.Add(1) - 1) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler logging 'attrs' with each record, sampled along with this one's.
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampling: h.sampling, count: h.count}
+}
+
+// WithGroup returns a handler logging attributes in the group 'name', sampled along with this one's records.
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampling: h.sampling, count: h.count}
+}
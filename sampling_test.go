@@ -0,0 +1,72 @@
+package telnet
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogSampling_sampled(t *testing.T) {
+	tests := []struct {
+		Sampling LogSampling
+		Expected string
+	}{
+		{Sampling: LogSampling{First: 2, Every: 3}, Expected: "0 1 2 5 8"},
+		{Sampling: LogSampling{First: 3}, Expected: "0 1 2"},
+		{Sampling: LogSampling{Every: 4}, Expected: "0 4 8"},
+		{Sampling: LogSampling{First: 1, Every: 1}, Expected: "0 1 2 3 4 5 6 7 8 9"},
+	}
+
+	for testNumber, test := range tests {
+		var sampled []string
+		for n := int64(0); n < 10; n++ {
+			if test.Sampling.sampled(n) {
+				sampled = append(sampled, string(rune('0'+n)))
+			}
+		}
+
+		if actual := strings.Join(sampled, " "); test.Expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.Expected, actual)
+		}
+	}
+}
+
+func TestServer_LogSampling(t *testing.T) {
+	var output bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey || attr.Key == slog.LevelKey {
+				return slog.Attr{}
+			}
+
+			return attr
+		},
+	}))
+
+	server := NewServer(WithLogger(logger), WithLogSampling(LogSampling{First: 1, Every: 2}))
+	session, _ := newTestSession(t, server)
+
+	// Records below the logger's level aren't counted, and derived loggers share the session's sample.
+	session.Logger().Debug("skipped")
+	session.Logger().Info("a")
+	session.Logger().With("derived", true).Info("b")
+	session.Logger().Info("c")
+	session.Logger().WithGroup("group").Info("d", "n", 1)
+	session.Logger().Info("e")
+
+	// Each session is sampled separately.
+	other, _ := newTestSession(t, server)
+	other.Logger().Info("f")
+
+	expected := "msg=a\nmsg=b derived=true\nmsg=d group.n=1\nmsg=f\n"
+	if actual := output.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := logger, NewServer(WithLogger(logger)).sessionLogger(); expected != actual {
+		t.Errorf("Expected the server's logger, but actually got %v.", actual)
+	}
+}
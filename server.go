@@ -1,17 +1,33 @@
 package telnet
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// defaultBufferSize is the size of a session's input and output buffers, unless configured otherwise.
+const defaultBufferSize = 4096
+
+// defaultNegotiation is sent to clients as they connect, unless configured otherwise (see WithInitialNegotiation).
+// SGA is disabled, as clients connecting without defining a host port negotiate SGA, which causes ENTER to be handled
+// incorrectly if the server enables and disables echoing (e.g. to mask the user's password during auth).
+var defaultNegotiation = []Negotiation{{Verb: WONT, Option: SGA}}
+
+// sessionNumbers numbers sessions, so their IDs are unique even when clients share an address (such as "pipe").
+var sessionNumbers atomic.Uint64
+
 // ListenAndServe listens on the TCP network address 'addr' and then spawns a call to ServeTELNET
 // method on 'handler' to serve each incoming connection.
 func ListenAndServe(addr string, handler HandlerFunc) error {
@@ -28,7 +44,8 @@ func Serve(listener net.Listener, handler HandlerFunc) error {
 type (
 	// Server defines parameters of a running TELNET server.
 	Server struct {
-		listener     net.Listener
+		listener     net.Listener // guarded by listenerMu, as Drain and Shutdown may run alongside Serve
+		listenerMu   sync.Mutex
 		ConnCallback func(ctx context.Context, conn net.Conn) net.Conn // optional callback for wrapping net.Conn before handling
 		Handler      HandlerFunc                                       // handler to invoke; default is telnet.EchoHandler if nil
 		TLSConfig    *tls.Config                                       // optional TLS configuration; used by ListenAndServeTLS
@@ -37,14 +54,49 @@ type (
 		Addr         string // TCP address to listen on; ":23" or ":992" if empty (used with ListenAndServe or ListenAndServeTLS respectively).
 		Timeout      time.Duration
 		handlesMu    sync.Mutex
+		sessions     map[string]*Session // the sessions being served, by ID; guarded by handlesMu
+		active       sync.WaitGroup      // the connections being handled, for Drain to wait on
+		stats        counters            // the traffic of every session served
+		events       eventBus            // the subscribers to the sessions' events (see Subscribe)
+
+		// The settings below are configured with the Options given to NewServer (see config.go), so new ones don't
+		// grow the exported struct.
+		newlinePolicy         NewlinePolicy                                            // see WithNewlinePolicy
+		readBufferSize        int                                                      // see WithBufferSizes
+		writeBufferSize       int                                                      // see WithBufferSizes
+		parseMode             ParseMode                                                // see WithParseMode
+		aytResponse           string                                                   // see WithAYTResponse
+		forwardAYT            bool                                                     // see WithForwardAYT
+		disableGoAhead        bool                                                     // see WithoutGoAhead
+		writeTimeout          time.Duration                                            // see WithWriteTimeout
+		tcp                   TCPOptions                                               // see WithTCPOptions
+		byteLatency           Latency                                                  // see WithLatency
+		baudRate              int                                                      // see WithLatency
+		clock                 Clock                                                    // see WithClock
+		connContext           func(ctx context.Context, conn net.Conn) context.Context // see WithConnContext
+		outputQueueSize       int                                                      // see WithOutputQueue
+		overflowPolicy        OverflowPolicy                                           // see WithOutputQueue
+		maxSubnegotiationSize int                                                      // see WithMaxSubnegotiationSize
+		negotiationRate       int                                                      // see WithNegotiationLimits
+		negotiationLimit      int                                                      // see WithNegotiationLimits
+		floodPolicy           FloodPolicy                                              // see WithNegotiationLimits
+		initialNegotiation    []Negotiation                                            // see WithInitialNegotiation
+		inputMode             InputMode                                                // see WithInputMode
+		enricher              Enricher                                                 // see WithEnricher
+		panicHandler          func(session *Session, recovered any, stack []byte)      // see WithPanicHandler
+		disablePanicRecovery  bool                                                     // see WithoutPanicRecovery
+		logSampling           LogSampling                                              // see WithLogSampling
 	}
 
 	// serverConn is used to wrap a handle with context.
 	serverConn struct {
 		net.Conn
 
-		ctx    context.Context
-		cancel context.CancelFunc
+		id       string // the session's ID (see Session.ID)
+		ctx      context.Context
+		cancel   context.CancelFunc
+		hijacked *atomic.Bool // set once the handler has taken over the connection, so the server leaves it open
+		logger   *slog.Logger // the session's logger (see Session.Logger)
 	}
 )
 
@@ -66,19 +118,18 @@ func (server *Server) ListenAndServe() error {
 
 // Serve accepts an incoming TELNET client connection on the net.Listener 'listener'.
 func (server *Server) Serve(listener net.Listener) error {
+	server.listenerMu.Lock()
 	if server.listener != nil {
+		server.listenerMu.Unlock()
 		return errors.New("server already listening")
 	}
+	server.listener = listener
+	server.listenerMu.Unlock()
 
 	defer listener.Close()
-	server.listener = listener
-	server.handles = make(map[string]context.CancelFunc)
+	server.track()
 
-	handler := server.Handler
-	if handler == nil {
-		server.logger.Debug("no handler set, using EchoHandler")
-		handler = EchoHandler
-	}
+	handler := server.handler()
 
 	for {
 		rawConn, err := listener.Accept()
@@ -86,29 +137,83 @@ func (server *Server) Serve(listener net.Listener) error {
 			return err
 		}
 
-		var ctx context.Context
-		var cancel context.CancelFunc
+		conn := server.accept(rawConn)
+		conn.logger.Debug("received new connection", "FROM", conn.RemoteAddr().String())
 
-		if server.Timeout > 0 {
-			ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(server.Timeout))
-		} else {
-			ctx, cancel = context.WithCancel(context.Background())
-		}
+		// Spawn a new goroutine to handle the new client connection.
+		server.active.Add(1)
+		go server.handle(conn, handler)
+	}
+}
 
-		if server.ConnCallback != nil {
-			rawConn = server.ConnCallback(ctx, rawConn)
-		}
+// ServeConn serves a TELNET session over a connection the caller already has, rather than one accepted by Serve, e.g.
+// an SSH channel, a WebSocket adapter or a serial port wrapper. It blocks until the handler returns, then closes
+// 'conn' (unless the handler hijacked it). The connection is configured like those Serve accepts (see ConnContext,
+// ConnCallback and TCP), and its session counts towards the server's Stats and can be attached to.
+func (server *Server) ServeConn(conn net.Conn) {
+	server.track()
+	server.active.Add(1)
+	server.handle(server.accept(conn), server.handler())
+}
+
+// handler returns the server's Handler, or EchoHandler if it hasn't one.
+func (server *Server) handler() HandlerFunc {
+	if server.Handler == nil {
+		server.serverLogger().Debug("no handler set, using EchoHandler")
+		return EchoHandler
+	}
 
-		conn := serverConn{
-			Conn:   rawConn,
-			cancel: cancel,
-			ctx:    ctx,
+	return server.Handler
+}
+
+// track readies the server to keep track of the sessions it serves.
+func (server *Server) track() {
+	server.handlesMu.Lock()
+	defer server.handlesMu.Unlock()
+
+	if server.handles == nil {
+		server.handles = make(map[string]context.CancelFunc)
+	}
+
+	if server.sessions == nil {
+		server.sessions = make(map[string]*Session)
+	}
+}
+
+// accept prepares a new client connection to be handled: it's tuned, and given its context.
+func (server *Server) accept(rawConn net.Conn) serverConn {
+	logger := server.sessionLogger()
+
+	if err := server.tcp.apply(rawConn); err != nil {
+		logger.Debug("failed to tune telnet connection", "from", rawConn.RemoteAddr().String(), "err", err)
+	}
+
+	ctx := withClock(context.Background(), server.clock)
+	if server.connContext != nil {
+		if ctx = server.connContext(ctx, rawConn); ctx == nil {
+			panic("telnet: ConnContext returned nil")
 		}
+	}
 
-		server.logger.Debug("received new connection", "FROM", conn.RemoteAddr().String())
+	var cancel context.CancelFunc
 
-		// Spawn a new goroutine to handle the new client connection.
-		go server.handle(conn, handler)
+	if server.Timeout > 0 {
+		ctx, cancel = WithTimeout(ctx, server.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	if server.ConnCallback != nil {
+		rawConn = server.ConnCallback(ctx, rawConn)
+	}
+
+	return serverConn{
+		Conn:     rawConn,
+		id:       rawConn.RemoteAddr().String() + "#" + strconv.FormatUint(sessionNumbers.Add(1), 10),
+		cancel:   cancel,
+		ctx:      ctx,
+		hijacked: new(atomic.Bool),
+		logger:   logger,
 	}
 }
 
@@ -116,13 +221,40 @@ func (server *Server) SetLogger(logger *slog.Logger) {
 	server.logger = logger
 }
 
-func (server *Server) Shutdown() error {
-	if server.listener != nil {
-		if err := server.listener.Close(); err != nil {
+// serverLogger returns the server's logger, or slog.Default() if it hasn't one (e.g. a Server literal).
+func (server *Server) serverLogger() *slog.Logger {
+	if server.logger == nil {
+		return slog.Default()
+	}
+
+	return server.logger
+}
+
+// currentListener returns the listener the server's serving, or nil if it isn't serving one.
+func (server *Server) currentListener() net.Listener {
+	server.listenerMu.Lock()
+	defer server.listenerMu.Unlock()
+
+	return server.listener
+}
+
+// closeListener stops the server accepting connections, if it's serving a listener.
+func (server *Server) closeListener() error {
+	if listener := server.currentListener(); listener != nil {
+		if err := listener.Close(); err != nil {
 			return fmt.Errorf("failed to close listener: %w", err)
 		}
 	}
 
+	return nil
+}
+
+func (server *Server) Shutdown() error {
+	if err := server.closeListener(); err != nil {
+		return err
+	}
+
+	server.handlesMu.Lock()
 	wg := sync.WaitGroup{}
 	wg.Add(len(server.handles))
 
@@ -132,38 +264,48 @@ func (server *Server) Shutdown() error {
 			cancel()
 		}()
 	}
+	server.handlesMu.Unlock()
 
 	wg.Wait()
 
 	return nil
 }
 
-// handle manages the lifecycle of a TELNET client connection.
+// handle manages the lifecycle of a TELNET client connection. Its goroutine, and those it starts, are labelled with the
+// session's ID and the client's IP (see sessionLabels), so CPU and goroutine profiles attribute their cost to the
+// session. The labels are carried by the session's context too, for the handler to pass on with pprof.Do.
 func (server *Server) handle(conn serverConn, handler HandlerFunc) {
-	defer conn.Close()
+	defer server.active.Done()
+
+	conn.ctx = pprof.WithLabels(conn.ctx, sessionLabels(conn))
+	pprof.SetGoroutineLabels(conn.ctx)
 
-	// Leave a slight delay to close the context (needed to allow the connection to gracefully close).
 	defer func() {
-		if recovery := recover(); recovery != nil {
-			server.logger.Error("recovered from handle panic", "recovered", recovery, "stack", string(debug.Stack()))
+		if !conn.hijacked.Load() {
+			_ = conn.Close()
 		}
 	}()
 
+	// Registered before the handler runs, so Shutdown can't miss the session.
+	server.handlesMu.Lock()
+	server.handles[conn.id] = conn.cancel
+	server.handlesMu.Unlock()
+
 	// Close the handle if context is cancelled.
 	go func() {
-		server.handlesMu.Lock()
-		server.handles[conn.RemoteAddr().String()] = conn.cancel
-		server.handlesMu.Unlock()
-
 		<-conn.ctx.Done()
-		server.logger.Debug("received context completion, closing telnet connection", "from", conn.RemoteAddr().String())
 
-		if err := conn.Conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
-			server.logger.Error("failed to close telnet connection", "from", conn.RemoteAddr().String(), "err", err)
+		// A hijacked connection belongs to the handler now, so is left open.
+		if !conn.hijacked.Load() {
+			conn.logger.Debug("received context completion, closing telnet connection", "from", conn.RemoteAddr().String())
+
+			if err := conn.Conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				conn.logger.Error("failed to close telnet connection", "from", conn.RemoteAddr().String(), "err", err)
+			}
 		}
 
 		server.handlesMu.Lock()
-		delete(server.handles, conn.RemoteAddr().String())
+		delete(server.handles, conn.id)
 		server.handlesMu.Unlock()
 	}()
 
@@ -171,22 +313,183 @@ func (server *Server) handle(conn serverConn, handler HandlerFunc) {
 		conn.cancel()
 	}()
 
-	r := newReader(conn)
-	w := newWriter(conn)
+	session := server.newSession(conn)
+	server.stats[statSessions].Add(1)
+
+	server.handlesMu.Lock()
+	server.sessions[session.id] = session
+	server.handlesMu.Unlock()
+
+	defer func() {
+		server.handlesMu.Lock()
+		delete(server.sessions, session.id)
+		server.handlesMu.Unlock()
+	}()
+
+	// Handle panics while the connection's still open, before the session's ended.
+	started := false
+	defer func() {
+		if !server.disablePanicRecovery {
+			if recovered := recover(); recovered != nil {
+				server.recovered(session, recovered, debug.Stack())
+			}
+		}
+
+		if started {
+			server.events.publish(SessionEnded, session)
+		}
+	}()
+
+	// Sessions in InputChar mode need SGA, so don't disable it by default, and negotiate whatever else they need after
+	// the configured negotiation.
+	negotiation := server.initialNegotiation
+	if negotiation == nil && server.inputMode != InputChar {
+		negotiation = defaultNegotiation
+	}
+
+	for _, command := range negotiation {
+		if _, err := session.WriteCommand(IAC, command.Verb, command.Option); err != nil {
+			return
+		}
+	}
+
+	if server.inputMode == InputChar {
+		if err := session.SetInputMode(InputChar); err != nil {
+			return
+		}
+	}
+
+	if server.enricher != nil {
+		server.enrich(session)
+	}
+
+	server.events.publish(SessionStarted, session)
+	started = true
 
-	// TODO: handle real protocol negotiation
-	// Disable SGA by default. Clients connecting without defining a host port negotiate SGA, which causes ENTER to be
-	// handled incorrectly if the server enables and disables echoing (e.g. to mask the user's password during auth).
-	if _, err := WriteCommand(w, IAC, WONT, SGA); err != nil {
+	handler.ServeTELNET(session)
+
+	if err := session.flushQueue(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, ErrHijacked) {
+		session.logger.Debug("failed to flush telnet connection", "from", conn.RemoteAddr().String(), "err", err)
+	}
+}
+
+// recovered handles a panic in the handler of 'session' with the server's PanicHandler, flushing whatever it wrote to
+// the client, or logs it if there isn't one.
+func (server *Server) recovered(session *Session, recovered any, stack []byte) {
+	if server.panicHandler == nil {
+		session.logger.Error("recovered from handle panic", "recovered", recovered, "stack", string(stack))
 		return
 	}
 
-	handler.ServeTELNET(&Session{
-		ctx:    conn.ctx,
-		Conn:   conn,
-		reader: r,
-		writer: w,
-	})
+	server.panicHandler(session, recovered, stack)
+
+	if err := session.flushQueue(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, ErrHijacked) {
+		session.logger.Debug("failed to flush telnet connection", "from", session.RemoteAddr().String(), "err", err)
+	}
+}
+
+// sessionLabels returns the pprof labels of the session for 'conn': "session_id", its ID (see Session.ID), and
+// "remote_ip", the client's IP address.
+func sessionLabels(conn serverConn) pprof.LabelSet {
+	var ip string
+	if addr := addrIP(conn.RemoteAddr()); addr != nil {
+		ip = addr.String()
+	}
+
+	return pprof.Labels("session_id", conn.id, "remote_ip", ip)
+}
+
+// CloseWrite half-closes the client connection (see Session.CloseWrite).
+func (conn serverConn) CloseWrite() error {
+	return closeWrite(conn.Conn)
+}
+
+// newSession wraps a client connection in a Session configured from the server.
+func (server *Server) newSession(conn serverConn) *Session {
+	readBufferSize := server.readBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = defaultBufferSize
+	}
+
+	writeBufferSize := server.writeBufferSize
+	if writeBufferSize <= 0 {
+		writeBufferSize = defaultBufferSize
+	}
+
+	logger := conn.logger
+	if logger == nil {
+		logger = server.sessionLogger()
+	}
+
+	session := &Session{
+		id:          conn.id,
+		ctx:         conn.ctx,
+		Conn:        conn,
+		logger:      logger,
+		serverStats: &server.stats,
+		events:      &server.events,
+	}
+	session.ctx = context.WithValue(conn.ctx, sessionKey{}, session)
+
+	count := func(stat int) func(n int) {
+		return func(n int) { session.count(stat, n) }
+	}
+
+	var output io.Writer = &countingWriter{
+		Writer: &timeoutWriter{ctx: conn.ctx, conn: conn, timeout: server.writeTimeout},
+		count:  count(statBytesWritten),
+	}
+
+	perByte := server.byteLatency
+	if server.baudRate > 0 {
+		perByte.Fixed += 10 * time.Second / time.Duration(server.baudRate)
+	}
+
+	if perByte != (Latency{}) {
+		output = &pacedWriter{ctx: conn.ctx, writer: output, perByte: perByte}
+	}
+
+	var queue *outputQueue
+	if server.outputQueueSize > 0 {
+		queue = newOutputQueue(conn.ctx, conn.cancel, output, server.outputQueueSize, server.overflowPolicy)
+		output = queue
+
+		go queue.run()
+	}
+
+	buffered := bufio.NewWriterSize(output, writeBufferSize)
+
+	session.reader = newReaderSize(&countingReader{Reader: conn, count: count(statBytesRead)}, readBufferSize)
+	session.writer = newWriter(buffered)
+	session.buffered = buffered
+	session.queue = queue
+	session.reader.mode = server.parseMode
+	session.reader.maxSubnegotiation = server.maxSubnegotiationSize
+	if session.reader.maxSubnegotiation <= 0 {
+		session.reader.maxSubnegotiation = maxSubnegotiationSize
+	}
+	if server.negotiationRate > 0 || server.negotiationLimit > 0 {
+		guard := &floodGuard{
+			ctx:    conn.ctx,
+			cancel: conn.cancel,
+			rate:   server.negotiationRate,
+			limit:  server.negotiationLimit,
+			policy: server.floodPolicy,
+		}
+		session.reader.allowNegotiation = guard.allow
+	}
+
+	session.reader.onOption = session.receivedOption
+	session.reader.onCommand = session.receivedCommand
+	session.reader.onSubnegotiation = session.receivedSubnegotiation
+	session.aytResponse = server.aytResponse
+	session.forwardAYT = server.forwardAYT
+	session.goAhead = !server.disableGoAhead
+	session.writer.ctx = conn.ctx
+	session.writer.newline = server.newlinePolicy
+	session.watch()
+
+	return session
 }
 
 // The HandlerFunc type is an adapter to allow the use of ordinary functions as TELNET handlers.
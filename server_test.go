@@ -1,9 +1,20 @@
 package telnet
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestEchoHandler(t *testing.T) {
@@ -297,3 +308,866 @@ func TestEchoHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestSession_Flush(t *testing.T) {
+	var output bytes.Buffer
+	buffered := bufio.NewWriterSize(&output, 64)
+
+	session := &Session{
+		ctx:      context.Background(),
+		reader:   newReader(bytes.NewReader([]byte("a"))),
+		writer:   newWriter(buffered),
+		buffered: buffered,
+	}
+
+	if err := session.WriteString("apple"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "", output.String(); expected != actual {
+		t.Errorf("Expected %q before flushing, but actually got %q.", expected, actual)
+	}
+
+	// Reading should flush any pending output first.
+	var p [1]byte
+	if _, err := session.Read(p[:]); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "apple", output.String(); expected != actual {
+		t.Errorf("Expected %q after reading, but actually got %q.", expected, actual)
+	}
+}
+
+func TestSession_AYT(t *testing.T) {
+	var output bytes.Buffer
+
+	session := &Session{
+		ctx:    context.Background(),
+		reader: newReader(bytes.NewReader([]byte{'a', IAC, AYT, IAC, IP, 'b'})),
+		writer: newWriter(&output),
+	}
+	session.reader.onCommand = session.receivedCommand
+
+	input, err := io.ReadAll(session)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "ab", string(input); expected != actual {
+		t.Errorf("Expected to read %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := DefaultAYTResponse, output.String(); expected != actual {
+		t.Errorf("Expected to write %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestSession_AYTHijacked(t *testing.T) {
+	var output bytes.Buffer
+
+	session := &Session{ctx: context.Background(), writer: newWriter(&output)}
+	session.hijacked.Store(true)
+
+	// The connection belongs to the handler once it's hijacked, so the session doesn't answer the AYT itself.
+	session.receivedCommand(AYT)
+
+	if _, err := session.writeUntee([]byte("x")); !errors.Is(err, ErrHijacked) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrHijacked, err, err)
+	}
+
+	if actual := output.String(); actual != "" {
+		t.Errorf("Expected nothing to be written, but actually got %q.", actual)
+	}
+}
+
+func TestSession_ForwardAYT(t *testing.T) {
+	var output bytes.Buffer
+
+	session := &Session{
+		ctx:        context.Background(),
+		reader:     newReader(bytes.NewReader([]byte{'a', IAC, AYT, 'b'})),
+		writer:     newWriter(&output),
+		forwardAYT: true,
+	}
+	session.reader.onCommand = session.receivedCommand
+	signals := session.Signals()
+
+	if _, err := io.ReadAll(session); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if actual := output.String(); actual != "" {
+		t.Errorf("Expected nothing to be written, but actually got %q.", actual)
+	}
+
+	select {
+	case signal := <-signals:
+		if expected := (Signal{Command: AYT}); expected != signal {
+			t.Errorf("Expected %+v, but actually got %+v.", expected, signal)
+		}
+	default:
+		t.Error("Expected the AYT to be forwarded to the handler, but it wasn't.")
+	}
+}
+
+func TestSession_ReadLineGoAhead(t *testing.T) {
+	tests := []struct {
+		GoAhead  bool
+		SGA      bool
+		Expected []byte
+	}{
+		{
+			GoAhead:  true,
+			Expected: []byte{IAC, GA},
+		},
+		{
+			GoAhead:  true,
+			SGA:      true,
+			Expected: []byte{},
+		},
+		{
+			GoAhead:  false,
+			Expected: []byte{},
+		},
+	}
+
+	for testNumber, test := range tests {
+		var output bytes.Buffer
+
+		session := &Session{
+			ctx:     context.Background(),
+			reader:  newReader(bytes.NewReader([]byte("apple\r\n"))),
+			writer:  newWriter(&output),
+			goAhead: test.GoAhead,
+		}
+
+		if test.SGA {
+			session.options.sent(WILL, SGA)
+			session.options.received(DO, SGA)
+		}
+
+		if _, err := session.ReadLine(); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected, actual := string(test.Expected), output.String(); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+			continue
+		}
+	}
+}
+
+func TestSession_ReadContext(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	go client.Write([]byte("a"))
+
+	var p [1]byte
+	if n, err := session.ReadContext(context.Background(), p[:]); err != nil || n != 1 || p[0] != 'a' {
+		t.Fatalf("Expected to read %q, but actually got %q (%v).", "a", p[:n], err)
+	}
+
+	// A blocked read is interrupted when the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := session.ReadContext(ctx, p[:]); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, but actually got: (%T) %v.", context.DeadlineExceeded, err, err)
+	}
+
+	// The session can still be read from afterwards.
+	go client.Write([]byte("b"))
+
+	if n, err := session.Read(p[:]); err != nil || n != 1 || p[0] != 'b' {
+		t.Errorf("Expected to read %q, but actually got %q (%v).", "b", p[:n], err)
+	}
+}
+
+func TestSession_ReadClosed(t *testing.T) {
+	session, _ := newTestSession(t, &Server{})
+
+	read := make(chan error, 1)
+	go func() {
+		var p [1]byte
+		_, err := session.Read(p[:])
+		read <- err
+	}()
+
+	// A blocked read is interrupted when the session's context is done.
+	session.Conn.(serverConn).cancel()
+
+	select {
+	case err := <-read:
+		if !errors.Is(err, ErrSessionClosed) || !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Expected %v, but actually got: (%T) %v.", ErrSessionClosed, err, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the read to be interrupted, but it wasn't.")
+	}
+
+	// Later reads fail straight away.
+	var p [1]byte
+	if _, err := session.Read(p[:]); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrSessionClosed, err, err)
+	}
+}
+
+func TestSession_AwaitOption(t *testing.T) {
+	tests := []struct {
+		Sent     []byte
+		Reply    []byte
+		Want     bool
+		Expected error
+	}{
+		{Sent: []byte{IAC, DO, NAWS}, Reply: []byte{IAC, WILL, NAWS}, Want: true},
+		{Sent: []byte{IAC, DO, TTYPE}, Reply: []byte{IAC, WONT, TTYPE}, Want: true, Expected: ErrOptionRefused},
+		{Sent: []byte{IAC, WILL, ECHO}, Reply: []byte{IAC, DO, ECHO}, Want: true},
+		{Sent: []byte{IAC, WILL, ECHO}, Reply: []byte{IAC, DONT, ECHO}, Want: true, Expected: ErrOptionRefused},
+		{Sent: []byte{IAC, WONT, SGA}, Reply: []byte{IAC, DONT, SGA}, Want: false},
+
+		// Negotiation of other options isn't an answer.
+		{Sent: []byte{IAC, DO, NAWS}, Reply: []byte{IAC, WILL, TTYPE}, Want: true, Expected: context.DeadlineExceeded},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, &Server{})
+
+		if _, err := session.WriteCommand(test.Sent[0], test.Sent[1], test.Sent[2]); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		go func() {
+			if !expect(t, client, test.Sent) {
+				return
+			}
+
+			client.Write(append(test.Reply, 'a'))
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+		if err := session.AwaitOption(ctx, test.Sent[2], test.Want); !errors.Is(err, test.Expected) {
+			t.Errorf("For test #%d, expected %v, but actually got: (%T) %v.", testNumber, test.Expected, err, err)
+		}
+
+		cancel()
+
+		// Data sent alongside the answer is kept for the next read.
+		buffer := make([]byte, 1)
+		if _, err := session.Read(buffer); err != nil || buffer[0] != 'a' {
+			t.Errorf("For test #%d, expected %q, but actually got %q (%v).", testNumber, "a", buffer, err)
+		}
+	}
+}
+
+func TestSession_ReadPassword(t *testing.T) {
+	tests := []struct {
+		Input    []byte
+		Password string
+		Fatal    bool
+	}{
+		{Input: []byte("hunter2\r\n"), Password: "hunter2"},
+
+		// The client's echoing is restored even though reading fails.
+		{Input: []byte{'h', IAC, 1}, Fatal: true},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, NewServer(WithParseMode(ParseStrict), WithoutGoAhead()))
+
+		go client.Write(test.Input)
+
+		expected := string(append(append([]byte("Password: "), IAC, WILL, ECHO, IAC, WONT, ECHO), "\r\n"...))
+
+		output := make(chan []byte, 1)
+		go func() {
+			actual := make([]byte, len(expected))
+			_, _ = io.ReadFull(client, actual)
+			output <- actual
+		}()
+
+		password, err := session.ReadPassword("Password: ")
+
+		var protocolErr *ProtocolError
+		if test.Fatal != errors.As(err, &protocolErr) {
+			t.Errorf("For test #%d, did not expect the error: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected, actual := test.Password, password; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		if actual := string(<-output); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestSession_ConcurrentWrites(t *testing.T) {
+	var output bytes.Buffer
+	buffered := bufio.NewWriterSize(&output, 64)
+
+	session := &Session{
+		ctx:      context.Background(),
+		reader:   newReader(bytes.NewReader(nil)),
+		writer:   newWriter(buffered),
+		buffered: buffered,
+	}
+	session.SetNewlinePolicy(NewlineCRLF)
+
+	var group sync.WaitGroup
+	for _, letter := range "abcdefgh" {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			for range 100 {
+				_ = session.WriteString(strings.Repeat(string(letter), 50) + "\n")
+				_, _ = session.WriteCommand(IAC, WILL, ECHO)
+				_ = session.Flush()
+			}
+		}()
+	}
+
+	group.Wait()
+
+	// Every command and line should have been written whole, without another write landing in the middle of it.
+	data := bytes.ReplaceAll(output.Bytes(), []byte{IAC, WILL, ECHO}, nil)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+
+	if expected, actual := 800, len(lines); expected != actual {
+		t.Fatalf("Expected %d lines, but actually got %d.", expected, actual)
+	}
+
+	for _, line := range lines {
+		if expected := strings.Repeat(line[:1], 50); expected != line {
+			t.Fatalf("Expected %q, but actually got %q.", expected, line)
+		}
+	}
+}
+
+func TestSession_CloseWriteUnsupported(t *testing.T) {
+	session, _ := newTestSession(t, &Server{})
+
+	if err := session.CloseWrite(); !errors.Is(err, ErrCloseWriteUnsupported) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrCloseWriteUnsupported, err, err)
+	}
+}
+
+func TestSession_CloseGracefully(t *testing.T) {
+	tests := []struct {
+		Negotiated bool
+		Expected   []byte
+	}{
+		{
+			Negotiated: true,
+			Expected:   append([]byte("Goodbye!\r\n"), IAC, DO, TM),
+		},
+		{
+			Negotiated: false,
+			Expected:   []byte("Goodbye!\r\n"),
+		},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, &Server{})
+		if test.Negotiated {
+			session.options.received(DO, SGA)
+		}
+
+		closed := make(chan error, 1)
+		go func() {
+			closed <- session.CloseGracefully("Goodbye!\r\n")
+		}()
+
+		expect(t, client, test.Expected)
+
+		// The session waits for the client to answer the timing mark before closing.
+		if test.Negotiated {
+			if _, err := client.Write([]byte{IAC, WONT, TM}); err != nil {
+				t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			}
+		}
+
+		if err := <-closed; err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("For test #%d, expected %v, but actually got: (%T) %v.", testNumber, io.EOF, err, err)
+		}
+	}
+}
+
+func TestSession_Drain(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+	session.options.received(DO, SGA)
+
+	// Each drain sends its own timing mark, and waits for it to be answered.
+	for i := 0; i < 2; i++ {
+		if err := session.WriteString("Goodbye!\r\n"); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		drained := make(chan error, 1)
+		go func() {
+			drained <- session.Drain(context.Background())
+		}()
+
+		expect(t, client, append([]byte("Goodbye!\r\n"), IAC, DO, TM))
+
+		if _, err := client.Write([]byte{IAC, WILL, TM}); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		if err := <-drained; err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+	}
+
+	// A client that never answers is given up on once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	go io.Copy(io.Discard, client)
+
+	if err := session.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", context.DeadlineExceeded, err, err)
+	}
+}
+
+func TestSession_DrainTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session := (&Server{}).newSession(serverConn{Conn: conn, ctx: ctx, cancel: cancel, hijacked: new(atomic.Bool)})
+
+	output := bytes.Repeat([]byte("Goodbye!\r\n"), 4096)
+	if _, err = session.Write(output); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if err = session.Drain(context.Background()); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if runtime.GOOS == "linux" {
+		if unsent, err := unsentBytes(conn.(*net.TCPConn)); err != nil || unsent != 0 {
+			t.Errorf("Expected 0 unsent bytes, but actually got %d: %v.", unsent, err)
+		}
+	}
+
+	// Closing straight after draining doesn't lose any of the output.
+	if err = session.Close(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	received, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if !bytes.Equal(output, received) {
+		t.Errorf("Expected %d bytes, but actually got %d.", len(output), len(received))
+	}
+}
+
+func TestServer_ConnContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	values := make(chan any, 1)
+
+	server := NewServer(
+		WithHandler(func(session *Session) {
+			values <- session.Context().Value(contextKey{})
+		}),
+		WithConnContext(func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, contextKey{}, "apple")
+		}),
+	)
+
+	go server.Serve(listener)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	if expected, actual := "apple", <-values; expected != actual {
+		t.Errorf("Expected the context value %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestServer_Enricher(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	enrichments := make(chan Enrichment, 1)
+
+	server := NewServer(
+		WithHandler(func(session *Session) {
+			enrichment, _ := session.Enrichment()
+			enrichments <- enrichment
+		}),
+		WithEnricher(EnricherFunc(func(ctx context.Context, ip net.IP) (Enrichment, error) {
+			if !ip.IsLoopback() {
+				return Enrichment{}, errors.New("not loopback")
+			}
+
+			return Enrichment{Country: "NL", ASN: 64496}, nil
+		})),
+	)
+
+	go server.Serve(listener)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	if expected, actual := (Enrichment{Country: "NL", ASN: 64496}), <-enrichments; expected != actual {
+		t.Errorf("Expected %+v, but actually got %+v.", expected, actual)
+	}
+}
+
+func TestServer_InitialNegotiation(t *testing.T) {
+	tests := []struct {
+		Negotiation []Negotiation
+		Expected    []byte
+	}{
+		{Negotiation: nil, Expected: []byte{IAC, WONT, SGA}},
+		{Negotiation: []Negotiation{}, Expected: nil},
+		{Negotiation: []Negotiation{{WILL, ECHO}, {DO, TTYPE}}, Expected: []byte{IAC, WILL, ECHO, IAC, DO, TTYPE}},
+	}
+
+	for testNumber, test := range tests {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		server := NewServer(WithHandler(func(session *Session) {
+			_ = session.WriteString("$ ")
+		}))
+		server.initialNegotiation = test.Negotiation
+
+		go server.Serve(listener)
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		output, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected, actual := string(append(test.Expected, "$ "...)), string(output); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		conn.Close()
+		listener.Close()
+	}
+}
+
+func TestServer_InitialNegotiationReplies(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	// The handler awaits the terminal types requested up front, without asking for them again.
+	server := NewServer(
+		WithInitialNegotiation(Negotiation{DO, TTYPE}),
+		WithHandler(func(session *Session) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			types, _ := session.AwaitTerminalTypes(ctx)
+			_ = session.WriteLine(strings.Join(types, ","))
+		}),
+	)
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	expect(t, conn, []byte{IAC, DO, TTYPE})
+	conn.Write([]byte{IAC, WILL, TTYPE})
+
+	expect(t, conn, []byte{IAC, SB, TTYPE, 1, IAC, SE})
+	conn.Write(append(append([]byte{IAC, SB, TTYPE, 0}, "XTERM"...), IAC, SE))
+
+	expect(t, conn, []byte{IAC, SB, TTYPE, 1, IAC, SE})
+	conn.Write(append(append([]byte{IAC, SB, TTYPE, 0}, "XTERM"...), IAC, SE))
+
+	if expected, actual := "XTERM\r\n", readString(t, bufio.NewReader(conn)); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestServer_ProfilerLabels(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	labels := make(chan [2]string, 1)
+
+	server := NewServer(WithHandler(func(session *Session) {
+		id, _ := pprof.Label(session.Context(), "session_id")
+		ip, _ := pprof.Label(session.Context(), "remote_ip")
+		labels <- [2]string{id, ip}
+	}))
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	if expected, actual := conn.LocalAddr().String()+"#", <-labels; !strings.HasPrefix(actual[0], expected) || actual[1] != "127.0.0.1" {
+		t.Errorf("Expected the ID %q... and IP %q, but actually got %q.", expected, "127.0.0.1", actual)
+	}
+}
+
+func TestServer_SessionIDs(t *testing.T) {
+	ids := make(chan string, 2)
+	ended := make(chan struct{}, 2)
+
+	server := NewServer(WithHandler(func(session *Session) {
+		defer func() { ended <- struct{}{} }()
+
+		ids <- session.ID()
+		_, _ = session.ReadLine()
+	}), WithInitialNegotiation())
+
+	// Both clients' address is "pipe", but their sessions are told apart, and both are shut down.
+	for i := 0; i < 2; i++ {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+
+		go server.ServeConn(serverConn)
+	}
+
+	if first, second := <-ids, <-ids; first == second || !strings.HasPrefix(first, "pipe#") {
+		t.Errorf("Expected unique IDs for the sessions, but actually got %q and %q.", first, second)
+	}
+
+	if err := server.Shutdown(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ended:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected Shutdown to end both sessions, but it didn't.")
+		}
+	}
+}
+
+func TestServer_ShutdownUnblocksWrites(t *testing.T) {
+	written := make(chan error, 1)
+	started := make(chan struct{})
+
+	server := NewServer(WithHandler(func(session *Session) {
+		close(started)
+
+		// Nobody reads from the client's end of the pipe, so this blocks until the session ends.
+		_, err := session.Write(bytes.Repeat([]byte("a"), 64*1024))
+		if err == nil {
+			err = session.Flush()
+		}
+
+		written <- err
+	}), WithInitialNegotiation())
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	go server.ServeConn(serverConn)
+
+	<-started
+
+	if err := server.Shutdown(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	select {
+	case err := <-written:
+		if err == nil {
+			t.Error("Expected the write to fail, but it didn't.")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected Shutdown to unblock the write, but it didn't.")
+	}
+}
+
+func TestSession_WriteAfterContextDone(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := (&Server{}).newSession(serverConn{Conn: conn, ctx: ctx, cancel: cancel, hijacked: new(atomic.Bool)})
+
+	written := make(chan error, 1)
+	go func() {
+		// Nobody reads from the client's end of the pipe, which is left open, so this blocks until the context is done.
+		_, err := session.Write(bytes.Repeat([]byte("a"), 64*1024))
+		written <- err
+	}()
+
+	// Read the first byte, so the write is under way (and blocked on the rest) before the context is done.
+	if _, err := io.ReadFull(client, make([]byte, 1)); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	cancel()
+
+	select {
+	case err := <-written:
+		if err == nil {
+			t.Error("Expected the write to fail, but it didn't.")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the context ending to unblock the write, but it didn't.")
+	}
+}
+
+func TestServer_ServeConn(t *testing.T) {
+	server := NewServer(WithHandler(func(session *Session) {
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+
+		_ = session.WriteLine("You wrote: ", line)
+	}), WithInitialNegotiation(), WithoutGoAhead())
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		server.ServeConn(serverConn)
+	}()
+
+	client := NewConn(clientConn)
+	defer client.Close()
+
+	if err := WriteLine(client, "apple"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	line, err := ReadLine(client)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := "You wrote: apple"; expected != line {
+		t.Errorf("Expected %q, but actually got %q.", expected, line)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected ServeConn to return once the handler did, but it didn't.")
+	}
+
+	if expected, actual := int64(1), server.Stats().Sessions; expected != actual {
+		t.Errorf("Expected %d, but actually got %d.", expected, actual)
+	}
+}
+
+func TestServer_PanicHandler(t *testing.T) {
+	var stack []byte
+
+	server := NewServer(WithHandler(func(session *Session) {
+		_ = session.WriteLine("apple")
+		panic("banana")
+	}), WithInitialNegotiation(), WithPanicHandler(func(session *Session, recovered any, trace []byte) {
+		stack = trace
+		_ = session.WriteLine("crashed: ", fmt.Sprint(recovered))
+	}))
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.ServeConn(serverConn)
+	}()
+
+	expect(t, clientConn, []byte("apple\r\ncrashed: banana\r\n"))
+	<-done
+
+	if !bytes.Contains(stack, []byte("TestServer_PanicHandler")) {
+		t.Errorf("Expected the stack trace of the handler, but actually got %q.", stack)
+	}
+}
+
+func TestServer_DisablePanicRecovery(t *testing.T) {
+	server := NewServer(WithHandler(func(session *Session) {
+		panic("banana")
+	}), WithInitialNegotiation(), WithoutPanicRecovery(), WithPanicHandler(func(*Session, any, []byte) {
+		t.Error("Did not expect the panic handler to be called, but it was.")
+	}))
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	recovered := func() (recovered any) {
+		defer func() {
+			recovered = recover()
+		}()
+
+		server.ServeConn(serverConn)
+		return nil
+	}()
+
+	if expected := "banana"; recovered != expected {
+		t.Errorf("Expected the panic to reach the caller with %q, but actually got %v.", expected, recovered)
+	}
+}
@@ -1,37 +1,781 @@
 package telnet
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"io"
+	"log/slog"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// DefaultAYTResponse is sent in reply to IAC AYT (are you there), unless the server is configured otherwise.
+const DefaultAYTResponse = "\r\n[Yes]\r\n"
+
+// closeTimeout bounds how long CloseGracefully waits for the client to confirm it's received the session's output.
+const closeTimeout = 5 * time.Second
+
+// drainInterval is how often Drain checks whether the OS has sent the session's output.
+const drainInterval = 10 * time.Millisecond
+
+// Session is a client's connection to the server. Its Write, WriteLine, WriteString, Writef and WriteCommand methods
+// (and Flush) may be called from multiple goroutines at once, e.g. to print notifications while the handler waits on
+// input; each call's output is written whole, without interleaving with others. Reads must still come from one
+// goroutine at a time.
 type Session struct {
-	ctx context.Context
+	id     string
+	ctx    context.Context
+	logger *slog.Logger // the server's logger, sampled for the session (see WithLogSampling)
 	net.Conn
 	*reader
 	*writer
+	buffered *bufio.Writer // optional output buffer between the writer and the connection
+	queue    *outputQueue  // optional output queue between the buffer and the connection
+	options  options
+
+	aytResponse string // sent in reply to IAC AYT; "[Yes]" if empty
+	forwardAYT  bool   // leaves replying to IAC AYT to the handler, via Signals
+	goAhead     bool   // whether to send IAC GA before reading a line, when SGA isn't negotiated
+
+	pending  []byte // data received while awaiting negotiation (or peeked), returned by the next Read
+	editedCR bool   // set when EditLine (or Page) read a CR, so the LF or NUL after it can be skipped
+	marked   bool   // set when the client answers IAC DO TM, confirming it's processed everything sent before
+	ttype    terminalTypes
+	environ  environment
+	window   windowSize
+	store    store
+	viewers  []*viewer // viewers watching the session (see Server.Watch and TeeOutput); guarded by writeMu
+
+	stopWatch func() bool // stops watch interrupting reads once the session's context is done
+
+	inputMode InputMode // how the client sends input (see SetInputMode)
+	readCR    bool      // set when Read returned a CR in InputChar mode, so the LF or NUL after it can be dropped
+
+	stats       counters  // the session's traffic
+	serverStats *counters // the server's traffic, which the session's counts are added to as well
+	events      *eventBus // the server's subscribers to the session's events; nil outside a Server
+
+	writeMu sync.Mutex // serialises writes, and the negotiation state the writer depends on
+
+	// hijacked is set once Hijack has handed the connection to the handler. It's only set with writeMu held, but can
+	// be read without it (e.g. by Server.Expvar), so metrics never wait on a write.
+	hijacked atomic.Bool
+
+	windowMu sync.Mutex // guards window, which the client can update while another goroutine reads it
+
+	interruptMu  sync.Mutex
+	interrupters []*interrupter // the contexts to cancel when the client interrupts (see WithInterrupt)
+
+	signalMu      sync.Mutex
+	signals       chan Signal // the control events the client sends (see Signals); nil until asked for
+	signalsClosed bool        // set once signals is closed, as the session's context is done
+
+	inputMu      sync.Mutex
+	injected     []byte    // input from viewers attached to the session (see Server.Attach), returned by the next Read
+	interrupting bool      // set while inject has interrupted reading from the client, with a read deadline
+	readDeadline time.Time // set by SetReadDeadline, and restored once the session's own deadlines are done with
+	inputTaps    []*viewer // writers copying the input read by the handler (see TeeInput)
+}
+
+// ID identifies the session to Server.Attach and Server.Watch: it's the client's address, numbered so it's unique even
+// if another client shares the address, e.g. "192.0.2.1:50000#1".
+func (s *Session) ID() string {
+	return s.id
 }
 
+// Context returns the session's context, which is cancelled once the client disconnects. It carries the session, for
+// SessionFromContext.
 func (s *Session) Context() context.Context {
 	return s.ctx
 }
 
+// Logger returns the logger for the session: the server's, with what it logs limited to a sample by the server's
+// LogSampling (shared with what the server logs about the session), so handlers can log freely.
+func (s *Session) Logger() *slog.Logger {
+	return s.logger
+}
+
+// Clock returns the Clock timing the session (see WithClock).
+func (s *Session) Clock() Clock {
+	return ContextClock(s.ctx)
+}
+
+// Read reads data from the client, flushing any buffered output first so the client sees everything (such as a
+// prompt) written before we block waiting on their response. In InputChar mode, it returns the client's key presses as
+// they're typed, with ENTER as a single CR (see SetInputMode), unless the client is transmitting in BINARY mode.
 func (s *Session) Read(data []byte) (n int, err error) {
-	return s.reader.Read(data)
+	defer func() {
+		s.teeInput(data[:n])
+	}()
+
+	for {
+		n, err = s.read(data)
+		if s.inputMode != InputChar || s.reader.binary || n == 0 {
+			return n, err
+		}
+
+		// Retry if all that was read was the rest of an ENTER.
+		if n = s.collapseEnter(data[:n]); n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// read is Read, without the InputChar handling of ENTER.
+func (s *Session) read(data []byte) (n int, err error) {
+	if s.ctx.Err() != nil {
+		return 0, ErrSessionClosed
+	}
+
+	if err = s.Flush(); err != nil {
+		return 0, s.closedErr(err)
+	}
+
+	if len(s.pending) > 0 {
+		n = copy(data, s.pending)
+		s.pending = s.pending[n:]
+
+		return n, nil
+	}
+
+	n, err = s.readClient(context.Background(), data)
+	return n, s.closedErr(err)
 }
 
+// ReadContext is like Read, but returns early with the context's error once 'ctx' is done, so a handler can stop
+// waiting on the client (e.g. when a command it's relaying input to exits). Unlike Read, it doesn't flush buffered
+// output first, so another goroutine can write to the session (and flush it) meanwhile.
+func (s *Session) ReadContext(ctx context.Context, data []byte) (n int, err error) {
+	defer func() {
+		s.teeInput(data[:n])
+	}()
+
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if s.hijacked.Load() {
+		return 0, ErrHijacked
+	}
+
+	if len(s.pending) > 0 {
+		n = copy(data, s.pending)
+		s.pending = s.pending[n:]
+
+		return n, nil
+	}
+
+	// Interrupt a blocked read as soon as the context is done.
+	if s.Conn != nil {
+		defer interruptOnDone(ctx, s.interruptRead)()
+	}
+
+	n, err = s.readClient(ctx, data)
+	if err != nil && ctx.Err() != nil {
+		return n, ctx.Err()
+	}
+
+	return n, s.closedErr(err)
+}
+
+// closedErr returns ErrSessionClosed in place of 'err' if the session's context is done, as that's why reading (or
+// writing) failed, unless it's the error the reader failed with (e.g. ErrNegotiationFlood), which ended the session.
+func (s *Session) closedErr(err error) error {
+	if err != nil && s.ctx.Err() != nil && (s.reader == nil || err != s.reader.failed) {
+		return ErrSessionClosed
+	}
+
+	return err
+}
+
+// watch interrupts any read blocked waiting on the client, or write blocked on a client that's stopped reading, once the
+// session's context is done, until Hijack stops it.
+func (s *Session) watch() {
+	if s.Conn == nil {
+		return
+	}
+
+	s.stopWatch = context.AfterFunc(s.ctx, func() {
+		// Wait for any read deadline being restored meanwhile (see restoreReadDeadline), so it's not left in place.
+		s.inputMu.Lock()
+		defer s.inputMu.Unlock()
+
+		_ = s.Conn.SetDeadline(time.Unix(1, 0))
+	})
+}
+
+// SetReadDeadline sets the deadline for reading from the client, like net.Conn's. The session sets deadlines of its own
+// to interrupt reads (e.g. for Server.Attach), restoring this one afterwards, so it should be set with this method
+// rather than on Session.Conn.
+func (s *Session) SetReadDeadline(t time.Time) error {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	s.readDeadline = t
+
+	// The read is being interrupted; the deadline is restored once it has been.
+	if s.interrupting {
+		return nil
+	}
+
+	return s.Conn.SetReadDeadline(t)
+}
+
+// SetDeadline sets the deadlines for reading from and writing to the client, like net.Conn's (see SetReadDeadline).
+func (s *Session) SetDeadline(t time.Time) error {
+	if err := s.Conn.SetWriteDeadline(t); err != nil {
+		return err
+	}
+
+	return s.SetReadDeadline(t)
+}
+
+// interruptRead sets the read deadline 't' for interruptOnDone, or restores the one set with SetReadDeadline for the
+// zero time.
+func (s *Session) interruptRead(t time.Time) error {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	if t.IsZero() {
+		s.restoreReadDeadline()
+		return nil
+	}
+
+	return s.Conn.SetReadDeadline(t)
+}
+
+// restoreReadDeadline puts back the read deadline set with SetReadDeadline once the session's own is done with. It's
+// left alone while inject is interrupting a read (interrupted restores it), or once the session has ended, so reads
+// stay interrupted. s.inputMu must be held.
+func (s *Session) restoreReadDeadline() {
+	if s.interrupting || s.ctx.Err() != nil {
+		return
+	}
+
+	_ = s.Conn.SetReadDeadline(s.readDeadline)
+}
+
+// ReadLine reads a line from the client. Unless SGA has been negotiated, IAC GA is sent first to tell the client it's
+// their turn to transmit (as required by RFC 854). In InputChar mode, the line is edited and echoed by the session, as
+// with EditLine.
 func (s *Session) ReadLine() (string, error) {
-	return ReadLine(s)
+	if s.inputMode == InputChar {
+		return s.EditLine("", nil)
+	}
+
+	if s.goAhead && !s.options.local(SGA) {
+		if _, err := s.Write(append(commandSignature(), IAC, GA)); err != nil {
+			return "", err
+		}
+	}
+
+	// Skip the LF or NUL after the CR that ended the line EditLine (or Page) last read, so it isn't read as a blank line.
+	var reader io.Reader = s
+	if s.editedCR {
+		s.editedCR = false
+
+		var buffer [1]byte
+		n, err := s.Read(buffer[:])
+		if err != nil {
+			return "", err
+		}
+
+		if n > 0 && buffer[0] != NL && buffer[0] != NUL {
+			reader = io.MultiReader(bytes.NewReader(buffer[:n]), s)
+		}
+	}
+
+	line, err := ReadLine(reader)
+	if err == nil {
+		s.count(statLines, 1)
+	}
+
+	return line, err
+}
+
+// ReadPassword writes 'prompt' and reads a line from the client without it being echoed, by offering to ECHO (without
+// doing so) while it's read. The client's own echoing is restored afterwards, even if reading fails, and the line is
+// ended on the client's screen as it wasn't echoed.
+func (s *Session) ReadPassword(prompt string) (password string, err error) {
+	// Clients already leave echoing to the session in InputChar mode (or after EditLine), so it just doesn't echo.
+	if s.options.offered(ECHO) {
+		return s.EditLine(prompt, &LineEditor{Secret: true})
+	}
+
+	if err = s.WriteString(prompt); err != nil {
+		return "", err
+	}
+
+	if _, err = s.WriteCommand(IAC, WILL, ECHO); err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_, restoreErr := s.WriteCommand(IAC, WONT, ECHO)
+		if restoreErr == nil {
+			restoreErr = s.WriteLine()
+		}
+
+		if restoreErr == nil {
+			restoreErr = s.Flush()
+		}
+
+		if err == nil {
+			err = restoreErr
+		}
+	}()
+
+	return s.ReadLine()
 }
 
 func (s *Session) Write(data []byte) (n int, err error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return 0, ErrHijacked
+	}
+
+	if !isCommand(data) {
+		s.writeViewers(data)
+	}
+
 	return s.writer.Write(data)
 }
 
+// Flush writes any buffered output to the client.
+func (s *Session) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return ErrHijacked
+	}
+
+	if s.buffered == nil {
+		return nil
+	}
+
+	return s.buffered.Flush()
+}
+
+// flushQueue flushes any buffered output, then waits for the session's output queue (if it has one) to be written.
+func (s *Session) flushQueue() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return ErrHijacked
+	}
+
+	return s.flushQueueLocked()
+}
+
+// flushQueueLocked is flushQueue, for when s.writeMu is already held.
+func (s *Session) flushQueueLocked() error {
+	if s.buffered != nil {
+		if err := s.buffered.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if s.queue == nil {
+		return nil
+	}
+
+	return s.queue.drain()
+}
+
+// CloseWrite flushes any buffered output, then shuts down the writing side of the connection, so the client reads
+// EOF (e.g. to mark the end of a proxied stream or file transfer) while the session can still read what the client
+// sends. It returns ErrCloseWriteUnsupported if the underlying connection can't be half-closed, e.g. net.Pipe.
+func (s *Session) CloseWrite() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return ErrHijacked
+	}
+
+	if err := s.flushQueueLocked(); err != nil {
+		return err
+	}
+
+	return closeWrite(s.Conn)
+}
+
+// Hijack lets the handler take over the connection, like http.Hijacker, e.g. to switch to another protocol (such as a
+// file transfer) partway through the session. Any buffered output is flushed first. It returns the raw connection,
+// and a reader holding whatever the session had already read from the client but not yet processed, followed by the
+// rest of the connection.
+//
+// Afterwards, the session's reads and writes fail with ErrHijacked, and the server no longer closes the connection
+// (even after the handler returns, or the server shuts down), so the caller must close it.
+func (s *Session) Hijack() (net.Conn, *bufio.Reader, error) {
+	conn, input, err := s.hijack()
+	if err == nil {
+		s.events.publish(SessionHijacked, s)
+	}
+
+	return conn, input, err
+}
+
+// hijack hands the connection over for Hijack.
+func (s *Session) hijack() (net.Conn, *bufio.Reader, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return nil, nil, ErrHijacked
+	}
+
+	if err := s.flushQueueLocked(); err != nil {
+		return nil, nil, err
+	}
+
+	conn := s.Conn
+	if server, ok := conn.(serverConn); ok {
+		server.hijacked.Store(true)
+		conn = server.Conn
+	}
+
+	s.hijacked.Store(true)
+
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+
+	// Clear any deadline the session left, so it doesn't surprise the new owner.
+	_ = conn.SetDeadline(time.Time{})
+
+	unread, _ := s.reader.buffered.Peek(s.reader.buffered.Buffered())
+	input := append(append(s.pending, s.takeInjected()...), unread...)
+	s.pending = nil
+
+	return conn, bufio.NewReader(io.MultiReader(bytes.NewReader(input), conn)), nil
+}
+
+// CloseGracefully ends the session cleanly: it writes 'msg' (if not empty) as a goodbye line, flushes any buffered
+// output, and closes the connection. If the client speaks TELNET, it's first sent IAC DO TM (timing mark, RFC 860),
+// and the connection isn't closed until it answers (or a few seconds pass), so the client has processed everything
+// written before it's disconnected. This avoids a handler's final output racing with the server closing the
+// connection once the handler returns.
+//
+// 'msg' is written as is (see WriteString), so should include its own line break, e.g. "Goodbye!\r\n".
+func (s *Session) CloseGracefully(msg string) error {
+	if msg != "" {
+		if err := s.WriteString(msg); err != nil {
+			return err
+		}
+	}
+
+	marking, err := s.startDrain()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := WithTimeout(s.ctx, closeTimeout)
+	defer cancel()
+
+	// The client may not support TM (or answer at all), so carry on closing regardless.
+	_ = s.awaitDrain(ctx, marking)
+
+	if s.Conn == nil {
+		return nil
+	}
+
+	return s.Conn.Close()
+}
+
+// Drain flushes any buffered output, and waits for the client to receive it (until 'ctx' is done), so it isn't lost if
+// the connection is closed straight after, e.g. a goodbye message before a fast disconnect. If the client speaks
+// TELNET, it's sent IAC DO TM (timing mark, RFC 860) and Drain waits for its answer, confirming it's processed
+// everything before. Otherwise, Drain waits for the OS to send everything queued on the connection, which it can only
+// tell for TCP connections on Linux (elsewhere, it returns once the output is flushed).
+//
+// It returns ctx.Err() if 'ctx' is done first. CloseGracefully drains the session before closing it.
+func (s *Session) Drain(ctx context.Context) error {
+	marking, err := s.startDrain()
+	if err != nil {
+		return err
+	}
+
+	return s.awaitDrain(ctx, marking)
+}
+
+// startDrain starts draining the session's output for Drain, flushing it and sending IAC DO TM if the client speaks
+// TELNET, which it reports.
+func (s *Session) startDrain() (marking bool, err error) {
+	if !s.options.peerNegotiated() {
+		return false, s.flushQueue()
+	}
+
+	s.marked = false
+
+	if _, err = s.WriteCommand(IAC, DO, TM); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// awaitDrain waits for the output flushed by startDrain to reach the client: its answer to IAC DO TM if 'marking',
+// otherwise the OS sending it.
+func (s *Session) awaitDrain(ctx context.Context, marking bool) error {
+	if marking {
+		return s.await(ctx, func() bool { return s.marked })
+	}
+
+	conn := s.Conn
+	if server, ok := conn.(serverConn); ok {
+		conn = server.Conn
+	}
+
+	tcpConn, ok := tcpConnOf(conn)
+	if !ok {
+		return nil
+	}
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for {
+		unsent, err := unsentBytes(tcpConn)
+		if err != nil || unsent == 0 {
+			// Where the OS can't tell, there's nothing more to wait for.
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WriteCommand writes a command to the client, e.g. IAC WILL ECHO, recording any negotiation it makes before other
+// writes can follow it.
 func (s *Session) WriteCommand(command byte, option byte, action byte) (n int, err error) {
-	return WriteCommand(s, command, option, action)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return 0, ErrHijacked
+	}
+
+	n, err = WriteCommand(s.writer, command, option, action)
+	if err == nil && command == IAC {
+		s.options.sent(option, action)
+		s.updateNewlinePolicy()
+	}
+
+	return n, err
 }
 
+// WriteLine writes 'text' to the client as a line, ending it with CR LF.
 func (s *Session) WriteLine(text ...string) error {
 	return WriteLine(s, text...)
 }
+
+// WriteString writes 'text' to the client as is, e.g. for a prompt, or output that already ends its lines.
+func (s *Session) WriteString(text ...string) error {
+	return WriteString(s, text...)
+}
+
+// Writef writes the text formatted from 'format' and 'args' (see fmt.Sprintf) to the client as is.
+func (s *Session) Writef(format string, args ...any) error {
+	return Writef(s, format, args...)
+}
+
+// NewlinePolicy returns the newline policy configured for the session.
+func (s *Session) NewlinePolicy() NewlinePolicy {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.writer.newline
+}
+
+// SetNewlinePolicy sets how line endings written to the session are translated.
+//
+// The policy is suspended while BINARY is negotiated for our side of the connection, during which data is always
+// written raw.
+func (s *Session) SetNewlinePolicy(policy NewlinePolicy) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.writer.newline = policy
+}
+
+// receivedOption records a negotiation command from the client.
+func (s *Session) receivedOption(verb byte, option byte) {
+	s.count(statNegotiations, 1)
+
+	s.writeMu.Lock()
+	s.options.received(verb, option)
+	s.updateNewlinePolicy()
+	s.writeMu.Unlock()
+
+	switch option {
+	case TM:
+		if verb == WILL || verb == WONT {
+			s.marked = true
+		}
+	case TTYPE:
+		s.receivedTerminalTypeOption(verb)
+	case NEWENVIRON:
+		s.receivedEnvironmentOption(verb)
+	case NAWS:
+		s.receivedWindowSizeOption(verb)
+	case BINARY:
+		s.receivedBinaryOption()
+	}
+}
+
+// receivedSubnegotiation handles a subnegotiation from the client.
+func (s *Session) receivedSubnegotiation(option byte, payload []byte) {
+	s.count(statSubnegotiations, 1)
+
+	switch option {
+	case TTYPE:
+		s.receivedTerminalType(payload)
+	case NEWENVIRON:
+		s.receivedEnvironment(payload)
+	case NAWS:
+		s.receivedWindowSize(payload)
+	}
+}
+
+// LocalOption reports whether the server has agreed with the client to perform 'option' (e.g. ECHO, once the client
+// has answered IAC WILL ECHO with DO).
+func (s *Session) LocalOption(option byte) bool {
+	return s.options.local(option)
+}
+
+// RemoteOption reports whether the client has agreed with the server to perform 'option' (e.g. NAWS, once it has
+// answered IAC DO NAWS with WILL).
+func (s *Session) RemoteOption(option byte) bool {
+	return s.options.remote(option)
+}
+
+// AwaitOption waits until the client has answered the last negotiation sent for 'option' (e.g. with WriteCommand), or
+// 'ctx' is done, so the handler can rely on the client's capabilities rather than sleeping. It returns nil once the
+// option is enabled (or disabled, if 'want' is false) on the side that was negotiated (WILL or WONT for ours, DO or
+// DONT for the client's), or ErrOptionRefused if the client answered otherwise. Any data the client sends in the
+// meantime is kept for the next Read.
+//
+// Clients don't answer negotiations that don't change an option's state (RFC 1143), so a handler should only await
+// those that do.
+func (s *Session) AwaitOption(ctx context.Context, option byte, want bool) error {
+	var enabled bool
+
+	err := s.await(ctx, func() (answered bool) {
+		answered, enabled = s.options.answered(option)
+		return answered
+	})
+	if err != nil {
+		return err
+	}
+
+	if enabled != want {
+		return ErrOptionRefused
+	}
+
+	return nil
+}
+
+// await reads from the client until 'done' reports true or 'ctx' is done, so negotiation replies get processed.
+// Any data the client sends in the meantime is set aside for the next Read.
+func (s *Session) await(ctx context.Context, done func() bool) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+
+	// Interrupt a blocked read as soon as the context is done.
+	if s.Conn != nil {
+		defer interruptOnDone(ctx, s.interruptRead)()
+	}
+
+	var buffer [256]byte
+
+	for !done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := s.reader.pump(buffer[:])
+		s.pending = append(s.pending, buffer[:n]...)
+
+		// Set aside any input from attached viewers that interrupted the read, too.
+		if err != nil && s.interrupted(err) {
+			s.pending = append(s.pending, s.takeInjected()...)
+			continue
+		}
+
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			// The read deadline may fire a moment before the context notices its own deadline has passed.
+			if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+				return context.DeadlineExceeded
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// receivedCommand handles a single byte command from the client.
+func (s *Session) receivedCommand(command byte) {
+	s.count(statCommands, 1)
+
+	switch command {
+	case AYT:
+		if s.forwardAYT {
+			break
+		}
+
+		response := s.aytResponse
+		if response == "" {
+			response = DefaultAYTResponse
+		}
+
+		// The client is waiting on us, so don't leave the response sitting in the buffer.
+		if _, err := s.writeUntee([]byte(response)); err == nil {
+			_ = s.Flush()
+		}
+	case IP, BRK:
+		s.interrupt()
+	}
+
+	switch command {
+	case IP, BRK, AYT, AO, EC, EL:
+		s.signal(Signal{Command: command})
+	}
+}
+
+// writeUntee writes 'data' to the client without copying it to TeeOutput's writers or attached viewers, e.g. for
+// replies the handler didn't write.
+func (s *Session) writeUntee(data []byte) (n int, err error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.hijacked.Load() {
+		return 0, ErrHijacked
+	}
+
+	return s.writer.Write(data)
+}
+
+// updateNewlinePolicy applies the negotiated BINARY and LINEMODE state to the writer. s.writeMu must be held.
+func (s *Session) updateNewlinePolicy() {
+	s.writer.binary = s.options.local(BINARY)
+	s.writer.linemode = s.options.remote(LINEMODE)
+}
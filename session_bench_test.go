@@ -0,0 +1,104 @@
+package telnet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+)
+
+func BenchmarkSession_WriteLine(b *testing.B) {
+	buffered := bufio.NewWriter(io.Discard)
+	session := &Session{
+		ctx:      context.Background(),
+		writer:   newWriter(buffered),
+		buffered: buffered,
+	}
+	session.SetNewlinePolicy(NewlineCRLF)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := session.WriteString("uname: command not found\n"); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := session.Flush(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkSession_Concurrent(b *testing.B) {
+	input := bytes.Repeat([]byte("uname -a\r\n"), 100)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+
+	b.RunParallel(func(pb *testing.PB) {
+		source := bytes.NewReader(input)
+		buffered := bufio.NewWriter(io.Discard)
+		session := &Session{
+			ctx:      context.Background(),
+			reader:   newReader(source),
+			writer:   newWriter(buffered),
+			buffered: buffered,
+		}
+
+		for pb.Next() {
+			source.Reset(input)
+			session.reader.buffered.Reset(source)
+
+			for {
+				line, err := session.ReadLine()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					b.Fatal(err)
+				}
+
+				if err = session.WriteString(line, "\r\n$ "); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkServer_Echo(b *testing.B) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	server := &Server{Handler: EchoHandler, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	go server.Serve(listener)
+	defer server.Shutdown()
+
+	conn, err := Dial("tcp", listener.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+
+	data := bytes.Repeat([]byte("apple banana cherry "), 50)
+	p := make([]byte, len(data))
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err = conn.Write(data); err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err = io.ReadFull(conn, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// aliases is a session's command aliases, by name.
+type aliases map[string]string
+
+// newAliases copies 'base', so the session can modify its aliases without affecting other sessions.
+func newAliases(base map[string]string) aliases {
+	a := make(aliases, len(base))
+	for name, value := range base {
+		a[name] = value
+	}
+
+	return a
+}
+
+// builtins returns the alias commands for the session.
+func (a aliases) builtins() map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"alias":   a.alias,
+		"unalias": a.unalias,
+	}
+}
+
+// expand replaces the first word of 'line' with its alias, if it has one. Like a real shell, an alias starting with
+// another alias is expanded again, but never with the same alias twice.
+func (a aliases) expand(line string) string {
+	expanded := make(map[string]bool)
+
+	for {
+		trimmed := strings.TrimLeft(line, " \t")
+
+		name, rest := trimmed, ""
+		if end := strings.IndexAny(trimmed, " \t"); end >= 0 {
+			name, rest = trimmed[:end], trimmed[end:]
+		}
+
+		value, ok := a[name]
+		if !ok || expanded[name] {
+			return line
+		}
+
+		expanded[name] = true
+		line = value + rest
+	}
+}
+
+func (a aliases) alias(session *telnet.Session, args []string) error {
+	if len(args) == 1 {
+		names := make([]string, 0, len(a))
+		for name := range a {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		args = append(args, names...)
+	}
+
+	var (
+		builder strings.Builder
+		missing []string
+	)
+
+	for _, arg := range args[1:] {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if hasValue {
+			a[name] = value
+			continue
+		}
+
+		if value, ok := a[name]; ok {
+			builder.WriteString("alias " + name + "='" + strings.ReplaceAll(value, "'", `'\''`) + "'\r\n")
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if err := session.WriteString(builder.String()); err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: not found", strings.Join(missing, ": not found\n"))
+	}
+
+	return nil
+}
+
+func (a aliases) unalias(session *telnet.Session, args []string) error {
+	if len(args) > 1 && args[1] == "-a" {
+		clear(a)
+		return nil
+	}
+
+	var missing []string
+
+	for _, name := range args[1:] {
+		if _, ok := a[name]; !ok {
+			missing = append(missing, name)
+			continue
+		}
+
+		delete(a, name)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: not found", strings.Join(missing, ": not found\n"))
+	}
+
+	return nil
+}
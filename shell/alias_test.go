@@ -0,0 +1,32 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestAliases_Expand(t *testing.T) {
+	a := newAliases(map[string]string{
+		"l":   "ll -a",
+		"ll":  "ls -l",
+		"ls":  "ls --color=auto",
+		"bye": "exit",
+	})
+
+	tests := []struct {
+		Line     string
+		Expected string
+	}{
+		{Line: "ll /tmp", Expected: "ls --color=auto -l /tmp"},
+		{Line: "l", Expected: "ls --color=auto -l -a"},
+		{Line: "ls", Expected: "ls --color=auto"},
+		{Line: "bye", Expected: "exit"},
+		{Line: "echo ll", Expected: "echo ll"},
+		{Line: "lll", Expected: "lll"},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, a.expand(test.Line); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnterminatedQuote is returned by SplitArgs when a quoted string isn't closed.
+var ErrUnterminatedQuote = errors.New("unterminated quoted string")
+
+// SplitArgs splits a command line into arguments like a POSIX shell: arguments are separated by unquoted whitespace,
+// single quotes preserve everything literally, double quotes preserve everything except backslash escapes of $, `, "
+// and \, and a backslash outside of quotes escapes the following character.
+func SplitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, char := range line {
+		switch {
+		case escaped:
+			// Within double quotes, a backslash only escapes characters that are otherwise special.
+			if quote == '"' && !strings.ContainsRune("$`\"\\", char) {
+				current.WriteRune('\\')
+			}
+
+			current.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				current.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArg = true
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, ErrUnterminatedQuote
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// Stage is one command of a command line, along with the operator joining it to the command before it.
+type Stage struct {
+	// Operator is the control operator before the command: ";", "&", "&&", "||" or "|", or "" for the first command.
+	Operator string
+
+	// Command is the command's text, with surrounding whitespace removed.
+	Command string
+
+	// Background is set if the command is followed by &, to run it in the background.
+	Background bool
+}
+
+// SplitCommands splits a command line into its commands on the control operators ;, &, &&, || and | (and newlines,
+// which act like ;), ignoring operators that are quoted or escaped. Empty commands (e.g. after a trailing ;) are
+// dropped.
+func SplitCommands(line string) ([]Stage, error) {
+	var (
+		stages   []Stage
+		operator string
+		start    int
+		quote    byte
+	)
+
+	add := func(end int, next string) {
+		// An empty command keeps the operator before it, so "a && ; b" only runs b if a succeeds.
+		if command := strings.TrimSpace(line[start:end]); command != "" {
+			stages = append(stages, Stage{Operator: operator, Command: command, Background: next == "&"})
+			operator = next
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch {
+		case char == '\\' && quote != '\'':
+			if i++; i == len(line) {
+				return nil, ErrUnterminatedQuote
+			}
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == ';' || char == '\n':
+			add(i, ";")
+			start = i + 1
+		case isRedirection(line, i):
+		case char == '&' || char == '|':
+			next := string(char)
+			if i+1 < len(line) && line[i+1] == char {
+				next += next
+			}
+
+			add(i, next)
+			i += len(next) - 1
+			start = i + 1
+		}
+	}
+
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+
+	add(len(line), "")
+
+	return stages, nil
+}
+
+// isRedirection reports whether the & or | at line[i] is part of a redirection (e.g. "2>&1", "&>" or ">|") rather
+// than a control operator.
+func isRedirection(line string, i int) bool {
+	if line[i] != '&' && line[i] != '|' {
+		return false
+	}
+
+	if i > 0 && (line[i-1] == '>' || (line[i-1] == '<' && line[i] == '&')) {
+		return true
+	}
+
+	return line[i] == '&' && i+1 < len(line) && line[i+1] == '>'
+}
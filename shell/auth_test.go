@@ -0,0 +1,296 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestNewAuthHandlerFunc(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Err      error
+		Expected bool
+	}{
+		{Input: "admin\r\nwrong\r\nadmin\r\nsecret\r\n", Expected: true},
+		{Input: "admin\r\nwrong\r\nadmin\r\nwrong\r\n", Expected: false},
+		{Input: "admin\r\nsecret\r\n", Err: errors.New("directory unavailable"), Expected: false},
+	}
+
+	for testNumber, test := range tests {
+		var attempts int
+
+		handler := NewAuthHandlerFunc(func(ctx context.Context, username string, password string, remoteAddr net.Addr) (bool, error) {
+			attempts++
+
+			if ctx == nil || remoteAddr == nil {
+				t.Errorf("For test #%d, expected a context and remote address, but actually got %v and %v.", testNumber, ctx, remoteAddr)
+			}
+
+			return username == "admin" && password == "secret", test.Err
+		}, 2)
+
+		results := make(chan string, 1)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		go telnet.Serve(listener, func(session *telnet.Session) {
+			// Don't wait the default 3 seconds after each failed login.
+			session.Set(throttleKey{}, &Throttle{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+			var username string
+			if handler(session) {
+				user, _ := CurrentUser(session)
+				username = user.Username
+			}
+
+			results <- username
+		})
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if _, err = conn.Write([]byte(test.Input)); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		select {
+		case username := <-results:
+			if expected, actual := test.Expected, username == "admin"; expected != actual {
+				t.Errorf("For test #%d, expected the login to succeed to be %t, but actually got %t.", testNumber, expected, actual)
+			}
+		case <-time.After(10 * time.Second):
+			t.Errorf("For test #%d, expected the AuthHandler to return, but it didn't.", testNumber)
+		}
+
+		if test.Err != nil && attempts != 1 {
+			t.Errorf("For test #%d, expected an error to end the login after 1 attempt, but actually got %d.", testNumber, attempts)
+		}
+
+		conn.Close()
+		listener.Close()
+	}
+}
+
+func TestNewCaptureAuthHandler(t *testing.T) {
+	type result struct {
+		success  bool
+		attempts []LoginAttempt
+	}
+
+	results := make(chan result, 1)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	handler := NewCaptureAuthHandler(3)
+
+	hash, err := HashPassword("admin")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	var canaries CredentialStore = MapCredentialStore{"admin": {Username: "admin", PasswordHash: hash, Canary: true}}
+
+	go telnet.Serve(listener, func(session *telnet.Session) {
+		session.Set(throttleKey{}, &Throttle{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+		session.Set(canaryKey{}, canaries)
+
+		success := handler(session)
+		results <- result{success: success, attempts: LoginAttempts(session)}
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("root\r\nroot\r\nadmin\r\nadmin\r\nroot\r\n12345\r\n")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	var actual result
+	select {
+	case actual = <-results:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected the AuthHandler to return, but it didn't.")
+	}
+
+	if !actual.success {
+		t.Errorf("Expected the third login to succeed, but it didn't.")
+	}
+
+	expected := []LoginAttempt{
+		{Username: "root", Password: "root", Number: 1, IP: "127.0.0.1"},
+		{Username: "admin", Password: "admin", Number: 2, IP: "127.0.0.1", Canary: true},
+		{Username: "root", Password: "12345", Number: 3, IP: "127.0.0.1", Success: true},
+	}
+
+	if len(actual.attempts) != len(expected) {
+		t.Fatalf("Expected %d login attempts, but actually got %d.", len(expected), len(actual.attempts))
+	}
+
+	for testNumber, attempt := range actual.attempts {
+		if attempt.Time.IsZero() {
+			t.Errorf("For test #%d, expected the attempt's time to be set, but it wasn't.", testNumber)
+		}
+
+		attempt.Time, attempt.Duration = time.Time{}, 0
+
+		if expected, actual := expected[testNumber], attempt; expected != actual {
+			t.Errorf("For test #%d, expected %+v, but actually got %+v.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestServer_SecondFactorErrors(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Err    error
+		Logged bool
+	}{
+		// The client disconnecting at the code prompt isn't worth logging.
+		{Input: "admin\r\nsecret\r\n"},
+		{Input: "admin\r\nsecret\r\n123456\r\n", Err: errors.New("authenticator unavailable"), Logged: true},
+	}
+
+	for testNumber, test := range tests {
+		var logs lockedBuffer
+
+		server := &Server{
+			AuthHandler:  NewAuthHandler("admin", "secret", 1),
+			SecondFactor: stubSecondFactor{err: test.Err},
+		}
+
+		done := make(chan struct{})
+
+		ts := telnettest.NewUnstartedMemoryServer(func(session *telnet.Session) {
+			defer close(done)
+
+			server.HandlerFunc(session)
+		})
+		ts.Config.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+		ts.Start()
+
+		if _, err := ts.Conn.Write([]byte(test.Input)); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if test.Err == nil {
+			// Wait for the code prompt before disconnecting.
+			var response []byte
+			for !bytes.Contains(response, []byte(DefaultMessages.CodePrompt)) {
+				buffer := make([]byte, 256)
+
+				n, err := ts.Conn.Read(buffer)
+				if err != nil {
+					t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+				}
+
+				response = append(response, buffer[:n]...)
+			}
+
+			_ = ts.Conn.Close()
+		}
+
+		<-done
+		ts.Close()
+
+		if expected, actual := test.Logged, strings.Contains(logs.String(), "failed to verify login"); expected != actual {
+			t.Errorf("For test #%d, expected the error to be logged to be %t, but actually got %t.", testNumber, expected, actual)
+		}
+	}
+}
+
+// stubSecondFactor is a SecondFactor all users are enrolled in, failing to verify their codes with 'err'.
+type stubSecondFactor struct {
+	err error
+}
+
+func (s stubSecondFactor) Enrolled(*User) bool {
+	return true
+}
+
+func (s stubSecondFactor) Verify(context.Context, *User, string) (bool, error) {
+	return false, s.err
+}
+
+func TestServer_AuthTimeout(t *testing.T) {
+	server := &Server{AuthHandler: NewAuthHandler("admin", "secret", 3), AuthTimeout: time.Minute}
+	clock := telnettest.NewClock(time.Now())
+
+	ts := telnettest.NewMemoryServer(server.HandlerFunc, telnet.WithClock(clock))
+	defer ts.Close()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	// Read everything until the server disconnects.
+	response, err := io.ReadAll(ts.Conn)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "Login: \r\nLogin timed out after 60 seconds.\r\n", string(response); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestServer_LoginDelay(t *testing.T) {
+	tests := []struct {
+		Delay   time.Duration
+		Advance time.Duration
+	}{
+		{Delay: -1},
+		{Delay: time.Minute, Advance: time.Minute},
+	}
+
+	for testNumber, test := range tests {
+		server := &Server{AuthHandler: NewAuthHandler("admin", "secret", 2), LoginDelay: test.Delay}
+		clock := telnettest.NewClock(time.Now())
+
+		ts := telnettest.NewMemoryServer(server.HandlerFunc, telnet.WithClock(clock))
+
+		client := ts.Client()
+
+		err := client.Run(
+			telnettest.Expect("Login: "),
+			telnettest.Send("admin\r\n"),
+			telnettest.Expect("Password: "),
+			telnettest.Send("wrong\r\n"),
+		)
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if test.Advance > 0 {
+			clock.BlockUntil(1)
+			clock.Advance(test.Advance)
+		}
+
+		if _, err = client.Expect("Login incorrect\n"); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		_ = client.Close()
+		ts.Close()
+	}
+}
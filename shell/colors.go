@@ -0,0 +1,25 @@
+package shell
+
+import (
+	"strings"
+)
+
+// DefaultColors shows a bold green prompt, and red error messages.
+var DefaultColors = Colors{Prompt: "1;32", Error: "31"}
+
+// Colors holds the ANSI SGR parameters (e.g. "1;32" for bold green) used to color shell output; empty parameters leave
+// that output plain.
+type Colors struct {
+	Prompt string
+	Error  string
+}
+
+// colorize wraps 'text' in the ANSI escape codes for 'color', leaving any trailing line break uncolored.
+func colorize(color string, text string) string {
+	trimmed := strings.TrimRight(text, "\r\n")
+	if color == "" || trimmed == "" {
+		return text
+	}
+
+	return "\x1b[" + color + "m" + trimmed + "\x1b[0m" + text[len(trimmed):]
+}
@@ -0,0 +1,40 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestColorize(t *testing.T) {
+	tests := []struct {
+		Color    string
+		Text     string
+		Expected string
+	}{
+		{
+			Color:    "",
+			Text:     "$ ",
+			Expected: "$ ",
+		},
+		{
+			Color:    "1;32",
+			Text:     "$ ",
+			Expected: "\x1b[1;32m$ \x1b[0m",
+		},
+		{
+			Color:    "31",
+			Text:     "foo: command not found\r\n",
+			Expected: "\x1b[31mfoo: command not found\x1b[0m\r\n",
+		},
+		{
+			Color:    "31",
+			Text:     "\r\n",
+			Expected: "\r\n",
+		},
+	}
+
+	for testNumber, test := range tests {
+		if actual := colorize(test.Color, test.Text); test.Expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.Expected, actual)
+		}
+	}
+}
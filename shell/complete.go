@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// commandNames returns the names of the server's commands and the session's builtins and aliases, sorted and
+// deduplicated. A regex command's name is the first word of its literal prefix (e.g. "docker" for "^docker .*$").
+func (s *Server) commandNames(state *sessionState) []string {
+	seen := map[string]bool{DefaultHistoryCommand: true}
+
+	for _, name := range s.exitCommands() {
+		seen[name] = true
+	}
+
+	for name := range s.handlers {
+		seen[name] = true
+	}
+
+	for name := range state.builtins {
+		seen[name] = true
+	}
+
+	for name := range state.aliases {
+		seen[name] = true
+	}
+
+	for _, command := range s.Commands {
+		pattern, err := regexp.Compile(command.Regex)
+		if err != nil {
+			continue
+		}
+
+		prefix, _ := pattern.LiteralPrefix()
+		if fields := strings.Fields(prefix); len(fields) > 0 {
+			seen[fields[0]] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// complete returns the completions for the last word of 'line': command names for the first word, and the server's
+// extra completions (such as fake file paths) and paths within the session's filesystem (if set) for any other word.
+func (s *Server) complete(state *sessionState, line string) []string {
+	words := strings.Split(line, " ")
+	word := words[len(words)-1]
+
+	candidates := s.Completions
+	if len(words) == 1 {
+		candidates = append(s.commandNames(state), s.Completions...)
+	}
+
+	var completions []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, candidate)
+		}
+	}
+
+	if state.files != nil && len(words) > 1 {
+		completions = append(completions, state.files.complete(word)...)
+	}
+
+	return completions
+}
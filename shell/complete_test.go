@@ -0,0 +1,60 @@
+package shell
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestServer_Complete(t *testing.T) {
+	server := &Server{
+		LineEditing: true,
+		Commands:    []Command{{Regex: "^docker ps$", Response: "CONTAINER ID\r\n"}},
+		Aliases:     map[string]string{"dir": "ls -l"},
+		FileSystem:  fstest.MapFS{"etc/passwd": {Data: []byte("root:x:0:0:root:/root:/bin/sh\n")}},
+		JobControl:  true,
+	}
+
+	server.Handle("busybox", func(session *telnet.Session, args []string) error {
+		return nil
+	})
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "dock", Expected: "er "},
+		{Input: "busy", Expected: "box "},
+		{Input: "di", Expected: "r "},
+		{Input: "jo", Expected: "bs "},
+		{Input: "cat /e", Expected: "tc/"},
+		{Input: "cat /etc/pa", Expected: "sswd "},
+	}
+
+	for testNumber, test := range tests {
+		if err := client.Send(test.Input + "\t"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		// The line editor echoes the input, followed by the rest of the completed word.
+		if expected, actual := test.Input+test.Expected, expectOutput(t, client, test.Expected); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		if err := client.Send("\r\n"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		expectOutput(t, client, DefaultPrompt)
+	}
+}
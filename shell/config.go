@@ -0,0 +1,134 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultMaxAttempts = 3
+
+type (
+	// Config describes a shell server, so emulation profiles can be shipped and edited without recompiling. It can be
+	// written as YAML or JSON.
+	Config struct {
+		// Auth enables authentication, if set.
+		Auth *AuthConfig `json:"auth" yaml:"auth"`
+
+		// Banner is sent as soon as the client connects, before authentication.
+		Banner string `json:"banner" yaml:"banner"`
+
+		// Version is sent after the banner.
+		Version string `json:"version" yaml:"version"`
+
+		Prompt         string `json:"prompt" yaml:"prompt"`
+		WelcomeMessage string `json:"welcomeMessage" yaml:"welcomeMessage"`
+		ExitMessage    string `json:"exitMessage" yaml:"exitMessage"`
+
+		// ExitCommands are the commands ending the session; just DefaultExitCommand if empty.
+		ExitCommands []string `json:"exitCommands" yaml:"exitCommands"`
+
+		// Aliases enables command aliases, starting with these.
+		Aliases map[string]string `json:"aliases" yaml:"aliases"`
+
+		// Messages replaces the messages the shell sends, e.g. to translate them.
+		Messages *Messages `json:"messages" yaml:"messages"`
+
+		// Environment enables shell variables, starting with these.
+		Environment map[string]string `json:"environment" yaml:"environment"`
+
+		// Commands are matched against each command line, in order.
+		Commands []Command `json:"commands" yaml:"commands"`
+	}
+
+	// AuthConfig holds the credentials a client must log in with.
+	AuthConfig struct {
+		Username string `json:"username" yaml:"username"`
+		Password string `json:"password" yaml:"password"`
+
+		// CredentialsFile is the path of a file of users to log in as instead (see FileCredentialStore).
+		CredentialsFile string `json:"credentialsFile" yaml:"credentialsFile"`
+
+		// MaxAttempts is the number of login attempts allowed; DefaultMaxAttempts if unset.
+		MaxAttempts int `json:"maxAttempts" yaml:"maxAttempts"`
+
+		// AcceptAfter, if set, accepts any credentials on this attempt, after rejecting the ones before, instead of
+		// checking them (see NewCaptureAuthHandler).
+		AcceptAfter int `json:"acceptAfter" yaml:"acceptAfter"`
+	}
+)
+
+// LoadConfig reads a YAML or JSON Config from 'r', and builds a Server from it.
+func LoadConfig(r io.Reader) (*Server, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var config Config
+
+	// JSON is valid YAML, but decoding it as JSON gives clearer errors.
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		if err = decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON config: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		if err = decoder.Decode(&config); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to decode YAML config: %w", err)
+		}
+	}
+
+	return config.Server()
+}
+
+// Server builds a Server from the config.
+func (c *Config) Server() (*Server, error) {
+	server := &Server{
+		Banner:         c.Banner,
+		Version:        c.Version,
+		Prompt:         c.Prompt,
+		WelcomeMessage: c.WelcomeMessage,
+		ExitMessage:    c.ExitMessage,
+		ExitCommands:   c.ExitCommands,
+		Aliases:        c.Aliases,
+		Messages:       c.Messages,
+		Environment:    c.Environment,
+		Commands:       c.Commands,
+	}
+
+	if err := server.Compile(); err != nil {
+		return nil, err
+	}
+
+	if c.Auth != nil {
+		maxAttempts := c.Auth.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = DefaultMaxAttempts
+		}
+
+		switch {
+		case c.Auth.AcceptAfter > 0:
+			server.AuthHandler = NewCaptureAuthHandler(c.Auth.AcceptAfter)
+		case c.Auth.CredentialsFile != "":
+			store, err := NewFileCredentialStore(c.Auth.CredentialsFile)
+			if err != nil {
+				return nil, err
+			}
+
+			server.AuthHandler = NewCredentialAuthHandler(store, maxAttempts)
+		default:
+			server.AuthHandler = NewAuthHandler(c.Auth.Username, c.Auth.Password, maxAttempts)
+		}
+	}
+
+	return server, nil
+}
@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Config string
+	}{
+		{
+			Name: "YAML",
+			Config: `
+banner: "BusyBox v1.19.4\r\n"
+prompt: "# "
+exitCommands: [exit, logout]
+auth:
+  username: root
+  password: vizxv
+commands:
+  - regex: "^uname$"
+    response: "Linux\r\n"
+`,
+		},
+		{
+			Name: "JSON",
+			Config: `{
+	"banner": "BusyBox v1.19.4\r\n",
+	"prompt": "# ",
+	"exitCommands": ["exit", "logout"],
+	"auth": {"username": "root", "password": "vizxv"},
+	"commands": [{"regex": "^uname$", "response": "Linux\r\n"}]
+}`,
+		},
+	}
+
+	for _, test := range tests {
+		server, err := LoadConfig(strings.NewReader(test.Config))
+		if err != nil {
+			t.Errorf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+			continue
+		}
+
+		if expected, actual := "BusyBox v1.19.4\r\n", server.Banner; expected != actual {
+			t.Errorf("For %s, expected banner %q, but actually got %q.", test.Name, expected, actual)
+		}
+
+		if expected, actual := "# ", server.Prompt; expected != actual {
+			t.Errorf("For %s, expected prompt %q, but actually got %q.", test.Name, expected, actual)
+		}
+
+		if expected, actual := []string{"exit", "logout"}, server.ExitCommands; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("For %s, expected exit commands %q, but actually got %q.", test.Name, expected, actual)
+		}
+
+		if server.AuthHandler == nil {
+			t.Errorf("For %s, expected an AuthHandler.", test.Name)
+		}
+
+		if len(server.Commands) != 1 || server.Commands[0].Regex != "^uname$" || server.Commands[0].Response != "Linux\r\n" {
+			t.Errorf("For %s, expected 1 uname command, but actually got %+v.", test.Name, server.Commands)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []string{
+		`{"unknown": true}`,
+		`unknown: true`,
+		`commands: [{regex: "(", response: ""}]`,
+	}
+
+	for testNumber, test := range tests {
+		if _, err := LoadConfig(strings.NewReader(test)); err == nil {
+			t.Errorf("For test #%d, expected an error, but did not actually get one.", testNumber)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package shell
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUserNotFound is returned by a CredentialStore when there's no user with the requested username.
+var ErrUserNotFound = errors.New("user not found")
+
+// dummyPasswordHash is verified against when a login's user isn't found in a store that isn't a DummyHashStore, so it
+// takes as long as a wrong password for a user with a hash from HashPassword does.
+const dummyPasswordHash = "$2a$10$46yfkh74MVSRYNYEZh1x5eEJ6lhgQdfnBVKzHq/llOGAnLhusCLta"
+
+type (
+	// User is an account clients can log in as.
+	User struct {
+		Username string
+
+		// PasswordHash is the hash of the user's password, in bcrypt ("$2a$...") or argon2 PHC
+		// ("$argon2id$v=19$m=65536,t=3,p=4$salt$hash") format.
+		PasswordHash string
+
+		// Attributes holds extra details about the user, such as their home directory or privilege level.
+		Attributes map[string]string
+
+		// Canary marks the user's credentials as bait that no legitimate client knows, e.g. ones planted in a config
+		// file: any login with them is reported as a canary (see Server.Canaries), whether it's accepted or not.
+		Canary bool
+	}
+
+	// CredentialStore looks up the users clients can log in as.
+	CredentialStore interface {
+		// LookupUser returns the user with 'username', or ErrUserNotFound if there isn't one.
+		LookupUser(username string) (*User, error)
+	}
+
+	// DummyHashStore is a CredentialStore providing the hash verified against when a login's user isn't found, so it
+	// takes as long as a wrong password does, and the time taken doesn't reveal which usernames exist. It should be
+	// made like the store's users' hashes (with the same algorithm and parameters). Logins for users missing from
+	// other stores are verified against a bcrypt hash like HashPassword's.
+	DummyHashStore interface {
+		CredentialStore
+
+		// DummyPasswordHash returns the hash to verify against when a login's user isn't found.
+		DummyPasswordHash() string
+	}
+
+	// MapCredentialStore is a CredentialStore holding users in memory, by username.
+	MapCredentialStore map[string]*User
+
+	// FileCredentialStore is a CredentialStore reading users from a file, which is reloaded whenever it changes. Each
+	// line of the file holds a user as "username:hash", optionally followed by ":key=value,key=value" attributes, where
+	// "canary=true" marks the user as a Canary. Blank lines and lines starting with # are ignored.
+	FileCredentialStore struct {
+		path    string
+		mu      sync.Mutex
+		users   MapCredentialStore
+		modTime time.Time
+	}
+
+	// userKey is the key the logged in *User is stored under in a session.
+	userKey struct{}
+)
+
+// CurrentUser returns the user 'session' logged in as, if it logged in with an AuthHandler from NewAuthHandler or
+// NewCredentialAuthHandler.
+func CurrentUser(session *telnet.Session) (*User, bool) {
+	return telnet.Value[*User](session, userKey{})
+}
+
+// LookupUser returns the user with 'username', or ErrUserNotFound.
+func (m MapCredentialStore) LookupUser(username string) (*User, error) {
+	user, ok := m[username]
+	if !ok || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// DummyPasswordHash returns the hash of one of the store's users, so verifying logins for users it doesn't hold takes
+// as long as verifying those for its users (see DummyHashStore).
+func (m MapCredentialStore) DummyPasswordHash() string {
+	for _, user := range m {
+		if user != nil && user.PasswordHash != "" {
+			return user.PasswordHash
+		}
+	}
+
+	return dummyPasswordHash
+}
+
+// NewFileCredentialStore returns a FileCredentialStore for the file at 'path', which is read immediately to check
+// it's valid.
+func NewFileCredentialStore(path string) (*FileCredentialStore, error) {
+	store := &FileCredentialStore{path: path}
+
+	if _, err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// LookupUser returns the user with 'username', or ErrUserNotFound. If the file can no longer be read, the users it
+// last held are used.
+func (f *FileCredentialStore) LookupUser(username string) (*User, error) {
+	users, err := f.load()
+	if err != nil && users == nil {
+		return nil, err
+	}
+
+	return users.LookupUser(username)
+}
+
+// DummyPasswordHash returns the hash of one of the file's users (see DummyHashStore).
+func (f *FileCredentialStore) DummyPasswordHash() string {
+	users, _ := f.load()
+	return users.DummyPasswordHash()
+}
+
+// load returns the file's users, reloading them if the file has changed.
+func (f *FileCredentialStore) load() (MapCredentialStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.users, fmt.Errorf("failed to stat credential file: %w", err)
+	}
+
+	if f.users != nil && info.ModTime().Equal(f.modTime) {
+		return f.users, nil
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return f.users, fmt.Errorf("failed to open credential file: %w", err)
+	}
+	defer file.Close()
+
+	users, err := ReadCredentials(file)
+	if err != nil {
+		return f.users, err
+	}
+
+	f.users = users
+	f.modTime = info.ModTime()
+
+	return users, nil
+}
+
+// ReadCredentials reads users from 'r', in FileCredentialStore's format.
+func ReadCredentials(r io.Reader) (MapCredentialStore, error) {
+	users := MapCredentialStore{}
+	scanner := bufio.NewScanner(r)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid credentials on line %d: expected username:hash", lineNumber)
+		}
+
+		user := &User{Username: fields[0], PasswordHash: fields[1]}
+
+		if len(fields) == 3 && fields[2] != "" {
+			user.Attributes = make(map[string]string)
+
+			for _, attribute := range strings.Split(fields[2], ",") {
+				key, value, ok := strings.Cut(attribute, "=")
+				if !ok {
+					return nil, fmt.Errorf("invalid attribute %q on line %d: expected key=value", attribute, lineNumber)
+				}
+
+				if key == "canary" {
+					canary, err := strconv.ParseBool(value)
+					if err != nil {
+						return nil, fmt.Errorf("invalid canary attribute %q on line %d: expected true or false", value, lineNumber)
+					}
+
+					user.Canary = canary
+					continue
+				}
+
+				user.Attributes[key] = value
+			}
+		}
+
+		users[user.Username] = user
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	return users, nil
+}
+
+// dummyHash returns the hash to verify against when a login's user isn't found in 'store'.
+func dummyHash(store CredentialStore) string {
+	if store, ok := store.(DummyHashStore); ok {
+		if hash := store.DummyPasswordHash(); hash != "" {
+			return hash
+		}
+	}
+
+	return dummyPasswordHash
+}
+
+// isCanary reports whether 'username' and 'password' are the credentials of a Canary user in 'store'.
+func isCanary(store CredentialStore, username string, password string) bool {
+	user, err := store.LookupUser(username)
+	if err != nil || !user.Canary {
+		return false
+	}
+
+	return VerifyPassword(user.PasswordHash, password)
+}
+
+// HashPassword hashes 'password' with bcrypt, for use as a User's PasswordHash.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// VerifyPassword reports whether 'password' matches 'hash', a bcrypt or argon2 (argon2i or argon2id) hash. Hashes in
+// any other format never match.
+func VerifyPassword(hash string, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "$argon2"):
+		return verifyArgon2(hash, password)
+	default:
+		return false
+	}
+}
+
+// verifyArgon2 reports whether 'password' matches the argon2 PHC string 'hash'
+// ("$argon2id$v=19$m=65536,t=3,p=4$salt$hash", with the salt and hash in unpadded base64).
+func verifyArgon2(hash string, password string) bool {
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[2] != "v="+strconv.Itoa(argon2.Version) {
+		return false
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+
+	if iterations == 0 || threads == 0 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return false
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	var actual []byte
+
+	switch fields[1] {
+	case "argon2id":
+		actual = argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	case "argon2i":
+		actual = argon2.Key([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	default:
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(actual, expected) == 1
+}
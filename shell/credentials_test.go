@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := HashPassword("admin")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	salt := []byte("saltsaltsaltsalt")
+	argon2Hash := "$argon2id$v=19$m=1024,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("admin"), salt, 1, 1024, 1, 32))
+
+	tests := []struct {
+		Hash     string
+		Password string
+		Expected bool
+	}{
+		{Hash: bcryptHash, Password: "admin", Expected: true},
+		{Hash: bcryptHash, Password: "admin1", Expected: false},
+		{Hash: argon2Hash, Password: "admin", Expected: true},
+		{Hash: argon2Hash, Password: "root", Expected: false},
+		{Hash: strings.Replace(argon2Hash, "t=1", "t=0", 1), Password: "admin", Expected: false},
+		{Hash: "admin", Password: "admin", Expected: false},
+		{Hash: "", Password: "", Expected: false},
+	}
+
+	for testNumber, test := range tests {
+		if actual := VerifyPassword(test.Hash, test.Password); test.Expected != actual {
+			t.Errorf("For test #%d, expected %t, but actually got %t.", testNumber, test.Expected, actual)
+		}
+	}
+
+	// The hash verified against for unknown users takes as long as those from HashPassword.
+	if cost, err := bcrypt.Cost([]byte(dummyPasswordHash)); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("Expected a bcrypt hash with cost %d, but actually got %d (%v).", bcrypt.DefaultCost, cost, err)
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	salt := []byte("saltsaltsaltsalt")
+	argon2Hash := "$argon2id$v=19$m=1024,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("admin"), salt, 1, 1024, 1, 32))
+
+	tests := []struct {
+		Store    CredentialStore
+		Expected string
+	}{
+		// Unknown users of a store hashing with argon2 are verified against an argon2 hash, like its users.
+		{Store: MapCredentialStore{"admin": {Username: "admin", PasswordHash: argon2Hash}}, Expected: argon2Hash},
+		{Store: MapCredentialStore{}, Expected: dummyPasswordHash},
+		{Store: stubCredentialStore{}, Expected: dummyPasswordHash},
+	}
+
+	for testNumber, test := range tests {
+		if actual := dummyHash(test.Store); test.Expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.Expected, actual)
+		}
+	}
+}
+
+func TestMapCredentialStore(t *testing.T) {
+	store := MapCredentialStore{"root": {Username: "root", PasswordHash: "$2a$10$hash"}, "admin": nil}
+
+	if user, err := store.LookupUser("root"); err != nil || user.PasswordHash != "$2a$10$hash" {
+		t.Errorf("Expected root's hash, but actually got %+v (%v).", user, err)
+	}
+
+	// Users without an entry, or with a nil one, aren't found.
+	for _, username := range []string{"guest", "admin"} {
+		if _, err := store.LookupUser(username); err != ErrUserNotFound {
+			t.Errorf("For %q, expected %v, but actually got: (%T) %v.", username, ErrUserNotFound, err, err)
+		}
+	}
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users")
+
+	contents := "# Users\n\nroot:$2a$10$hash:home=/root,level=15\nguest:$2a$10$other\nbackup:$2a$10$bait:canary=true\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	store, err := NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	user, err := store.LookupUser("root")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if user.PasswordHash != "$2a$10$hash" || user.Attributes["home"] != "/root" || user.Attributes["level"] != "15" {
+		t.Errorf("Expected root's hash and attributes, but actually got %+v.", user)
+	}
+
+	if user.Canary {
+		t.Errorf("Expected root not to be a canary, but it was.")
+	}
+
+	if user, err = store.LookupUser("backup"); err != nil || !user.Canary || user.Attributes["canary"] != "" {
+		t.Errorf("Expected backup to be a canary, without a canary attribute, but actually got %+v (%v).", user, err)
+	}
+
+	if _, err = store.LookupUser("admin"); err != ErrUserNotFound {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrUserNotFound, err, err)
+	}
+
+	if _, err = ReadCredentials(strings.NewReader("root\n")); err == nil {
+		t.Errorf("Expected an error for a line without a hash, but didn't get one.")
+	}
+
+	if _, err = ReadCredentials(strings.NewReader("root:$2a$10$hash:canary=maybe\n")); err == nil {
+		t.Errorf("Expected an error for an invalid canary attribute, but didn't get one.")
+	}
+}
+
+// stubCredentialStore is a CredentialStore without any users, that isn't a DummyHashStore.
+type stubCredentialStore struct{}
+
+func (stubCredentialStore) LookupUser(string) (*User, error) {
+	return nil, ErrUserNotFound
+}
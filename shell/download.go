@@ -0,0 +1,572 @@
+package shell
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	// DefaultDownloadTimeout caps how long fetching a payload can take, unless a Downloader is configured otherwise.
+	DefaultDownloadTimeout = 30 * time.Second
+
+	// DefaultMaxDownloadSize caps the size of quarantined payloads, unless a Downloader is configured otherwise.
+	DefaultMaxDownloadSize = 10 << 20
+
+	// DefaultMaxFileSystemSize caps the total size of each session's FileSystem, unless a Downloader is configured
+	// otherwise. It's kept small, as each session's FileSystem is held in memory, and a honeypot may serve hundreds of
+	// sessions at once; payloads larger than it are still quarantined, but not saved to the FileSystem.
+	DefaultMaxFileSystemSize = 4 << 20
+)
+
+var (
+	// ErrDownloadTooLarge is recorded when a payload is larger than Downloader.MaxSize, and so isn't quarantined.
+	ErrDownloadTooLarge = errors.New("download exceeds the maximum size")
+
+	// ErrForbiddenAddress is recorded when a payload is hosted on a loopback, private or link-local address, and
+	// Downloader.AllowPrivate isn't set.
+	ErrForbiddenAddress = errors.New("forbidden address")
+)
+
+type (
+	// Downloader emulates the wget, curl and tftp commands, capturing the URLs clients try to download from (usually
+	// malware droppers). Downloads always appear to succeed, and the file appears in the session's FileSystem, if it
+	// has one.
+	Downloader struct {
+		// OnDownload, if set, is called with every download attempted.
+		OnDownload func(session *telnet.Session, download Download)
+
+		// QuarantineDir, if set, has each payload actually fetched (over HTTP, HTTPS or TFTP) into this directory,
+		// named after its SHA-256 hash and never executable.
+		QuarantineDir string
+
+		// MaxSize caps the size of quarantined payloads; DefaultMaxDownloadSize if unset.
+		MaxSize int64
+
+		// MaxFileSystemSize caps the total size of the session's FileSystem, beyond which downloads aren't saved to it;
+		// DefaultMaxFileSystemSize if unset.
+		MaxFileSystemSize int64
+
+		// Timeout caps how long fetching a payload can take; DefaultDownloadTimeout if unset.
+		Timeout time.Duration
+
+		// AllowPrivate allows payloads to be fetched from loopback, private and link-local addresses. They're refused
+		// by default, so clients can't use the server to reach the operator's own network.
+		AllowPrivate bool
+	}
+
+	// Download describes a download attempted by a client.
+	Download struct {
+		Time time.Time
+
+		// Command is the command used: "wget", "curl" or "tftp".
+		Command string
+
+		// URL is the URL of the payload.
+		URL string
+
+		// Path, Size and SHA256 describe the quarantined payload, if it was fetched.
+		Path   string
+		Size   int64
+		SHA256 string
+
+		// Err is why the payload couldn't be fetched, if it couldn't.
+		Err error
+	}
+)
+
+// builtins returns the download commands for a session, saving files to 'files' if it's set.
+func (d *Downloader) builtins(files *fileSystem) map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"curl": func(session *telnet.Session, args []string) error {
+			return d.curl(session, files, args)
+		},
+		"tftp": func(session *telnet.Session, args []string) error {
+			return d.tftp(session, files, args)
+		},
+		"wget": func(session *telnet.Session, args []string) error {
+			return d.wget(session, files, args)
+		},
+	}
+}
+
+// wget emulates BusyBox's wget.
+func (d *Downloader) wget(session *telnet.Session, files *fileSystem, args []string) error {
+	options, operands := parseOptions(args[1:], "OPTUYt", "output-document", "directory-prefix", "user-agent", "header")
+	if len(operands) == 0 {
+		return errors.New("missing URL")
+	}
+
+	_, quiet := options["q"]
+
+	for _, operand := range operands {
+		rawURL := withScheme(operand)
+		download := d.download(session, "wget", rawURL)
+
+		// Payloads are never written to the client, in case they're piped into a shell.
+		output := valueOrDefault(options["O"], valueOrDefault(options["output-document"], remoteName(rawURL)))
+		if output == "-" {
+			continue
+		}
+
+		if prefix := valueOrDefault(options["P"], options["directory-prefix"]); prefix != "" && options["O"] == "" {
+			output = path.Join(prefix, output)
+		}
+
+		if files != nil {
+			if err := d.save(files, output, download.data); err != nil {
+				return err
+			}
+		}
+
+		if quiet {
+			continue
+		}
+
+		host, port := hostPort(rawURL)
+
+		message := fmt.Sprintf("Connecting to %s (%s:%s)\r\nsaving to '%s'\r\n%-20s 100%% |%s| %5d  0:00:00 ETA\r\n'%s' saved\r\n",
+			host, host, port, output, path.Base(output), strings.Repeat("*", 32), len(download.data), output)
+
+		if err := session.WriteString(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// curl emulates curl.
+func (d *Downloader) curl(session *telnet.Session, files *fileSystem, args []string) error {
+	options, operands := parseOptions(args[1:], "oAHXdeurTx", "output", "user-agent", "header", "request", "data", "referer")
+	if len(operands) == 0 {
+		return errors.New("try 'curl --help' or 'curl --manual' for more information")
+	}
+
+	_, silent := options["s"]
+	_, remote := options["O"]
+
+	for _, operand := range operands {
+		rawURL := withScheme(operand)
+		download := d.download(session, "curl", rawURL)
+
+		output := valueOrDefault(options["o"], options["output"])
+		if remote {
+			output = remoteName(rawURL)
+		}
+
+		// Payloads are never written to the client, in case they're piped into a shell.
+		if output == "" || output == "-" {
+			continue
+		}
+
+		if files != nil {
+			if err := d.save(files, output, download.data); err != nil {
+				return fmt.Errorf("(23) Failed writing body")
+			}
+		}
+
+		if silent {
+			continue
+		}
+
+		size := len(download.data)
+
+		message := fmt.Sprintf("  %% Total    %% Received %% Xferd  Average Speed   Time    Time     Time  Current\r\n"+
+			"                                 Dload  Upload   Total   Spent    Left  Speed\r\n"+
+			"100 %5d  100 %5d    0     0  %5d      0 --:--:-- --:--:-- --:--:-- %5d\r\n", size, size, size*4, size*4)
+
+		if err := session.WriteString(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tftp emulates BusyBox's tftp, which is silent on success.
+func (d *Downloader) tftp(session *telnet.Session, files *fileSystem, args []string) error {
+	options, operands := parseOptions(args[1:], "lrb")
+	if len(operands) == 0 {
+		return errors.New("Usage: tftp [OPTIONS] HOST [PORT]")
+	}
+
+	// Uploads aren't emulated, but appear to succeed.
+	if _, get := options["g"]; !get {
+		return nil
+	}
+
+	remoteFile := valueOrDefault(options["r"], options["l"])
+	if remoteFile == "" {
+		return errors.New("Usage: tftp [OPTIONS] HOST [PORT]")
+	}
+
+	port := "69"
+	if len(operands) > 1 {
+		port = operands[1]
+	}
+
+	rawURL := "tftp://" + net.JoinHostPort(operands[0], port) + "/" + strings.TrimPrefix(remoteFile, "/")
+	download := d.download(session, "tftp", rawURL)
+
+	if files != nil {
+		localFile := valueOrDefault(options["l"], path.Base(remoteFile))
+		if err := d.save(files, localFile, download.data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// save writes 'data' to the file 'name' in 'files', unless the filesystem would grow beyond MaxFileSystemSize,
+// returning the error BusyBox would.
+func (d *Downloader) save(files *fileSystem, name string, data []byte) error {
+	maxSize := d.MaxFileSystemSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSystemSize
+	}
+
+	if files.sizeWith(name, data) > maxSize {
+		return errors.New("write error: No space left on device")
+	}
+
+	if err := files.writeFile(name, data); err != nil {
+		return fmt.Errorf("can't open '%s': No such file or directory", name)
+	}
+
+	return nil
+}
+
+// sessionDownload is a download, along with the data to save to the session's FileSystem.
+type sessionDownload struct {
+	Download
+	data []byte
+}
+
+// download records the download of 'rawURL' by 'command', fetching it into quarantine if configured. The returned data
+// is the payload if it was fetched, or filler of a plausible size.
+func (d *Downloader) download(session *telnet.Session, command string, rawURL string) sessionDownload {
+	download := sessionDownload{Download: Download{Time: session.Clock().Now(), Command: command, URL: rawURL}}
+
+	if d.QuarantineDir != "" {
+		download.data, download.Err = d.quarantine(session.Context(), &download.Download)
+	}
+
+	if download.data == nil {
+		download.data = make([]byte, 1024+crc32.ChecksumIEEE([]byte(rawURL))%65536)
+	}
+
+	if d.OnDownload != nil {
+		d.OnDownload(session, download.Download)
+	}
+
+	return download
+}
+
+// quarantine fetches the payload of 'download', saving it to the QuarantineDir.
+func (d *Downloader) quarantine(ctx context.Context, download *Download) ([]byte, error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDownloadTimeout
+	}
+
+	maxSize := d.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDownloadSize
+	}
+
+	ctx, cancel := telnet.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data, err := d.fetch(ctx, download.URL, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	download.SHA256 = hex.EncodeToString(hash[:])
+	download.Size = int64(len(data))
+	download.Path = filepath.Join(d.QuarantineDir, download.SHA256)
+
+	if err = os.MkdirAll(d.QuarantineDir, 0o700); err != nil {
+		return data, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	// Payloads are named after their hash, so one already quarantined is identical.
+	if _, err = os.Stat(download.Path); err == nil {
+		return data, nil
+	}
+
+	if err = os.WriteFile(download.Path, data, 0o600); err != nil {
+		return data, fmt.Errorf("failed to quarantine payload: %w", err)
+	}
+
+	return data, nil
+}
+
+// fetch fetches the payload at 'rawURL', up to 'maxSize' bytes.
+func (d *Downloader) fetch(ctx context.Context, rawURL string, maxSize int64) ([]byte, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return d.fetchHTTP(ctx, rawURL, maxSize)
+	case "tftp":
+		return d.fetchTFTP(ctx, parsed.Host, strings.TrimPrefix(parsed.Path, "/"), maxSize)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", parsed.Scheme)
+	}
+}
+
+// fetchHTTP fetches the payload at 'rawURL' over HTTP or HTTPS, up to 'maxSize' bytes.
+func (d *Downloader) fetchHTTP(ctx context.Context, rawURL string, maxSize int64) ([]byte, error) {
+	dialer := &net.Dialer{Control: d.checkAddress}
+	transport := &http.Transport{DialContext: dialer.DialContext}
+	defer transport.CloseIdleConnections()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := (&http.Client{Transport: transport}).Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("server returned %s", response.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, ErrDownloadTooLarge
+	}
+
+	return data, nil
+}
+
+// TFTP opcodes (see RFC 1350).
+const (
+	tftpRRQ   = 1
+	tftpDATA  = 3
+	tftpACK   = 4
+	tftpERROR = 5
+)
+
+// fetchTFTP fetches 'file' from the TFTP server at 'address', up to 'maxSize' bytes.
+func (d *Downloader) fetchTFTP(ctx context.Context, address string, file string, maxSize int64) ([]byte, error) {
+	server, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.checkAddress("udp", server.String(), nil); err != nil {
+		return nil, err
+	}
+
+	var config net.ListenConfig
+
+	conn, err := config.ListenPacket(ctx, "udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	// Closing the connection interrupts the transfer, if the session ends first.
+	defer context.AfterFunc(ctx, func() { _ = conn.Close() })()
+
+	request := binary.BigEndian.AppendUint16(nil, tftpRRQ)
+	request = append(append(append(request, file...), 0), "octet\x00"...)
+
+	if _, err = conn.WriteTo(request, server); err != nil {
+		return nil, err
+	}
+
+	var (
+		data     []byte
+		expected uint16   = 1
+		peer     net.Addr // the server replies from a new port, which the rest of the transfer uses
+		packet   = make([]byte, 516)
+	)
+
+	for {
+		n, addr, err := conn.ReadFrom(packet)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			return nil, err
+		}
+
+		if from, ok := addr.(*net.UDPAddr); !ok || !from.IP.Equal(server.IP) || (peer != nil && addr.String() != peer.String()) {
+			continue
+		}
+
+		peer = addr
+
+		if n < 4 {
+			return nil, errors.New("malformed tftp packet")
+		}
+
+		switch binary.BigEndian.Uint16(packet) {
+		case tftpDATA:
+			block := binary.BigEndian.Uint16(packet[2:])
+			if block == expected {
+				if data = append(data, packet[4:n]...); int64(len(data)) > maxSize {
+					return nil, ErrDownloadTooLarge
+				}
+
+				expected++
+			}
+
+			if _, err = conn.WriteTo(append(binary.BigEndian.AppendUint16(nil, tftpACK), packet[2:4]...), peer); err != nil {
+				return nil, err
+			}
+
+			if block == expected-1 && n < len(packet) {
+				return data, nil
+			}
+		case tftpERROR:
+			return nil, fmt.Errorf("tftp server error: %s", strings.TrimRight(string(packet[4:n]), "\x00"))
+		default:
+			return nil, errors.New("unexpected tftp packet")
+		}
+	}
+}
+
+// checkAddress refuses connections to loopback, private and link-local addresses, unless AllowPrivate is set.
+func (d *Downloader) checkAddress(network string, address string, _ syscall.RawConn) error {
+	if d.AllowPrivate {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
+
+// parseOptions splits 'args' into options and operands, like getopt. Short options in 'withValue', and the long
+// options 'longWithValue', take a value: the rest of the argument (e.g. "-Ofile" or "--output=file"), or the next
+// argument. Other options are recorded with an empty value.
+func parseOptions(args []string, withValue string, longWithValue ...string) (map[string]string, []string) {
+	options := make(map[string]string)
+	var operands []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return options, append(operands, args[i+1:]...)
+		case strings.HasPrefix(arg, "--"):
+			name, value, found := strings.Cut(arg[2:], "=")
+			if !found && i+1 < len(args) && slices.Contains(longWithValue, name) {
+				i++
+				value = args[i]
+			}
+
+			options[name] = value
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for j := 1; j < len(arg); j++ {
+				name := arg[j : j+1]
+				if !strings.Contains(withValue, name) {
+					options[name] = ""
+					continue
+				}
+
+				value := arg[j+1:]
+				if value == "" && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+
+				options[name] = value
+				break
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	return options, operands
+}
+
+// withScheme adds "http://" to 'rawURL' if it has no scheme, as wget and curl do.
+func withScheme(rawURL string) string {
+	if strings.Contains(rawURL, "://") {
+		return rawURL
+	}
+
+	return "http://" + rawURL
+}
+
+// remoteName returns the file name wget and curl save 'rawURL' to by default.
+func remoteName(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "index.html"
+	}
+
+	if name := path.Base(parsed.Path); name != "." && name != "/" {
+		return name
+	}
+
+	return "index.html"
+}
+
+// hostPort returns the host and port 'rawURL' would be downloaded from.
+func hostPort(rawURL string) (string, string) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL, "80"
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return parsed.Hostname(), port
+}
@@ -0,0 +1,196 @@
+package shell
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		Args             []string
+		ExpectedOptions  map[string]string
+		ExpectedOperands []string
+	}{
+		{
+			Args:             []string{"-qO-", "http://192.0.2.1/x.sh"},
+			ExpectedOptions:  map[string]string{"q": "", "O": "-"},
+			ExpectedOperands: []string{"http://192.0.2.1/x.sh"},
+		},
+		{
+			Args:             []string{"-O", "/tmp/x", "--header", "Host: a", "--no-check-certificate", "192.0.2.1/x"},
+			ExpectedOptions:  map[string]string{"O": "/tmp/x", "header": "Host: a", "no-check-certificate": ""},
+			ExpectedOperands: []string{"192.0.2.1/x"},
+		},
+		{
+			Args:             []string{"-g", "-r", "mips", "192.0.2.1", "--", "-69"},
+			ExpectedOptions:  map[string]string{"g": "", "r": "mips"},
+			ExpectedOperands: []string{"192.0.2.1", "-69"},
+		},
+	}
+
+	for testNumber, test := range tests {
+		options, operands := parseOptions(test.Args, "Or", "header")
+
+		if !reflect.DeepEqual(test.ExpectedOptions, options) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.ExpectedOptions, options)
+		}
+
+		if !reflect.DeepEqual(test.ExpectedOperands, operands) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.ExpectedOperands, operands)
+		}
+	}
+}
+
+func TestDownloader_Quarantine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			_, _ = w.Write([]byte(strings.Repeat("x", 11)))
+			return
+		}
+
+		_, _ = w.Write([]byte("#!/bin/sh\n"))
+	}))
+	defer server.Close()
+
+	downloader := &Downloader{QuarantineDir: t.TempDir(), MaxSize: 10, AllowPrivate: true}
+
+	download := &Download{URL: server.URL + "/x.sh"}
+
+	data, err := downloader.quarantine(context.Background(), download)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "#!/bin/sh\n", string(data); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if stored, err := os.ReadFile(download.Path); err != nil || string(stored) != string(data) {
+		t.Errorf("Expected the payload to be quarantined at %q, but actually got %q (%v).", download.Path, stored, err)
+	}
+
+	if download.Size != int64(len(data)) || len(download.SHA256) != 64 {
+		t.Errorf("Expected the payload's size and hash, but actually got %d and %q.", download.Size, download.SHA256)
+	}
+
+	if _, err = downloader.quarantine(context.Background(), &Download{URL: server.URL + "/large"}); !errors.Is(err, ErrDownloadTooLarge) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrDownloadTooLarge, err, err)
+	}
+
+	// Loopback addresses are refused by default.
+	downloader.AllowPrivate = false
+
+	if _, err = downloader.quarantine(context.Background(), &Download{URL: server.URL + "/x.sh"}); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrForbiddenAddress, err, err)
+	}
+}
+
+func TestDownloader_FetchTFTP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	payload := []byte(strings.Repeat("a", 512) + "bc")
+
+	// Serve the payload in two blocks, waiting for each to be acknowledged.
+	go func() {
+		packet := make([]byte, 516)
+
+		_, client, err := conn.ReadFrom(packet)
+		if err != nil {
+			return
+		}
+
+		for block, start := uint16(1), 0; start <= len(payload); block, start = block+1, start+512 {
+			data := binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, tftpDATA), block)
+			data = append(data, payload[start:min(start+512, len(payload))]...)
+
+			if _, err = conn.WriteTo(data, client); err != nil {
+				return
+			}
+
+			if _, _, err = conn.ReadFrom(packet); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	downloader := &Downloader{AllowPrivate: true}
+
+	data, err := downloader.fetchTFTP(ctx, conn.LocalAddr().String(), "mips", 1024)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := string(payload), string(data); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	// A transfer the server doesn't answer ends with the context.
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err = downloader.fetchTFTP(ctx, conn.LocalAddr().String(), "mips", 1024); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", context.Canceled, err, err)
+	}
+}
+
+func TestDownloader_MaxFileSystemSize(t *testing.T) {
+	server := &Server{
+		FileSystem: fstest.MapFS{"tmp": {Mode: fs.ModeDir | 0o755}},
+		Downloader: &Downloader{MaxFileSystemSize: 1000},
+	}
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	// Downloads are always at least 1KiB, so none fit.
+	if expected, actual := "wget: write error: No space left on device\r\n", enter(t, client, "wget -q http://192.0.2.1/x -O /tmp/x"); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := "", enter(t, client, "ls /tmp"); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestDownloader_DefaultMaxFileSystemSize(t *testing.T) {
+	files, err := newFileSystem(fstest.MapFS{}, telnet.RealClock)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	downloader := &Downloader{}
+
+	if err = downloader.save(files, "small", make([]byte, DefaultMaxFileSystemSize/2)); err != nil {
+		t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if err = downloader.save(files, "large", make([]byte, DefaultMaxFileSystemSize/2+1)); err == nil {
+		t.Error("Expected an error, but didn't get one.")
+	}
+}
@@ -0,0 +1,206 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// environment is a session's shell variables, along with the exit status of its last command.
+type environment struct {
+	vars   map[string]string
+	status int
+}
+
+// newEnvironment creates an environment from each of 'layers' in turn, so later layers override earlier ones.
+func newEnvironment(layers ...map[string]string) *environment {
+	env := &environment{vars: make(map[string]string)}
+
+	for _, layer := range layers {
+		for name, value := range layer {
+			env.vars[name] = value
+		}
+	}
+
+	return env
+}
+
+// builtins returns the environment commands for the session.
+func (e *environment) builtins() map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"echo":   e.echo,
+		"env":    e.env,
+		"export": e.export,
+		"unset":  e.unset,
+	}
+}
+
+// expand replaces the variable references ($NAME, ${NAME} and $?) in 'line' with their values, leaving quoting to
+// SplitArgs. Nothing is expanded within single quotes, or after a backslash.
+func (e *environment) expand(line string) string {
+	var builder strings.Builder
+	var quote byte
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch {
+		case char == '\\' && quote != '\'' && i+1 < len(line):
+			builder.WriteString(line[i : i+2])
+			i++
+			continue
+		case char == '\'' && quote != '"':
+			quote ^= '\''
+		case char == '"' && quote != '\'':
+			quote ^= '"'
+		case char == '$' && quote != '\'':
+			name, size := variableName(line[i+1:])
+			if size > 0 {
+				builder.WriteString(quoteValue(e.lookup(name), quote == '"'))
+				i += size
+				continue
+			}
+		}
+
+		builder.WriteByte(char)
+	}
+
+	return builder.String()
+}
+
+// lookup returns the value of the variable 'name', or "" if it isn't set.
+func (e *environment) lookup(name string) string {
+	if name == "?" {
+		return strconv.Itoa(e.status)
+	}
+
+	return e.vars[name]
+}
+
+func (e *environment) echo(session *telnet.Session, args []string) error {
+	newline := "\r\n"
+
+	args = args[1:]
+	if len(args) > 0 && args[0] == "-n" {
+		newline = ""
+		args = args[1:]
+	}
+
+	return session.WriteString(strings.Join(args, " "), newline)
+}
+
+func (e *environment) env(session *telnet.Session, args []string) error {
+	var builder strings.Builder
+
+	for _, name := range e.names() {
+		builder.WriteString(name + "=" + e.vars[name] + "\r\n")
+	}
+
+	return session.WriteString(builder.String())
+}
+
+func (e *environment) export(session *telnet.Session, args []string) error {
+	if len(args) == 1 {
+		var builder strings.Builder
+
+		for _, name := range e.names() {
+			builder.WriteString("export " + name + "='" + strings.ReplaceAll(e.vars[name], "'", `'\''`) + "'\r\n")
+		}
+
+		return session.WriteString(builder.String())
+	}
+
+	for _, arg := range args[1:] {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if _, size := variableName(name); size == 0 || size != len(name) || name == "?" {
+			return fmt.Errorf("'%s': not a valid identifier", arg)
+		}
+
+		if hasValue {
+			e.vars[name] = value
+		} else if _, ok := e.vars[name]; !ok {
+			e.vars[name] = ""
+		}
+	}
+
+	return nil
+}
+
+func (e *environment) unset(session *telnet.Session, args []string) error {
+	for _, name := range args[1:] {
+		delete(e.vars, name)
+	}
+
+	return nil
+}
+
+// names returns the names of the set variables, sorted.
+func (e *environment) names() []string {
+	names := make([]string, 0, len(e.vars))
+	for name := range e.vars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// variableName parses the variable name at the start of 's' (the text after a $), returning the name and how many
+// bytes of 's' it takes up, or a size of 0 if there isn't one.
+func variableName(s string) (name string, size int) {
+	if strings.HasPrefix(s, "?") {
+		return "?", 1
+	}
+
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0
+		}
+
+		if name, size = variableName(s[1:end]); size == 0 || size != end-1 {
+			return "", 0
+		}
+
+		return name, end + 1
+	}
+
+	for size < len(s) {
+		char := s[size]
+		if char != '_' && (char < 'a' || char > 'z') && (char < 'A' || char > 'Z') && (size == 0 || char < '0' || char > '9') {
+			break
+		}
+
+		size++
+	}
+
+	return s[:size], size
+}
+
+// quoteValue escapes the characters in a variable's value that SplitArgs would otherwise interpret as quoting.
+func quoteValue(value string, inDoubleQuotes bool) string {
+	special := `\'"`
+	if inDoubleQuotes {
+		special = "\\\"$`"
+	}
+
+	if !strings.ContainsAny(value, special) {
+		return value
+	}
+
+	var builder strings.Builder
+
+	for _, char := range value {
+		if strings.ContainsRune(special, char) {
+			builder.WriteByte('\\')
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,62 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvironment_Expand(t *testing.T) {
+	env := newEnvironment(map[string]string{"HOME": "/root", "QUOTED": `it's "here"`})
+	env.status = 127
+
+	tests := []struct {
+		Line     string
+		Expected []string
+	}{
+		{
+			Line:     "cd $HOME/.ssh",
+			Expected: []string{"cd", "/root/.ssh"},
+		},
+		{
+			Line:     `echo ${HOME}x '$HOME' "$HOME" \$HOME $? $MISSING.`,
+			Expected: []string{"echo", "/rootx", "$HOME", "/root", "$HOME", "127", "."},
+		},
+		{
+			Line:     `echo $QUOTED "$QUOTED"`,
+			Expected: []string{"echo", "it's", `"here"`, `it's "here"`},
+		},
+		{
+			Line:     "echo $ ${ ${1} $1",
+			Expected: []string{"echo", "$", "${", "${1}", "$1"},
+		},
+	}
+
+	for testNumber, test := range tests {
+		actual, err := SplitArgs(env.expand(test.Line))
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.Expected, actual) {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, test.Expected, actual)
+			continue
+		}
+	}
+
+	if err := env.export(nil, []string{"export", "PATH=/bin", "TERM"}); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "/bin", env.vars["PATH"]; expected != actual {
+		t.Errorf("Expected PATH %q, but actually got %q.", expected, actual)
+	}
+
+	if _, ok := env.vars["TERM"]; !ok {
+		t.Errorf("Expected TERM to be set, but it isn't.")
+	}
+
+	if err := env.export(nil, []string{"export", "1X=y"}); err == nil {
+		t.Errorf("Expected an error exporting an invalid name, but didn't get one.")
+	}
+}
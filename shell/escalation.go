@@ -0,0 +1,327 @@
+package shell
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	// DefaultEscalationAttempts is the number of passwords sudo asks for before giving up, unless configured otherwise.
+	DefaultEscalationAttempts = 3
+
+	// rootUser is the user sudo and su switch to by default.
+	rootUser = "root"
+)
+
+type (
+	// Escalation provides the sudo, su and passwd builtins, which ask for passwords (reporting each to OnEscalation),
+	// and switch the session to another user (root, unless told otherwise) when they're accepted. The session's prompt
+	// switches from "$" to "#" while it's privileged (see Privileged), and the exit builtin returns to the previous user,
+	// as it would leave a real shell started by sudo or su.
+	Escalation struct {
+		// OnEscalation, if set, is called with every password entered, e.g. to log the credentials tried.
+		OnEscalation func(session *telnet.Session, attempt EscalationAttempt)
+
+		// Password, if set, is the only password accepted. Otherwise, any password is, so attackers carry on.
+		Password string
+
+		// MaxAttempts is the number of passwords sudo asks for before giving up; DefaultEscalationAttempts if unset.
+		MaxAttempts int
+	}
+
+	// EscalationAttempt describes a password entered into sudo, su or passwd, as passed to Escalation.OnEscalation.
+	EscalationAttempt struct {
+		Time    time.Time
+		Command string
+
+		// Username is the user the client tried to become, or whose password they tried to change.
+		Username string
+		Password string
+
+		// NewPassword is the password passwd was asked to change to, if it was.
+		NewPassword string
+		Success     bool
+	}
+)
+
+// builtins returns the privilege escalation commands for 'server'.
+func (e *Escalation) builtins(server *Server) map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"passwd": e.passwd,
+		"su": func(session *telnet.Session, args []string) error {
+			return e.su(session, server, args)
+		},
+		"sudo": func(session *telnet.Session, args []string) error {
+			return e.sudo(session, server, args)
+		},
+	}
+}
+
+// sudo emulates sudo, running a command as root, or starting a root shell if it's run without one (or with -s or -i).
+func (e *Escalation) sudo(session *telnet.Session, server *Server, args []string) error {
+	// Options end at the command to run, which can have options of its own.
+	i := 1
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		if args[i] == "--" {
+			i++
+			break
+		}
+
+		if strings.HasSuffix(args[i], "u") && i+1 < len(args) {
+			i++
+		}
+	}
+
+	options, _ := parseOptions(args[1:i], "u")
+	command := args[i:]
+
+	target := valueOrDefault(options["u"], rootUser)
+
+	if _, ok := options["k"]; ok && len(command) == 0 {
+		getState(session).sudoed = false
+		return nil
+	}
+
+	if !Privileged(session) && !getState(session).sudoed {
+		messages := getState(session).messages
+
+		maxAttempts := e.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = DefaultEscalationAttempts
+		}
+
+		accepted := false
+
+		for attempt := 0; attempt < maxAttempts && !accepted; attempt++ {
+			password, err := readPassword(session, fmt.Sprintf(messages.SudoPrompt, EffectiveUser(session)))
+			if err != nil {
+				return err
+			}
+
+			accepted = e.verify(password)
+			e.report(session, EscalationAttempt{Command: "sudo", Username: target, Password: password, Success: accepted})
+
+			if !accepted && attempt+1 < maxAttempts {
+				if err = session.WriteString(messages.SudoRetry); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !accepted {
+			if err := session.Writef(messages.SudoFailed, maxAttempts); err != nil {
+				return err
+			}
+
+			return ExitError(1)
+		}
+
+		// Like sudo's timestamp, the password isn't asked for again.
+		getState(session).sudoed = true
+	}
+
+	pushUser(session, target)
+
+	// Without a command (or with -s or -i), the client is left in a shell as 'target' until they exit it.
+	if len(command) == 0 {
+		return nil
+	}
+
+	defer popUser(session)
+
+	return execArgs(session, server, command)
+}
+
+// su emulates su, starting a shell as another user (root if no user is given), or running a command as them with -c.
+func (e *Escalation) su(session *telnet.Session, server *Server, args []string) error {
+	options, operands := parseOptions(args[1:], "c", "command")
+
+	target := rootUser
+	for _, operand := range operands {
+		if operand != "-" {
+			target = operand
+			break
+		}
+	}
+
+	if !Privileged(session) {
+		messages := getState(session).messages
+
+		password, err := readPassword(session, messages.PasswordPrompt)
+		if err != nil {
+			return err
+		}
+
+		accepted := e.verify(password)
+		e.report(session, EscalationAttempt{Command: "su", Username: target, Password: password, Success: accepted})
+
+		if !accepted {
+			if err = session.WriteString(messages.SuFailed); err != nil {
+				return err
+			}
+
+			return ExitError(1)
+		}
+	}
+
+	pushUser(session, target)
+
+	command := valueOrDefault(options["c"], options["command"])
+	if command == "" {
+		return nil
+	}
+
+	defer popUser(session)
+
+	args, err := SplitArgs(command)
+	if err != nil {
+		return err
+	}
+
+	return execArgs(session, server, args)
+}
+
+// passwd emulates passwd, asking for the current password (unless the session is privileged), and the new one twice.
+func (e *Escalation) passwd(session *telnet.Session, args []string) error {
+	user := EffectiveUser(session)
+	privileged := Privileged(session)
+	messages := getState(session).messages
+
+	target := user
+	if len(args) > 1 {
+		target = args[len(args)-1]
+	}
+
+	if target != user && !privileged {
+		if err := session.Writef(messages.PasswdDenied, target); err != nil {
+			return err
+		}
+
+		return ExitError(1)
+	}
+
+	if err := session.Writef(messages.PasswdChanging, target); err != nil {
+		return err
+	}
+
+	attempt := EscalationAttempt{Command: "passwd", Username: target}
+
+	if !privileged {
+		password, err := readPassword(session, messages.PasswdCurrentPrompt)
+		if err != nil {
+			return err
+		}
+
+		attempt.Password = password
+
+		if !e.verify(password) {
+			e.report(session, attempt)
+
+			if err = session.WriteString(messages.PasswdAuthFailed); err != nil {
+				return err
+			}
+
+			return ExitError(10)
+		}
+	}
+
+	newPassword, err := readPassword(session, messages.PasswdNewPrompt)
+	if err != nil {
+		return err
+	}
+
+	retyped, err := readPassword(session, messages.PasswdRetypePrompt)
+	if err != nil {
+		return err
+	}
+
+	attempt.NewPassword = newPassword
+	attempt.Success = newPassword == retyped
+	e.report(session, attempt)
+
+	if !attempt.Success {
+		if err = session.WriteString(messages.PasswdMismatch); err != nil {
+			return err
+		}
+
+		return ExitError(10)
+	}
+
+	return session.WriteString(messages.PasswdUpdated)
+}
+
+// verify reports whether 'password' is accepted.
+func (e *Escalation) verify(password string) bool {
+	if e.Password == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(password), []byte(e.Password)) == 1
+}
+
+// report passes 'attempt' to OnEscalation, if it's set.
+func (e *Escalation) report(session *telnet.Session, attempt EscalationAttempt) {
+	if e.OnEscalation != nil {
+		attempt.Time = session.Clock().Now()
+		e.OnEscalation(session, attempt)
+	}
+}
+
+// execArgs runs 'args' with Server.Exec, returning its exit status as an ExitError if it failed.
+func execArgs(session *telnet.Session, server *Server, args []string) error {
+	if status := server.Exec(session, args); status != 0 {
+		return ExitError(status)
+	}
+
+	return nil
+}
+
+// readPassword writes 'prompt', and reads a line without echoing it.
+func readPassword(session *telnet.Session, prompt string) (string, error) {
+	return session.EditLine(prompt, &telnet.LineEditor{Secret: true})
+}
+
+// pushUser switches the session to 'user', until the exit builtin returns to the previous one.
+func pushUser(session *telnet.Session, user string) {
+	state := getState(session)
+	state.users = append(state.users, user)
+}
+
+// popUser returns the session to the user it was before the last pushUser, reporting whether there was one.
+func popUser(session *telnet.Session) bool {
+	state := getState(session)
+	if len(state.users) == 0 {
+		return false
+	}
+
+	state.users = state.users[:len(state.users)-1]
+
+	return true
+}
+
+// EffectiveUser returns the user the session is acting as: the last one it switched to with sudo or su (see
+// Escalation), or else the user it logged in as (see CurrentUser), or else its USER variable, or else "user".
+func EffectiveUser(session *telnet.Session) string {
+	if users := getState(session).users; len(users) > 0 {
+		return users[len(users)-1]
+	}
+
+	if user, ok := CurrentUser(session); ok && user.Username != "" {
+		return user.Username
+	}
+
+	if name, ok := Getenv(session, "USER"); ok && name != "" {
+		return name
+	}
+
+	return "user"
+}
+
+// Privileged reports whether the session is acting as root.
+func Privileged(session *telnet.Session) bool {
+	return EffectiveUser(session) == rootUser
+}
@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestEscalation(t *testing.T) {
+	var attempts []string
+
+	server := &Server{
+		Environment: map[string]string{"USER": "admin"},
+		Escalation: &Escalation{
+			Password:    "secret",
+			MaxAttempts: 2,
+			OnEscalation: func(session *telnet.Session, attempt EscalationAttempt) {
+				attempts = append(attempts, attempt.Command+" "+attempt.Username+" "+attempt.Password+" "+attempt.NewPassword)
+			},
+		},
+	}
+
+	server.Handle("whoami", func(session *telnet.Session, args []string) error {
+		return session.WriteString(EffectiveUser(session), "\r\n")
+	})
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "sudo whoami", Expected: "[sudo] password for admin: "},
+		{Input: "wrong", Expected: "\r\nSorry, try again.\r\n[sudo] password for admin: "},
+		{Input: "secret", Expected: "\r\nroot\r\n$ "},
+		{Input: "sudo -u nobody whoami", Expected: "nobody\r\n$ "},
+		{Input: "su", Expected: "Password: "},
+		{Input: "secret", Expected: "\r\n# "},
+		{Input: "passwd", Expected: "Changing password for root.\r\nNew password: "},
+		{Input: "hunter2", Expected: "\r\nRetype new password: "},
+		{Input: "hunter2", Expected: "\r\npasswd: password updated successfully\r\n# "},
+		{Input: "exit", Expected: "$ "},
+		{Input: "whoami", Expected: "admin\r\n$ "},
+	}
+
+	for testNumber, test := range tests {
+		if err := client.Send(test.Input + "\r\n"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected, actual := test.Expected, expectOutput(t, client, test.Expected[len(test.Expected)-2:]); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+
+	expected := []string{"sudo root wrong ", "sudo root secret ", "su root secret ", "passwd root  hunter2"}
+	if !reflect.DeepEqual(expected, attempts) {
+		t.Errorf("Expected attempts %q, but actually got %q.", expected, attempts)
+	}
+}
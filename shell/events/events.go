@@ -0,0 +1,340 @@
+// Package events forwards what happens in shell honeypot sessions (login attempts, commands, downloads and privilege
+// escalations) to external systems such as syslog or an HTTP webhook, so they integrate with existing SIEM pipelines.
+// Any other system (e.g. Kafka) can be fed by implementing Sink.
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+// DefaultBufferSize is the number of events a Forwarder queues, unless configured otherwise.
+const DefaultBufferSize = 1024
+
+var (
+	// ErrBufferFull is returned by Forwarder.Send when its queue is full, and the event was dropped.
+	ErrBufferFull = errors.New("event buffer full")
+
+	// ErrClosed is returned by Forwarder.Send after the Forwarder has been closed.
+	ErrClosed = errors.New("forwarder closed")
+)
+
+// Type is the kind of an Event.
+type Type string
+
+const (
+	TypeLogin      Type = "login"
+	TypeCommand    Type = "command"
+	TypeDownload   Type = "download"
+	TypeEscalation Type = "escalation"
+
+	// TypeCanary is sent, before the TypeLogin event, for login attempts using the credentials of a canary user (see
+	// shell.Server.Canaries), which are a sure sign of an intruder. Its Login is set.
+	TypeCanary Type = "canary"
+)
+
+type (
+	// Event is something that happened in a shell session. Exactly one of Login, Command, Download and Escalation is
+	// set, depending on Type (Login for TypeCanary).
+	Event struct {
+		Type Type      `json:"type"`
+		Time time.Time `json:"time"`
+
+		// RemoteAddr and LocalAddr are the addresses of the client and the server.
+		RemoteAddr string `json:"remoteAddr"`
+		LocalAddr  string `json:"localAddr"`
+
+		// Username is the user the client logged in as (see shell.CurrentUser), if any.
+		Username string `json:"username,omitempty"`
+
+		// Enrichment describes the client's address, if the server has an Enricher (see telnet.WithEnricher).
+		Enrichment *telnet.Enrichment `json:"enrichment,omitempty"`
+
+		Login      *Login      `json:"login,omitempty"`
+		Command    *Command    `json:"command,omitempty"`
+		Download   *Download   `json:"download,omitempty"`
+		Escalation *Escalation `json:"escalation,omitempty"`
+	}
+
+	// Login describes a login attempt.
+	Login struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Success  bool   `json:"success"`
+		Canary   bool   `json:"canary,omitempty"`
+
+		// Attempt is the attempt's position in the session, starting from 1.
+		Attempt int `json:"attempt"`
+
+		// Duration is how long the client took to enter the credentials, if known.
+		Duration time.Duration `json:"duration,omitempty"`
+	}
+
+	// Command describes a command run (see shell.Record).
+	Command struct {
+		Line     string      `json:"line"`
+		Command  string      `json:"command"`
+		Match    shell.Match `json:"match"`
+		Regex    string      `json:"regex,omitempty"`
+		Status   int         `json:"status"`
+		Response string      `json:"response"`
+	}
+
+	// Download describes a URL the client tried to download from (see shell.Download).
+	Download struct {
+		Command string `json:"command"`
+		URL     string `json:"url"`
+		Path    string `json:"path,omitempty"`
+		Size    int64  `json:"size,omitempty"`
+		SHA256  string `json:"sha256,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+
+	// Escalation describes a password entered into sudo, su or passwd (see shell.EscalationAttempt).
+	Escalation struct {
+		Command     string `json:"command"`
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		NewPassword string `json:"newPassword,omitempty"`
+		Success     bool   `json:"success"`
+	}
+
+	// Sink receives events, e.g. to forward them to another system.
+	Sink interface {
+		Send(ctx context.Context, event Event) error
+	}
+
+	// The SinkFunc type is an adapter to allow the use of ordinary functions as a Sink.
+	SinkFunc func(ctx context.Context, event Event) error
+
+	// Forwarder is a Sink queuing events to send to other Sinks in the background, so slow Sinks (such as a webhook)
+	// don't hold up sessions. Events are dropped if the queue fills up.
+	Forwarder struct {
+		logger *slog.Logger
+		sinks  []Sink
+		events chan Event
+
+		mu     sync.RWMutex // guards closed, so events aren't sent once the queue is closed
+		closed bool
+		done   chan struct{}
+	}
+)
+
+// Send calls f(ctx, event).
+func (f SinkFunc) Send(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// NewEvent returns an Event of type 'eventType', filled in with the session's details.
+func NewEvent(session *telnet.Session, eventType Type) Event {
+	event := Event{Type: eventType, Time: session.Clock().Now()}
+
+	if addr := session.RemoteAddr(); addr != nil {
+		event.RemoteAddr = addr.String()
+	}
+
+	if addr := session.LocalAddr(); addr != nil {
+		event.LocalAddr = addr.String()
+	}
+
+	if user, ok := shell.CurrentUser(session); ok {
+		event.Username = user.Username
+	}
+
+	if enrichment, ok := session.Enrichment(); ok {
+		event.Enrichment = &enrichment
+	}
+
+	return event
+}
+
+// Attach sends the login attempts, commands, downloads and privilege escalations of the server's sessions to 'sink',
+// by way of its OnLogin, Recorder, Downloader.OnDownload (if it has a Downloader) and Escalation.OnEscalation (if it
+// has an Escalation). Any hooks already set are kept, and called before the event is sent. Wrap 'sink' in a Forwarder
+// unless it's fast.
+func Attach(server *shell.Server, sink Sink) {
+	server.OnLogin = chain(server.OnLogin, LoginHandler(sink))
+
+	if previous, recorder := server.Recorder, Recorder(sink); previous != nil {
+		server.Recorder = shell.RecorderFunc(func(session *telnet.Session, record shell.Record) error {
+			return errors.Join(previous.Record(session, record), recorder.Record(session, record))
+		})
+	} else {
+		server.Recorder = recorder
+	}
+
+	if server.Downloader != nil {
+		server.Downloader.OnDownload = chain(server.Downloader.OnDownload, DownloadHandler(sink))
+	}
+
+	if server.Escalation != nil {
+		server.Escalation.OnEscalation = chain(server.Escalation.OnEscalation, EscalationHandler(sink))
+	}
+}
+
+// chain returns a hook calling 'previous' (if set), then 'next'.
+func chain[T any](previous func(*telnet.Session, T), next func(*telnet.Session, T)) func(*telnet.Session, T) {
+	if previous == nil {
+		return next
+	}
+
+	return func(session *telnet.Session, value T) {
+		previous(session, value)
+		next(session, value)
+	}
+}
+
+// DownloadHandler returns a function for shell.Downloader.OnDownload sending each download to 'sink'.
+func DownloadHandler(sink Sink) func(session *telnet.Session, download shell.Download) {
+	return func(session *telnet.Session, download shell.Download) {
+		event := NewEvent(session, TypeDownload)
+		event.Time = download.Time
+		event.Download = &Download{
+			Command: download.Command,
+			URL:     download.URL,
+			Path:    download.Path,
+			Size:    download.Size,
+			SHA256:  download.SHA256,
+		}
+
+		if download.Err != nil {
+			event.Download.Error = download.Err.Error()
+		}
+
+		_ = sink.Send(session.Context(), event)
+	}
+}
+
+// EscalationHandler returns a function for shell.Escalation.OnEscalation sending each password entered to 'sink'.
+func EscalationHandler(sink Sink) func(session *telnet.Session, attempt shell.EscalationAttempt) {
+	return func(session *telnet.Session, attempt shell.EscalationAttempt) {
+		event := NewEvent(session, TypeEscalation)
+		event.Time = attempt.Time
+		event.Escalation = &Escalation{
+			Command:     attempt.Command,
+			Username:    attempt.Username,
+			Password:    attempt.Password,
+			NewPassword: attempt.NewPassword,
+			Success:     attempt.Success,
+		}
+
+		_ = sink.Send(session.Context(), event)
+	}
+}
+
+// LoginHandler returns a function for shell.Server.OnLogin sending each login attempt to 'sink', preceded by a
+// TypeCanary event if it used a canary's credentials.
+func LoginHandler(sink Sink) func(session *telnet.Session, attempt shell.LoginAttempt) {
+	return func(session *telnet.Session, attempt shell.LoginAttempt) {
+		event := NewEvent(session, TypeLogin)
+		event.Time = attempt.Time
+		event.Login = &Login{
+			Username: attempt.Username,
+			Password: attempt.Password,
+			Success:  attempt.Success,
+			Canary:   attempt.Canary,
+			Attempt:  attempt.Number,
+			Duration: attempt.Duration,
+		}
+
+		if attempt.Canary {
+			canary := event
+			canary.Type = TypeCanary
+			_ = sink.Send(session.Context(), canary)
+		}
+
+		_ = sink.Send(session.Context(), event)
+	}
+}
+
+// Recorder returns a shell.Recorder sending each command to 'sink'.
+func Recorder(sink Sink) shell.Recorder {
+	return shell.RecorderFunc(func(session *telnet.Session, record shell.Record) error {
+		event := NewEvent(session, TypeCommand)
+		event.Time = record.Time
+		event.Command = &Command{
+			Line:     record.Line,
+			Command:  record.Command,
+			Match:    record.Match,
+			Regex:    record.Regex,
+			Status:   record.Status,
+			Response: record.Response,
+		}
+
+		return sink.Send(session.Context(), event)
+	})
+}
+
+// NewForwarder returns a Forwarder queuing up to 'bufferSize' events (DefaultBufferSize if unset) to send to 'sinks'.
+// Errors from the sinks are logged with 'logger'; slog.Default() if nil.
+func NewForwarder(logger *slog.Logger, bufferSize int, sinks ...Sink) *Forwarder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	forwarder := &Forwarder{
+		logger: logger,
+		sinks:  sinks,
+		events: make(chan Event, bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	go forwarder.run()
+
+	return forwarder
+}
+
+// Send queues 'event' to be sent, returning ErrBufferFull if it had to be dropped.
+func (f *Forwarder) Send(_ context.Context, event Event) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+
+	select {
+	case f.events <- event:
+		return nil
+	default:
+		f.logger.Warn("dropped event, as the buffer is full", "type", event.Type)
+		return ErrBufferFull
+	}
+}
+
+// Close stops accepting events, and waits for the queued ones to be sent.
+func (f *Forwarder) Close() error {
+	f.mu.Lock()
+	if !f.closed {
+		f.closed = true
+		close(f.events)
+	}
+	f.mu.Unlock()
+
+	<-f.done
+
+	return nil
+}
+
+// run sends the queued events to each sink, until the Forwarder is closed.
+func (f *Forwarder) run() {
+	defer close(f.done)
+
+	for event := range f.events {
+		for _, sink := range f.sinks {
+			if err := sink.Send(context.Background(), event); err != nil {
+				f.logger.Error("failed to send event", "type", event.Type, "err", err)
+			}
+		}
+	}
+}
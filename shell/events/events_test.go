@@ -0,0 +1,184 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestAttach(t *testing.T) {
+	var called []string
+	var sent []Type
+
+	server := &shell.Server{
+		OnLogin: func(*telnet.Session, shell.LoginAttempt) { called = append(called, "login") },
+		Recorder: shell.RecorderFunc(func(*telnet.Session, shell.Record) error {
+			called = append(called, "record")
+			return nil
+		}),
+		Downloader: &shell.Downloader{OnDownload: func(*telnet.Session, shell.Download) { called = append(called, "download") }},
+		Escalation: &shell.Escalation{OnEscalation: func(*telnet.Session, shell.EscalationAttempt) {
+			called = append(called, "escalation")
+		}},
+	}
+
+	Attach(server, SinkFunc(func(ctx context.Context, event Event) error {
+		sent = append(sent, event.Type)
+		return nil
+	}))
+
+	done := make(chan struct{})
+
+	ts := telnettest.NewServer(func(session *telnet.Session) {
+		defer close(done)
+
+		server.OnLogin(session, shell.LoginAttempt{Username: "root"})
+		_ = server.Recorder.Record(session, shell.Record{Command: "id"})
+		server.Downloader.OnDownload(session, shell.Download{URL: "http://192.0.2.1/x"})
+		server.Escalation.OnEscalation(session, shell.EscalationAttempt{Command: "sudo"})
+	})
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	<-done
+
+	// The hooks the server already had are still called, as well as the events being sent.
+	if expected, actual := []string{"login", "record", "download", "escalation"}, called; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the hooks %q to be called, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := []Type{TypeLogin, TypeCommand, TypeDownload, TypeEscalation}, sent; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the events %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestForwarder(t *testing.T) {
+	var received []Event
+
+	forwarder := NewForwarder(slog.New(slog.NewTextHandler(io.Discard, nil)), 2, SinkFunc(func(ctx context.Context, event Event) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	events := []Event{
+		{Type: TypeLogin, Login: &Login{Username: "root", Password: "admin"}},
+		{Type: TypeCommand, Command: &Command{Line: "uname -a", Command: "uname -a"}},
+	}
+
+	for _, event := range events {
+		// Events may be sent as soon as they're queued, so they can't be used to fill the buffer.
+		if err := forwarder.Send(context.Background(), event); err != nil && err != ErrBufferFull {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}
+
+	if err := forwarder.Close(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if !reflect.DeepEqual(events, received) {
+		t.Errorf("Expected %+v, but actually got %+v.", events, received)
+	}
+
+	if err := forwarder.Send(context.Background(), events[0]); err != ErrClosed {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrClosed, err, err)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan Event, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		received <- event
+	}))
+	defer server.Close()
+
+	event := Event{Type: TypeDownload, Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Download: &Download{URL: "http://192.0.2.1/x.sh"}}
+
+	sink := NewWebhookSink(server.URL, http.Header{"Authorization": {"Bearer token"}})
+	if err := sink.Send(context.Background(), event); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if actual := <-received; !reflect.DeepEqual(event, actual) {
+		t.Errorf("Expected %+v, but actually got %+v.", event, actual)
+	}
+
+	if err := NewWebhookSink(server.URL, nil).Send(context.Background(), event); err == nil {
+		t.Error("Expected an error for an unauthorized request, but actually got none.")
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	sink := NewSyslogSink("udp", listener.LocalAddr().String(), DefaultSyslogFacility, "honeypot")
+	defer sink.Close()
+
+	event := Event{Type: TypeLogin, Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Login: &Login{Username: "root"}}
+	if err = sink.Send(context.Background(), event); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+
+	buffer := make([]byte, 2048)
+	n, _, err := listener.ReadFrom(buffer)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	message := string(buffer[:n])
+
+	expected := "<134>1 2024-01-02T03:04:05Z "
+	if !strings.HasPrefix(message, expected) {
+		t.Errorf("Expected a message starting with %q, but actually got %q.", expected, message)
+	}
+
+	for _, expected = range []string{" honeypot ", " login - {", `"username":"root"`} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in the message, but actually got %q.", expected, message)
+		}
+	}
+
+	// Canaries are sent as alerts.
+	event.Type = TypeCanary
+
+	data, err := sink.format(event)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected = "<129>1 "; !strings.HasPrefix(string(data), expected) {
+		t.Errorf("Expected a message starting with %q, but actually got %q.", expected, data)
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSyslogFacility is the local0 facility.
+	DefaultSyslogFacility = 16
+
+	// syslogSeverity is the informational severity, used for every event but canaries.
+	syslogSeverity = 6
+
+	// syslogCanarySeverity is the alert severity, used for TypeCanary events, which call for immediate action.
+	syslogCanarySeverity = 1
+)
+
+// SyslogSink is a Sink sending each event to a syslog server as an RFC 5424 message, with the event as JSON. Messages
+// sent over TCP are newline-terminated. It's safe for concurrent use.
+type SyslogSink struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink returns a SyslogSink sending to the syslog server at 'address' over 'network' ("udp" or "tcp"), as
+// 'appName' with 'facility' (e.g. DefaultSyslogFacility). It connects when the first event is sent.
+func NewSyslogSink(network string, address string, facility int, appName string) *SyslogSink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		network:  network,
+		address:  address,
+		facility: facility,
+		appName:  valueOrNil(appName),
+		hostname: hostname,
+	}
+}
+
+// Send sends 'event' to the syslog server, reconnecting once if the connection was lost.
+func (s *SyslogSink) Send(ctx context.Context, event Event) error {
+	message, err := s.format(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			var dialer net.Dialer
+			if s.conn, err = dialer.DialContext(ctx, s.network, s.address); err != nil {
+				return fmt.Errorf("failed to connect to syslog server: %w", err)
+			}
+		}
+
+		if _, err = s.conn.Write(message); err == nil {
+			return nil
+		}
+
+		_ = s.conn.Close()
+		s.conn = nil
+
+		if attempt > 0 {
+			return fmt.Errorf("failed to write to syslog server: %w", err)
+		}
+	}
+}
+
+// Close closes the connection to the syslog server, if any.
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+// format formats 'event' as a syslog message.
+func (s *SyslogSink) format(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	severity := syslogSeverity
+	if event.Type == TypeCanary {
+		severity = syslogCanarySeverity
+	}
+
+	message := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		s.facility*8+severity,
+		event.Time.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+		valueOrNil(string(event.Type)),
+		data,
+	)
+
+	if strings.HasPrefix(s.network, "tcp") {
+		message += "\n"
+	}
+
+	return []byte(message), nil
+}
+
+// valueOrNil returns 'value', or the syslog NILVALUE ("-") if it's empty.
+func valueOrNil(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
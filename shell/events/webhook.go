@@ -0,0 +1,65 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultWebhookTimeout is how long a WebhookSink waits for the webhook to respond.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// WebhookSink is a Sink POSTing each event to an HTTP webhook as JSON.
+type WebhookSink struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+// NewWebhookSink returns a WebhookSink POSTing to 'url', with 'headers' (e.g. for authorization) if set.
+func NewWebhookSink(url string, headers http.Header) *WebhookSink {
+	return &WebhookSink{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: DefaultWebhookTimeout},
+	}
+}
+
+// Send POSTs 'event' to the webhook, returning an error unless it responds with a 2xx status.
+func (w *WebhookSink) Send(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	for name, values := range w.headers {
+		request.Header[name] = values
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send event to webhook: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	// Drain the body, so the connection can be reused.
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", response.Status)
+	}
+
+	return nil
+}
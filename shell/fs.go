@@ -0,0 +1,367 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing/fstest"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// fileSystem is a session's private, writable copy of Server.FileSystem, along with its working directory.
+type fileSystem struct {
+	files fstest.MapFS
+	cwd   string
+	clock telnet.Clock // times files' modifications
+}
+
+// newFileSystem copies 'base' into memory, so the session can modify it without affecting other sessions. Files it
+// modifies are timed by 'clock'.
+func newFileSystem(base fs.FS, clock telnet.Clock) (*fileSystem, error) {
+	files := fstest.MapFS{}
+
+	err := fs.WalkDir(base, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		file := &fstest.MapFile{Mode: info.Mode(), ModTime: info.ModTime()}
+
+		if !entry.IsDir() {
+			if file.Data, err = fs.ReadFile(base, name); err != nil {
+				return err
+			}
+		}
+
+		files[name] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSystem{files: files, cwd: "/", clock: clock}, nil
+}
+
+// resolve converts a path relative to the working directory into an absolute path, and the matching fs.FS name.
+func (f *fileSystem) resolve(name string) (absolute string, fsName string) {
+	if !path.IsAbs(name) {
+		name = path.Join(f.cwd, name)
+	}
+
+	absolute = path.Clean(name)
+	if absolute == "/" {
+		return absolute, "."
+	}
+
+	return absolute, absolute[1:]
+}
+
+// builtins returns the filesystem commands for the session.
+func (f *fileSystem) builtins() map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"cat":   f.cat,
+		"cd":    f.cd,
+		"ls":    f.ls,
+		"pwd":   f.pwd,
+		"rm":    f.rm,
+		"touch": f.touch,
+	}
+}
+
+func (f *fileSystem) cat(session *telnet.Session, args []string) error {
+	var errs []error
+
+	for _, arg := range args[1:] {
+		_, name := f.resolve(arg)
+
+		data, err := fs.ReadFile(f.files, name)
+		if err != nil {
+			errs = append(errs, fileError(arg, err))
+			continue
+		}
+
+		if err = session.WriteString(toCRLF(string(data))); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *fileSystem) cd(session *telnet.Session, args []string) error {
+	target := "/"
+	if len(args) > 1 {
+		target = args[1]
+	}
+
+	absolute, name := f.resolve(target)
+
+	info, err := fs.Stat(f.files, name)
+	if err != nil {
+		return fileError(target, err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s: Not a directory", target)
+	}
+
+	f.cwd = absolute
+	return nil
+}
+
+func (f *fileSystem) ls(session *telnet.Session, args []string) error {
+	var long, all bool
+	var targets []string
+
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			long = long || strings.Contains(arg, "l")
+			all = all || strings.Contains(arg, "a")
+			continue
+		}
+
+		targets = append(targets, arg)
+	}
+
+	if len(targets) == 0 {
+		targets = []string{"."}
+	}
+
+	var errs []error
+
+	for _, target := range targets {
+		_, name := f.resolve(target)
+
+		info, err := fs.Stat(f.files, name)
+		if err != nil {
+			errs = append(errs, fileError(target, err))
+			continue
+		}
+
+		var entries []fs.FileInfo
+
+		if info.IsDir() {
+			dirEntries, err := fs.ReadDir(f.files, name)
+			if err != nil {
+				errs = append(errs, fileError(target, err))
+				continue
+			}
+
+			for _, entry := range dirEntries {
+				if !all && strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+
+				if entryInfo, err := entry.Info(); err == nil {
+					entries = append(entries, entryInfo)
+				}
+			}
+		} else {
+			entries = append(entries, renamedFileInfo{FileInfo: info, name: target})
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+		if len(targets) > 1 && info.IsDir() {
+			if err = session.WriteLine(target, ":"); err != nil {
+				return err
+			}
+		}
+
+		if err = session.WriteString(formatListing(entries, long)); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *fileSystem) pwd(session *telnet.Session, args []string) error {
+	return session.WriteLine(f.cwd)
+}
+
+func (f *fileSystem) rm(session *telnet.Session, args []string) error {
+	var recursive, force bool
+	var errs []error
+
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			recursive = recursive || strings.ContainsAny(arg, "rR")
+			force = force || strings.Contains(arg, "f")
+			continue
+		}
+
+		_, name := f.resolve(arg)
+
+		info, err := fs.Stat(f.files, name)
+		if err != nil {
+			if !force {
+				errs = append(errs, fmt.Errorf("can't remove '%s': No such file or directory", arg))
+			}
+			continue
+		}
+
+		if info.IsDir() && !recursive {
+			errs = append(errs, fmt.Errorf("'%s' is a directory", arg))
+			continue
+		}
+
+		for existing := range f.files {
+			if existing == name || strings.HasPrefix(existing, name+"/") {
+				delete(f.files, existing)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *fileSystem) touch(session *telnet.Session, args []string) error {
+	var errs []error
+
+	for _, arg := range args[1:] {
+		_, name := f.resolve(arg)
+
+		if file, ok := f.files[name]; ok {
+			file.ModTime = f.clock.Now()
+			continue
+		}
+
+		if info, err := fs.Stat(f.files, path.Dir(name)); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s: No such file or directory", arg))
+			continue
+		}
+
+		f.files[name] = &fstest.MapFile{Mode: 0o644, ModTime: f.clock.Now()}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeFile creates or replaces the file 'name' with 'data', if its directory exists.
+func (f *fileSystem) writeFile(name string, data []byte) error {
+	_, fsName := f.resolve(name)
+
+	if info, err := fs.Stat(f.files, path.Dir(fsName)); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s: No such file or directory", name)
+	}
+
+	if info, err := fs.Stat(f.files, fsName); err == nil && info.IsDir() {
+		return fmt.Errorf("%s: Is a directory", name)
+	}
+
+	f.files[fsName] = &fstest.MapFile{Data: data, Mode: 0o644, ModTime: f.clock.Now()}
+
+	return nil
+}
+
+// sizeWith returns the total size of the files, were 'name' to be written with 'data'.
+func (f *fileSystem) sizeWith(name string, data []byte) int64 {
+	_, fsName := f.resolve(name)
+
+	size := int64(len(data))
+	for existing, file := range f.files {
+		if existing != fsName {
+			size += int64(len(file.Data))
+		}
+	}
+
+	return size
+}
+
+// complete returns the paths matching the partial path 'word', relative to the working directory.
+func (f *fileSystem) complete(word string) []string {
+	dir, prefix := path.Split(word)
+
+	_, name := f.resolve(dir)
+	if dir == "" {
+		_, name = f.resolve(".")
+	}
+
+	entries, err := fs.ReadDir(f.files, name)
+	if err != nil {
+		return nil
+	}
+
+	var completions []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+
+		completion := dir + entry.Name()
+		if entry.IsDir() {
+			completion += "/"
+		}
+
+		completions = append(completions, completion)
+	}
+
+	return completions
+}
+
+// fileError converts an fs error into the message a shell would show.
+func fileError(name string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s: No such file or directory", name)
+	case errors.Is(err, fs.ErrInvalid):
+		// Reading a directory fails as invalid.
+		return fmt.Errorf("%s: Is a directory", name)
+	}
+
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// formatListing formats directory entries like ls.
+func formatListing(entries []fs.FileInfo, long bool) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	for i, entry := range entries {
+		if !long {
+			if i > 0 {
+				builder.WriteString("  ")
+			}
+
+			builder.WriteString(entry.Name())
+			continue
+		}
+
+		fmt.Fprintf(&builder, "%s    1 root     root     %8d %s %s\r\n", entry.Mode().String(), entry.Size(), entry.ModTime().Format("Jan _2 15:04"), entry.Name())
+	}
+
+	if !long {
+		builder.WriteString("\r\n")
+	}
+
+	return builder.String()
+}
+
+// toCRLF converts bare LFs in 's' into CR LF, as expected by TELNET clients.
+func toCRLF(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// renamedFileInfo reports a file under the name the client used for it.
+type renamedFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (r renamedFileInfo) Name() string {
+	return r.name
+}
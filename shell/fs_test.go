@@ -0,0 +1,91 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestFileSystem(t *testing.T) {
+	base := fstest.MapFS{
+		"etc/passwd":     {Data: []byte("root:x:0:0:root:/root:/bin/sh\n")},
+		"tmp/.hidden":    {Data: []byte("secret")},
+		"var/log/syslog": {Data: []byte("")},
+	}
+
+	files, err := newFileSystem(base, telnet.RealClock)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if err = files.cd(nil, []string{"cd", "/var/log/../../etc"}); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "/etc", files.cwd; expected != actual {
+		t.Errorf("Expected cwd %q, but actually got %q.", expected, actual)
+	}
+
+	if err = files.cd(nil, []string{"cd", "passwd"}); err == nil || err.Error() != "passwd: Not a directory" {
+		t.Errorf("Expected a 'Not a directory' error, but actually got: %v.", err)
+	}
+
+	if err = files.touch(nil, []string{"touch", "shadow", "/missing/file"}); err == nil {
+		t.Errorf("Expected an error touching a file in a missing directory, but didn't get one.")
+	}
+
+	if _, ok := files.files["etc/shadow"]; !ok {
+		t.Errorf("Expected touch to create etc/shadow, but it doesn't exist.")
+	}
+
+	if _, ok := base["etc/shadow"]; ok {
+		t.Errorf("Expected touch to leave the server's filesystem untouched, but etc/shadow was created there.")
+	}
+
+	if expected, actual := []string{"shadow"}, files.complete("sh"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected completions %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := []string{"/var/log/"}, files.complete("/var/l"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected completions %q, but actually got %q.", expected, actual)
+	}
+
+	if err = files.rm(nil, []string{"rm", "/var"}); err == nil || err.Error() != "'/var' is a directory" {
+		t.Errorf("Expected an 'is a directory' error, but actually got: %v.", err)
+	}
+
+	if err = files.rm(nil, []string{"rm", "-rf", "/var", "/missing"}); err != nil {
+		t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for name := range files.files {
+		if name == "var" || name == "var/log" || name == "var/log/syslog" {
+			t.Errorf("Expected rm -rf to remove %q, but it still exists.", name)
+		}
+	}
+}
+
+func TestServer_FileSystemError(t *testing.T) {
+	server := &Server{FileSystem: os.DirFS(filepath.Join(t.TempDir(), "missing"))}
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	// The client is told something went wrong, rather than just disconnected.
+	response, err := client.ExpectEOF()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := DefaultWelcomeMessage+DefaultMessages.InternalError, response; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
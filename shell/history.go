@@ -0,0 +1,64 @@
+package shell
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	DefaultHistoryCommand = "history"
+	DefaultHistorySize    = 1000
+)
+
+type (
+	// HistoryStore persists the commands entered during shell sessions, so operators can review what each client tried.
+	HistoryStore interface {
+		// AppendHistory records a command entered by the session.
+		AppendHistory(session *telnet.Session, command string) error
+	}
+
+	// The HistoryStoreFunc type is an adapter to allow the use of ordinary functions as a HistoryStore.
+	HistoryStoreFunc func(session *telnet.Session, command string) error
+
+	// history holds the commands entered during a single session, oldest first.
+	history struct {
+		entries []string
+		size    int
+	}
+)
+
+// AppendHistory calls f(session, command).
+func (f HistoryStoreFunc) AppendHistory(session *telnet.Session, command string) error {
+	return f(session, command)
+}
+
+// add records a command, dropping the oldest entry if the history is full.
+func (h *history) add(command string) {
+	if strings.TrimSpace(command) == "" {
+		return
+	}
+
+	if h.size > 0 && len(h.entries) >= h.size {
+		h.entries = h.entries[1:]
+	}
+
+	h.entries = append(h.entries, command)
+}
+
+// String formats the history like bash's history builtin.
+func (h *history) String() string {
+	var builder strings.Builder
+
+	for i, entry := range h.entries {
+		number := strconv.Itoa(i + 1)
+		builder.WriteString(strings.Repeat(" ", max(0, 5-len(number))))
+		builder.WriteString(number)
+		builder.WriteString("  ")
+		builder.WriteString(entry)
+		builder.WriteString("\r\n")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestHistory(t *testing.T) {
+	h := &history{size: 2}
+
+	for _, command := range []string{"id", " ", "uname -a", "cat /proc/cpuinfo"} {
+		h.add(command)
+	}
+
+	// Blank lines aren't recorded, and the oldest entry is dropped once the history is full.
+	if expected, actual := "    1  uname -a\r\n    2  cat /proc/cpuinfo\r\n", h.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestServer_History(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		stored []string
+	)
+
+	server := &Server{
+		HistorySize: 2,
+		HistoryStore: HistoryStoreFunc(func(session *telnet.Session, command string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			stored = append(stored, command)
+
+			// Failing to persist a command doesn't affect the session.
+			return errors.New("disk full")
+		}),
+	}
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	for _, command := range []string{"ls", "", "id"} {
+		if err := client.Send(command + "\r\n"); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		expectOutput(t, client, DefaultPrompt)
+	}
+
+	if err := client.Send("history\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// The history builtin lists itself, and "ls" has been dropped to keep to the HistorySize.
+	if expected, actual := "    1  id\r\n    2  history\r\n"+DefaultPrompt, expectOutput(t, client, DefaultPrompt); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if expected, actual := []string{"ls", "id", "history"}, stored; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the HistoryStore to get %q, but actually got %q.", expected, actual)
+	}
+}
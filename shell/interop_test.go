@@ -0,0 +1,105 @@
+//go:build interop
+
+package shell
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+// TestInterop logs in to a shell with real TELNET clients, checking the password isn't echoed and the client's window
+// size is received. It needs the clients installed, so is only built with the "interop" tag, and best run in the
+// container described by testdata/interop/Dockerfile.
+func TestInterop(t *testing.T) {
+	clients := []telnettest.ExternalClient{telnettest.InetutilsTelnet, telnettest.BusyboxTelnet, telnettest.Plink}
+
+	for _, client := range clients {
+		t.Run(client.Name, func(t *testing.T) {
+			if !client.Available() {
+				t.Skipf("%s isn't installed.", client.Name)
+			}
+
+			shell := &Server{
+				AuthHandler:       NewAuthHandler("root", "hunter2", 1),
+				LoginDelay:        -1,
+				RequestWindowSize: true,
+				Banner:            "interop\r\n",
+				Commands:          []Command{{Regex: "^ping$", Response: "pong\r\n"}},
+			}
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+
+			server := telnet.NewServer(telnet.WithHandler(shell.HandlerFunc))
+			sessions := make(chan *telnet.Session, 1)
+			server.Subscribe(func(event telnet.SessionEvent) {
+				if event.Type == telnet.SessionStarted {
+					sessions <- event.Session
+				}
+			})
+
+			go server.Serve(listener)
+			defer server.Shutdown()
+
+			terminal, err := client.Start(listener.Addr().String(), 100, 40)
+			if err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+			defer terminal.Close()
+
+			steps := []struct {
+				expect string
+				send   string
+			}{
+				{expect: "interop"},
+				{expect: "Login: ", send: "root\r"},
+				{expect: "Password: ", send: "hunter2\r"},
+			}
+
+			for _, step := range steps {
+				if _, err = terminal.Expect(step.expect); err != nil {
+					t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+				}
+
+				if step.send != "" {
+					if err = terminal.Send(step.send); err != nil {
+						t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+					}
+				}
+			}
+
+			output, err := terminal.Expect(DefaultPrompt)
+			if err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+
+			if strings.Contains(output, "hunter2") {
+				t.Errorf("Expected the password not to be echoed, but it was: %q.", output)
+			}
+
+			if width, height, ok := (<-sessions).WindowSize(); !ok || width != 100 || height != 40 {
+				t.Errorf("Expected a window size of 100x40, but actually got %dx%d (%t).", width, height, ok)
+			}
+
+			for _, text := range []string{"ping\r", "exit\r"} {
+				if err = terminal.Send(text); err != nil {
+					t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+				}
+			}
+
+			if _, err = terminal.Expect("pong"); err != nil {
+				t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+
+			if _, err = terminal.Wait(); err != nil {
+				t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+		})
+	}
+}
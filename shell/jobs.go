@@ -0,0 +1,200 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+type (
+	// jobs is a session's fake job table (see Server.JobControl). Its jobs never really run in the background, but are
+	// listed as if they did.
+	jobs struct {
+		list    []*job // the jobs, the current one (most recently started, stopped or continued) last
+		nextPID int
+	}
+
+	// job is a command run in the background, or stopped with Ctrl-Z.
+	job struct {
+		id      int
+		pid     int
+		command string
+		stopped bool
+		resume  func(session *telnet.Session) error // carries on with the command in the foreground (fg), if it can
+	}
+)
+
+// newJobs returns an empty job table, whose process IDs start somewhere plausible.
+func newJobs() *jobs {
+	return &jobs{nextPID: 1000 + rand.IntN(30000)}
+}
+
+// builtins returns the job control commands for the session.
+func (j *jobs) builtins() map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"bg":   j.bg,
+		"fg":   j.fg,
+		"jobs": j.jobs,
+	}
+}
+
+// start adds 'command' to the table as a job running in the background.
+func (j *jobs) start(command string) *job {
+	id := 1
+	for _, other := range j.list {
+		id = max(id, other.id+1)
+	}
+
+	started := &job{id: id, pid: j.nextPID, command: command}
+	j.list = append(j.list, started)
+	j.nextPID += 1 + rand.IntN(8)
+
+	return started
+}
+
+// stop adds 'command' to the table as a job stopped with Ctrl-Z, which 'resume' carries on with.
+func (j *jobs) stop(command string, resume func(session *telnet.Session) error) *job {
+	stopped := j.start(command)
+	stopped.stopped, stopped.resume = true, resume
+
+	return stopped
+}
+
+// find returns the job identified by 'spec' (e.g. "%1", "1", "%+", "%-" or "%ping"), or the current job if it's empty.
+func (j *jobs) find(spec string) (*job, error) {
+	name := spec
+	if name == "" {
+		name = "current"
+	}
+
+	switch spec {
+	case "", "%", "%%", "%+":
+		if len(j.list) > 0 {
+			return j.list[len(j.list)-1], nil
+		}
+	case "%-":
+		if len(j.list) > 1 {
+			return j.list[len(j.list)-2], nil
+		}
+	default:
+		if id, err := strconv.Atoi(strings.TrimPrefix(spec, "%")); err == nil {
+			for _, found := range j.list {
+				if found.id == id {
+					return found, nil
+				}
+			}
+
+			break
+		}
+
+		for i := len(j.list) - 1; i >= 0; i-- {
+			if strings.HasPrefix(j.list[i].command, strings.TrimPrefix(spec, "%")) {
+				return j.list[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s: no such job", name)
+}
+
+// remove removes 'removed' from the table.
+func (j *jobs) remove(removed *job) {
+	for i, other := range j.list {
+		if other == removed {
+			j.list = append(j.list[:i], j.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// format describes 'described' like the jobs builtin, with its process ID if 'long' is set, e.g.
+// "[1]+  Running                 ./bot &".
+func (j *jobs) format(described *job, long bool) string {
+	marker := ' '
+	switch {
+	case described == j.list[len(j.list)-1]:
+		marker = '+'
+	case len(j.list) > 1 && described == j.list[len(j.list)-2]:
+		marker = '-'
+	}
+
+	status, suffix := "Running", " &"
+	if described.stopped {
+		status, suffix = "Stopped", ""
+	}
+
+	if long {
+		return fmt.Sprintf("[%d]%c %d %-24s%s%s\r\n", described.id, marker, described.pid, status, described.command, suffix)
+	}
+
+	return fmt.Sprintf("[%d]%c  %-24s%s%s\r\n", described.id, marker, status, described.command, suffix)
+}
+
+func (j *jobs) jobs(session *telnet.Session, args []string) error {
+	var long, pids bool
+
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-l":
+			long = true
+		case "-p":
+			pids = true
+		default:
+			return fmt.Errorf("%s: invalid option\nusage: jobs [-lp]", arg)
+		}
+	}
+
+	var builder strings.Builder
+
+	for _, listed := range j.list {
+		if pids {
+			builder.WriteString(strconv.Itoa(listed.pid) + "\r\n")
+		} else {
+			builder.WriteString(j.format(listed, long))
+		}
+	}
+
+	return session.WriteString(builder.String())
+}
+
+func (j *jobs) fg(session *telnet.Session, args []string) error {
+	found, err := j.find(strings.Join(args[1:], " "))
+	if err != nil {
+		return err
+	}
+
+	j.remove(found)
+
+	if err = session.WriteString(found.command + "\r\n"); err != nil {
+		return err
+	}
+
+	// A command that was backgrounded in the first place is taken to have finished.
+	if found.resume == nil {
+		return nil
+	}
+
+	return found.resume(session)
+}
+
+func (j *jobs) bg(session *telnet.Session, args []string) error {
+	found, err := j.find(strings.Join(args[1:], " "))
+	if err != nil {
+		return err
+	}
+
+	if !found.stopped {
+		return errors.New("job " + strconv.Itoa(found.id) + " already in background")
+	}
+
+	// The job becomes the current one.
+	j.remove(found)
+	j.list = append(j.list, found)
+	found.stopped = false
+
+	return session.Writef("[%d]+ %s &\r\n", found.id, found.command)
+}
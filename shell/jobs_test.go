@@ -0,0 +1,85 @@
+package shell
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestServer_JobControl(t *testing.T) {
+	server := &Server{Environment: map[string]string{}, JobControl: true}
+	server.HandleStream("ping", fakePing)
+	server.Handle("./bot", func(session *telnet.Session, args []string) error {
+		return ExitError(1)
+	})
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	if output := enter(t, client, "./bot &"); !regexp.MustCompile(`^\[1\] \d+\r\n$`).MatchString(output) {
+		t.Errorf("Expected the job's number and process ID, but actually got %q.", output)
+	}
+
+	// Ctrl-Z stops a command streaming its output.
+	if err := client.Send("ping 192.0.2.1\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expectOutput(t, client, "icmp_seq=1\r\n")
+
+	if err := client.Send("\x1a"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "^Z\r\n[2]+  Stopped                 ping 192.0.2.1\r\n"+DefaultPrompt, expectOutput(t, client, DefaultPrompt); !strings.HasSuffix(actual, expected) {
+		t.Errorf("Expected the output to end with %q, but actually got %q.", expected, actual)
+	}
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "echo $?", Expected: "148\r\n"},
+		{Input: "jobs", Expected: "[1]-  Running                 ./bot &\r\n[2]+  Stopped                 ping 192.0.2.1\r\n"},
+		{Input: "bg", Expected: "[2]+ ping 192.0.2.1 &\r\n"},
+		{Input: "bg %2", Expected: "bg: job 2 already in background\r\n"},
+		{Input: "fg %./b", Expected: "./bot\r\n"},
+		{Input: "echo $?", Expected: "0\r\n"},
+		{Input: "jobs", Expected: "[2]+  Running                 ping 192.0.2.1 &\r\n"},
+		{Input: "fg %5", Expected: "fg: %5: no such job\r\n"},
+		{Input: "jobs -x", Expected: "jobs: -x: invalid option\r\njobs: usage: jobs [-lp]\r\n"},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, enter(t, client, test.Input); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+
+	// fg carries on with a stopped command in the foreground.
+	if err := client.Send("fg\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "ping 192.0.2.1\r\n64 bytes from 192.0.2.1: icmp_seq=1\r\n", expectOutput(t, client, "icmp_seq=1\r\n"); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if err := client.SendCommand(telnet.IAC, telnet.IP); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expectOutput(t, client, "^C\r\n"+DefaultPrompt)
+
+	if expected, actual := "fg: current: no such job\r\n", enter(t, client, "fg"); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
@@ -0,0 +1,125 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+)
+
+type (
+	// commandMatcher matches command lines against a Server's Commands, compiled once by Server.Compile. Regexes anchored
+	// to the start of the line with a literal prefix (e.g. "^uname$" or "^docker .*$") are indexed in a prefix trie, so
+	// only those whose prefix the line starts with are tried; the rest are tried against every line.
+	commandMatcher struct {
+		commands []Command // the Commands compiled, to tell when they've changed since
+		regexes  []*regexp.Regexp
+		root     *trieNode
+	}
+
+	// trieNode is a node of a commandMatcher's prefix trie, reached by the bytes of a literal prefix.
+	trieNode struct {
+		children map[byte]*trieNode
+
+		// prefixed are the indexes of the commands with this literal prefix, which lines starting with it may match.
+		prefixed []int
+
+		// exact are the indexes of the commands matching only this literal line, without needing their regex run.
+		exact []int
+	}
+)
+
+// newCommandMatcher compiles 'commands', returning an error for the first invalid regex.
+func newCommandMatcher(commands []Command) (*commandMatcher, error) {
+	matcher := &commandMatcher{
+		commands: slices.Clone(commands),
+		regexes:  make([]*regexp.Regexp, len(commands)),
+		root:     &trieNode{},
+	}
+
+	for i, command := range commands {
+		regex, err := regexp.Compile(command.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for command %d: %w", i, err)
+		}
+
+		matcher.regexes[i] = regex
+
+		prefix, exact := anchoredLiteral(command.Regex)
+
+		node := matcher.root
+		for j := 0; j < len(prefix); j++ {
+			child := node.children[prefix[j]]
+			if child == nil {
+				if node.children == nil {
+					node.children = make(map[byte]*trieNode)
+				}
+
+				child = &trieNode{}
+				node.children[prefix[j]] = child
+			}
+
+			node = child
+		}
+
+		if exact {
+			node.exact = append(node.exact, i)
+		} else {
+			node.prefixed = append(node.prefixed, i)
+		}
+	}
+
+	return matcher, nil
+}
+
+// match returns the index of the first command matching 'line', or -1 if none do.
+func (m *commandMatcher) match(line string) int {
+	var candidates, exact []int
+
+	node := m.root
+	for i := 0; node != nil; i++ {
+		candidates = append(candidates, node.prefixed...)
+
+		if i == len(line) {
+			exact = node.exact
+			break
+		}
+
+		node = node.children[line[i]]
+	}
+
+	// Commands are tried in order, whichever part of the trie they were found in.
+	candidates = append(candidates, exact...)
+	slices.Sort(candidates)
+
+	for _, i := range candidates {
+		if slices.Contains(exact, i) || m.regexes[i].MatchString(line) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// anchoredLiteral returns the literal prefix of lines 'pattern' can match, if it's anchored to the start of the line
+// (e.g. "uname" for "^uname$"), and whether it only matches that literal line.
+func anchoredLiteral(pattern string) (prefix string, exact bool) {
+	regex, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+
+	regex = regex.Simplify()
+	if regex.Op != syntax.OpConcat || len(regex.Sub) < 2 || regex.Sub[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+
+	literal := regex.Sub[1]
+	if literal.Op != syntax.OpLiteral || literal.Flags&syntax.FoldCase != 0 {
+		return "", false
+	}
+
+	exact = len(regex.Sub) == 3 && regex.Sub[2].Op == syntax.OpEndText
+
+	return string(literal.Rune), exact
+}
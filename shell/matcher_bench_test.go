@@ -0,0 +1,39 @@
+package shell
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkServer_MatchCommand(b *testing.B) {
+	commands := make([]Command, 200)
+	for i := range commands {
+		commands[i] = Command{Regex: fmt.Sprintf("^command%d( .*)?$", i)}
+	}
+
+	benchmarks := []struct {
+		Name    string
+		Compile bool
+	}{
+		{Name: "Uncompiled"},
+		{Name: "Compiled", Compile: true},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.Name, func(b *testing.B) {
+			server := &Server{Commands: commands}
+			if benchmark.Compile {
+				if err := server.Compile(); err != nil {
+					b.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				server.matchCommand(nil, "command199 --verbose")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package shell
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestCommandMatcher(t *testing.T) {
+	commands := []Command{
+		{Regex: "^uname$"},
+		{Regex: "^uname -a$"},
+		{Regex: "^docker .*$"},
+		{Regex: "passwd"},
+		{Regex: "^(?i)enable$"},
+		{Regex: "^docker ps$"},
+		{Regex: "^$"},
+	}
+
+	matcher, err := newCommandMatcher(commands)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	tests := []struct {
+		Line     string
+		Expected int
+	}{
+		{Line: "uname", Expected: 0},
+		{Line: "uname -a", Expected: 1},
+		{Line: "uname -r", Expected: -1},
+		{Line: "docker ps", Expected: 2},
+		{Line: "cat /etc/passwd", Expected: 3},
+		{Line: "docker passwd", Expected: 2},
+		{Line: "ENABLE", Expected: 4},
+		{Line: "", Expected: 6},
+		{Line: "unam", Expected: -1},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, matcher.match(test.Line); expected != actual {
+			t.Errorf("For test #%d, expected %d, but actually got %d.", testNumber, expected, actual)
+		}
+	}
+
+	if _, err = NewCommandServer(Command{Regex: "("}); err == nil {
+		t.Errorf("Expected an error for an invalid regex, but did not actually get one.")
+	}
+}
+
+func TestServer_MatchCommand(t *testing.T) {
+	var logs lockedBuffer
+
+	// Without Compile, the invalid regex is only found when it's tried.
+	server := &Server{Commands: []Command{{Regex: "("}, {Regex: "^uname$", Response: "Linux\r\n"}}}
+
+	ts := telnettest.NewUnstartedServer(server.HandlerFunc)
+	ts.Config.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	ts.Start()
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	for testNumber := 0; testNumber < 2; testNumber++ {
+		if expected, actual := "Linux\r\n", enter(t, client, "uname"); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+
+	if actual := strings.Count(logs.String(), "failed to compile a command's regex"); actual != 1 {
+		t.Errorf("Expected the invalid regex to be logged once, but it actually was %d times.", actual)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use, e.g. by a logger and a test.
+type lockedBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.String()
+}
@@ -0,0 +1,118 @@
+package shell
+
+import (
+	"reflect"
+)
+
+// DefaultMessages are the messages shells send, unless Server.Messages overrides them.
+var DefaultMessages = Messages{
+	Welcome:             DefaultWelcomeMessage,
+	Exit:                DefaultExitMessage,
+	CommandNotFound:     "%s" + DefaultCommandNotFound,
+	IgnoredEOF:          "Use \"%s\" to leave the shell.\r\n",
+	InternalError:       "Internal error, closing the connection.\r\n",
+	LoginPrompt:         "Login: ",
+	PasswordPrompt:      "Password: ",
+	CodePrompt:          "Verification code: ",
+	LoginIncorrect:      "\nLogin incorrect\n",
+	MaxAttemptsExceeded: "Maximum number of tries exceeded (%d)\n",
+	LockedOut:           "Too many failed logins, try again later\r\n",
+	LoginTimeout:        "\r\nLogin timed out after %d seconds.\r\n",
+	SudoPrompt:          "[sudo] password for %s: ",
+	SudoRetry:           "Sorry, try again.\r\n",
+	SudoFailed:          "sudo: %d incorrect password attempts\r\n",
+	SuFailed:            "su: Authentication failure\r\n",
+	PasswdDenied:        "passwd: You may not view or modify password information for %s.\r\n",
+	PasswdChanging:      "Changing password for %s.\r\n",
+	PasswdCurrentPrompt: "Current password: ",
+	PasswdNewPrompt:     "New password: ",
+	PasswdRetypePrompt:  "Retype new password: ",
+	PasswdAuthFailed:    "passwd: Authentication token manipulation error\r\npasswd: password unchanged\r\n",
+	PasswdMismatch:      "Sorry, passwords do not match.\r\npasswd: Authentication token manipulation error\r\npasswd: password unchanged\r\n",
+	PasswdUpdated:       "passwd: password updated successfully\r\n",
+}
+
+// Messages is a catalog of the messages a shell sends, so they can be translated (e.g. to imitate a device with
+// Chinese firmware). Messages left empty fall back to DefaultMessages. Some are formatted with fmt.Sprintf, with the
+// arguments described.
+type Messages struct {
+	// Welcome is sent after the client logs in. Server.WelcomeMessage takes precedence, if it's set.
+	Welcome string `json:"welcome" yaml:"welcome"`
+
+	// Exit is sent when the client exits. Server.ExitMessage takes precedence, if it's set.
+	Exit string `json:"exit" yaml:"exit"`
+
+	// CommandNotFound is the response to commands nothing handles, formatted with the command's name. Server's
+	// CommandNotFound takes precedence, if it's set.
+	CommandNotFound string `json:"commandNotFound" yaml:"commandNotFound"`
+
+	// IgnoredEOF is sent when Ctrl-D is ignored (see Server.IgnoreEOF), formatted with the first exit command.
+	IgnoredEOF string `json:"ignoredEOF" yaml:"ignoredEOF"`
+
+	// InternalError is sent before closing the connection when the shell can't be set up (e.g. its FileSystem fails
+	// to load).
+	InternalError string `json:"internalError" yaml:"internalError"`
+
+	// LoginPrompt and PasswordPrompt ask for the credentials of the AuthHandlers from NewAuthHandler and
+	// NewCredentialAuthHandler.
+	LoginPrompt    string `json:"loginPrompt" yaml:"loginPrompt"`
+	PasswordPrompt string `json:"passwordPrompt" yaml:"passwordPrompt"`
+
+	// CodePrompt asks for a verification code, for users with a second factor (see Server.SecondFactor).
+	CodePrompt string `json:"codePrompt" yaml:"codePrompt"`
+
+	// LoginIncorrect is sent after each failed login.
+	LoginIncorrect string `json:"loginIncorrect" yaml:"loginIncorrect"`
+
+	// MaxAttemptsExceeded is sent when the client runs out of login attempts, formatted with how many they had.
+	MaxAttemptsExceeded string `json:"maxAttemptsExceeded" yaml:"maxAttemptsExceeded"`
+
+	// LockedOut is sent to clients disconnected by Server.LoginThrottle.
+	LockedOut string `json:"lockedOut" yaml:"lockedOut"`
+
+	// LoginTimeout is sent to clients disconnected by Server.AuthTimeout, formatted with the timeout in seconds.
+	LoginTimeout string `json:"loginTimeout" yaml:"loginTimeout"`
+
+	// SudoPrompt asks for sudo's password, formatted with the user's name. SudoRetry is sent after a wrong one, and
+	// SudoFailed, formatted with the number of attempts, once they've run out.
+	SudoPrompt string `json:"sudoPrompt" yaml:"sudoPrompt"`
+	SudoRetry  string `json:"sudoRetry" yaml:"sudoRetry"`
+	SudoFailed string `json:"sudoFailed" yaml:"sudoFailed"`
+
+	// SuFailed is sent when su is given the wrong password. It asks for the password with PasswordPrompt.
+	SuFailed string `json:"suFailed" yaml:"suFailed"`
+
+	// PasswdDenied is sent when passwd is asked to change another user's password without privileges, and
+	// PasswdChanging before it asks for the passwords, both formatted with the user's name.
+	PasswdDenied   string `json:"passwdDenied" yaml:"passwdDenied"`
+	PasswdChanging string `json:"passwdChanging" yaml:"passwdChanging"`
+
+	// PasswdCurrentPrompt, PasswdNewPrompt and PasswdRetypePrompt ask passwd for the current password, the new one,
+	// and the new one again.
+	PasswdCurrentPrompt string `json:"passwdCurrentPrompt" yaml:"passwdCurrentPrompt"`
+	PasswdNewPrompt     string `json:"passwdNewPrompt" yaml:"passwdNewPrompt"`
+	PasswdRetypePrompt  string `json:"passwdRetypePrompt" yaml:"passwdRetypePrompt"`
+
+	// PasswdAuthFailed is sent when passwd is given the wrong current password, PasswdMismatch when the new ones
+	// differ, and PasswdUpdated when they match.
+	PasswdAuthFailed string `json:"passwdAuthFailed" yaml:"passwdAuthFailed"`
+	PasswdMismatch   string `json:"passwdMismatch" yaml:"passwdMismatch"`
+	PasswdUpdated    string `json:"passwdUpdated" yaml:"passwdUpdated"`
+}
+
+// withDefaults returns a copy of the messages, with the empty ones filled in from DefaultMessages.
+func (m *Messages) withDefaults() *Messages {
+	merged := DefaultMessages
+	if m == nil {
+		return &merged
+	}
+
+	messages, defaults := reflect.ValueOf(m).Elem(), reflect.ValueOf(&merged).Elem()
+	for i := 0; i < messages.NumField(); i++ {
+		if message := messages.Field(i).String(); message != "" {
+			defaults.Field(i).SetString(message)
+		}
+	}
+
+	return &merged
+}
@@ -0,0 +1,30 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestMessages_WithDefaults(t *testing.T) {
+	messages := (&Messages{CommandNotFound: "-sh: %s: 未找到命令\n", LoginIncorrect: "\n登录错误\n"}).withDefaults()
+
+	tests := []struct {
+		Actual   string
+		Expected string
+	}{
+		{Actual: messages.CommandNotFound, Expected: "-sh: %s: 未找到命令\n"},
+		{Actual: messages.LoginIncorrect, Expected: "\n登录错误\n"},
+		{Actual: messages.LoginPrompt, Expected: DefaultMessages.LoginPrompt},
+		{Actual: messages.Exit, Expected: DefaultExitMessage},
+		{Actual: (*Messages)(nil).withDefaults().Welcome, Expected: DefaultWelcomeMessage},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, test.Actual; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+
+	if DefaultMessages.CommandNotFound != "%s"+DefaultCommandNotFound {
+		t.Errorf("Expected withDefaults to leave DefaultMessages untouched, but actually got %+v.", DefaultMessages)
+	}
+}
@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"net"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// Persona is a device a Server can imitate (e.g. a BusyBox based router, or a Cisco switch). See the personas package
+// for ready-made ones.
+type Persona interface {
+	// Banner is sent as soon as the client connects, before authentication.
+	Banner() string
+
+	// Prompt builds the prompt shown before each command.
+	Prompt(session *telnet.Session) string
+
+	// Auth returns the handler for the device's login, or nil if it doesn't require one.
+	Auth() AuthHandler
+
+	// Commands configures the device's commands on 'server', along with anything else they rely on (such as its
+	// FileSystem and Environment).
+	Commands(server *Server)
+}
+
+// NewServer returns a Server imitating 'persona'. The Server can be customised further before serving, such as to
+// replace its AuthHandler or add Commands.
+func NewServer(persona Persona) *Server {
+	server := &Server{
+		Banner:            persona.Banner(),
+		PromptFunc:        persona.Prompt,
+		AuthHandler:       persona.Auth(),
+		RequestWindowSize: true,
+	}
+
+	persona.Commands(server)
+
+	return server
+}
+
+// PortHandler returns a handler serving each connection with the Server for the local port it was accepted on, so a
+// listener accepting connections on several ports can imitate a different device on each (e.g. a Cisco router on 23
+// and a DVR on 2323). Connections on other ports are served by 'fallback', or closed if it's nil.
+func PortHandler(servers map[int]*Server, fallback *Server) telnet.HandlerFunc {
+	return func(session *telnet.Session) {
+		server := fallback
+
+		if addr, ok := session.LocalAddr().(*net.TCPAddr); ok && servers[addr.Port] != nil {
+			server = servers[addr.Port]
+		}
+
+		if server != nil {
+			server.HandlerFunc(session)
+		}
+	}
+}
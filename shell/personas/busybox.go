@@ -0,0 +1,266 @@
+package personas
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+const (
+	// BusyBoxVersion is the version line BusyBox prints in its usage and shell banner.
+	BusyBoxVersion = "BusyBox v1.22.1 (2014-05-22 19:41:08 CST)"
+
+	// BusyBoxHostname is the hostname of the BusyBox persona's fake device.
+	BusyBoxHostname = "OpenWrt"
+)
+
+// BusyBoxApplets are the applets the BusyBox persona's busybox binary claims to include.
+var BusyBoxApplets = []string{
+	"[", "[[", "arp", "ash", "awk", "basename", "cat", "chmod", "chown", "clear", "cp", "crond", "cut", "date", "dd",
+	"df", "dmesg", "echo", "egrep", "env", "expr", "false", "fgrep", "find", "free", "grep", "gunzip", "gzip", "head",
+	"hostname", "id", "ifconfig", "kill", "killall", "ln", "logger", "ls", "md5sum", "mkdir", "mount", "mv", "nc",
+	"netstat", "nslookup", "passwd", "pidof", "ping", "ps", "pwd", "reboot", "rm", "rmdir", "route", "sed", "sh",
+	"sleep", "sort", "tail", "tar", "tftp", "top", "touch", "tr", "true", "umount", "uname", "uptime", "vi", "wc",
+	"wget", "which", "whoami", "xargs",
+}
+
+// busyBoxNoOps are commands scanners send to escape restricted CLIs into a shell, which BusyBox accepts silently.
+var busyBoxNoOps = []string{"enable", "linuxshell", "sh", "shell", "system"}
+
+// BusyBox is a Persona imitating an embedded Linux router running BusyBox's ash shell, the most common target of
+// telnet scanners. It includes the busybox multi-call binary, common applets, BusyBox's error messages and a fake
+// filesystem with typical /proc contents. It doesn't require a login.
+type BusyBox struct{}
+
+// busyBoxDevice describes a device running BusyBox, for the personas built on it.
+type busyBoxDevice struct {
+	system     shell.SystemProfile
+	elfMachine uint16
+	elfFlags   uint32
+	processes  string
+}
+
+// busyBoxRouter is the device imitated by BusyBox.
+var busyBoxRouter = busyBoxDevice{
+	system: shell.SystemProfile{
+		Hostname:      BusyBoxHostname,
+		KernelRelease: "2.6.36",
+		KernelVersion: "#1 Tue May 5 09:35:47 CST 2015",
+		Machine:       "mips",
+		Uptime:        41*24*time.Hour + 7*time.Hour + 12*time.Minute,
+		CPUInfo:       busyBoxCPUInfo,
+		Interfaces: []shell.NetworkInterface{
+			{Name: "br-lan", MAC: "14:cc:20:1a:2b:3c", Addr: netip.MustParsePrefix("192.168.1.1/24")},
+			{Name: "eth0", MAC: "14:cc:20:1a:2b:3c"},
+			{Name: "eth1", MAC: "14:cc:20:1a:2b:3d", Addr: netip.MustParsePrefix("203.0.113.2/30")},
+			{Name: "lo", Addr: netip.MustParsePrefix("127.0.0.1/8")},
+		},
+	},
+	elfMachine: 8, // MIPS
+	elfFlags:   0x50001007,
+	processes:  busyBoxProcesses,
+}
+
+func (BusyBox) Banner() string {
+	return ""
+}
+
+func (BusyBox) Prompt(session *telnet.Session) string {
+	return "# "
+}
+
+func (BusyBox) Auth() shell.AuthHandler {
+	return nil
+}
+
+func (BusyBox) Commands(server *shell.Server) {
+	busyBoxRouter.configure(server)
+}
+
+// configure sets up 'server' to imitate the device's shell.
+func (d busyBoxDevice) configure(server *shell.Server) {
+	server.WelcomeMessage = "\r\n\r\n" + BusyBoxVersion + " built-in shell (ash)\r\nEnter 'help' for a list of built-in commands.\r\n\r\n"
+	server.ExitMessage = "\r\n"
+	server.LineEditing = true
+	server.FileSystem = d.fileSystem()
+	server.System = &d.system
+	server.Environment = map[string]string{
+		"HOME":  "/root",
+		"PATH":  "/usr/sbin:/usr/bin:/sbin:/bin",
+		"SHELL": "/bin/ash",
+		"TERM":  "vt102",
+		"USER":  "root",
+	}
+	server.Downloader = &shell.Downloader{}
+	server.CommandNotFound = func(name string) string {
+		// Applets without a handler or Command quietly do nothing.
+		if name == "" || isApplet(name) {
+			return ""
+		}
+
+		return "-sh: " + name + ": not found\r\n"
+	}
+
+	server.Handle("busybox", func(session *telnet.Session, args []string) error {
+		if len(args) == 1 {
+			return session.WriteString(busyBoxUsage())
+		}
+
+		if args[1] == "--list" {
+			return session.WriteString(strings.Join(BusyBoxApplets, "\r\n"), "\r\n")
+		}
+
+		if !isApplet(args[1]) {
+			return fmt.Errorf("%s: applet not found", args[1])
+		}
+
+		server.Exec(session, args[1:])
+		return nil
+	})
+
+	server.Handle("id", writeHandler("uid=0(root) gid=0(root)\r\n"))
+	server.Handle("whoami", writeHandler("root\r\n"))
+	server.Handle("ps", writeHandler(d.processes))
+
+	for _, name := range busyBoxNoOps {
+		server.Handle(name, func(session *telnet.Session, args []string) error { return nil })
+	}
+}
+
+// busyBoxProcesses is the output of ps.
+const busyBoxProcesses = "  PID USER       VSZ STAT COMMAND\r\n" +
+	"    1 root      1540 S    init\r\n" +
+	"    2 root         0 SW   [kthreadd]\r\n" +
+	"    3 root         0 SW   [ksoftirqd/0]\r\n" +
+	"  512 root      1536 S    /sbin/syslogd -C16\r\n" +
+	"  540 root      1532 S    /sbin/klogd\r\n" +
+	"  611 root      1068 S    /sbin/ubusd\r\n" +
+	"  789 root      1540 S    /usr/sbin/telnetd -l /bin/login.sh\r\n" +
+	"  801 root      1520 S    /usr/sbin/dropbear -P /var/run/dropbear.1.pid -p 22\r\n" +
+	"  832 root      1544 S    /usr/sbin/crond -c /etc/crontabs -l 5\r\n" +
+	" 1203 root      1548 S    -ash\r\n" +
+	" 1290 root      1544 R    ps\r\n"
+
+// isApplet reports whether 'name' (or the file it names, like "/bin/ls") is one of BusyBoxApplets.
+func isApplet(name string) bool {
+	name = name[strings.LastIndexByte(name, '/')+1:]
+
+	for _, applet := range BusyBoxApplets {
+		if applet == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// busyBoxUsage returns the output of running busybox without arguments.
+func busyBoxUsage() string {
+	var builder strings.Builder
+
+	builder.WriteString(BusyBoxVersion + " multi-call binary.\r\n")
+	builder.WriteString("BusyBox is copyrighted by many authors between 1998-2012.\r\n")
+	builder.WriteString("Licensed under GPLv2. See source distribution for detailed\r\n")
+	builder.WriteString("copyright notices.\r\n\r\n")
+	builder.WriteString("Usage: busybox [function [arguments]...]\r\n")
+	builder.WriteString("   or: busybox --list[-full]\r\n")
+	builder.WriteString("   or: function [arguments]...\r\n\r\n")
+	builder.WriteString("Currently defined functions:\r\n")
+
+	line := "\t"
+	for i, applet := range BusyBoxApplets {
+		if i > 0 {
+			line += ", "
+		}
+
+		if len(line)+len(applet) > 70 {
+			builder.WriteString(strings.TrimRight(line, " ") + "\r\n")
+			line = "\t"
+		}
+
+		line += applet
+	}
+
+	builder.WriteString(line + "\r\n")
+
+	return builder.String()
+}
+
+// fileSystem returns the device's fake filesystem. Its /proc and /etc files describing the device are added from its
+// system profile.
+func (d busyBoxDevice) fileSystem() fs.FS {
+	modTime := time.Date(2015, time.May, 5, 9, 35, 47, 0, time.UTC)
+	dir := &fstest.MapFile{Mode: fs.ModeDir | 0o755, ModTime: modTime}
+	exe := &fstest.MapFile{Data: elfHeader(d.elfMachine, d.elfFlags), Mode: 0o755, ModTime: modTime}
+
+	files := fstest.MapFS{
+		"bin/busybox":   exe,
+		"dev":           dir,
+		"etc/passwd":    {Data: []byte("root:x:0:0:root:/root:/bin/ash\nnobody:*:65534:65534:nobody:/var:/bin/false\n"), Mode: 0o644, ModTime: modTime},
+		"etc/shadow":    {Data: []byte("root:$1$wvQd2sZ3$5lD.p9w8dcbqRCmPCoZwZ/:16560:0:99999:7:::\nnobody:*:0:0:99999:7:::\n"), Mode: 0o600, ModTime: modTime},
+		"proc/meminfo":  {Data: []byte("MemTotal:          61152 kB\nMemFree:           23904 kB\nBuffers:            2288 kB\nCached:            13532 kB\n"), Mode: 0o444, ModTime: modTime},
+		"proc/mounts":   {Data: []byte("rootfs / rootfs rw 0 0\n/dev/root /rom squashfs ro,relatime 0 0\nproc /proc proc rw,noatime 0 0\nsysfs /sys sysfs rw,noatime 0 0\ntmpfs /tmp tmpfs rw,nosuid,nodev,noatime 0 0\n"), Mode: 0o444, ModTime: modTime},
+		"proc/self/exe": exe,
+		"root":          dir,
+		"tmp":           {Mode: fs.ModeDir | 0o777, ModTime: modTime},
+		"var/run":       dir,
+		"var/tmp":       {Mode: fs.ModeDir | 0o777, ModTime: modTime},
+	}
+
+	for _, applet := range BusyBoxApplets {
+		if !strings.ContainsAny(applet, "[") {
+			files["bin/"+applet] = &fstest.MapFile{Data: []byte("busybox"), Mode: fs.ModeSymlink | 0o777, ModTime: modTime}
+		}
+	}
+
+	return files
+}
+
+// busyBoxCPUInfo is the contents of /proc/cpuinfo.
+const busyBoxCPUInfo = `system type		: Atheros AR9330 rev 1
+machine			: TP-LINK TL-WR741ND v4
+processor		: 0
+cpu model		: MIPS 24Kc V7.4
+BogoMIPS		: 265.42
+wait instruction	: yes
+microsecond timers	: yes
+tlb_entries		: 16
+extra interrupt vector	: yes
+hardware watchpoint	: yes, count: 4, address/irw mask: [0x0000, 0x0ff8, 0x0ff8, 0x0ff8]
+ASEs implemented	: mips16
+shadow register sets	: 1
+kscratch registers	: 0
+core			: 0
+VCED exceptions		: not available
+VCEI exceptions		: not available
+`
+
+// elfHeader returns the ELF header of a 32-bit little endian executable for 'machine', which scanners read (e.g. from
+// /proc/self/exe) to pick which build of their malware to download.
+func elfHeader(machine uint16, flags uint32) []byte {
+	header := make([]byte, 52)
+	copy(header, "\x7fELF")
+	header[4] = 1 // 32-bit
+	header[5] = 1 // Little endian
+	header[6] = 1 // ELF version
+
+	binary.LittleEndian.PutUint16(header[16:], 2)          // Executable
+	binary.LittleEndian.PutUint16(header[18:], machine)    // Architecture
+	binary.LittleEndian.PutUint32(header[20:], 1)          // ELF version
+	binary.LittleEndian.PutUint32(header[24:], 0x00404a80) // Entry point
+	binary.LittleEndian.PutUint32(header[28:], 52)         // Program header offset
+	binary.LittleEndian.PutUint32(header[36:], flags)      // Architecture specific flags
+	binary.LittleEndian.PutUint16(header[40:], 52)         // ELF header size
+	binary.LittleEndian.PutUint16(header[42:], 32)         // Program header size
+	binary.LittleEndian.PutUint16(header[44:], 7)          // Program header count
+	binary.LittleEndian.PutUint16(header[46:], 40)         // Section header size
+
+	return header
+}
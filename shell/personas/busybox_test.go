@@ -0,0 +1,77 @@
+package personas
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"io/fs"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go/shell"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestBusyBox(t *testing.T) {
+	server := shell.NewServer(BusyBox{})
+
+	for _, name := range []string{"proc/self/exe", "bin/busybox"} {
+		data, err := fs.ReadFile(server.FileSystem, name)
+		if err != nil {
+			t.Fatalf("For %q, did not expect an error, but actually got one: (%T) %v.", name, err, err)
+		}
+
+		if !bytes.HasPrefix(data, []byte(elf.ELFMAG)) || elf.Class(data[elf.EI_CLASS]) != elf.ELFCLASS32 {
+			t.Fatalf("For %q, expected a 32-bit ELF header, but actually got %q.", name, data)
+		}
+
+		if machine := elf.Machine(binary.LittleEndian.Uint16(data[18:])); machine != elf.EM_MIPS {
+			t.Errorf("For %q, expected a MIPS executable, but actually got %v.", name, machine)
+		}
+	}
+
+	for applet, expected := range map[string]bool{"wget": true, "/bin/busybox": false, "/bin/tftp": true, "MIRAI": false} {
+		if actual := isApplet(applet); expected != actual {
+			t.Errorf("For %q, expected isApplet to be %t, but actually got %t.", applet, expected, actual)
+		}
+	}
+}
+
+func TestBusyBox_UnhandledApplets(t *testing.T) {
+	server := shell.NewServer(BusyBox{})
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	if _, err := client.Expect("# "); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		// Applets without a handler succeed silently, so droppers' command chains carry on.
+		{Input: "cd /tmp && chmod 777 x && mkdir y && echo ok", Expected: "ok\r\n"},
+		{Input: "rm -rf x; echo $?", Expected: "0\r\n"},
+		{Input: "mirai && echo ok; echo $?", Expected: "-sh: mirai: not found\r\n127\r\n"},
+	}
+
+	for testNumber, test := range tests {
+		if err := client.Send(test.Input + "\r\n"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		response, err := client.Expect("# ")
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		// The line editor echoes the command.
+		if expected, actual := test.Input+"\r\n"+test.Expected+"# ", response; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package personas
+
+import (
+	"strconv"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+const (
+	// CiscoIOSHostname is the hostname of the Cisco IOS persona's fake router.
+	CiscoIOSHostname = "Router"
+
+	// iosInvalidInput is IOS's response to commands it doesn't recognise, with the caret under the start of the
+	// command (after the 7 character prompt).
+	iosInvalidInput = "       ^\r\n% Invalid input detected at '^' marker.\r\n\r\n"
+)
+
+type (
+	// iosSession is the state of a client's IOS session.
+	iosSession struct {
+		privileged bool
+
+		// terminalLength is the number of lines per page set with "terminal length", where 0 disables paging, or -1 to
+		// follow the client's window height.
+		terminalLength int
+	}
+
+	// iosSessionKey is the key a session's *iosSession is stored under.
+	iosSessionKey struct{}
+
+	// CiscoIOS is a Persona imitating a Cisco router's IOS command line: a "User Access Verification" login, user (>)
+	// and privileged (#) exec modes switched with enable and disable, abbreviated commands (e.g. "sh run"), canned
+	// show version and show running-config output, and pagination with --More--. Any credentials are accepted.
+	CiscoIOS struct{}
+)
+
+func (CiscoIOS) Banner() string {
+	return "\r\n\r\nUser Access Verification\r\n\r\n"
+}
+
+func (CiscoIOS) Prompt(session *telnet.Session) string {
+	if getIOSSession(session).privileged {
+		return CiscoIOSHostname + "#"
+	}
+
+	return CiscoIOSHostname + ">"
+}
+
+func (CiscoIOS) Auth() shell.AuthHandler {
+	return anyLogin("Username: ", "Password: ", "% Login invalid\r\n\r\n")
+}
+
+func (CiscoIOS) Commands(server *shell.Server) {
+	server.WelcomeMessage = "\r\n"
+	server.ExitMessage = "\r\n"
+	server.LineEditing = true
+	server.GenericHandler = func(command string) string {
+		return iosInvalidInput
+	}
+
+	handleAbbreviated(server, "enable", 2, func(session *telnet.Session, args []string) error {
+		state := getIOSSession(session)
+		if state.privileged {
+			return nil
+		}
+
+		if _, err := session.EditLine("Password: ", &telnet.LineEditor{Secret: true}); err != nil {
+			return err
+		}
+
+		state.privileged = true
+		return nil
+	})
+
+	handleAbbreviated(server, "disable", 4, func(session *telnet.Session, args []string) error {
+		getIOSSession(session).privileged = false
+		return nil
+	})
+
+	for _, name := range []string{"logout", "quit"} {
+		handleAbbreviated(server, name, 2, func(session *telnet.Session, args []string) error {
+			return shell.ErrExit
+		})
+	}
+
+	handleAbbreviated(server, "terminal", 3, func(session *telnet.Session, args []string) error {
+		if len(args) != 3 || !abbreviates(args[1], "length", 3) {
+			return session.WriteString(iosInvalidInput)
+		}
+
+		length, err := strconv.Atoi(args[2])
+		if err != nil || length < 0 || length > 512 {
+			return session.WriteString(iosInvalidInput)
+		}
+
+		getIOSSession(session).terminalLength = length
+		return nil
+	})
+
+	handleAbbreviated(server, "show", 2, func(session *telnet.Session, args []string) error {
+		state := getIOSSession(session)
+
+		var output string
+
+		switch {
+		case len(args) == 2 && abbreviates(args[1], "version", 3):
+			output = iosVersion
+		case len(args) == 2 && abbreviates(args[1], "clock", 2):
+			output = "*09:35:47.123 UTC Tue May 5 2015\r\n"
+		case len(args) == 4 && abbreviates(args[1], "ip", 2) && abbreviates(args[2], "interface", 3) && abbreviates(args[3], "brief", 2):
+			output = iosInterfaces
+		case len(args) == 2 && state.privileged && abbreviates(args[1], "running-config", 3):
+			output = iosRunningConfig
+		default:
+			output = iosInvalidInput
+		}
+
+		pager := &telnet.Pager{Height: state.terminalLength, Prompt: " --More-- "}
+
+		switch state.terminalLength {
+		case -1:
+			pager.Height = 0
+		case 0:
+			pager.Height = -1
+		}
+
+		return session.Page(output, pager)
+	})
+}
+
+// getIOSSession returns the IOS state of 'session', creating it if needed.
+func getIOSSession(session *telnet.Session) *iosSession {
+	return telnet.ValueOrSet(session, iosSessionKey{}, func() *iosSession {
+		return &iosSession{terminalLength: -1}
+	})
+}
+
+// iosVersion is the output of show version.
+const iosVersion = "Cisco IOS Software, C2900 Software (C2900-UNIVERSALK9-M), Version 15.1(4)M4, RELEASE SOFTWARE (fc1)\r\n" +
+	"Technical Support: http://www.cisco.com/techsupport\r\n" +
+	"Copyright (c) 1986-2012 by Cisco Systems, Inc.\r\n" +
+	"Compiled Tue 20-Mar-12 18:57 by prod_rel_team\r\n" +
+	"\r\n" +
+	"ROM: System Bootstrap, Version 15.0(1r)M15, RELEASE SOFTWARE (fc1)\r\n" +
+	"\r\n" +
+	CiscoIOSHostname + " uptime is 41 weeks, 3 days, 7 hours, 12 minutes\r\n" +
+	"System returned to ROM by power-on\r\n" +
+	"System restarted at 02:23:11 UTC Tue Jul 22 2014\r\n" +
+	"System image file is \"flash0:c2900-universalk9-mz.SPA.151-4.M4.bin\"\r\n" +
+	"Last reload type: Normal Reload\r\n" +
+	"\r\n" +
+	"This product contains cryptographic features and is subject to United\r\n" +
+	"States and local country laws governing import, export, transfer and\r\n" +
+	"use. Delivery of Cisco cryptographic products does not imply\r\n" +
+	"third-party authority to import, export, distribute or use encryption.\r\n" +
+	"Importers, exporters, distributors and users are responsible for\r\n" +
+	"compliance with U.S. and local country laws. By using this product you\r\n" +
+	"agree to comply with applicable laws and regulations. If you are unable\r\n" +
+	"to comply with U.S. and local laws, return this product immediately.\r\n" +
+	"\r\n" +
+	"Cisco CISCO2911/K9 (revision 1.0) with 487424K/36864K bytes of memory.\r\n" +
+	"Processor board ID FTX1628838P\r\n" +
+	"3 Gigabit Ethernet interfaces\r\n" +
+	"1 terminal line\r\n" +
+	"DRAM configuration is 64 bits wide with parity enabled.\r\n" +
+	"255K bytes of non-volatile configuration memory.\r\n" +
+	"250880K bytes of ATA System CompactFlash 0 (Read/Write)\r\n" +
+	"\r\n" +
+	"Configuration register is 0x2102\r\n" +
+	"\r\n"
+
+// iosInterfaces is the output of show ip interface brief.
+const iosInterfaces = "Interface                  IP-Address      OK? Method Status                Protocol\r\n" +
+	"Embedded-Service-Engine0/0 unassigned      YES NVRAM  administratively down down\r\n" +
+	"GigabitEthernet0/0         203.0.113.2     YES NVRAM  up                    up\r\n" +
+	"GigabitEthernet0/1         192.168.1.1     YES NVRAM  up                    up\r\n" +
+	"GigabitEthernet0/2         unassigned      YES NVRAM  administratively down down\r\n"
+
+// iosRunningConfig is the output of show running-config.
+const iosRunningConfig = "Building configuration...\r\n" +
+	"\r\n" +
+	"Current configuration : 1642 bytes\r\n" +
+	"!\r\n" +
+	"version 15.1\r\n" +
+	"service timestamps debug datetime msec\r\n" +
+	"service timestamps log datetime msec\r\n" +
+	"no service password-encryption\r\n" +
+	"!\r\n" +
+	"hostname " + CiscoIOSHostname + "\r\n" +
+	"!\r\n" +
+	"boot-start-marker\r\n" +
+	"boot-end-marker\r\n" +
+	"!\r\n" +
+	"enable secret 5 $1$mERr$hx5rVt7rPNoS4wqbXKX7m0\r\n" +
+	"!\r\n" +
+	"no aaa new-model\r\n" +
+	"!\r\n" +
+	"ip cef\r\n" +
+	"no ipv6 cef\r\n" +
+	"!\r\n" +
+	"username admin privilege 15 password 0 cisco\r\n" +
+	"!\r\n" +
+	"interface GigabitEthernet0/0\r\n" +
+	" description WAN\r\n" +
+	" ip address 203.0.113.2 255.255.255.252\r\n" +
+	" ip nat outside\r\n" +
+	" duplex auto\r\n" +
+	" speed auto\r\n" +
+	"!\r\n" +
+	"interface GigabitEthernet0/1\r\n" +
+	" description LAN\r\n" +
+	" ip address 192.168.1.1 255.255.255.0\r\n" +
+	" ip nat inside\r\n" +
+	" duplex auto\r\n" +
+	" speed auto\r\n" +
+	"!\r\n" +
+	"interface GigabitEthernet0/2\r\n" +
+	" no ip address\r\n" +
+	" shutdown\r\n" +
+	"!\r\n" +
+	"ip forward-protocol nd\r\n" +
+	"no ip http server\r\n" +
+	"!\r\n" +
+	"ip nat inside source list 1 interface GigabitEthernet0/0 overload\r\n" +
+	"ip route 0.0.0.0 0.0.0.0 203.0.113.1\r\n" +
+	"!\r\n" +
+	"access-list 1 permit 192.168.1.0 0.0.0.255\r\n" +
+	"!\r\n" +
+	"snmp-server community public RO\r\n" +
+	"!\r\n" +
+	"line con 0\r\n" +
+	"line aux 0\r\n" +
+	"line vty 0 4\r\n" +
+	" login local\r\n" +
+	" transport input telnet ssh\r\n" +
+	"!\r\n" +
+	"end\r\n" +
+	"\r\n"
@@ -0,0 +1,26 @@
+package personas
+
+import (
+	"testing"
+)
+
+func TestAbbreviates(t *testing.T) {
+	tests := []struct {
+		Word     string
+		Keyword  string
+		Minimum  int
+		Expected bool
+	}{
+		{Word: "run", Keyword: "running-config", Minimum: 3, Expected: true},
+		{Word: "RUNNING-CONFIG", Keyword: "running-config", Minimum: 3, Expected: true},
+		{Word: "ru", Keyword: "running-config", Minimum: 3, Expected: false},
+		{Word: "runx", Keyword: "running-config", Minimum: 3, Expected: false},
+		{Word: "versions", Keyword: "version", Minimum: 3, Expected: false},
+	}
+
+	for testNumber, test := range tests {
+		if actual := abbreviates(test.Word, test.Keyword, test.Minimum); test.Expected != actual {
+			t.Errorf("For test #%d, expected %t, but actually got %t.", testNumber, test.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package personas
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+// DVRHostname is the hostname of the DVR persona's fake device. Many DVRs never set one.
+const DVRHostname = "(none)"
+
+// DVR is a Persona imitating a generic HiSilicon based DVR or IP camera: a BusyBox shell on ARM behind a bare
+// "(none) login:" prompt, the kind of device most Mirai variants were built to infect. Any credentials are accepted.
+type DVR struct{}
+
+// dvrDevice is the device imitated by DVR.
+var dvrDevice = busyBoxDevice{
+	system: shell.SystemProfile{
+		Hostname:      DVRHostname,
+		KernelRelease: "3.0.8",
+		KernelVersion: "#1 Wed Nov 19 16:53:17 CST 2014",
+		Machine:       "armv7l",
+		Uptime:        12*24*time.Hour + 3*time.Hour + 41*time.Minute,
+		CPUInfo:       dvrCPUInfo,
+		Interfaces: []shell.NetworkInterface{
+			{Name: "eth0", MAC: "00:12:31:5c:8e:07", Addr: netip.MustParsePrefix("192.168.1.10/24")},
+			{Name: "lo", Addr: netip.MustParsePrefix("127.0.0.1/8")},
+		},
+	},
+	elfMachine: 40,         // ARM
+	elfFlags:   0x05000002, // EABI5
+	processes:  dvrProcesses,
+}
+
+func (DVR) Banner() string {
+	return "\r\n"
+}
+
+func (DVR) Prompt(session *telnet.Session) string {
+	return "# "
+}
+
+func (DVR) Auth() shell.AuthHandler {
+	return anyLogin(DVRHostname+" login: ", "Password: ", "Login incorrect\r\n")
+}
+
+func (DVR) Commands(server *shell.Server) {
+	dvrDevice.configure(server)
+}
+
+// dvrCPUInfo is the contents of /proc/cpuinfo.
+const dvrCPUInfo = `Processor	: ARMv7 Processor rev 1 (v7l)
+BogoMIPS	: 1196.85
+Features	: swp half thumb fastmult vfp edsp neon vfpv3 tls
+CPU implementer	: 0x41
+CPU architecture: 7
+CPU variant	: 0x2
+CPU part	: 0xc09
+CPU revision	: 1
+
+Hardware	: hi3520d
+Revision	: 0000
+Serial		: 0000000000000000
+`
+
+// dvrProcesses is the output of ps.
+const dvrProcesses = "  PID USER       VSZ STAT COMMAND\r\n" +
+	"    1 root      1428 S    init\r\n" +
+	"    2 root         0 SW   [kthreadd]\r\n" +
+	"    3 root         0 SW   [ksoftirqd/0]\r\n" +
+	"  398 root      1432 S    telnetd\r\n" +
+	"  421 root      1428 S    /sbin/watchdog -t 10 /dev/watchdog\r\n" +
+	"  463 root     98304 S    ./Sofia\r\n" +
+	"  502 root      1436 S    -sh\r\n" +
+	"  577 root      1428 R    ps\r\n"
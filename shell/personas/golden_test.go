@@ -0,0 +1,25 @@
+package personas
+
+import (
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go/shell"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestDVRTranscript(t *testing.T) {
+	server := shell.NewServer(DVR{})
+
+	telnettest.Golden(t, "testdata/dvr.golden", server.HandlerFunc,
+		telnettest.Expect("login: "),
+		telnettest.Send("root\r\n"),
+		telnettest.Expect("Password: "),
+		telnettest.Send("xc3511\r\n"),
+		telnettest.Expect("# "),
+		telnettest.Send("uname -a\r\n"),
+		telnettest.Expect("# "),
+		telnettest.Send("cat /etc/hostname\r\n"),
+		telnettest.Expect("# "),
+		telnettest.Send("exit\r\n"),
+	)
+}
@@ -0,0 +1,145 @@
+package personas
+
+import (
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+const (
+	// HuaweiSysname is the system name of the Huawei persona's fake router.
+	HuaweiSysname = "Huawei"
+
+	// huaweiUnrecognized is VRP's response to commands it doesn't recognise, with the caret under the start of the
+	// command (after the 8 character prompt).
+	huaweiUnrecognized = "        ^\r\nError: Unrecognized command found at '^' position.\r\n"
+)
+
+// Huawei is a Persona imitating a Huawei router's VRP command line: its login, user view prompt, abbreviated display
+// commands (e.g. "dis ver") and error messages. Any credentials are accepted.
+type Huawei struct{}
+
+func (Huawei) Banner() string {
+	return "\r\n\r\nLogin authentication\r\n\r\n\r\n"
+}
+
+func (Huawei) Prompt(session *telnet.Session) string {
+	return "<" + HuaweiSysname + ">"
+}
+
+func (Huawei) Auth() shell.AuthHandler {
+	return anyLogin("Username:", "Password:", "Error: Local authentication is rejected.\r\n\r\n")
+}
+
+func (Huawei) Commands(server *shell.Server) {
+	server.WelcomeMessage = "\r\nInfo: The max number of VTY users is 5, and the number\r\n" +
+		"      of current VTY users on line is 1.\r\n" +
+		"      The current login time is 2015-05-05 09:35:47.\r\n"
+	server.ExitMessage = "\r\n  Info: The connection was closed by the remote host.\r\n"
+	server.LineEditing = true
+	server.GenericHandler = func(command string) string {
+		return huaweiUnrecognized
+	}
+
+	handleAbbreviated(server, "quit", 1, func(session *telnet.Session, args []string) error {
+		return shell.ErrExit
+	})
+
+	handleAbbreviated(server, "display", 3, func(session *telnet.Session, args []string) error {
+		var output string
+
+		switch {
+		case len(args) == 2 && abbreviates(args[1], "version", 3):
+			output = huaweiVersion
+		case len(args) == 2 && abbreviates(args[1], "current-configuration", 2):
+			output = huaweiConfiguration
+		case len(args) == 4 && abbreviates(args[1], "ip", 2) && abbreviates(args[2], "interface", 3) && abbreviates(args[3], "brief", 2):
+			output = huaweiInterfaces
+		default:
+			output = huaweiUnrecognized
+		}
+
+		return session.Page(output, &telnet.Pager{Prompt: "  ---- More ----"})
+	})
+}
+
+// huaweiVersion is the output of display version.
+const huaweiVersion = "Huawei Versatile Routing Platform Software\r\n" +
+	"VRP (R) software, Version 5.160 (AR2200 V200R009C00SPC500)\r\n" +
+	"Copyright (C) 2011-2018 HUAWEI TECH CO., LTD\r\n" +
+	"Huawei AR2220 Router uptime is 287 days, 7 hours, 12 minutes \r\n" +
+	"BKP 0 version information: \r\n" +
+	"1. PCB      Version  : AR01BAK2A VER.NC\r\n" +
+	"2. If Supporting PoE : No\r\n" +
+	"3. Board    Type     : AR2220\r\n" +
+	"4. MPU Slot Quantity : 1\r\n" +
+	"5. LPU Slot Quantity : 6\r\n" +
+	"\r\n" +
+	"MPU 0(Master) : uptime is 287 days, 7 hours, 11 minutes\r\n" +
+	"SDRAM Memory Size    : 2048     M bytes\r\n" +
+	"Flash 0 Memory Size  : 512      M bytes\r\n" +
+	"MPU version information : \r\n" +
+	"1. PCB      Version  : AR01SRU2A VER.A\r\n" +
+	"2. MAB      Version  : 0\r\n" +
+	"3. Board    Type     : AR2220\r\n" +
+	"4. CPLD0    Version  : 0\r\n" +
+	"5. BootROM  Version  : 0\r\n"
+
+// huaweiInterfaces is the output of display ip interface brief.
+const huaweiInterfaces = "*down: administratively down\r\n" +
+	"^down: standby\r\n" +
+	"(l): loopback\r\n" +
+	"(s): spoofing\r\n" +
+	"The number of interface that is UP in Physical is 3\r\n" +
+	"The number of interface that is DOWN in Physical is 1\r\n" +
+	"The number of interface that is UP in Protocol is 3\r\n" +
+	"The number of interface that is DOWN in Protocol is 1\r\n" +
+	"\r\n" +
+	"Interface                         IP Address/Mask      Physical   Protocol  \r\n" +
+	"GigabitEthernet0/0/0              203.0.113.6/30       up         up        \r\n" +
+	"GigabitEthernet0/0/1              192.168.1.1/24       up         up        \r\n" +
+	"GigabitEthernet0/0/2              unassigned           *down      down      \r\n" +
+	"NULL0                             unassigned           up         up(s)     \r\n"
+
+// huaweiConfiguration is the output of display current-configuration.
+const huaweiConfiguration = "[V200R009C00SPC500]\r\n" +
+	"#\r\n" +
+	" sysname " + HuaweiSysname + "\r\n" +
+	"#\r\n" +
+	" snmp-agent local-engineid 800007DB03D4B110A5A1B2\r\n" +
+	"#\r\n" +
+	" clock timezone UTC add 00:00:00\r\n" +
+	"#\r\n" +
+	"aaa \r\n" +
+	" authentication-scheme default\r\n" +
+	" authorization-scheme default\r\n" +
+	" accounting-scheme default\r\n" +
+	" domain default \r\n" +
+	" domain default_admin \r\n" +
+	" local-user admin password irreversible-cipher %^%#Fk1xC}VRu1]n6uFx&S3L`~p)<8`BQ5E0*1XZ7<:B%^%#\r\n" +
+	" local-user admin privilege level 15\r\n" +
+	" local-user admin service-type telnet http\r\n" +
+	"#\r\n" +
+	"interface GigabitEthernet0/0/0\r\n" +
+	" ip address 203.0.113.6 255.255.255.252 \r\n" +
+	" nat outbound 2000\r\n" +
+	"#\r\n" +
+	"interface GigabitEthernet0/0/1\r\n" +
+	" ip address 192.168.1.1 255.255.255.0 \r\n" +
+	"#\r\n" +
+	"interface GigabitEthernet0/0/2\r\n" +
+	" shutdown\r\n" +
+	"#\r\n" +
+	"interface NULL0\r\n" +
+	"#\r\n" +
+	" telnet server enable\r\n" +
+	"#\r\n" +
+	"ip route-static 0.0.0.0 0.0.0.0 203.0.113.5\r\n" +
+	"#\r\n" +
+	"user-interface con 0\r\n" +
+	" authentication-mode aaa\r\n" +
+	"user-interface vty 0 4\r\n" +
+	" authentication-mode aaa\r\n" +
+	" user privilege level 15\r\n" +
+	" protocol inbound telnet\r\n" +
+	"#\r\n" +
+	"return\r\n"
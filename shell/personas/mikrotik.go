@@ -0,0 +1,107 @@
+package personas
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+// MikroTikIdentity is the system identity of the MikroTik persona's fake router.
+const MikroTikIdentity = "MikroTik"
+
+// MikroTik is a Persona imitating a MikroTik router's RouterOS console: its login, logo, menu style commands (e.g.
+// "/system resource print", with or without the leading slash) and error messages. Any credentials are accepted.
+type MikroTik struct{}
+
+func (MikroTik) Banner() string {
+	return ""
+}
+
+func (MikroTik) Prompt(session *telnet.Session) string {
+	return "[admin@" + MikroTikIdentity + "] > "
+}
+
+func (MikroTik) Auth() shell.AuthHandler {
+	return anyLogin("Login: ", "Password: ", "\r\nLogin failed, incorrect username or password\r\n\r\n")
+}
+
+func (MikroTik) Commands(server *shell.Server) {
+	server.WelcomeMessage = mikroTikLogo
+	server.ExitMessage = "\r\ninterrupted\r\n"
+	server.LineEditing = true
+	server.GenericHandler = func(command string) string {
+		name, _, _ := strings.Cut(strings.TrimPrefix(command, "/"), " ")
+		return "bad command name " + name + " (line 1 column 1)\r\n"
+	}
+
+	server.Handle("quit", func(session *telnet.Session, args []string) error {
+		return shell.ErrExit
+	})
+
+	for _, menu := range []string{"interface", "ip", "system", "user"} {
+		server.Handle(menu, func(session *telnet.Session, args []string) error {
+			command := strings.TrimPrefix(strings.Join(args, " "), "/")
+
+			output, ok := mikroTikOutputs[command]
+			if !ok && len(args) > 1 {
+				output = "bad command name " + args[1] + " (line 1 column " + strconv.Itoa(len(args[0])+2) + ")\r\n"
+			}
+
+			return session.WriteString(output)
+		})
+	}
+}
+
+// mikroTikLogo is shown after logging in.
+const mikroTikLogo = "\r\n\r\n\r\n\r\n" +
+	"  MMM      MMM       KKK                          TTTTTTTTTTT      KKK\r\n" +
+	"  MMMM    MMMM       KKK                          TTTTTTTTTTT      KKK\r\n" +
+	"  MMM MMMM MMM  III  KKK  KKK  RRRRRR     OOOOOO      TTT     III  KKK  KKK\r\n" +
+	"  MMM  MM  MMM  III  KKKKK     RRR  RRR  OOO  OOO     TTT     III  KKKKK\r\n" +
+	"  MMM      MMM  III  KKK KKK   RRRRRR    OOO  OOO     TTT     III  KKK KKK\r\n" +
+	"  MMM      MMM  III  KKK  KKK  RRR  RRR   OOOOOO      TTT     III  KKK  KKK\r\n" +
+	"\r\n" +
+	"  MikroTik RouterOS 6.48.6 (c) 1999-2021       https://www.mikrotik.com/\r\n" +
+	"\r\n" +
+	"Press F1 for help\r\n" +
+	"\r\n"
+
+// mikroTikOutputs holds the output of each supported command, without its leading slash.
+var mikroTikOutputs = map[string]string{
+	"interface print": "Flags: D - dynamic, X - disabled, R - running, S - slave \r\n" +
+		" #     NAME                                TYPE       ACTUAL-MTU L2MTU  MAX-L2MTU\r\n" +
+		" 0  R  ether1                              ether            1500  1598       2028\r\n" +
+		" 1  RS ether2                              ether            1500  1598       2028\r\n" +
+		" 2   S ether3                              ether            1500  1598       2028\r\n" +
+		" 3  R  wlan1                               wlan             1500  1600       2290\r\n" +
+		" 4  R  bridge                              bridge           1500  1598\r\n",
+	"ip address print": "Flags: X - disabled, I - invalid, D - dynamic \r\n" +
+		" #   ADDRESS            NETWORK         INTERFACE\r\n" +
+		" 0   192.168.88.1/24    192.168.88.0    bridge\r\n" +
+		" 1 D 198.51.100.23/24   198.51.100.0    ether1\r\n",
+	"system identity print": "  name: " + MikroTikIdentity + "\r\n",
+	"system resource print": "                   uptime: 3w2d4h12m17s\r\n" +
+		"                  version: 6.48.6 (long-term)\r\n" +
+		"               build-time: Dec/22/2021 11:20:47\r\n" +
+		"         factory-software: 6.44.6\r\n" +
+		"              free-memory: 38.9MiB\r\n" +
+		"             total-memory: 64.0MiB\r\n" +
+		"                      cpu: MIPS 24Kc V7.4\r\n" +
+		"                cpu-count: 1\r\n" +
+		"            cpu-frequency: 650MHz\r\n" +
+		"                 cpu-load: 2%\r\n" +
+		"           free-hdd-space: 3.9MiB\r\n" +
+		"          total-hdd-space: 16.0MiB\r\n" +
+		"  write-sect-since-reboot: 1021\r\n" +
+		"         write-sect-total: 53421\r\n" +
+		"               bad-blocks: 0%\r\n" +
+		"        architecture-name: mipsbe\r\n" +
+		"               board-name: hAP lite\r\n" +
+		"                 platform: MikroTik\r\n",
+	"user print": "Flags: X - disabled \r\n" +
+		" #   NAME                    GROUP                    ADDRESS            LAST-LOGGED-IN      \r\n" +
+		" 0   ;;; system default user\r\n" +
+		"     admin                   full                                        may/05/2015 09:35:47\r\n",
+}
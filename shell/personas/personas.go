@@ -0,0 +1,62 @@
+// Package personas provides ready-made shell.Persona implementations imitating common devices, so honeypots can
+// present a convincing target without hand-writing every command (see shell.NewServer).
+package personas
+
+import (
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+// anyLogin returns an AuthHandler prompting for a username and password like a device would, and accepting any
+// non-empty username, so clients reach the shell whatever credentials they guess. 'failure' is written after an empty
+// username.
+func anyLogin(usernamePrompt string, passwordPrompt string, failure string) shell.AuthHandler {
+	return func(session *telnet.Session) bool {
+		for attempts := 0; attempts < shell.DefaultMaxAttempts; attempts++ {
+			username, err := session.EditLine(usernamePrompt, nil)
+			if err != nil {
+				return false
+			}
+
+			password, err := session.EditLine(passwordPrompt, &telnet.LineEditor{Secret: true})
+			if err != nil {
+				return false
+			}
+
+			success := strings.TrimSpace(username) != ""
+			shell.RecordLogin(session, username, password, success)
+
+			if success {
+				return true
+			}
+
+			if err = session.WriteString(failure); err != nil {
+				return false
+			}
+		}
+
+		return false
+	}
+}
+
+// writeHandler returns a CommandFunc that writes 'output'.
+func writeHandler(output string) shell.CommandFunc {
+	return func(session *telnet.Session, args []string) error {
+		return session.WriteString(output)
+	}
+}
+
+// handleAbbreviated registers 'fn' for the command 'name', and each abbreviation of it at least 'minimum' characters
+// long, as IOS accepts any unambiguous abbreviation.
+func handleAbbreviated(server *shell.Server, name string, minimum int, fn shell.CommandFunc) {
+	for i := minimum; i <= len(name); i++ {
+		server.Handle(name[:i], fn)
+	}
+}
+
+// abbreviates reports whether 'word' is an abbreviation of 'keyword' at least 'minimum' characters long.
+func abbreviates(word string, keyword string, minimum int) bool {
+	return len(word) >= minimum && strings.HasPrefix(keyword, strings.ToLower(word))
+}
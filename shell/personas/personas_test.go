@@ -0,0 +1,32 @@
+package personas
+
+import (
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go/shell"
+)
+
+func TestPersonas(t *testing.T) {
+	tests := []struct {
+		Persona      shell.Persona
+		ExpectedAuth bool
+	}{
+		{Persona: BusyBox{}, ExpectedAuth: false},
+		{Persona: CiscoIOS{}, ExpectedAuth: true},
+		{Persona: DVR{}, ExpectedAuth: true},
+		{Persona: Huawei{}, ExpectedAuth: true},
+		{Persona: MikroTik{}, ExpectedAuth: true},
+	}
+
+	for testNumber, test := range tests {
+		server := shell.NewServer(test.Persona)
+
+		if expected, actual := test.ExpectedAuth, server.AuthHandler != nil; expected != actual {
+			t.Errorf("For test #%d, expected an AuthHandler to be %t, but actually got %t.", testNumber, expected, actual)
+		}
+
+		if server.PromptFunc == nil || !server.LineEditing {
+			t.Errorf("For test #%d, expected a PromptFunc and line editing, but actually got neither.", testNumber)
+		}
+	}
+}
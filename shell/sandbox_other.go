@@ -0,0 +1,17 @@
+//go:build !linux
+
+package shell
+
+import (
+	"os/exec"
+)
+
+// restrict applies the sandbox's restrictions to 'cmd'. Only Linux can isolate commands from the network, or run them
+// as another user.
+func (s *Sandbox) restrict(cmd *exec.Cmd) error {
+	if !s.AllowNetwork || s.UID != 0 || s.GID != 0 {
+		return ErrSandboxUnsupported
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package shell
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Sandboxes are only fully supported on Linux.")
+	}
+
+	sandbox := &Sandbox{
+		Commands: map[string]string{
+			"cat":   "/bin/cat",
+			"false": "/bin/false",
+			"say":   "/bin/echo",
+			"sleep": "/bin/sleep",
+		},
+		Timeout:   500 * time.Millisecond,
+		MaxOutput: 8,
+	}
+
+	// Commands won't run as root.
+	if os.Geteuid() == 0 {
+		if expected, actual := "say: "+ErrSandboxRoot.Error()+"\r\n", runSandboxed(t, &Server{Sandbox: sandbox}, "say hi"); expected != actual {
+			t.Errorf("Expected %q, but actually got %q.", expected, actual)
+		}
+
+		// Nor in root's group.
+		sandbox.UID = 65534
+
+		if expected, actual := "say: "+ErrSandboxRoot.Error()+"\r\n", runSandboxed(t, &Server{Sandbox: sandbox}, "say hi"); expected != actual {
+			t.Errorf("Expected %q, but actually got %q.", expected, actual)
+		}
+
+		sandbox.GID = 65534
+	}
+
+	ts := telnettest.NewServer((&Server{Environment: map[string]string{}, Sandbox: sandbox}).HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "say hi", Expected: "hi\r\n"},
+		{Input: "false; echo $?", Expected: "1\r\n"},
+		{Input: "say 123456789", Expected: "12345678say: " + ErrOutputLimit.Error() + "\r\n"},
+		{Input: "sleep 5", Expected: "sleep: timed out after 500ms\r\n"},
+	}
+
+	for testNumber, test := range tests {
+		if err := client.Send(test.Input + "\r\n"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected, actual := test.Expected+DefaultPrompt, expectOutput(t, client, DefaultPrompt); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+
+	// The client's input is relayed to the command, until they press Ctrl-C.
+	if err := client.Send("cat\r\nabc\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expectOutput(t, client, "abc\r\n")
+
+	if err := client.Send("\x03"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := DefaultPrompt, expectOutput(t, client, DefaultPrompt); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	// Without AllowNetwork, only the loopback interface is visible.
+	isolated := &Sandbox{Commands: map[string]string{"cat": "/bin/cat"}, UID: sandbox.UID, GID: sandbox.GID}
+
+	response := runSandboxed(t, &Server{Sandbox: isolated}, "cat /proc/net/dev")
+	if strings.Contains(response, "operation not permitted") {
+		t.Skip("Network namespaces aren't permitted here.")
+	}
+
+	if lines := strings.Split(strings.TrimSpace(response), "\r\n"); len(lines) != 3 || !strings.Contains(lines[2], "lo:") {
+		t.Errorf("Expected only the loopback interface, but actually got %q.", response)
+	}
+}
+
+// runSandboxed serves 'server' to a client entering 'command', returning the output up to the next prompt.
+func runSandboxed(t *testing.T, server *Server, command string) string {
+	t.Helper()
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	if err := client.Send(command + "\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	return strings.TrimSuffix(expectOutput(t, client, DefaultPrompt), DefaultPrompt)
+}
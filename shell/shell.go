@@ -1,9 +1,18 @@
 package shell
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"path"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/globalcyberalliance/telnet-go"
 )
@@ -11,11 +20,24 @@ import (
 const (
 	DefaultCommandNotFound = ": command not found\n"
 	DefaultExitCommand     = "exit"
+	DefaultIgnoredEOFs     = 10
 	DefaultExitMessage     = "Goodbye!\r\n"
 	DefaultPrompt          = "$ "
 	DefaultWelcomeMessage  = "\r\nWelcome!\r\n"
 )
 
+// ErrExit is returned by a CommandFunc to end the session, like the exit builtin (e.g. for a device's own logout
+// command).
+var ErrExit = errors.New("exit")
+
+// ExitError is returned by a CommandFunc to set its exit status (from 0 to 255) without writing an error message, e.g.
+// when relaying a real command's exit status.
+type ExitError int
+
+func (e ExitError) Error() string {
+	return "exit status " + strconv.Itoa(int(e))
+}
+
 type (
 	Command struct {
 		Regex    string
@@ -24,83 +46,746 @@ type (
 
 	Handler func(command string) string
 
+	// CommandFunc handles a command registered with Server.Handle. 'args' holds the command line split into
+	// arguments, including the command name itself as args[0]. Returning ErrExit ends the session, like the exit
+	// builtin.
+	CommandFunc func(session *telnet.Session, args []string) error
+
 	Server struct {
 		// AuthHandler handles authentication attempts against the server.
 		AuthHandler AuthHandler
 
+		// OnLogin, if set, is called with every attempt to log in (see RecordLogin), e.g. to log the credentials tried.
+		OnLogin func(session *telnet.Session, attempt LoginAttempt)
+
+		// Canaries, if set, holds Canary users whose credentials are checked against every login attempt, whatever the
+		// AuthHandler, so their use is flagged (see LoginAttempt.Canary) even if the login is rejected. It may be the
+		// AuthHandler's own CredentialStore.
+		Canaries CredentialStore
+
+		// SecondFactor, if set, asks users enrolled with it for a verification code after their password (e.g. TOTP),
+		// when they log in with an AuthHandler from NewAuthHandler, NewCredentialAuthHandler or NewAuthHandlerFunc.
+		SecondFactor SecondFactor
+
+		// AuthTimeout, if set, disconnects clients that haven't logged in this long after the AuthHandler starts, so
+		// clients left sitting at the login prompt don't hold their session open until the telnet.Server's Timeout.
+		AuthTimeout time.Duration
+
+		// LoginThrottle, if set, delays and locks out repeated failed logins from the same IP (see Throttle). Clients
+		// connecting from a locked out IP are disconnected before they're asked to log in.
+		LoginThrottle *Throttle
+
+		// LoginDelay is how long failed logins are delayed when there's no LoginThrottle: DefaultLoginDelay if unset,
+		// and not at all if it's negative (e.g. in tests).
+		LoginDelay time.Duration
+
 		// GenericHandler can be used as a fallback if no matching command is found within Commands.
 		GenericHandler Handler
 
+		// CommandNotFound builds the response to commands nothing handles (when GenericHandler isn't set), from the
+		// command's name; Messages.CommandNotFound if nil. An empty response makes the command succeed silently (e.g.
+		// for applets a persona has no handler for), rather than fail with the exit status 127.
+		CommandNotFound func(name string) string
+
+		// PromptFunc builds the prompt shown before each command, and takes precedence over Prompt. It can be used to
+		// build a dynamic prompt (e.g. "root@router:/tmp# ").
+		PromptFunc func(session *telnet.Session) string
+
+		// Prompt is shown before each command; DefaultPrompt if empty.
+		Prompt string
+
+		// WelcomeMessage is sent after the client logs in; Messages.Welcome if empty.
+		WelcomeMessage string
+
+		// ExitMessage is sent when the client exits; Messages.Exit if empty.
+		ExitMessage string
+
+		// Messages, if set, replaces the messages the shell sends (such as "command not found" and "Login incorrect"),
+		// e.g. to imitate a device in another language. Those left empty are DefaultMessages.
+		Messages *Messages
+
+		// ExitCommands are the commands ending the session (or leaving a shell started by sudo or su); just
+		// DefaultExitCommand if empty. With LineEditing, Ctrl-D on an empty line exits too, unless IgnoreEOF is set.
+		ExitCommands []string
+
+		// IgnoreEOF stops Ctrl-D on an empty line exiting, like a shell's ignoreeof option, until it's pressed
+		// DefaultIgnoredEOFs times in a row.
+		IgnoreEOF bool
+
+		// Aliases, if set, gives each session its own command aliases starting with these (e.g. "ll" for "ls -l"), which
+		// replace the first word of each command they match. The alias and unalias builtins are available.
+		Aliases map[string]string
+
+		// LineEditing reads commands using server-side line editing (see telnet.Session.EditLine), giving the client Tab
+		// completion of command names and Completions, and arrow key history recall.
+		LineEditing bool
+
+		// Completions are extra words offered for Tab completion, such as fake file paths.
+		Completions []string
+
+		// HistoryStore optionally persists every command entered, in addition to the session's own history.
+		HistoryStore HistoryStore
+
+		// Recorder, if set, records every command run, how it was handled and the response sent, for auditing.
+		Recorder Recorder
+
+		// HistorySize caps how many commands each session remembers for the history builtin; DefaultHistorySize if
+		// unset.
+		HistorySize int
+
+		// Banner is sent to the client as soon as they connect, before authentication.
+		Banner string
+
 		// Version is the server version sent to the client after the initial connection.
 		Version string
 
-		// Commands contains the available regex matching commands.
+		// FileSystem, if set, gives each session its own writable in-memory copy of it to explore with the cd, ls, pwd,
+		// cat, touch and rm builtins. Changes made by one session are never seen by others.
+		FileSystem fs.FS
+
+		// Environment, if set, gives each session its own shell environment starting with these variables. Variables
+		// ($NAME, ${NAME} and $? for the last command's exit status) are expanded in each command before it's matched,
+		// and the echo, env, export and unset builtins are available.
+		Environment map[string]string
+
+		// EnvironmentTimeout, if set, requests the client's environment variables (see telnet.Session.AwaitEnvironment)
+		// when it connects, waiting up to this long for them. They're added to the session's environment, over
+		// Environment.
+		EnvironmentTimeout time.Duration
+
+		// System, if set, describes the host the server imitates, providing the uname, hostname, uptime, ifconfig and ip
+		// builtins, and adding its /proc and /etc files to FileSystem.
+		System *SystemProfile
+
+		// Escalation, if set, provides the sudo, su and passwd builtins, capturing the passwords clients try and
+		// switching their prompt to "#" when they escalate (see Escalation).
+		Escalation *Escalation
+
+		// Sandbox, if set, runs its whitelisted commands for real (see Sandbox). It's meant for admin consoles, not
+		// honeypots.
+		Sandbox *Sandbox
+
+		// Downloader, if set, provides the wget, curl and tftp builtins, capturing the URLs clients download from.
+		Downloader *Downloader
+
+		// CommandLatency delays each command, like a device taking time to run it. Combine it with telnet.WithLatency to also slow
+		// down output.
+		CommandLatency telnet.Latency
+
+		// InterruptCommands lets clients abort a command during its CommandLatency delay by interrupting it (e.g. with
+		// Ctrl-C, see telnet.Session.WithInterrupt) like on a real device: "^C" is shown, the rest of the line is
+		// skipped, and the exit status is 130.
+		InterruptCommands bool
+
+		// JobControl emulates a shell's job control: commands followed by & are run as background jobs (their job number
+		// and a made-up process ID are shown), Ctrl-Z stops commands streaming their output (see HandleStream), and the
+		// jobs, fg and bg builtins are available. Jobs never really run in the background, but are listed as if they did.
+		JobControl bool
+
+		// Colors, if set, colors the prompt and error messages for clients supporting ANSI escape codes (see
+		// telnet.Session.SupportsANSI), which requires TerminalTypeTimeout to be set too. Other clients get plain text.
+		Colors *Colors
+
+		// RequestWindowSize asks the client for its window size when it connects (without waiting for it), so output
+		// paged with telnet.Session.Page fits the client's window.
+		RequestWindowSize bool
+
+		// TerminalTypeTimeout, if set, requests the client's terminal types (see telnet.Session.AwaitTerminalTypes) when
+		// it connects, waiting up to this long for them.
+		TerminalTypeTimeout time.Duration
+
+		// Commands contains the available regex matching commands. Call Compile after changing them to match them
+		// quickly; otherwise each regex is compiled for every command line.
 		Commands []Command
+
+		// matcher matches command lines against Commands, if they've been compiled.
+		matcher *commandMatcher
+
+		// invalidRegexes records the invalid regexes of uncompiled Commands that have been logged, so each is only
+		// logged once.
+		invalidRegexes sync.Map
+
+		// handlers contains the commands registered with Handle, by name.
+		handlers map[string]CommandFunc
 	}
 )
 
 func (s *Server) HandlerFunc(session *telnet.Session) {
+	state := &sessionState{history: &history{size: s.HistorySize}, messages: s.Messages.withDefaults()}
+	if state.history.size <= 0 {
+		state.history.size = DefaultHistorySize
+	}
+
+	session.Set(sessionStateKey{}, state)
+
+	if s.Aliases != nil {
+		state.aliases = newAliases(s.Aliases)
+		state.addBuiltins(state.aliases.builtins())
+	}
+
+	if s.Environment != nil || s.EnvironmentTimeout > 0 {
+		var clientVars map[string]string
+
+		if s.EnvironmentTimeout > 0 {
+			ctx, cancel := telnet.WithTimeout(session.Context(), s.EnvironmentTimeout)
+			clientVars, _ = session.AwaitEnvironment(ctx)
+			cancel()
+		}
+
+		state.env = newEnvironment(s.Environment, clientVars)
+		state.addBuiltins(state.env.builtins())
+	}
+
+	if s.JobControl {
+		state.jobs = newJobs()
+		state.addBuiltins(state.jobs.builtins())
+	}
+
+	if s.RequestWindowSize {
+		if err := session.RequestWindowSize(); err != nil {
+			return
+		}
+	}
+
+	if s.TerminalTypeTimeout > 0 {
+		ctx, cancel := telnet.WithTimeout(session.Context(), s.TerminalTypeTimeout)
+		_, _ = session.AwaitTerminalTypes(ctx)
+		cancel()
+	}
+
+	if s.Colors != nil && session.SupportsANSI() {
+		state.colors = s.Colors
+	}
+
+	if s.Banner != "" {
+		if err := session.WriteString(s.Banner); err != nil {
+			return
+		}
+	}
+
+	if s.Version != "" {
+		if err := session.WriteLine(s.Version); err != nil {
+			return
+		}
+	}
+
+	if s.LoginThrottle != nil {
+		if locked, _ := s.LoginThrottle.Locked(remoteIP(session)); locked {
+			_ = session.WriteString(state.messages.LockedOut)
+			return
+		}
+
+		session.Set(throttleKey{}, s.LoginThrottle)
+	}
+
+	if s.LoginDelay != 0 {
+		session.Set(loginDelayKey{}, max(s.LoginDelay, 0))
+	}
+
+	if s.OnLogin != nil {
+		session.Set(loginKey{}, s.OnLogin)
+	}
+
+	if s.SecondFactor != nil {
+		session.Set(secondFactorKey{}, s.SecondFactor)
+	}
+
+	if s.Canaries != nil {
+		session.Set(canaryKey{}, s.Canaries)
+	}
+
 	// If the AuthHandler is configured and the user fails login, return.
-	if s.AuthHandler != nil && !s.AuthHandler(session) {
+	if s.AuthHandler != nil && !s.authenticate(session) {
 		return
 	}
 
-	if err := session.WriteLine(DefaultWelcomeMessage); err != nil {
+	if err := session.WriteString(valueOrDefault(s.WelcomeMessage, state.messages.Welcome)); err != nil {
 		return
 	}
 
-	for {
-		if err := session.WriteLine(DefaultPrompt); err != nil {
+	if s.FileSystem != nil {
+		var err error
+
+		if state.files, err = newFileSystem(s.FileSystem, session.Clock()); err != nil {
+			session.Logger().Error("failed to load the shell's filesystem", "err", err)
+			_ = session.WriteString(state.colorError(state.messages.InternalError))
 			return
 		}
 
-		line, err := session.ReadLine()
-		if err != nil {
-			return
+		state.addBuiltins(state.files.builtins())
+	}
+
+	if s.System != nil {
+		if state.files != nil {
+			s.System.writeFiles(state.files, session.Clock())
 		}
 
-		fields := strings.Split(line, " ")
-		if len(fields) == 0 {
-			if err = session.WriteLine(DefaultExitMessage); err != nil {
+		state.addBuiltins(s.System.builtins())
+	}
+
+	if s.Downloader != nil {
+		state.addBuiltins(s.Downloader.builtins(state.files))
+	}
+
+	if s.Escalation != nil {
+		state.addBuiltins(s.Escalation.builtins(s))
+	}
+
+	if s.Sandbox != nil {
+		state.addBuiltins(s.Sandbox.builtins())
+	}
+
+	editor := &telnet.LineEditor{
+		Complete: func(line string) []string {
+			return s.complete(state, line)
+		},
+		History: func() []string {
+			return state.history.entries
+		},
+	}
+
+	var eofs int
+
+	for {
+		line, err := s.readCommand(session, editor)
+
+		// With line editing, io.EOF means the client pressed Ctrl-D on an empty line.
+		if s.LineEditing && errors.Is(err, io.EOF) {
+			if eofs++; s.IgnoreEOF && eofs < DefaultIgnoredEOFs {
+				if err = session.Writef(state.messages.IgnoredEOF, s.exitCommands()[0]); err != nil {
+					return
+				}
+
+				continue
+			}
+
+			if s.exit(session) {
 				return
 			}
+
+			continue
+		}
+
+		if err != nil {
 			return
 		}
 
-		if fields[0] == DefaultExitCommand {
-			if err = session.WriteLine(DefaultExitMessage); err != nil {
-				return
+		eofs = 0
+
+		state.history.add(line)
+
+		if s.HistoryStore != nil && strings.TrimSpace(line) != "" {
+			if err = s.HistoryStore.AppendHistory(session, line); err != nil {
+				session.Logger().Error("failed to append to the shell history", "err", err)
 			}
-			return
 		}
 
-		var matched bool
+		// Run each command of compound lines (e.g. "cd /tmp; wget http://host/x && chmod +x x") in turn, falling back to
+		// the whole line if it can't be split.
+		stages, err := SplitCommands(line)
+		if err != nil || len(stages) == 0 {
+			stages = []Stage{{Command: line}}
+		}
+
+		var status int
 
-		for _, command := range s.Commands {
-			matched, err = regexp.MatchString(command.Regex, line)
-			if err != nil {
-				fmt.Println(err.Error())
+		for i, stage := range stages {
+			if (stage.Operator == "&&" && status != 0) || (stage.Operator == "||" && status == 0) {
 				continue
 			}
 
-			if matched {
-				if err = session.WriteLine(command.Response); err != nil {
+			command := stage.Command
+
+			var (
+				record   *Record
+				response capture
+			)
+
+			if s.Recorder != nil && strings.TrimSpace(command) != "" {
+				started := newRecord(session, line, command)
+				record = &started
+				response.stop = session.TeeOutput(&response.Buffer)
+			}
+
+			if state.aliases != nil {
+				command = state.aliases.expand(command)
+			}
+
+			switch name := strings.Split(strings.TrimLeft(command, " "), " ")[0]; {
+			case slices.Contains(s.exitCommands(), name):
+				if s.exit(session) {
+					s.record(session, record, MatchBuiltin, -1, &response)
 					return
 				}
-				break
+
+				status = 0
+				s.record(session, record, MatchBuiltin, status, &response)
+				continue
+			case name == DefaultHistoryCommand:
+				if err = session.WriteString(state.history.String()); err != nil {
+					return
+				}
+
+				status = 0
+				s.record(session, record, MatchBuiltin, status, &response)
+				continue
 			}
-		}
 
-		if !matched {
-			if s.GenericHandler != nil {
-				if err = session.WriteLine(s.GenericHandler(line)); err != nil {
+			if state.env != nil {
+				command = state.env.expand(command)
+			}
+
+			if record != nil {
+				record.Command = command
+			}
+
+			// Lines that can't be split (e.g. with unterminated quotes) are left to Commands and GenericHandler.
+			args, err := SplitArgs(command)
+			if err != nil {
+				args = nil
+			}
+
+			// Only the last command of a pipeline has its output shown.
+			piped := i+1 < len(stages) && stages[i+1].Operator == "|"
+
+			// Background jobs start straight away, without the CommandLatency delay.
+			background := stage.Background && state.jobs != nil
+			if background {
+				job := state.jobs.start(stage.Command)
+				if err = session.Writef("[%d] %d\r\n", job.id, job.pid); err != nil {
 					return
 				}
 			} else {
-				if err = session.WriteLine(fields[0], DefaultCommandNotFound); err != nil {
+				interrupted, err := s.delayCommand(session)
+				if err != nil {
 					return
 				}
+
+				if interrupted {
+					if err = session.WriteString("^C\r\n"); err != nil {
+						return
+					}
+
+					status = 130
+					if record != nil {
+						s.record(session, record, record.Match, status, &response)
+					}
+
+					break
+				}
+			}
+
+			status = s.run(session, state, command, args, piped, record)
+			if record != nil {
+				s.record(session, record, record.Match, status, &response)
+			}
+
+			if status < 0 {
+				return
+			}
+
+			// Starting a background job succeeds, whatever becomes of it.
+			if background {
+				status = 0
+				if state.env != nil {
+					state.env.status = 0
+				}
+			}
+		}
+	}
+}
+
+// NewCommandServer returns a Server responding to 'commands', with their regexes compiled (see Server.Compile).
+func NewCommandServer(commands ...Command) (*Server, error) {
+	server := &Server{Commands: commands}
+	if err := server.Compile(); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// Compile compiles the regexes of the server's Commands once, returning an error for the first invalid one, instead of
+// compiling them for every command line. It must be called again after the Commands change, or they're matched the
+// slow way again. Regexes anchored to the start of the line with a literal prefix (e.g. "^uname$" or "^docker .*$")
+// are only tried against lines starting with it.
+func (s *Server) Compile() error {
+	matcher, err := newCommandMatcher(s.Commands)
+	if err != nil {
+		return err
+	}
+
+	s.matcher = matcher
+
+	return nil
+}
+
+// matchCommand returns the index of the first of the Commands matching 'line', or -1 if none do. Invalid regexes never
+// match, and are logged to the session's logger the first time they're tried.
+func (s *Server) matchCommand(session *telnet.Session, line string) int {
+	if s.matcher != nil && slices.Equal(s.matcher.commands, s.Commands) {
+		return s.matcher.match(line)
+	}
+
+	for i, command := range s.Commands {
+		matched, err := regexp.MatchString(command.Regex, line)
+		if err != nil {
+			if _, logged := s.invalidRegexes.LoadOrStore(command.Regex, true); !logged {
+				session.Logger().Error("failed to compile a command's regex", "regex", command.Regex, "err", err)
+			}
+
+			continue
+		}
+
+		if matched {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// exit leaves the shell started by sudo or su, if there is one, and otherwise sends the ExitMessage, reporting whether
+// the session should end.
+func (s *Server) exit(session *telnet.Session) bool {
+	if popUser(session) {
+		return false
+	}
+
+	_ = session.WriteString(valueOrDefault(s.ExitMessage, getState(session).messages.Exit))
+
+	return true
+}
+
+// exitCommands returns the commands ending the session.
+func (s *Server) exitCommands() []string {
+	if len(s.ExitCommands) == 0 {
+		return []string{DefaultExitCommand}
+	}
+
+	return s.ExitCommands
+}
+
+// Exec runs the command 'args' in the session's shell, as if the client had entered it, and returns its exit status
+// (or -1 if the session should end, as the client can no longer be written to or the command exited). Handlers can
+// use it to run other commands, such as a multi-call binary (e.g. busybox) running one of its applets.
+func (s *Server) Exec(session *telnet.Session, args []string) int {
+	return s.run(session, getState(session), strings.Join(args, " "), args, false, nil)
+}
+
+// run runs the command 'line', split into 'args' (if it could be), returning its exit status, or -1 if the session
+// should end. If 'piped' is set, responses from Commands and GenericHandler are discarded, as the command's output
+// would be piped into the next command. If 'record' is set, how the command was handled is recorded in it.
+func (s *Server) run(session *telnet.Session, state *sessionState, line string, args []string, piped bool, record *Record) int {
+	if record == nil {
+		record = &Record{}
+	}
+
+	status := s.dispatch(session, state, line, args, piped, record)
+
+	if state.env != nil && status >= 0 {
+		state.env.status = status
+	}
+
+	return status
+}
+
+// dispatch runs a command for run, looking it up in the handlers, then the builtins, then Commands.
+func (s *Server) dispatch(session *telnet.Session, state *sessionState, line string, args []string, piped bool, record *Record) int {
+	if len(args) > 0 {
+		fn, match := s.handlers[args[0]], MatchHandler
+		if fn == nil {
+			fn, match = state.builtins[args[0]], MatchBuiltin
+		}
+
+		// Commands can also be run by path (e.g. "/bin/busybox").
+		if name := path.Base(args[0]); fn == nil && name != args[0] {
+			if fn, match = s.handlers[name], MatchHandler; fn == nil {
+				fn, match = state.builtins[name], MatchBuiltin
+			}
+		}
+
+		if fn != nil {
+			record.Match = match
+
+			err := fn(session, args)
+			if err == nil {
+				return 0
+			}
+
+			if errors.Is(err, ErrExit) {
+				_ = session.WriteString(valueOrDefault(s.ExitMessage, getState(session).messages.Exit))
+				return -1
+			}
+
+			var exitErr ExitError
+			if errors.As(err, &exitErr) {
+				return int(exitErr) & 0xff
+			}
+
+			// Prefix every line of the error, as several files can fail at once (e.g. "rm a b").
+			message := strings.ReplaceAll(err.Error(), "\n", "\r\n"+args[0]+": ")
+			if err = session.WriteString(state.colorError(args[0] + ": " + message + "\r\n")); err != nil {
+				return -1
 			}
+
+			return 1
+		}
+	}
+
+	if i := s.matchCommand(session, line); i >= 0 {
+		command := s.Commands[i]
+		record.Match, record.Regex = MatchCommand, command.Regex
+
+		if piped {
+			return 0
+		}
+
+		if err := session.WriteString(command.Response); err != nil {
+			return -1
+		}
+
+		return 0
+	}
+
+	if s.GenericHandler != nil {
+		record.Match = MatchGeneric
+
+		response := s.GenericHandler(line)
+		if piped {
+			return 0
 		}
+
+		if err := session.WriteString(response); err != nil {
+			return -1
+		}
+
+		return 0
 	}
+
+	record.Match = MatchNotFound
+	name := strings.Split(line, " ")[0]
+
+	response := fmt.Sprintf(state.messages.CommandNotFound, name)
+	if s.CommandNotFound != nil {
+		if response = s.CommandNotFound(name); response == "" {
+			return 0
+		}
+	}
+
+	if err := session.WriteString(state.colorError(response)); err != nil {
+		return -1
+	}
+
+	return 127
+}
+
+// record stops capturing the command's response, and records it with the Recorder along with its 'match' and
+// 'status'. It does nothing if 'record' is nil.
+func (s *Server) record(session *telnet.Session, record *Record, match Match, status int, response *capture) {
+	if record == nil {
+		return
+	}
+
+	response.stop()
+	record.Match, record.Status, record.Response = match, status, response.String()
+
+	if err := s.Recorder.Record(session, *record); err != nil {
+		session.Logger().Error("failed to record shell command", "err", err)
+	}
+}
+
+// authenticate runs the AuthHandler, within the AuthTimeout if there is one, reporting whether the client logged in.
+func (s *Server) authenticate(session *telnet.Session) bool {
+	if s.AuthTimeout <= 0 || session.Conn == nil {
+		return s.AuthHandler(session)
+	}
+
+	// Fail the AuthHandler's reads once the time's up.
+	timer := session.Clock().AfterFunc(s.AuthTimeout, func() {
+		_ = session.SetReadDeadline(time.Unix(1, 0))
+	})
+
+	ok := s.AuthHandler(session)
+
+	if !timer.Stop() {
+		_ = session.Writef(getState(session).messages.LoginTimeout, int(s.AuthTimeout.Seconds()))
+		return false
+	}
+
+	return ok
+}
+
+// Handle registers a function to handle the command 'name', taking precedence over the builtins and Commands.
+// Any error it returns is written to the client, prefixed with the command name. Handle isn't safe to call while the
+// server is serving.
+func (s *Server) Handle(name string, fn CommandFunc) {
+	if s.handlers == nil {
+		s.handlers = make(map[string]CommandFunc)
+	}
+
+	s.handlers[name] = fn
+}
+
+// delayCommand waits for the CommandLatency delay before a command, reporting whether the client interrupted it (see
+// InterruptCommands).
+func (s *Server) delayCommand(session *telnet.Session) (interrupted bool, err error) {
+	ctx := session.Context()
+
+	if s.InterruptCommands && s.CommandLatency != (telnet.Latency{}) {
+		var cancel context.CancelFunc
+		ctx, cancel = session.WithInterrupt(ctx)
+		defer cancel()
+	}
+
+	if err = s.CommandLatency.Sleep(ctx); err != nil {
+		if errors.Is(context.Cause(ctx), telnet.ErrInterrupted) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return false, nil
+}
+
+// readCommand prompts the client for their next command, and reads it.
+func (s *Server) readCommand(session *telnet.Session, editor *telnet.LineEditor) (string, error) {
+	if s.LineEditing {
+		return session.EditLine(s.prompt(session), editor)
+	}
+
+	if err := session.WriteString(s.prompt(session)); err != nil {
+		return "", err
+	}
+
+	return session.ReadLine()
+}
+
+// prompt returns the prompt to show the client before their next command.
+func (s *Server) prompt(session *telnet.Session) string {
+	prompt := valueOrDefault(s.Prompt, DefaultPrompt)
+	if s.PromptFunc != nil {
+		prompt = s.PromptFunc(session)
+	}
+
+	// Privileged shells end their prompts with "#" instead of "$".
+	if trimmed := strings.TrimRight(prompt, " "); strings.HasSuffix(trimmed, "$") && Privileged(session) {
+		prompt = trimmed[:len(trimmed)-1] + "#" + prompt[len(trimmed):]
+	}
+
+	if colors := getState(session).colors; colors != nil {
+		return colorize(colors.Prompt, prompt)
+	}
+
+	return prompt
+}
+
+// valueOrDefault returns 'value', or 'fallback' if it's empty.
+func valueOrDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
 }
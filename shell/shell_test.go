@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestServer_PromptAndMessages(t *testing.T) {
+	tests := []struct {
+		Server  *Server
+		Welcome string
+		Prompt  string
+		Exit    string
+	}{
+		{Server: &Server{}, Welcome: DefaultWelcomeMessage, Prompt: DefaultPrompt, Exit: DefaultExitMessage},
+		{
+			Server:  &Server{Prompt: "router> ", WelcomeMessage: "Hi!\r\n", ExitMessage: "Bye!\r\n"},
+			Welcome: "Hi!\r\n",
+			Prompt:  "router> ",
+			Exit:    "Bye!\r\n",
+		},
+		{
+			// PromptFunc takes precedence over Prompt.
+			Server: &Server{
+				Prompt: "router> ",
+				PromptFunc: func(session *telnet.Session) string {
+					return "root@" + session.RemoteAddr().Network() + ":~$ "
+				},
+			},
+			Welcome: DefaultWelcomeMessage,
+			Prompt:  "root@tcp:~$ ",
+			Exit:    DefaultExitMessage,
+		},
+	}
+
+	for testNumber, test := range tests {
+		ts := telnettest.NewServer(test.Server.HandlerFunc)
+		client := ts.Client()
+
+		if expected, actual := test.Welcome+test.Prompt, expectOutput(t, client, test.Prompt); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		// The prompt is shown again after each command.
+		if err := client.Send("ls\r\n"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected, actual := "ls"+DefaultCommandNotFound+test.Prompt, expectOutput(t, client, test.Prompt); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		if err := client.Send("exit\r\n"); err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		response, err := client.ExpectEOF()
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if expected, actual := test.Exit, response; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+
+		_ = client.Close()
+		ts.Close()
+	}
+}
+
+// expectOutput waits for 'client' to receive 'text', returning the output up to and including it, and failing the
+// test if it doesn't arrive.
+func expectOutput(t *testing.T, client *telnettest.Client, text string) string {
+	t.Helper()
+
+	output, err := client.Expect(text)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	return output
+}
+
+// enter enters 'line' on 'client', returning the output up to the next DefaultPrompt (without it).
+func enter(t *testing.T, client *telnettest.Client, line string) string {
+	t.Helper()
+
+	if err := client.Send(line + "\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	return strings.TrimSuffix(expectOutput(t, client, DefaultPrompt), DefaultPrompt)
+}
@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+type (
+	// sessionState holds a session's shell state between commands.
+	sessionState struct {
+		history  *history
+		messages *Messages
+		env      *environment   // nil unless Server.Environment or Server.EnvironmentTimeout is set
+		aliases  aliases        // nil unless Server.Aliases is set
+		files    *fileSystem    // nil unless Server.FileSystem is set
+		colors   *Colors        // nil unless Server.Colors is set, and the client supports ANSI escape codes
+		logins   []LoginAttempt // the attempts recorded by RecordLogin
+		users    []string       // the users switched to with sudo and su, most recent last
+		sudoed   bool           // whether sudo accepted a password, so it isn't asked for again
+		jobs     *jobs          // nil unless Server.JobControl is set
+		builtins map[string]CommandFunc
+	}
+
+	// sessionStateKey is the key a session's *sessionState is stored under (see telnet.Session.Set).
+	sessionStateKey struct{}
+)
+
+// getState returns the shell state of 'session', creating it if the session isn't being served by a Server.
+func getState(session *telnet.Session) *sessionState {
+	return telnet.ValueOrSet(session, sessionStateKey{}, func() *sessionState {
+		return &sessionState{history: &history{size: DefaultHistorySize}, messages: DefaultMessages.withDefaults()}
+	})
+}
+
+// addBuiltins makes 'builtins' available to the session.
+func (s *sessionState) addBuiltins(builtins map[string]CommandFunc) {
+	if s.builtins == nil {
+		s.builtins = make(map[string]CommandFunc)
+	}
+
+	for name, fn := range builtins {
+		s.builtins[name] = fn
+	}
+}
+
+// colorError colors the error message 'text', if the session uses colors.
+func (s *sessionState) colorError(text string) string {
+	if s.colors == nil {
+		return text
+	}
+
+	return colorize(s.colors.Error, text)
+}
+
+// Getwd returns the session's working directory in the Server's FileSystem, or "/" if it doesn't have one. It can be
+// used to build prompts (e.g. "root@router:/tmp# ").
+func Getwd(session *telnet.Session) string {
+	if state := getState(session); state.files != nil {
+		return state.files.cwd
+	}
+
+	return "/"
+}
+
+// Getenv returns the value of the session's shell variable 'name', and whether it's set. Variables are only
+// available when the Server's Environment or EnvironmentTimeout is set.
+func Getenv(session *telnet.Session, name string) (string, bool) {
+	state := getState(session)
+	if state.env == nil {
+		return "", false
+	}
+
+	value, ok := state.env.vars[name]
+	return value, ok
+}
+
+// Setenv sets the session's shell variable 'name' to 'value'. It does nothing unless the Server's Environment or
+// EnvironmentTimeout is set.
+func Setenv(session *telnet.Session, name string, value string) {
+	if state := getState(session); state.env != nil {
+		state.env.vars[name] = value
+	}
+}
@@ -0,0 +1,115 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+type (
+	// StreamFunc handles a command registered with Server.HandleStream, writing its output to 'w' as it goes, e.g. a
+	// fake ping or tail -f. Each write is sent to the client straight away, with its line endings translated to CR LF.
+	// 'ctx' is cancelled when the client interrupts the command (e.g. with Ctrl-C) or the session ends, and the
+	// function should return promptly then; telnet.Sleep waits on it, for output paced like the real command's.
+	// 'args' holds the command line split into arguments, including the command name as args[0].
+	StreamFunc func(ctx context.Context, w io.Writer, args []string) error
+
+	// streamOutput relays a StreamFunc's output to the client, flushing each write.
+	streamOutput struct {
+		session *telnet.Session
+	}
+)
+
+// HandleStream registers a function streaming the output of the command 'name' (see StreamFunc), taking precedence
+// over the builtins and Commands like Handle. The client can interrupt it at any time (whatever InterruptCommands is
+// set to), like a real shell: "^C" is shown, and the exit status is 130. With JobControl, Ctrl-Z stops it instead,
+// as a job fg carries on with, with the exit status 148. Any other error it returns is written to the client, prefixed
+// with the command name. HandleStream isn't safe to call while the server is serving.
+func (s *Server) HandleStream(name string, fn StreamFunc) {
+	s.Handle(name, func(session *telnet.Session, args []string) error {
+		return stream(session, args, fn)
+	})
+}
+
+// StreamReader returns a StreamFunc copying the reader returned by 'open' to the client as it's read, e.g. the read
+// end of an io.Pipe fed by a goroutine (which should stop once 'ctx' is done). When the command is interrupted, the
+// reader is closed if it's an io.Closer, to stop a read waiting on more.
+func StreamReader(open func(ctx context.Context, args []string) (io.Reader, error)) StreamFunc {
+	return func(ctx context.Context, w io.Writer, args []string) error {
+		r, err := open(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		if closer, ok := r.(io.Closer); ok {
+			defer closer.Close()
+
+			stop := context.AfterFunc(ctx, func() {
+				_ = closer.Close()
+			})
+			defer stop()
+		}
+
+		if _, err = io.Copy(w, r); ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return err
+	}
+}
+
+// stream runs 'fn' for HandleStream, until it returns or the client interrupts (or suspends) it.
+func stream(session *telnet.Session, args []string, fn StreamFunc) error {
+	// Output is flushed as it's written, so make sure anything written before is seen first.
+	if err := session.Flush(); err != nil {
+		return err
+	}
+
+	state := getState(session)
+
+	withInterrupt := session.WithInterrupt
+	if state.jobs != nil {
+		withInterrupt = session.WithJobControl
+	}
+
+	ctx, cancel := withInterrupt(session.Context())
+	defer cancel()
+
+	err := fn(ctx, &streamOutput{session: session}, args)
+
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, telnet.ErrInterrupted):
+		if err = session.WriteString("^C\r\n"); err != nil {
+			return err
+		}
+
+		return ExitError(130)
+	case errors.Is(cause, telnet.ErrSuspended):
+		stopped := state.jobs.stop(strings.Join(args, " "), func(session *telnet.Session) error {
+			return stream(session, args, fn)
+		})
+
+		if err = session.WriteString("^Z\r\n" + state.jobs.format(stopped, false)); err != nil {
+			return err
+		}
+
+		return ExitError(148)
+	}
+
+	return err
+}
+
+func (o *streamOutput) Write(p []byte) (int, error) {
+	if err := o.session.WriteString(toCRLF(string(p))); err != nil {
+		return 0, err
+	}
+
+	if err := o.session.Flush(); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestServer_HandleStream(t *testing.T) {
+	server := &Server{Environment: map[string]string{}}
+
+	server.HandleStream("ping", fakePing)
+
+	server.HandleStream("cat", StreamReader(func(ctx context.Context, args []string) (io.Reader, error) {
+		if len(args) < 2 {
+			return nil, errors.New("missing file")
+		}
+
+		return strings.NewReader("line 1\nline 2\n"), nil
+	}))
+
+	ts := telnettest.NewServer(server.HandlerFunc)
+	defer ts.Close()
+
+	client := ts.Client()
+	defer client.Close()
+
+	expectOutput(t, client, DefaultPrompt)
+
+	// The output is seen as it's written, until the client interrupts the command.
+	if err := client.Send("ping 192.0.2.1\r\n"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expectOutput(t, client, "icmp_seq=3\r\n")
+
+	if err := client.SendCommand(telnet.IAC, telnet.IP); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if response := expectOutput(t, client, DefaultPrompt); !strings.HasSuffix(response, "^C\r\n"+DefaultPrompt) {
+		t.Errorf("Expected the output to end with %q, but actually got %q.", "^C\r\n"+DefaultPrompt, response)
+	}
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "echo $?", Expected: "130\r\n"},
+		{Input: "cat file", Expected: "line 1\r\nline 2\r\n"},
+		{Input: "cat", Expected: "cat: missing file\r\n"},
+		{Input: "echo $?", Expected: "1\r\n"},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, enter(t, client, test.Input); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
+
+// fakePing is a StreamFunc imitating ping, which runs until it's interrupted.
+func fakePing(ctx context.Context, w io.Writer, args []string) error {
+	for seq := 1; ; seq++ {
+		if _, err := fmt.Fprintf(w, "64 bytes from %s: icmp_seq=%d\n", args[1], seq); err != nil {
+			return err
+		}
+
+		if err := telnet.Sleep(ctx, 10*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package shell
+
+import (
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	DefaultKernelName      = "Linux"
+	DefaultOperatingSystem = "GNU/Linux"
+	DefaultMTU             = 1500
+	DefaultLoopbackMTU     = 65536
+)
+
+// started is when the process started, which Server.System's uptime counts from.
+var started = time.Now()
+
+type (
+	// SystemProfile describes the host a Server imitates, so the uname, hostname, uptime, ifconfig and ip builtins, and
+	// the /proc and /etc files scanners read, all agree with each other.
+	SystemProfile struct {
+		// Hostname is the host's name (uname -n).
+		Hostname string
+
+		// KernelName is the kernel's name (uname -s); DefaultKernelName if empty.
+		KernelName string
+
+		// KernelRelease is the kernel's release (uname -r, e.g. "2.6.36").
+		KernelRelease string
+
+		// KernelVersion is the kernel's build (uname -v, e.g. "#1 Tue May 5 09:35:47 CST 2015").
+		KernelVersion string
+
+		// Machine is the hardware name (uname -m, e.g. "mips" or "armv7l").
+		Machine string
+
+		// OperatingSystem is the operating system's name (uname -o); DefaultOperatingSystem if empty.
+		OperatingSystem string
+
+		// Uptime is how long the host claims to have been up when the server started.
+		Uptime time.Duration
+
+		// CPUInfo is the contents of /proc/cpuinfo.
+		CPUInfo string
+
+		// Interfaces are the host's network interfaces, in the order they're listed.
+		Interfaces []NetworkInterface
+	}
+
+	// NetworkInterface is a network interface of a SystemProfile.
+	NetworkInterface struct {
+		// Name is the interface's name (e.g. "eth0").
+		Name string
+
+		// MAC is the interface's hardware address (e.g. "14:cc:20:1a:2b:3c"). It's ignored for loopback interfaces.
+		MAC string
+
+		// Addr is the interface's address and network (e.g. 192.168.1.1/24), if it has one.
+		Addr netip.Prefix
+
+		// MTU is the interface's MTU; DefaultMTU if unset, or DefaultLoopbackMTU for loopback interfaces.
+		MTU int
+	}
+)
+
+// builtins returns the commands describing the host.
+func (p *SystemProfile) builtins() map[string]CommandFunc {
+	return map[string]CommandFunc{
+		"hostname": p.hostname,
+		"ifconfig": p.ifconfig,
+		"ip":       p.ip,
+		"uname":    p.uname,
+		"uptime":   p.uptime,
+	}
+}
+
+// writeFiles adds the host's /proc and /etc files to the session's filesystem, as of the time by 'clock'.
+func (p *SystemProfile) writeFiles(files *fileSystem, clock telnet.Clock) {
+	now := clock.Now()
+	uptime := p.uptimeAt(now).Seconds()
+
+	proc := map[string]string{
+		"etc/hostname": p.Hostname + "\n",
+		"proc/uptime":  fmt.Sprintf("%.2f %.2f\n", uptime, uptime*0.97),
+		"proc/version": p.kernelName() + " version " + p.KernelRelease + " (builder@buildhost) (gcc version 4.6.3) " + p.KernelVersion + "\n",
+	}
+
+	if p.CPUInfo != "" {
+		proc["proc/cpuinfo"] = p.CPUInfo
+	}
+
+	for name, data := range proc {
+		var mode fs.FileMode = 0o444
+		if strings.HasPrefix(name, "etc/") {
+			mode = 0o644
+		}
+
+		files.files[name] = &fstest.MapFile{Data: []byte(data), Mode: mode, ModTime: now}
+	}
+}
+
+func (p *SystemProfile) hostname(session *telnet.Session, args []string) error {
+	// Setting the hostname quietly does nothing.
+	if len(args) > 1 {
+		return nil
+	}
+
+	return session.WriteLine(p.Hostname)
+}
+
+func (p *SystemProfile) uname(session *telnet.Session, args []string) error {
+	output, err := p.unameOutput(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return session.WriteLine(output)
+}
+
+// unameOutput returns the output of uname with the options 'args'.
+func (p *SystemProfile) unameOutput(args []string) (string, error) {
+	fields := map[byte]string{
+		's': p.kernelName(),
+		'n': p.Hostname,
+		'r': p.KernelRelease,
+		'v': p.KernelVersion,
+		'm': p.Machine,
+		'o': valueOrDefault(p.OperatingSystem, DefaultOperatingSystem),
+	}
+
+	selected := map[byte]bool{}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return "", fmt.Errorf("invalid option -- '%s'", arg)
+		}
+
+		for i := 1; i < len(arg); i++ {
+			flag := arg[i]
+
+			if flag == 'a' {
+				for name := range fields {
+					selected[name] = true
+				}
+
+				continue
+			}
+
+			if _, ok := fields[flag]; !ok {
+				return "", fmt.Errorf("invalid option -- '%c'", flag)
+			}
+
+			selected[flag] = true
+		}
+	}
+
+	if len(selected) == 0 {
+		selected['s'] = true
+	}
+
+	var values []string
+	for _, flag := range []byte("snrvmo") {
+		if selected[flag] {
+			values = append(values, fields[flag])
+		}
+	}
+
+	return strings.Join(values, " "), nil
+}
+
+func (p *SystemProfile) uptime(session *telnet.Session, args []string) error {
+	now := session.Clock().Now()
+	uptime := p.uptimeAt(now)
+
+	days := int(uptime.Hours()) / 24
+	hours := int(uptime.Hours()) % 24
+	minutes := int(uptime.Minutes()) % 60
+
+	unit := "days"
+	if days == 1 {
+		unit = "day"
+	}
+
+	return session.Writef(" %s up %d %s, %2d:%02d,  load average: 0.00, 0.01, 0.05\r\n", now.Format("15:04:05"), days, unit, hours, minutes)
+}
+
+func (p *SystemProfile) ifconfig(session *telnet.Session, args []string) error {
+	// Configuring interfaces quietly does nothing.
+	if len(args) > 2 {
+		return nil
+	}
+
+	var name string
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	output, err := p.ifconfigOutput(name)
+	if err != nil {
+		return err
+	}
+
+	return session.WriteString(output)
+}
+
+// ifconfigOutput returns the output of ifconfig for the interface 'name', or for every interface if it's empty.
+func (p *SystemProfile) ifconfigOutput(name string) (string, error) {
+	var builder strings.Builder
+
+	for _, iface := range p.Interfaces {
+		if name != "" && iface.Name != name {
+			continue
+		}
+
+		if iface.loopback() {
+			fmt.Fprintf(&builder, "%-10sLink encap:Local Loopback  \r\n", iface.Name)
+		} else {
+			fmt.Fprintf(&builder, "%-10sLink encap:Ethernet  HWaddr %s  \r\n", iface.Name, strings.ToUpper(iface.MAC))
+		}
+
+		if addr := iface.Addr; addr.IsValid() {
+			switch {
+			case addr.Addr().Is4() && iface.loopback():
+				fmt.Fprintf(&builder, "          inet addr:%s  Mask:%s\r\n", addr.Addr(), netmask(addr))
+			case addr.Addr().Is4():
+				fmt.Fprintf(&builder, "          inet addr:%s  Bcast:%s  Mask:%s\r\n", addr.Addr(), broadcast(addr), netmask(addr))
+			default:
+				fmt.Fprintf(&builder, "          inet6 addr: %s Scope:%s\r\n", addr, scope(addr.Addr(), "Host", "Link", "Global"))
+			}
+		}
+
+		flags := "UP BROADCAST RUNNING MULTICAST"
+		if iface.loopback() {
+			flags = "UP LOOPBACK RUNNING"
+		}
+
+		fmt.Fprintf(&builder, "          %s  MTU:%d  Metric:1\r\n\r\n", flags, iface.mtu())
+	}
+
+	if name != "" && builder.Len() == 0 {
+		return "", fmt.Errorf("%s: error fetching interface information: Device not found", name)
+	}
+
+	return builder.String(), nil
+}
+
+func (p *SystemProfile) ip(session *telnet.Session, args []string) error {
+	output, err := p.ipOutput(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return session.WriteString(output)
+}
+
+// ipOutput returns the output of ip with the arguments 'args'. Only its addr and link objects are supported.
+func (p *SystemProfile) ipOutput(args []string) (string, error) {
+	if len(args) == 0 {
+		return "Usage: ip [OPTIONS] address|route|link|tunnel|neigh|rule [COMMAND]\r\n", nil
+	}
+
+	var addresses bool
+
+	switch object := args[0]; {
+	case object != "" && strings.HasPrefix("address", object):
+		addresses = true
+	case object != "" && strings.HasPrefix("link", object):
+	default:
+		return "", fmt.Errorf("unknown object %q", object)
+	}
+
+	// Allow "ip addr show eth0" and "ip addr show dev eth0".
+	var name string
+	if len(args) > 1 && args[1] == "show" {
+		args = args[2:]
+		if len(args) > 0 && args[0] == "dev" {
+			args = args[1:]
+		}
+
+		if len(args) > 0 {
+			name = args[0]
+		}
+	}
+
+	var builder strings.Builder
+
+	for i, iface := range p.Interfaces {
+		if name != "" && iface.Name != name {
+			continue
+		}
+
+		if iface.loopback() {
+			fmt.Fprintf(&builder, "%d: %s: <LOOPBACK,UP,LOWER_UP> mtu %d qdisc noqueue \r\n", i+1, iface.Name, iface.mtu())
+			builder.WriteString("    link/loopback 00:00:00:00:00:00 brd 00:00:00:00:00:00\r\n")
+		} else {
+			fmt.Fprintf(&builder, "%d: %s: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu %d qdisc pfifo_fast qlen 1000\r\n", i+1, iface.Name, iface.mtu())
+			fmt.Fprintf(&builder, "    link/ether %s brd ff:ff:ff:ff:ff:ff\r\n", strings.ToLower(iface.MAC))
+		}
+
+		if addr := iface.Addr; addresses && addr.IsValid() {
+			scope := scope(addr.Addr(), "host", "link", "global")
+
+			switch {
+			case addr.Addr().Is4() && !iface.loopback():
+				fmt.Fprintf(&builder, "    inet %s brd %s scope %s %s\r\n", addr, broadcast(addr), scope, iface.Name)
+			case addr.Addr().Is4():
+				fmt.Fprintf(&builder, "    inet %s scope %s %s\r\n", addr, scope, iface.Name)
+			default:
+				fmt.Fprintf(&builder, "    inet6 %s scope %s \r\n", addr, scope)
+			}
+		}
+	}
+
+	if name != "" && builder.Len() == 0 {
+		return "", fmt.Errorf("can't find device '%s'", name)
+	}
+
+	return builder.String(), nil
+}
+
+// kernelName returns the profile's KernelName, or DefaultKernelName if it's empty.
+func (p *SystemProfile) kernelName() string {
+	return valueOrDefault(p.KernelName, DefaultKernelName)
+}
+
+// uptimeAt returns how long the host claims to have been up at 'now'. A clock behind the process's start (e.g. a fake
+// one) counts from Uptime.
+func (p *SystemProfile) uptimeAt(now time.Time) time.Duration {
+	return p.Uptime + max(0, now.Sub(started))
+}
+
+// loopback reports whether the interface is a loopback interface.
+func (i NetworkInterface) loopback() bool {
+	return i.Name == "lo" || (i.Addr.IsValid() && i.Addr.Addr().IsLoopback())
+}
+
+// mtu returns the interface's MTU, or its default if it's unset.
+func (i NetworkInterface) mtu() int {
+	switch {
+	case i.MTU > 0:
+		return i.MTU
+	case i.loopback():
+		return DefaultLoopbackMTU
+	default:
+		return DefaultMTU
+	}
+}
+
+// netmask returns the netmask of the IPv4 network 'prefix' (e.g. 255.255.255.0 for a /24).
+func netmask(prefix netip.Prefix) netip.Addr {
+	mask := ^uint32(0) << (32 - prefix.Bits())
+	if prefix.Bits() == 0 {
+		mask = 0
+	}
+
+	return netip.AddrFrom4([4]byte{byte(mask >> 24), byte(mask >> 16), byte(mask >> 8), byte(mask)})
+}
+
+// broadcast returns the broadcast address of the IPv4 network 'prefix'.
+func broadcast(prefix netip.Prefix) netip.Addr {
+	addr, mask := prefix.Addr().As4(), netmask(prefix).As4()
+	for i := range addr {
+		addr[i] |= ^mask[i]
+	}
+
+	return netip.AddrFrom4(addr)
+}
+
+// scope returns the name of the scope of 'addr', from the names of the host, link and global scopes.
+func scope(addr netip.Addr, host string, link string, global string) string {
+	switch {
+	case addr.IsLoopback():
+		return host
+	case addr.IsLinkLocalUnicast():
+		return link
+	default:
+		return global
+	}
+}
@@ -0,0 +1,106 @@
+package shell
+
+import (
+	"io/fs"
+	"net/netip"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestSystemProfile(t *testing.T) {
+	profile := &SystemProfile{
+		Hostname:      "router",
+		KernelRelease: "2.6.36",
+		KernelVersion: "#1 Tue May 5 09:35:47 CST 2015",
+		Machine:       "mips",
+		CPUInfo:       "cpu model\t\t: MIPS 24Kc V7.4\n",
+		Interfaces: []NetworkInterface{
+			{Name: "eth0", MAC: "14:CC:20:1A:2B:3C", Addr: netip.MustParsePrefix("192.168.1.1/24")},
+			{Name: "lo", Addr: netip.MustParsePrefix("127.0.0.1/8")},
+		},
+	}
+
+	unameTests := []struct {
+		Args     []string
+		Expected string
+	}{
+		{Args: nil, Expected: "Linux"},
+		{Args: []string{"-a"}, Expected: "Linux router 2.6.36 #1 Tue May 5 09:35:47 CST 2015 mips GNU/Linux"},
+		{Args: []string{"-mn"}, Expected: "router mips"},
+		{Args: []string{"-r", "-s"}, Expected: "Linux 2.6.36"},
+	}
+
+	for testNumber, test := range unameTests {
+		actual, err := profile.unameOutput(test.Args)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected := test.Expected; expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+
+	if _, err := profile.unameOutput([]string{"-x"}); err == nil || err.Error() != "invalid option -- 'x'" {
+		t.Errorf("Expected an invalid option error, but actually got: %v.", err)
+	}
+
+	ifconfig, err := profile.ifconfigOutput("")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for _, expected := range []string{
+		"eth0      Link encap:Ethernet  HWaddr 14:CC:20:1A:2B:3C  \r\n",
+		"inet addr:192.168.1.1  Bcast:192.168.1.255  Mask:255.255.255.0\r\n",
+		"UP BROADCAST RUNNING MULTICAST  MTU:1500  Metric:1\r\n",
+		"lo        Link encap:Local Loopback  \r\n",
+		"inet addr:127.0.0.1  Mask:255.0.0.0\r\n",
+		"UP LOOPBACK RUNNING  MTU:65536  Metric:1\r\n",
+	} {
+		if !strings.Contains(ifconfig, expected) {
+			t.Errorf("Expected ifconfig's output to contain %q, but actually got %q.", expected, ifconfig)
+		}
+	}
+
+	if _, err = profile.ifconfigOutput("wlan0"); err == nil {
+		t.Errorf("Expected an error for a missing interface, but didn't get one.")
+	}
+
+	ip, err := profile.ipOutput([]string{"a", "show", "dev", "eth0"})
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := "1: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 qdisc pfifo_fast qlen 1000\r\n" +
+		"    link/ether 14:cc:20:1a:2b:3c brd ff:ff:ff:ff:ff:ff\r\n" +
+		"    inet 192.168.1.1/24 brd 192.168.1.255 scope global eth0\r\n"
+	if expected != ip {
+		t.Errorf("Expected %q, but actually got %q.", expected, ip)
+	}
+
+	files := &fileSystem{files: fstest.MapFS{}, cwd: "/"}
+	profile.writeFiles(files, telnettest.NewClock(started.Add(2*time.Hour)))
+
+	for name, expected := range map[string]string{
+		"etc/hostname": "router\n",
+		"proc/uptime":  "7200.00 6984.00\n",
+		"proc/cpuinfo": profile.CPUInfo,
+		"proc/version": "Linux version 2.6.36 (builder@buildhost) (gcc version 4.6.3) #1 Tue May 5 09:35:47 CST 2015\n",
+	} {
+		data, err := fs.ReadFile(files.files, name)
+		if err != nil {
+			t.Errorf("For %q, did not expect an error, but actually got one: (%T) %v.", name, err, err)
+			continue
+		}
+
+		if actual := string(data); expected != actual {
+			t.Errorf("For %q, expected %q, but actually got %q.", name, expected, actual)
+		}
+	}
+}
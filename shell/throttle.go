@@ -0,0 +1,205 @@
+package shell
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	// DefaultLoginDelay is the delay after a failed login, unless a Throttle is configured otherwise.
+	DefaultLoginDelay = 3 * time.Second
+
+	// DefaultMaxLoginDelay caps a Throttle's delays, unless configured otherwise.
+	DefaultMaxLoginDelay = time.Minute
+
+	// DefaultFailureWindow is how long a Throttle remembers failed logins, unless configured otherwise.
+	DefaultFailureWindow = 15 * time.Minute
+
+	// DefaultLockoutDuration is how long a Throttle locks an IP out for, unless configured otherwise.
+	DefaultLockoutDuration = 15 * time.Minute
+)
+
+type (
+	// Throttle slows down brute-force attacks by tracking failed logins per source IP: each failure doubles the delay
+	// before the client can try again, and too many failures lock the IP out for a while. It's used by the
+	// AuthHandlers from this package when set as a Server's LoginThrottle, and can be shared between Servers.
+	Throttle struct {
+		// BaseDelay is the delay after an IP's first failed login, doubling with each further failure;
+		// DefaultLoginDelay if unset.
+		BaseDelay time.Duration
+
+		// MaxDelay caps the delay after a failed login; DefaultMaxLoginDelay if unset.
+		MaxDelay time.Duration
+
+		// Window is how long failed logins are remembered for; DefaultFailureWindow if unset.
+		Window time.Duration
+
+		// LockoutThreshold is the number of failed logins within Window that locks an IP out for LockoutDuration.
+		// Lockouts are disabled if unset.
+		LockoutThreshold int
+
+		// LockoutDuration is how long an IP is locked out for once it reaches LockoutThreshold;
+		// DefaultLockoutDuration if unset.
+		LockoutDuration time.Duration
+
+		// OnFailure is called after each failed login (e.g. to feed fail2ban), with the number of failures from 'ip'
+		// within Window.
+		OnFailure func(ip string, username string, failures int)
+
+		// OnLockout is called when 'ip' is locked out (e.g. to raise an alert).
+		OnLockout func(ip string, until time.Time)
+
+		// Clock tells the time failures and lockouts are measured by; telnet.RealClock if unset.
+		Clock telnet.Clock
+
+		mu      sync.Mutex
+		sources map[string]*throttleSource
+		pruned  time.Time // when sources was last pruned
+	}
+
+	// throttleSource tracks the failed logins from an IP.
+	throttleSource struct {
+		failures    int
+		lastFailure time.Time
+		lockedUntil time.Time
+	}
+
+	// throttleKey is the key a session's Server.LoginThrottle is stored under, for its AuthHandler to find.
+	throttleKey struct{}
+)
+
+// Locked reports whether 'ip' is locked out, and until when.
+func (t *Throttle) Locked(ip string) (bool, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	source, ok := t.sources[ip]
+	if !ok || !t.now().Before(source.lockedUntil) {
+		return false, time.Time{}
+	}
+
+	return true, source.lockedUntil
+}
+
+// Failed records a failed login from 'ip' as 'username', returning how long to wait before the next attempt, and
+// whether the IP is now locked out.
+func (t *Throttle) Failed(ip string, username string) (time.Duration, bool) {
+	now := t.now()
+
+	t.mu.Lock()
+
+	if t.sources == nil {
+		t.sources = make(map[string]*throttleSource)
+	}
+
+	window := durationOrDefault(t.Window, DefaultFailureWindow)
+	t.prune(now, window)
+
+	// The IP's failures may have expired without being pruned yet.
+	source, ok := t.sources[ip]
+	if !ok || source.expired(now, window) {
+		source = &throttleSource{}
+		t.sources[ip] = source
+	}
+
+	source.failures++
+	source.lastFailure = now
+	failures := source.failures
+
+	delay := durationOrDefault(t.BaseDelay, DefaultLoginDelay)
+	maxDelay := durationOrDefault(t.MaxDelay, DefaultMaxLoginDelay)
+
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, maxDelay)
+
+	var locked bool
+	if t.LockoutThreshold > 0 && failures >= t.LockoutThreshold {
+		locked = true
+		source.failures = 0
+		source.lockedUntil = now.Add(durationOrDefault(t.LockoutDuration, DefaultLockoutDuration))
+	}
+
+	lockedUntil := source.lockedUntil
+	t.mu.Unlock()
+
+	// Call back without holding the lock, in case the callbacks use the Throttle.
+	if t.OnFailure != nil {
+		t.OnFailure(ip, username, failures)
+	}
+
+	if locked && t.OnLockout != nil {
+		t.OnLockout(ip, lockedUntil)
+	}
+
+	return delay, locked
+}
+
+// Succeeded records a successful login from 'ip', forgetting its failures.
+func (t *Throttle) Succeeded(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if source, ok := t.sources[ip]; ok && !t.now().Before(source.lockedUntil) {
+		delete(t.sources, ip)
+	}
+}
+
+// now returns the time by the Throttle's Clock.
+func (t *Throttle) now() time.Time {
+	if t.Clock == nil {
+		return telnet.RealClock.Now()
+	}
+
+	return t.Clock.Now()
+}
+
+// prune forgets IPs that haven't failed to log in within 'window', and aren't locked out. It only scans the IPs once
+// per window, so a flood of failures doesn't each pay for it; until then, stale failures are at most twice as old.
+func (t *Throttle) prune(now time.Time, window time.Duration) {
+	if now.Sub(t.pruned) < window {
+		return
+	}
+
+	t.pruned = now
+
+	for ip, source := range t.sources {
+		if source.expired(now, window) {
+			delete(t.sources, ip)
+		}
+	}
+}
+
+// expired reports whether the IP hasn't failed to log in within 'window', and isn't locked out, so can be forgotten.
+func (s *throttleSource) expired(now time.Time, window time.Duration) bool {
+	return now.Sub(s.lastFailure) > window && !now.Before(s.lockedUntil)
+}
+
+// remoteIP returns the IP address of the client of 'session'.
+func remoteIP(session *telnet.Session) string {
+	addr := session.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+// durationOrDefault returns 'value', or 'fallback' if it's unset.
+func durationOrDefault(value time.Duration, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,106 @@
+package shell
+
+import (
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+func TestThrottle(t *testing.T) {
+	var (
+		failures []int
+		lockouts int
+	)
+
+	clock := telnettest.NewClock(time.Now())
+
+	throttle := &Throttle{
+		Clock:            clock,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Second,
+		LockoutThreshold: 5,
+		LockoutDuration:  time.Hour,
+		OnFailure: func(ip string, username string, count int) {
+			failures = append(failures, count)
+		},
+		OnLockout: func(ip string, until time.Time) {
+			lockouts++
+		},
+	}
+
+	tests := []struct {
+		Delay  time.Duration
+		Locked bool
+	}{
+		{Delay: time.Second},
+		{Delay: 2 * time.Second},
+		{Delay: 4 * time.Second},
+		{Delay: 5 * time.Second},
+		{Delay: 5 * time.Second, Locked: true},
+	}
+
+	for testNumber, test := range tests {
+		delay, locked := throttle.Failed("192.0.2.1", "root")
+		if delay != test.Delay || locked != test.Locked {
+			t.Errorf("For test #%d, expected (%v, %t), but actually got (%v, %t).", testNumber, test.Delay, test.Locked, delay, locked)
+		}
+	}
+
+	if locked, _ := throttle.Locked("192.0.2.1"); !locked {
+		t.Error("Expected the IP to be locked out, but it wasn't.")
+	}
+
+	if locked, _ := throttle.Locked("192.0.2.2"); locked {
+		t.Error("Did not expect another IP to be locked out, but it was.")
+	}
+
+	// Successful logins don't lift lockouts.
+	throttle.Succeeded("192.0.2.1")
+
+	if locked, _ := throttle.Locked("192.0.2.1"); !locked {
+		t.Error("Expected the IP to still be locked out, but it wasn't.")
+	}
+
+	if expected, actual := 5, len(failures); expected != actual || lockouts != 1 {
+		t.Errorf("Expected %d failure and 1 lockout callbacks, but actually got %d and %d.", expected, actual, lockouts)
+	}
+
+	// Other IPs' failures are tracked separately, and forgotten after a success.
+	throttle.Failed("192.0.2.2", "admin")
+	throttle.Succeeded("192.0.2.2")
+
+	if delay, _ := throttle.Failed("192.0.2.2", "admin"); delay != time.Second {
+		t.Errorf("Expected %v, but actually got %v.", time.Second, delay)
+	}
+
+	// Lockouts expire after LockoutDuration.
+	clock.Advance(time.Hour)
+
+	if locked, _ := throttle.Locked("192.0.2.1"); locked {
+		t.Error("Expected the lockout to have expired, but it hasn't.")
+	}
+}
+
+func TestThrottle_Defaults(t *testing.T) {
+	clock := telnettest.NewClock(time.Now())
+	throttle := &Throttle{Clock: clock, LockoutThreshold: 2}
+
+	throttle.Failed("192.0.2.1", "root")
+
+	// Failures are forgotten once they're older than the Window.
+	clock.Advance(DefaultFailureWindow + time.Second)
+
+	if delay, locked := throttle.Failed("192.0.2.1", "root"); delay != DefaultLoginDelay || locked {
+		t.Errorf("Expected (%v, false), but actually got (%v, %t).", DefaultLoginDelay, delay, locked)
+	}
+
+	// Without a LockoutDuration, IPs are locked out for DefaultLockoutDuration.
+	if _, locked := throttle.Failed("192.0.2.1", "root"); !locked {
+		t.Fatal("Expected the IP to be locked out, but it wasn't.")
+	}
+
+	if locked, until := throttle.Locked("192.0.2.1"); !locked || !until.Equal(clock.Now().Add(DefaultLockoutDuration)) {
+		t.Errorf("Expected the IP to be locked out until %v, but actually got (%t, %v).", clock.Now().Add(DefaultLockoutDuration), locked, until)
+	}
+}
@@ -0,0 +1,168 @@
+package shell
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+const (
+	// TOTPAttribute is the User attribute TOTP reads each user's secret from, unless configured otherwise.
+	TOTPAttribute = "totp"
+
+	DefaultTOTPDigits = 6
+	DefaultTOTPPeriod = 30 * time.Second
+	DefaultTOTPSkew   = 1
+)
+
+type (
+	// SecondFactor checks the verification codes users are asked for after their password (see Server.SecondFactor),
+	// e.g. from an authenticator app.
+	SecondFactor interface {
+		// Enrolled reports whether 'user' has a second factor, and so must enter a verification code.
+		Enrolled(user *User) bool
+
+		// Verify reports whether 'code' is a valid verification code for 'user'.
+		Verify(ctx context.Context, user *User, code string) (bool, error)
+	}
+
+	// TOTP is a SecondFactor checking RFC 6238 time-based one-time passwords, as generated by authenticator apps. Each
+	// code is only accepted once.
+	TOTP struct {
+		// Secret returns the base32 encoded secret of 'user', and whether they have one; the user's TOTPAttribute
+		// attribute if nil.
+		Secret func(user *User) (string, bool)
+
+		// Digits is the length of the codes; DefaultTOTPDigits if unset.
+		Digits int
+
+		// Period is how long each code is valid for; DefaultTOTPPeriod if unset.
+		Period time.Duration
+
+		// Skew is how many periods either side of the current one are accepted too, to allow for clock drift;
+		// DefaultTOTPSkew if unset, or none if negative.
+		Skew int
+
+		// AllowUnenrolled lets users without a secret log in without a verification code. Otherwise, they can't log in.
+		AllowUnenrolled bool
+
+		mu   sync.Mutex
+		used map[string]uint64 // the counter of the last code each user logged in with, so it can't be replayed
+	}
+)
+
+// GenerateTOTPSecret returns a random base32 encoded secret, to give a user (e.g. as their TOTPAttribute attribute)
+// and add to their authenticator app.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret), nil
+}
+
+// Enrolled reports whether 'user' has a secret, or true if unenrolled users aren't allowed, so they're asked for a code
+// that can never be valid.
+func (t *TOTP) Enrolled(user *User) bool {
+	_, ok := t.secret(user)
+	return ok || !t.AllowUnenrolled
+}
+
+// Verify reports whether 'code' is the user's current code by the session's clock (see telnet.ContextClock), or one
+// within Skew periods of it, and hasn't been used before.
+func (t *TOTP) Verify(ctx context.Context, user *User, code string) (bool, error) {
+	encoded, ok := t.secret(user)
+	if !ok {
+		return false, nil
+	}
+
+	// Authenticator apps show secrets in groups of 4, often in lower case.
+	encoded = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(encoded, " ", ""), "="))
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	digits := t.Digits
+	if digits <= 0 {
+		digits = DefaultTOTPDigits
+	}
+
+	period := t.Period
+	if period <= 0 {
+		period = DefaultTOTPPeriod
+	}
+
+	skew := t.Skew
+	switch {
+	case skew == 0:
+		skew = DefaultTOTPSkew
+	case skew < 0:
+		skew = 0
+	}
+
+	code = strings.TrimSpace(code)
+	current := uint64(telnet.ContextClock(ctx).Now().UnixNano() / int64(period))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for offset := -skew; offset <= skew; offset++ {
+		counter := current + uint64(offset)
+		if hmac.Equal([]byte(totpCode(secret, counter, digits)), []byte(code)) {
+			if last, ok := t.used[user.Username]; ok && counter <= last {
+				return false, nil
+			}
+
+			if t.used == nil {
+				t.used = make(map[string]uint64)
+			}
+
+			t.used[user.Username] = counter
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// secret returns the user's encoded secret, and whether they have one.
+func (t *TOTP) secret(user *User) (string, bool) {
+	if t.Secret != nil {
+		return t.Secret(user)
+	}
+
+	secret, ok := user.Attributes[TOTPAttribute]
+	return secret, ok && secret != ""
+}
+
+// totpCode returns the HOTP code (RFC 4226) for 'secret' and 'counter', with 'digits' digits.
+func totpCode(secret []byte, counter uint64, digits int) string {
+	mac := hmac.New(sha1.New, secret)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	modulus := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+
+	code := strconv.FormatUint(uint64(value)%modulus, 10)
+
+	return strings.Repeat("0", digits-len(code)) + code
+}
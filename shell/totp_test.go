@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"context"
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// The SHA1 test vectors from RFC 6238, Appendix B.
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		Time     int64
+		Expected string
+	}{
+		{Time: 59, Expected: "94287082"},
+		{Time: 1111111109, Expected: "07081804"},
+		{Time: 1111111111, Expected: "14050471"},
+		{Time: 1234567890, Expected: "89005924"},
+		{Time: 2000000000, Expected: "69279037"},
+		{Time: 20000000000, Expected: "65353130"},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, totpCode(secret, uint64(test.Time/30), 8); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestTOTP_Verify(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	totp := &TOTP{}
+	user := &User{Username: "admin", Attributes: map[string]string{TOTPAttribute: secret}}
+	counter := uint64(time.Now().Unix() / 30)
+
+	tests := []struct {
+		Code     string
+		Expected bool
+	}{
+		{Code: "abcdef", Expected: false},
+		{Code: totpCode(key, counter-1, DefaultTOTPDigits), Expected: true},
+		{Code: totpCode(key, counter, DefaultTOTPDigits), Expected: true},
+		{Code: totpCode(key, counter, DefaultTOTPDigits), Expected: false}, // Replayed.
+		{Code: totpCode(key, counter-1, DefaultTOTPDigits), Expected: false},
+		{Code: totpCode(key, counter+5, DefaultTOTPDigits), Expected: false},
+	}
+
+	for testNumber, test := range tests {
+		ok, err := totp.Verify(context.Background(), user, test.Code)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected, actual := test.Expected, ok; expected != actual {
+			t.Errorf("For test #%d, expected %t, but actually got %t.", testNumber, expected, actual)
+		}
+	}
+
+	unenrolled := &User{Username: "guest"}
+	if !totp.Enrolled(unenrolled) {
+		t.Errorf("Expected unenrolled users to need a code unless AllowUnenrolled is set, but they don't.")
+	}
+
+	if (&TOTP{AllowUnenrolled: true}).Enrolled(unenrolled) {
+		t.Errorf("Expected unenrolled users to not need a code with AllowUnenrolled set, but they do.")
+	}
+}
@@ -0,0 +1,55 @@
+package telnet
+
+import "context"
+
+// signalBuffer is how many signals Session.Signals holds for the handler before dropping new ones.
+const signalBuffer = 64
+
+// Signal is a control event from the client, delivered by Session.Signals.
+type Signal struct {
+	// Command is the command the client sent: IP, BRK, AYT, AO, EC or EL, or NAWS when it's changed its window size.
+	Command byte
+	// Width and Height are the client's new window size, for NAWS.
+	Width  int
+	Height int
+}
+
+// Signals returns a channel delivering the control events the client sends, in order: interrupts (IP and BRK), AYT,
+// AO, EC and EL commands, and window size changes (NAWS, see RequestWindowSize). This lets the handler react to them
+// without handling the negotiation itself; the session still answers them as usual (e.g. replying to AYT).
+//
+// Signals are only seen while the session reads from the client, and are dropped if the handler lets more than 64 of
+// them build up. The channel is closed once the session's context is done.
+func (s *Session) Signals() <-chan Signal {
+	s.signalMu.Lock()
+	defer s.signalMu.Unlock()
+
+	if s.signals == nil {
+		s.signals = make(chan Signal, signalBuffer)
+
+		context.AfterFunc(s.ctx, func() {
+			s.signalMu.Lock()
+			defer s.signalMu.Unlock()
+
+			close(s.signals)
+			s.signalsClosed = true
+		})
+	}
+
+	return s.signals
+}
+
+// signal delivers 'signal' to the channel returned by Signals, if the handler has asked for it and there's room.
+func (s *Session) signal(signal Signal) {
+	s.signalMu.Lock()
+	defer s.signalMu.Unlock()
+
+	if s.signals == nil || s.signalsClosed {
+		return
+	}
+
+	select {
+	case s.signals <- signal:
+	default:
+	}
+}
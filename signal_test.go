@@ -0,0 +1,30 @@
+package telnet
+
+import "testing"
+
+func TestSession_Signals(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+	signals := session.Signals()
+
+	go func() {
+		client.Write([]byte{'a', IAC, IP, IAC, AO, IAC, SB, NAWS, 0, 80, 0, 24, IAC, SE, IAC, EL, IAC, AYT, 'b'})
+		expect(t, client, []byte(DefaultAYTResponse))
+	}()
+
+	buffer := make([]byte, 2)
+	if _, err := session.Read(buffer); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := []Signal{{Command: IP}, {Command: AO}, {Command: NAWS, Width: 80, Height: 24}, {Command: EL}, {Command: AYT}}
+	for testNumber, want := range expected {
+		if actual := <-signals; actual != want {
+			t.Errorf("For test #%d, expected %+v, but actually got %+v.", testNumber, want, actual)
+		}
+	}
+
+	session.Conn.(serverConn).cancel()
+	if _, ok := <-signals; ok {
+		t.Error("Expected the channel to be closed once the session's context is done, but it wasn't.")
+	}
+}
@@ -0,0 +1,314 @@
+// Package sshproxy relays TELNET sessions to an SSH server, so a TELNET front door (such as a honeypot, or a shim
+// while migrating) can sit in front of hosts only reachable over SSH.
+package sshproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// DefaultTerminalType is the terminal type requested for the SSH session when the TELNET client doesn't send its
+	// own, unless configured otherwise.
+	DefaultTerminalType = "xterm"
+
+	// negotiationTimeout bounds how long the proxy waits for the client's window size and terminal type.
+	negotiationTimeout = 2 * time.Second
+
+	// maxLoginAttempts is how many times a client is prompted for credentials when Proxy.Login is set.
+	maxLoginAttempts = 3
+)
+
+// errLoginFailed is returned by Proxy.dial when the client runs out of login attempts.
+var errLoginFailed = errors.New("sshproxy: login failed")
+
+// Proxy is a TELNET handler relaying each session to a shell on an SSH server, passing the client's window size
+// (NAWS) on to the SSH session as it changes. Use its ServeTELNET method as the server's handler:
+//
+//	proxy := &sshproxy.Proxy{Addr: "10.0.0.5:22", Config: config}
+//	server := &telnet.Server{Handler: proxy.ServeTELNET}
+type Proxy struct {
+	// Addr is the SSH server's address, e.g. "10.0.0.5:22".
+	Addr string
+
+	// Config configures the SSH connection. It must have a HostKeyCallback, and its User and Auth are used to log in
+	// unless Login is set.
+	Config *ssh.ClientConfig
+
+	// Auth, if set, authenticates the TELNET client before the SSH server is dialled (e.g. a shell.AuthHandler),
+	// ending the session if it returns false.
+	Auth func(session *telnet.Session) bool
+
+	// Login, if set, prompts the TELNET client for a username and password, and logs in to the SSH server with them
+	// instead of Config's User and Auth.
+	Login bool
+
+	// TerminalType is requested for the SSH session when the TELNET client doesn't send its own (TTYPE);
+	// DefaultTerminalType if unset.
+	TerminalType string
+}
+
+// ServeTELNET relays 'session' to a shell on the SSH server, until either end closes.
+func (p *Proxy) ServeTELNET(session *telnet.Session) {
+	if p.Auth != nil && !p.Auth(session) {
+		return
+	}
+
+	client, err := p.dial(session)
+	if err != nil {
+		if !errors.Is(err, errLoginFailed) {
+			session.Logger().Error("failed to connect to ssh server", "addr", p.Addr, "err", err)
+			_ = session.WriteLine("Connection to the remote host failed.")
+		}
+
+		return
+	}
+	defer client.Close()
+
+	if err = p.relay(session, client); err != nil {
+		session.Logger().Error("failed to relay ssh session", "addr", p.Addr, "err", err)
+	}
+}
+
+// dial connects to the SSH server, prompting for the credentials to log in with if p.Login is set.
+func (p *Proxy) dial(session *telnet.Session) (*ssh.Client, error) {
+	if !p.Login {
+		return p.connect(session.Context(), p.Config)
+	}
+
+	for attempt := 0; attempt < maxLoginAttempts; attempt++ {
+		username, password, err := readCredentials(session)
+		if err != nil {
+			return nil, err
+		}
+
+		config := *p.Config
+		config.User = username
+		config.Auth = []ssh.AuthMethod{
+			ssh.Password(password),
+			ssh.KeyboardInteractive(func(_ string, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+
+				return answers, nil
+			}),
+		}
+
+		client, err := p.connect(session.Context(), &config)
+		if err == nil {
+			return client, nil
+		}
+
+		// The SSH package doesn't have an error type for failed authentication.
+		if !strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, err
+		}
+
+		if err = session.WriteLine("Login incorrect"); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, errLoginFailed
+}
+
+// connect dials the SSH server, and logs in with 'config'. Connecting and logging in take up to config.Timeout (if
+// set), and give up once 'ctx' is done.
+func (p *Proxy) connect(ctx context.Context, config *ssh.ClientConfig) (*ssh.Client, error) {
+	dialer := net.Dialer{Timeout: config.Timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The handshake ignores 'ctx', so it's bounded by a deadline instead.
+	deadline, _ := ctx.Deadline()
+	if config.Timeout > 0 && (deadline.IsZero() || time.Now().Add(config.Timeout).Before(deadline)) {
+		deadline = time.Now().Add(config.Timeout)
+	}
+
+	_ = conn.SetDeadline(deadline)
+	stopInterrupt := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, p.Addr, config)
+	if !stopInterrupt() && err == nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, channels, requests), nil
+}
+
+// relay runs a shell on the SSH server for 'session', until either end closes.
+func (p *Proxy) relay(session *telnet.Session, client *ssh.Client) error {
+	sshSession, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sshSession.Close()
+
+	// The SSH server may not end the shell when its input closes, so close the connection once the session ends.
+	defer context.AfterFunc(session.Context(), func() {
+		_ = client.Close()
+	})()
+
+	ctx, cancel := telnet.WithTimeout(session.Context(), negotiationTimeout)
+	width, height, _ := session.AwaitWindowSize(ctx)
+	types, _ := session.AwaitTerminalTypes(ctx)
+	cancel()
+
+	if width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	terminalType := p.TerminalType
+	if len(types) > 0 {
+		terminalType = types[0]
+	} else if terminalType == "" {
+		terminalType = DefaultTerminalType
+	}
+
+	// The remote shell echoes input itself, so switch the client to character at a time mode.
+	for _, option := range []byte{telnet.ECHO, telnet.SGA} {
+		if _, err = session.WriteCommand(telnet.IAC, telnet.WILL, option); err != nil {
+			return err
+		}
+	}
+
+	if err = sshSession.RequestPty(terminalType, height, width, ssh.TerminalModes{}); err != nil {
+		return err
+	}
+
+	session.OnWindowSize(func(width int, height int) {
+		_ = sshSession.WindowChange(height, width)
+	})
+	defer session.OnWindowSize(nil)
+
+	stdin, err := sshSession.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	output := flushWriter{session}
+	sshSession.Stdout = output
+	sshSession.Stderr = output
+
+	if err = sshSession.Shell(); err != nil {
+		return err
+	}
+
+	ctx, cancel = context.WithCancel(session.Context())
+	defer cancel()
+
+	relayed := make(chan struct{})
+	go func() {
+		defer close(relayed)
+		defer stdin.Close()
+
+		relayInput(ctx, session, stdin)
+	}()
+
+	err = sshSession.Wait()
+	cancel()
+	<-relayed
+
+	// The connection was closed as the session ended.
+	if session.Context().Err() != nil {
+		return nil
+	}
+
+	// The shell exiting with a non-zero status (or none) isn't the proxy's error.
+	var exitErr *ssh.ExitError
+	var missingErr *ssh.ExitMissingError
+	if errors.As(err, &exitErr) || errors.As(err, &missingErr) {
+		return nil
+	}
+
+	return err
+}
+
+// relayInput copies the client's input to the SSH session until 'ctx' is done or either end closes, translating the
+// TELNET end of line (CR LF, or CR NUL) into the CR a terminal sends for Enter.
+func relayInput(ctx context.Context, session *telnet.Session, stdin io.Writer) {
+	var buffer [1024]byte
+	var cr bool
+
+	for {
+		n, err := session.ReadContext(ctx, buffer[:])
+
+		input := buffer[:0]
+		for _, b := range buffer[:n] {
+			if cr && (b == telnet.NL || b == telnet.NUL) {
+				cr = false
+				continue
+			}
+
+			cr = b == telnet.CR
+			input = append(input, b)
+		}
+
+		if len(input) > 0 {
+			if _, err := stdin.Write(input); err != nil {
+				return
+			}
+		}
+
+		// Skip any commands the client sends that we don't understand.
+		var protocolErr *telnet.ProtocolError
+		if errors.As(err, &protocolErr) && !protocolErr.Fatal {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readCredentials prompts the client for a username and password, hiding the password as it's typed.
+func readCredentials(session *telnet.Session) (username string, password string, err error) {
+	if err = session.WriteString("login: "); err != nil {
+		return "", "", err
+	}
+
+	if username, err = session.ReadLine(); err != nil {
+		return "", "", err
+	}
+
+	password, err = session.ReadPassword("Password: ")
+
+	return username, password, err
+}
+
+// flushWriter writes to a session, flushing each write straight away, as output from the SSH session doesn't come
+// ahead of a read that would flush it.
+type flushWriter struct {
+	session *telnet.Session
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.session.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, w.session.Flush()
+}
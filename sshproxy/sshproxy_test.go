@@ -0,0 +1,317 @@
+package sshproxy
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestProxy(t *testing.T) {
+	proxy := &Proxy{
+		Addr: newSSHServer(t),
+		Config: &ssh.ClientConfig{
+			User:            "root",
+			Auth:            []ssh.AuthMethod{ssh.Password("hunter2")},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+	}
+
+	server := telnettest.NewMemoryServer(proxy.ServeTELNET)
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	client.On(telnet.DO, telnet.NAWS, telnettest.Will(telnet.NAWS), telnettest.WindowSize(80, 24))
+	client.On(telnet.DO, telnet.TTYPE, telnettest.Will(telnet.TTYPE))
+	client.On(telnet.SB, telnet.TTYPE, telnettest.TerminalType("vt100"))
+
+	err := client.Run(
+		telnettest.Expect("pty vt100 80x24\r\n"),
+
+		// Enter arrives as the TELNET end of line, and reaches the shell as CR.
+		telnettest.Send("hello\r\n"),
+		telnettest.Expect("hello\r"),
+
+		// Resizing the client's window resizes the shell's.
+		func(client *telnettest.Client) error {
+			return client.SendCommand(telnettest.WindowSize(100, 40)...)
+		},
+		telnettest.Expect("window 100x40\r\n"),
+
+		telnettest.Send("exit\r\n"),
+	)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if _, err = client.ExpectEOF(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+}
+
+func TestProxy_SessionEnd(t *testing.T) {
+	proxy := &Proxy{
+		Addr: newSSHServer(t),
+		Config: &ssh.ClientConfig{
+			User:            "root",
+			Auth:            []ssh.AuthMethod{ssh.Password("hunter2")},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+	}
+
+	// Sessions report ending, including the one telnettest connects when it starts.
+	ended := make(chan struct{}, 2)
+
+	server := telnettest.NewMemoryServer(func(session *telnet.Session) {
+		defer func() { ended <- struct{}{} }()
+
+		proxy.ServeTELNET(session)
+	})
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	// Wait for the shell to be running.
+	if err := client.Run(telnettest.Expect("pty xterm 80x24\r\n"), telnettest.Send("hello\r\n"), telnettest.Expect("hello\r")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// The shell ignores its input ending, so the proxy must close the connection.
+	if err := server.Config.Shutdown(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for i := 0; i < cap(ended); i++ {
+		select {
+		case <-ended:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the sessions ending to end the relay, but it didn't.")
+		}
+	}
+}
+
+func TestProxy_Timeout(t *testing.T) {
+	// The server accepts connections, but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Read the client's handshake until it gives up.
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	proxy := &Proxy{
+		Addr: listener.Addr().String(),
+		Config: &ssh.ClientConfig{
+			User:            "root",
+			Auth:            []ssh.AuthMethod{ssh.Password("hunter2")},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			Timeout:         100 * time.Millisecond,
+		},
+	}
+
+	server := telnettest.NewMemoryServer(proxy.ServeTELNET)
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	response, err := client.ExpectEOF()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "Connection to the remote host failed.\r\n", response; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestProxy_Login(t *testing.T) {
+	proxy := &Proxy{
+		Addr:   newSSHServer(t),
+		Config: &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()},
+		Login:  true,
+	}
+
+	server := telnettest.NewMemoryServer(proxy.ServeTELNET)
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	err := client.Run(
+		telnettest.Expect("login: "),
+		telnettest.Send("root\r\n"),
+		telnettest.Expect("Password: "),
+		telnettest.Send("password\r\n"),
+		telnettest.Expect("Login incorrect\r\n"),
+
+		telnettest.Expect("login: "),
+		telnettest.Send("root\r\n"),
+		telnettest.Expect("Password: "),
+		telnettest.Send("hunter2\r\n"),
+		telnettest.Expect("pty xterm 80x24\r\n"),
+
+		telnettest.Send("exit\r\n"),
+	)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if _, err = client.ExpectEOF(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+}
+
+// newSSHServer starts an SSH server accepting root with the password "hunter2", returning its address. Its shell
+// reports the PTY and window changes it's sent, echoes input, and exits when sent "exit", but not when its input ends.
+func newSSHServer(t *testing.T) string {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "root" && string(password) == "hunter2" {
+				return nil, nil
+			}
+
+			return nil, errors.New("permission denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSSH(conn, config)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// serveSSH serves the test shell over 'conn'.
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go serveShell(channel, requests)
+	}
+}
+
+// serveShell answers the requests on a session channel, running the test shell once asked to.
+func serveShell(channel ssh.Channel, requests <-chan *ssh.Request) {
+	for request := range requests {
+		switch request.Type {
+		case "pty-req":
+			var pty struct {
+				Term                         string
+				Columns, Rows, Width, Height uint32
+				Modes                        string
+			}
+
+			if err := ssh.Unmarshal(request.Payload, &pty); err == nil {
+				_, _ = fmt.Fprintf(channel, "pty %s %dx%d\r\n", pty.Term, pty.Columns, pty.Rows)
+			}
+		case "window-change":
+			var window struct {
+				Columns, Rows, Width, Height uint32
+			}
+
+			if err := ssh.Unmarshal(request.Payload, &window); err == nil {
+				_, _ = fmt.Fprintf(channel, "window %dx%d\r\n", window.Columns, window.Rows)
+			}
+		case "shell":
+			go echo(channel)
+		}
+
+		if request.WantReply {
+			_ = request.Reply(true, nil)
+		}
+	}
+
+	_ = channel.Close()
+}
+
+// echo echoes the input on 'channel' until it's sent "exit", or the input ends (which, like some shells, it ignores,
+// leaving the channel open).
+func echo(channel ssh.Channel) {
+	var input []byte
+	var buffer [256]byte
+
+	for {
+		n, err := channel.Read(buffer[:])
+		if err != nil {
+			return
+		}
+
+		input = append(input, buffer[:n]...)
+		if bytes.Contains(input, []byte("exit\r")) {
+			_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+			_ = channel.Close()
+
+			return
+		}
+
+		if _, err = channel.Write(buffer[:n]); err != nil {
+			return
+		}
+	}
+}
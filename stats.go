@@ -0,0 +1,141 @@
+package telnet
+
+import (
+	"expvar"
+	"io"
+	"sync/atomic"
+)
+
+// The counters kept for a session (see Stats).
+const (
+	statBytesRead = iota
+	statBytesWritten
+	statLines
+	statCommands
+	statNegotiations
+	statSubnegotiations
+	statSessions
+	numStats
+)
+
+type (
+	// Stats counts the traffic of a session (see Session.Stats), or of every session a server has served (see
+	// Server.Stats), e.g. to spot exfiltration-sized transfers or noisy scanners.
+	Stats struct {
+		// BytesRead and BytesWritten count the raw bytes read from and written to the client, including commands.
+		BytesRead    int64 `json:"bytesRead"`
+		BytesWritten int64 `json:"bytesWritten"`
+
+		// Lines counts the lines read from the client with ReadLine or EditLine.
+		Lines int64 `json:"lines"`
+
+		// Commands counts the commands the client sent (e.g. IAC AYT), other than negotiation.
+		Commands int64 `json:"commands"`
+
+		// Negotiations counts the option negotiations (WILL, WONT, DO and DONT) the client sent, and Subnegotiations
+		// the subnegotiations (e.g. its window size).
+		Negotiations    int64 `json:"negotiations"`
+		Subnegotiations int64 `json:"subnegotiations"`
+
+		// Sessions counts the sessions served, for Server.Stats.
+		Sessions int64 `json:"sessions,omitempty"`
+	}
+
+	// serverVars is what Server.Expvar publishes: the server's Stats, and its sessions by state.
+	serverVars struct {
+		Stats
+
+		// ActiveSessions counts the sessions being served, of which HijackedSessions have been hijacked (see
+		// Session.Hijack).
+		ActiveSessions   int `json:"activeSessions"`
+		HijackedSessions int `json:"hijackedSessions"`
+	}
+
+	// counters holds the running counts behind Stats.
+	counters [numStats]atomic.Int64
+
+	// countingReader counts the bytes read through it.
+	countingReader struct {
+		io.Reader
+		count func(n int)
+	}
+
+	// countingWriter counts the bytes written through it.
+	countingWriter struct {
+		io.Writer
+		count func(n int)
+	}
+)
+
+// Stats returns the session's traffic so far.
+func (s *Session) Stats() Stats {
+	return s.stats.snapshot()
+}
+
+// Stats returns the traffic of every session the server has served, including those still running.
+func (server *Server) Stats() Stats {
+	return server.stats.snapshot()
+}
+
+// Expvar returns an expvar.Var reporting the server's Stats (counting every connection as a session), and how many
+// sessions it's serving by state, for operators that don't run Prometheus to scrape from /debug/vars of an existing
+// HTTP server:
+//
+//	expvar.Publish("telnet", server.Expvar())
+func (server *Server) Expvar() expvar.Var {
+	return expvar.Func(func() any {
+		vars := serverVars{Stats: server.Stats()}
+
+		server.handlesMu.Lock()
+		sessions := make([]*Session, 0, len(server.sessions))
+		for _, session := range server.sessions {
+			sessions = append(sessions, session)
+		}
+		server.handlesMu.Unlock()
+
+		for _, session := range sessions {
+			vars.ActiveSessions++
+
+			if session.hijacked.Load() {
+				vars.HijackedSessions++
+			}
+		}
+
+		return vars
+	})
+}
+
+// count adds 'n' to the session's counter 'stat', and the server's.
+func (s *Session) count(stat int, n int) {
+	s.stats[stat].Add(int64(n))
+
+	if s.serverStats != nil {
+		s.serverStats[stat].Add(int64(n))
+	}
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		BytesRead:       c[statBytesRead].Load(),
+		BytesWritten:    c[statBytesWritten].Load(),
+		Lines:           c[statLines].Load(),
+		Commands:        c[statCommands].Load(),
+		Negotiations:    c[statNegotiations].Load(),
+		Subnegotiations: c[statSubnegotiations].Load(),
+		Sessions:        c[statSessions].Load(),
+	}
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count(n)
+
+	return n, err
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.count(n)
+
+	return n, err
+}
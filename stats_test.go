@@ -0,0 +1,112 @@
+package telnet
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+func TestSession_Stats(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	stats := make(chan Stats, 1)
+
+	server := NewServer(WithHandler(func(session *Session) {
+		for i := 0; i < 2; i++ {
+			if _, err := session.ReadLine(); err != nil {
+				return
+			}
+		}
+
+		_ = session.WriteLine("bye")
+		_ = session.Flush()
+
+		stats <- session.Stats()
+	}), WithoutGoAhead())
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	input := []byte{IAC, AYT, IAC, WILL, NAWS, IAC, SB, NAWS, 0, 80, 0, 24, IAC, SE}
+	input = append(input, "apple\r\nbanana\r\n"...)
+
+	if _, err = conn.Write(input); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	output := append([]byte{IAC, WONT, SGA}, DefaultAYTResponse+"bye\r\n"...)
+	expect(t, conn, output)
+
+	expected := Stats{
+		BytesRead:       int64(len(input)),
+		BytesWritten:    int64(len(output)),
+		Lines:           2,
+		Commands:        1,
+		Negotiations:    1,
+		Subnegotiations: 1,
+	}
+
+	if actual := <-stats; expected != actual {
+		t.Errorf("Expected %+v, but actually got %+v.", expected, actual)
+	}
+
+	expected.Sessions = 1
+
+	if actual := server.Stats(); expected != actual {
+		t.Errorf("Expected %+v, but actually got %+v.", expected, actual)
+	}
+}
+
+func TestServer_Expvar(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	hijacked, release := make(chan *Session, 1), make(chan struct{})
+
+	server := NewServer(WithHandler(func(session *Session) {
+		conn, _, err := session.Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		hijacked <- session
+		<-release
+	}))
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	session := <-hijacked
+	defer close(release)
+
+	// Metrics never wait on a session's writes.
+	session.writeMu.Lock()
+	defer session.writeMu.Unlock()
+
+	var actual serverVars
+	if err = json.Unmarshal([]byte(server.Expvar().String()), &actual); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected := (serverVars{Stats: server.Stats(), ActiveSessions: 1, HijackedSessions: 1}); expected != actual {
+		t.Errorf("Expected %+v, but actually got %+v.", expected, actual)
+	}
+}
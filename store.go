@@ -0,0 +1,84 @@
+package telnet
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// store holds the values attached to a session with Session.Set.
+	store struct {
+		mu     sync.Mutex
+		values map[any]any
+	}
+
+	// sessionKey is the key a session's context carries the session under, for SessionFromContext.
+	sessionKey struct{}
+)
+
+// Set attaches 'value' to the session under 'key', so handlers can keep state (such as a login level or working
+// directory) for the rest of the session. Like context keys, keys should be of an unexported type (or at least be
+// namespaced) to avoid collisions between packages.
+func (s *Session) Set(key any, value any) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	if s.store.values == nil {
+		s.store.values = make(map[any]any)
+	}
+
+	s.store.values[key] = value
+}
+
+// Get returns the value attached to the session under 'key', if there is one.
+func (s *Session) Get(key any) (any, bool) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	value, ok := s.store.values[key]
+	return value, ok
+}
+
+// Delete removes the value attached to the session under 'key'.
+func (s *Session) Delete(key any) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	delete(s.store.values, key)
+}
+
+// Value returns the value of type T attached to 'session' under 'key'. It reports false if there isn't one, or it
+// has a different type.
+func Value[T any](session *Session, key any) (T, bool) {
+	value, _ := session.Get(key)
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+// ValueOrSet returns the value of type T attached to 'session' under 'key', first attaching the one built by 'init'
+// if there isn't one (or it has a different type).
+func ValueOrSet[T any](session *Session, key any, init func() T) T {
+	session.store.mu.Lock()
+	defer session.store.mu.Unlock()
+
+	if typed, ok := session.store.values[key].(T); ok {
+		return typed
+	}
+
+	if session.store.values == nil {
+		session.store.values = make(map[any]any)
+	}
+
+	typed := init()
+	session.store.values[key] = typed
+
+	return typed
+}
+
+// SessionFromContext returns the session 'ctx' belongs to (its Context, or a context derived from it), so code that's
+// only passed a context (such as an Enricher, or the verify function of a shell.NewAuthHandlerFunc) can get at the
+// values attached to the session.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*Session)
+	return session, ok
+}
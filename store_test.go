@@ -0,0 +1,76 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSession_Store(t *testing.T) {
+	type key string
+
+	session := &Session{}
+
+	if _, ok := session.Get(key("missing")); ok {
+		t.Errorf("Expected no value for a missing key, but actually got one.")
+	}
+
+	session.Set(key("level"), 15)
+
+	if level, ok := Value[int](session, key("level")); !ok || level != 15 {
+		t.Errorf("Expected level 15, but actually got %d (%t).", level, ok)
+	}
+
+	if _, ok := Value[string](session, key("level")); ok {
+		t.Errorf("Expected no string value for an int, but actually got one.")
+	}
+
+	// A different key type with the same underlying value doesn't collide.
+	if _, ok := session.Get("level"); ok {
+		t.Errorf("Expected no value for a key of another type, but actually got one.")
+	}
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		processes := ValueOrSet(session, key("processes"), func() []string {
+			calls++
+			return []string{"init"}
+		})
+
+		if len(processes) != 1 || processes[0] != "init" {
+			t.Errorf("Expected the initial processes, but actually got %q.", processes)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the initialiser to be called once, but it was actually called %d times.", calls)
+	}
+
+	session.Delete(key("level"))
+
+	if _, ok := session.Get(key("level")); ok {
+		t.Errorf("Expected no value after deleting it, but actually got one.")
+	}
+}
+
+func TestSessionFromContext(t *testing.T) {
+	type key string
+
+	session, _ := newTestSession(t, &Server{})
+	session.Set(key("user"), "root")
+
+	ctx, cancel := context.WithCancel(session.Context())
+	defer cancel()
+
+	found, ok := SessionFromContext(ctx)
+	if !ok || found != session {
+		t.Fatalf("Expected the session from its context, but actually got %p (%t).", found, ok)
+	}
+
+	if user, _ := Value[string](found, key("user")); user != "root" {
+		t.Errorf("Expected %q, but actually got %q.", "root", user)
+	}
+
+	if _, ok = SessionFromContext(context.Background()); ok {
+		t.Errorf("Expected no session from a context without one, but actually got one.")
+	}
+}
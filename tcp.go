@@ -0,0 +1,79 @@
+package telnet
+
+import (
+	"net"
+	"time"
+)
+
+// TCPOptions tunes TCP connections, for a Server (applied to each connection it accepts) or a Dialer. The zero value
+// leaves the defaults of the net package and the OS. Connections that aren't TCP (directly, or under TLS) are left
+// alone.
+type TCPOptions struct {
+	// KeepAlive is the period between keep-alive probes, which detect clients that vanished without closing the
+	// connection. It's left at the default (15 seconds) if 0, and keep-alives are disabled if it's negative.
+	KeepAlive time.Duration
+
+	// DisableNoDelay enables Nagle's algorithm, batching small writes into fewer packets, which suits bulk transfers.
+	// Interactive sessions are better off without it, so each keystroke and echo is sent immediately.
+	DisableNoDelay bool
+
+	// Linger is how long closing a connection may block sending data still queued (SO_LINGER), rounded down to the
+	// second. If it's 0, Close returns immediately and the OS sends the data in the background. If it's negative,
+	// queued data is discarded and the connection is reset (RST) on Close, tearing it down at once, e.g. for a
+	// honeypot shedding connections without leaving them in TIME_WAIT.
+	Linger time.Duration
+}
+
+// apply applies the options to 'conn'.
+func (o TCPOptions) apply(conn net.Conn) error {
+	if o == (TCPOptions{}) {
+		return nil
+	}
+
+	tcpConn, ok := tcpConnOf(conn)
+	if !ok {
+		return nil
+	}
+
+	if o.KeepAlive != 0 {
+		if err := tcpConn.SetKeepAlive(o.KeepAlive > 0); err != nil {
+			return err
+		}
+
+		if o.KeepAlive > 0 {
+			if err := tcpConn.SetKeepAlivePeriod(o.KeepAlive); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.DisableNoDelay {
+		if err := tcpConn.SetNoDelay(false); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case o.Linger > 0:
+		return tcpConn.SetLinger(int(o.Linger / time.Second))
+	case o.Linger < 0:
+		return tcpConn.SetLinger(0)
+	}
+
+	return nil
+}
+
+// tcpConnOf returns the TCP connection underlying 'conn', looking through TLS (and anything else that exposes its
+// connection with NetConn).
+func tcpConnOf(conn net.Conn) (*net.TCPConn, bool) {
+	for {
+		switch c := conn.(type) {
+		case *net.TCPConn:
+			return c, true
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil, false
+		}
+	}
+}
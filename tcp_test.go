@@ -0,0 +1,85 @@
+package telnet
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTCPOptions_Linger(t *testing.T) {
+	tests := []struct {
+		Options  TCPOptions
+		Expected error
+	}{
+		{Options: TCPOptions{}, Expected: io.EOF},
+		{Options: TCPOptions{KeepAlive: time.Minute, DisableNoDelay: true}, Expected: io.EOF},
+		{Options: TCPOptions{Linger: -1}, Expected: syscall.ECONNRESET},
+	}
+
+	for testNumber, test := range tests {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		if err = test.Options.apply(conn); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+		}
+
+		_ = conn.Close()
+		_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		if _, err = client.Read(make([]byte, 1)); !errors.Is(err, test.Expected) {
+			t.Errorf("For test #%d, expected %v, but actually got: (%T) %v.", testNumber, test.Expected, err, err)
+		}
+
+		client.Close()
+		listener.Close()
+	}
+}
+
+func TestTCPConnOf(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	pipe, _ := net.Pipe()
+	defer pipe.Close()
+
+	tests := []struct {
+		Conn     net.Conn
+		Expected bool
+	}{
+		{Conn: conn, Expected: true},
+		{Conn: tls.Client(conn, &tls.Config{}), Expected: true},
+		{Conn: pipe, Expected: false},
+	}
+
+	for testNumber, test := range tests {
+		if _, actual := tcpConnOf(test.Conn); test.Expected != actual {
+			t.Errorf("For test #%d, expected %t, but actually got %t.", testNumber, test.Expected, actual)
+		}
+	}
+}
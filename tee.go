@@ -0,0 +1,49 @@
+package telnet
+
+import (
+	"io"
+	"slices"
+)
+
+// TeeOutput copies the data written to the client (but not commands) to 'w' as well, until the returned function is
+// called or writing to 'w' fails. It can be used to capture the output of a command, e.g. for logging, and any number
+// of writers can be added, e.g. a recorder and a live dashboard (from a Server.Subscribe subscriber). Writes to the
+// client wait on 'w', so a slow writer slows the session down.
+func (s *Session) TeeOutput(w io.Writer) (stop func()) {
+	v := s.addViewer(w)
+
+	return func() {
+		s.removeViewer(v)
+	}
+}
+
+// TeeInput copies the data the handler reads from the client (without commands, which are processed by the session)
+// to 'w', until the returned function is called or writing to 'w' fails. Input sent by viewers attached to the session
+// (see Server.Attach) is copied as well, as the handler reads it. Reads wait on 'w', so a slow writer slows the
+// session down.
+func (s *Session) TeeInput(w io.Writer) (stop func()) {
+	v := &viewer{w: w, failed: make(chan struct{})}
+
+	s.inputMu.Lock()
+	s.inputTaps = append(s.inputTaps, v)
+	s.inputMu.Unlock()
+
+	return func() {
+		s.inputMu.Lock()
+		defer s.inputMu.Unlock()
+
+		s.inputTaps = slices.DeleteFunc(s.inputTaps, func(other *viewer) bool { return other == v })
+	}
+}
+
+// teeInput copies 'data', read by the handler, to the writers added by TeeInput, dropping any that fail.
+func (s *Session) teeInput(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	s.inputTaps = writeTo(s.inputTaps, data)
+}
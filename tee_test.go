@@ -0,0 +1,93 @@
+package telnet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestSession_TeeOutput(t *testing.T) {
+	var output, recorder, dashboard bytes.Buffer
+
+	session := &Session{writer: newWriter(&output)}
+
+	_ = session.WriteString("before")
+	stopRecorder := session.TeeOutput(&recorder)
+	stopDashboard := session.TeeOutput(&dashboard)
+	session.TeeOutput(failingWriter{})
+	_ = session.WriteString("during", "\xff")
+	_, _ = session.WriteCommand(IAC, WILL, ECHO)
+	stopDashboard()
+	_ = session.WriteString("+")
+	stopRecorder()
+	_ = session.WriteString("after")
+
+	if expected, actual := "during\xff+", recorder.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := "during\xff", dashboard.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := 0, len(session.viewers); expected != actual {
+		t.Errorf("Expected %d viewers, but actually got %d.", expected, actual)
+	}
+}
+
+func TestSession_TeeInput(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	var tee bytes.Buffer
+	stop := session.TeeInput(&tee)
+	session.TeeInput(failingWriter{})
+
+	go client.Write([]byte{'l', 's', IAC, NOP, IAC, IAC, '\r', '\n'})
+
+	data := make([]byte, 16)
+	for read := 0; read < 5; {
+		n, err := session.Read(data)
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		read += n
+	}
+
+	// Input injected by attached viewers is copied too, but only once it's read (not peeked at).
+	session.inject([]byte("pwd"))
+
+	if _, err := session.Peek(3); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "ls\xff\r\n", tee.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if _, err := session.Read(data); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	stop()
+	session.inject([]byte("exit"))
+
+	if _, err := session.Read(data); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "ls\xff\r\npwd", tee.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if expected, actual := 0, len(session.inputTaps); expected != actual {
+		t.Errorf("Expected %d input taps, but actually got %d.", expected, actual)
+	}
+}
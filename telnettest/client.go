@@ -0,0 +1,337 @@
+package telnettest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// DefaultTimeout is how long a Client's Expect waits for the expected output, unless configured otherwise.
+const DefaultTimeout = 5 * time.Second
+
+type (
+	// Client is a scriptable TELNET client for testing handlers. It answers the server's negotiations as told to with
+	// On (refusing the rest), and waits for expected output rather than sleeping, so tests are deterministic:
+	//
+	//	client := server.Client()
+	//	client.On(telnet.DO, telnet.NAWS, telnettest.Will(telnet.NAWS), telnettest.WindowSize(80, 24))
+	//
+	//	err := client.Run(
+	//		telnettest.Expect("Login: "),
+	//		telnettest.Send("root\r\n"),
+	//	)
+	Client struct {
+		// Timeout is how long Expect waits for the expected output; DefaultTimeout if unset.
+		Timeout time.Duration
+
+		conn  net.Conn
+		start sync.Once
+
+		mu         sync.Mutex
+		replies    map[[2]byte][][]byte
+		output     []byte   // the data received, up to what Expect has consumed
+		transcript []byte   // everything received, including commands
+		command    []byte   // the command being received, if any
+		escaped    bool     // whether the last byte of a subnegotiation being received was IAC
+		commands   [][]byte // the commands received, e.g. IAC DO NAWS
+		err        error    // the error that stopped the client reading
+		changed    chan struct{}
+	}
+
+	// Step is a step of a Client's script (see Client.Run).
+	Step func(client *Client) error
+)
+
+// NewClient returns a Client talking TELNET over 'conn'. The Client only starts reading once it's first used, so
+// replies set with On apply to the server's first negotiations.
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:    conn,
+		replies: make(map[[2]byte][][]byte),
+		changed: make(chan struct{}),
+	}
+}
+
+// Client connects a new scriptable Client to the server, panicking if it can't.
+func (s *Server) Client() *Client {
+	conn, err := s.dial()
+	if err != nil {
+		panic(fmt.Sprintf("telnettest: failed to connect to the server: %v", err))
+	}
+
+	return NewClient(conn)
+}
+
+// On sets the client's 'reply' to the server's 'command' (WILL, WONT, DO, DONT or SB) for 'option', replacing the
+// default: DO and WILL are refused with WONT and DONT, and nothing else is answered. An empty 'reply' ignores the
+// command.
+func (c *Client) On(command byte, option byte, reply ...[]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.replies[[2]byte{command, option}] = reply
+}
+
+// Run runs 'steps' in order, stopping at the first to fail.
+func (c *Client) Run(steps ...Step) error {
+	for i, step := range steps {
+		if err := step(c); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Expect waits for the server to send 'text', returning the output up to and including it, which later Expects
+// won't see again.
+func (c *Client) Expect(text string) (string, error) {
+	c.start.Do(c.startReading)
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+
+		if i := bytes.Index(c.output, []byte(text)); i >= 0 {
+			output := string(c.output[:i+len(text)])
+			c.output = c.output[i+len(text):]
+			c.mu.Unlock()
+
+			return output, nil
+		}
+
+		output, err, changed := string(c.output), c.err, c.changed
+		c.mu.Unlock()
+
+		if err != nil {
+			return "", fmt.Errorf("expected %q, but got %q before: %w", text, output, err)
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return "", fmt.Errorf("expected %q, but got %q after %s", text, output, timeout)
+		}
+	}
+}
+
+// ExpectEOF waits for the server to close the connection (e.g. once the handler returns), returning the output
+// Expect hasn't consumed.
+func (c *Client) ExpectEOF() (string, error) {
+	c.start.Do(c.startReading)
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		output, err, changed := string(c.output), c.err, c.changed
+		c.mu.Unlock()
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return output, nil
+		case err != nil:
+			return output, err
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return output, fmt.Errorf("expected the connection to close, but it's still open after %s", timeout)
+		}
+	}
+}
+
+// Transcript returns everything the server has sent so far, including commands, as sent.
+func (c *Client) Transcript() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return bytes.Clone(c.transcript)
+}
+
+// Send sends 'text' to the server, escaping any IAC bytes in it.
+func (c *Client) Send(text string) error {
+	c.start.Do(c.startReading)
+
+	_, err := c.conn.Write(bytes.ReplaceAll([]byte(text), []byte{telnet.IAC}, []byte{telnet.IAC, telnet.IAC}))
+	return err
+}
+
+// SendCommand sends 'command' to the server as is, e.g. IAC IP.
+func (c *Client) SendCommand(command ...byte) error {
+	c.start.Do(c.startReading)
+
+	_, err := c.conn.Write(command)
+	return err
+}
+
+// Commands returns the commands the server has sent so far, e.g. IAC DO NAWS, or IAC SB TTYPE SEND IAC SE (with any
+// IAC bytes in subnegotiations unescaped).
+func (c *Client) Commands() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([][]byte(nil), c.commands...)
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// startReading reads from the server in the background, answering its negotiations as they arrive.
+func (c *Client) startReading() {
+	go func() {
+		var buffer [1024]byte
+
+		for {
+			n, err := c.conn.Read(buffer[:])
+
+			c.mu.Lock()
+
+			c.transcript = append(c.transcript, buffer[:n]...)
+			for _, b := range buffer[:n] {
+				c.parse(b)
+			}
+
+			if err != nil {
+				c.err = err
+			}
+
+			close(c.changed)
+			c.changed = make(chan struct{})
+			c.mu.Unlock()
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// parse handles the next byte from the server, adding it to the output or the command being read. c.mu must be held.
+func (c *Client) parse(b byte) {
+	switch {
+	case c.command == nil && b == telnet.IAC:
+		c.command = []byte{b}
+	case c.command == nil:
+		c.output = append(c.output, b)
+	case len(c.command) == 1:
+		switch b {
+		case telnet.IAC:
+			c.output = append(c.output, b)
+			c.command = nil
+		case telnet.WILL, telnet.WONT, telnet.DO, telnet.DONT, telnet.SB:
+			c.command = append(c.command, b)
+		default:
+			c.received(append(c.command, b))
+		}
+	case c.command[1] != telnet.SB:
+		c.received(append(c.command, b))
+	case c.escaped:
+		// Within a subnegotiation, IAC IAC is an escaped IAC, and IAC SE ends it.
+		c.escaped = false
+
+		if b == telnet.SE {
+			c.received(append(c.command, telnet.IAC, telnet.SE))
+		} else {
+			c.command = append(c.command, b)
+		}
+	case b == telnet.IAC:
+		c.escaped = true
+	default:
+		c.command = append(c.command, b)
+	}
+}
+
+// received records 'command', and sends the reply to it. c.mu must be held.
+func (c *Client) received(command []byte) {
+	c.commands = append(c.commands, command)
+	c.command = nil
+
+	if len(command) < 3 {
+		return
+	}
+
+	replies, ok := c.replies[[2]byte{command[1], command[2]}]
+	if !ok {
+		switch command[1] {
+		case telnet.DO:
+			replies = [][]byte{{telnet.IAC, telnet.WONT, command[2]}}
+		case telnet.WILL:
+			replies = [][]byte{{telnet.IAC, telnet.DONT, command[2]}}
+		}
+	}
+
+	for _, reply := range replies {
+		if _, err := c.conn.Write(reply); err != nil {
+			c.err = err
+			return
+		}
+	}
+}
+
+// Will returns IAC WILL 'option', to accept a DO.
+func Will(option byte) []byte {
+	return []byte{telnet.IAC, telnet.WILL, option}
+}
+
+// Do returns IAC DO 'option', to accept a WILL.
+func Do(option byte) []byte {
+	return []byte{telnet.IAC, telnet.DO, option}
+}
+
+// Subnegotiation returns IAC SB 'option' 'payload' IAC SE, escaping any IAC bytes in 'payload'.
+func Subnegotiation(option byte, payload ...byte) []byte {
+	command := []byte{telnet.IAC, telnet.SB, option}
+	command = append(command, bytes.ReplaceAll(payload, []byte{telnet.IAC}, []byte{telnet.IAC, telnet.IAC})...)
+
+	return append(command, telnet.IAC, telnet.SE)
+}
+
+// WindowSize returns the NAWS subnegotiation sending a window size of 'width' by 'height' characters.
+func WindowSize(width int, height int) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(width))
+	return Subnegotiation(telnet.NAWS, binary.BigEndian.AppendUint16(payload, uint16(height))...)
+}
+
+// TerminalType returns the TTYPE IS subnegotiation sending the terminal type 'name', to answer SB TTYPE SEND.
+func TerminalType(name string) []byte {
+	return Subnegotiation(telnet.TTYPE, append([]byte{telnet.IS}, name...)...)
+}
+
+// Expect returns a Step waiting for the server to send 'text' (see Client.Expect).
+func Expect(text string) Step {
+	return func(client *Client) error {
+		_, err := client.Expect(text)
+		return err
+	}
+}
+
+// Send returns a Step sending 'text' to the server (see Client.Send).
+func Send(text string) Step {
+	return func(client *Client) error {
+		return client.Send(text)
+	}
+}
@@ -0,0 +1,81 @@
+package telnettest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+func TestClient(t *testing.T) {
+	server := NewMemoryServer(func(session *telnet.Session) {
+		ctx, cancel := context.WithTimeout(session.Context(), 5*time.Second)
+		defer cancel()
+
+		width, height, _ := session.AwaitWindowSize(ctx)
+		types, _ := session.AwaitTerminalTypes(ctx)
+		_ = session.Writef("%dx%d %v\r\nLogin: ", width, height, types)
+
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+
+		_ = session.WriteLine("Hello, ", line)
+	})
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	client.On(telnet.DO, telnet.NAWS, Will(telnet.NAWS), WindowSize(132, 43))
+	client.On(telnet.DO, telnet.TTYPE, Will(telnet.TTYPE))
+	client.On(telnet.SB, telnet.TTYPE, TerminalType("XTERM"))
+
+	err := client.Run(
+		Expect("132x43 [XTERM]\r\n"),
+		Expect("Login: "),
+		Send("root\r\n"),
+		Expect("Hello, root\r\n"),
+	)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := [][]byte{
+		{telnet.IAC, telnet.WONT, telnet.SGA},
+		{telnet.IAC, telnet.DO, telnet.NAWS},
+		{telnet.IAC, telnet.DO, telnet.TTYPE},
+		{telnet.IAC, telnet.SB, telnet.TTYPE, telnet.SEND, telnet.IAC, telnet.SE},
+	}
+
+	actual := client.Commands()
+	if len(actual) < len(expected) {
+		t.Fatalf("Expected at least %d commands, but actually got %q.", len(expected), actual)
+	}
+
+	for testNumber := range expected {
+		if expected, actual := expected[testNumber], actual[testNumber]; !bytes.Equal(expected, actual) {
+			t.Errorf("For test #%d, expected %v, but actually got %v.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestClient_ExpectTimeout(t *testing.T) {
+	server := NewServer(func(session *telnet.Session) {
+		_ = session.WriteString("Login: ")
+		<-session.Context().Done()
+	})
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	client.Timeout = 50 * time.Millisecond
+
+	if _, err := client.Expect("Password: "); err == nil {
+		t.Errorf("Expected an error, but didn't get one.")
+	}
+}
@@ -0,0 +1,171 @@
+package telnettest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+type (
+	// Clock is a fake telnet.Clock for tests, whose time only moves when Advance is called, so timeouts and delays
+	// happen instantly and deterministically. Give it to a server with telnet.WithClock:
+	//
+	//	clock := telnettest.NewClock(time.Now())
+	//	server := telnettest.NewMemoryServer(handler, telnet.WithClock(clock))
+	//
+	//	clock.BlockUntil(1) // wait for the handler to start its timeout
+	//	clock.Advance(time.Minute)
+	Clock struct {
+		mu      sync.Mutex
+		now     time.Time
+		timers  []*clockTimer // the active timers
+		changed chan struct{} // closed whenever a timer is scheduled or stopped, for BlockUntil
+	}
+
+	// clockTimer is a telnet.Timer scheduled by a Clock.
+	clockTimer struct {
+		clock *Clock
+		when  time.Time
+		c     chan time.Time // nil for timers from AfterFunc
+		f     func()         // nil for timers from NewTimer
+	}
+)
+
+// NewClock returns a Clock starting at 'now'.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a Timer sending the time on its channel once the clock has advanced by 'd'.
+func (c *Clock) NewTimer(d time.Duration) telnet.Timer {
+	timer := &clockTimer{clock: c, c: make(chan time.Time, 1)}
+	timer.Reset(d)
+
+	return timer
+}
+
+// AfterFunc returns a Timer calling 'f' once the clock has advanced by 'd'. 'f' is called by Advance, rather than in
+// its own goroutine.
+func (c *Clock) AfterFunc(d time.Duration, f func()) telnet.Timer {
+	timer := &clockTimer{clock: c, f: f}
+	timer.Reset(d)
+
+	return timer
+}
+
+// Advance moves the clock forward by 'd', firing the timers that become due, in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+
+	c.now = c.now.Add(d)
+
+	var due []*clockTimer
+	for _, timer := range c.timers {
+		if !timer.when.After(c.now) {
+			due = append(due, timer)
+		}
+	}
+
+	c.timers = slices.DeleteFunc(c.timers, func(timer *clockTimer) bool { return slices.Contains(due, timer) })
+	slices.SortStableFunc(due, func(a, b *clockTimer) int { return a.when.Compare(b.when) })
+
+	c.notify()
+	c.mu.Unlock()
+
+	for _, timer := range due {
+		timer.fire()
+	}
+}
+
+// BlockUntil waits until at least 'n' timers are waiting for the clock to advance, e.g. so a test knows a handler has
+// started its timeout before calling Advance.
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		timers, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+
+		if timers >= n {
+			return
+		}
+
+		<-changed
+	}
+}
+
+// notify wakes anything waiting in BlockUntil. c.mu must be held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (t *clockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop stops the timer, reporting whether it was active.
+func (t *clockTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.stop()
+}
+
+// Reset reschedules the timer to fire once the clock has advanced by 'd', reporting whether it was active. It fires
+// straight away if 'd' isn't positive.
+func (t *clockTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+
+	active := t.stop()
+
+	if d <= 0 {
+		t.when = t.clock.now
+		t.clock.mu.Unlock()
+		t.fire()
+
+		return active
+	}
+
+	t.when = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.notify()
+	t.clock.mu.Unlock()
+
+	return active
+}
+
+// stop removes the timer from the clock, reporting whether it was active. t.clock.mu must be held.
+func (t *clockTimer) stop() bool {
+	i := slices.Index(t.clock.timers, t)
+	if i < 0 {
+		return false
+	}
+
+	t.clock.timers = slices.Delete(t.clock.timers, i, i+1)
+	t.clock.notify()
+
+	return true
+}
+
+// fire sends the timer's time on its channel, or calls its function.
+func (t *clockTimer) fire() {
+	if t.f != nil {
+		t.f()
+		return
+	}
+
+	select {
+	case t.c <- t.when:
+	default:
+	}
+}
@@ -0,0 +1,85 @@
+package telnettest
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewClock(start)
+
+	var fired []string
+
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, "b") })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "a") })
+	stopped := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	timer := clock.NewTimer(3 * time.Second)
+
+	if !stopped.Stop() {
+		t.Errorf("Expected the timer to be active when stopped, but it wasn't.")
+	}
+
+	clock.Advance(2 * time.Second)
+
+	if expected, actual := []string{"a", "b"}, fired; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %q to fire, but actually got %q.", expected, actual)
+	}
+
+	select {
+	case <-timer.C():
+		t.Errorf("Expected the timer not to fire yet, but it did.")
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	select {
+	case when := <-timer.C():
+		if expected, actual := start.Add(3*time.Second), when; !expected.Equal(actual) {
+			t.Errorf("Expected the timer to fire at %v, but actually got %v.", expected, actual)
+		}
+	default:
+		t.Errorf("Expected the timer to fire, but it didn't.")
+	}
+
+	if expected, actual := start.Add(3*time.Second), clock.Now(); !expected.Equal(actual) {
+		t.Errorf("Expected the time to be %v, but actually got %v.", expected, actual)
+	}
+}
+
+func TestClock_Server(t *testing.T) {
+	clock := NewClock(time.Now())
+
+	server := NewMemoryServer(func(session *telnet.Session) {
+		ctx, cancel := telnet.WithTimeout(session.Context(), time.Hour)
+		defer cancel()
+
+		<-ctx.Done()
+
+		_ = telnet.Sleep(session.Context(), time.Minute)
+		_ = session.WriteString(ctx.Err().Error())
+	}, telnet.WithClock(clock))
+	defer server.Close()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+
+	// Wait for the Sleep.
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	output, err := io.ReadAll(server.Conn)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := context.DeadlineExceeded.Error(), string(output); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
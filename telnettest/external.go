@@ -0,0 +1,200 @@
+package telnettest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// ExternalClient is a real TELNET client program (such as inetutils telnet), to check a server interoperates with
+	// the clients people actually use (see Start). These tests are best run in a container with the clients installed
+	// (see shell/testdata/interop).
+	ExternalClient struct {
+		// Name identifies the client, e.g. "inetutils-telnet".
+		Name string
+
+		// Command returns the command line connecting the client to 'host' on 'port'.
+		Command func(host string, port string) []string
+	}
+
+	// Terminal is an ExternalClient running in a pseudo-terminal, where Expect and Send act on what a person using it
+	// would see and type.
+	Terminal struct {
+		// Timeout is how long Expect and Wait wait; DefaultTimeout if unset.
+		Timeout time.Duration
+
+		cmd   *exec.Cmd
+		stdin io.WriteCloser
+
+		mu      sync.Mutex
+		output  []byte // the output, up to what Expect has consumed
+		err     error  // the error that stopped the terminal reading, io.EOF once the client exits
+		changed chan struct{}
+		exited  chan struct{}
+	}
+)
+
+var (
+	// InetutilsTelnet is the telnet client of GNU inetutils, as found on most Linux distributions.
+	InetutilsTelnet = ExternalClient{Name: "inetutils-telnet", Command: func(host string, port string) []string {
+		return []string{"telnet", host, port}
+	}}
+
+	// BusyboxTelnet is BusyBox's telnet, as found on embedded devices.
+	BusyboxTelnet = ExternalClient{Name: "busybox-telnet", Command: func(host string, port string) []string {
+		return []string{"busybox", "telnet", host, port}
+	}}
+
+	// Plink is PuTTY's command line client.
+	Plink = ExternalClient{Name: "plink", Command: func(host string, port string) []string {
+		return []string{"plink", "-telnet", "-P", port, host}
+	}}
+)
+
+// Available reports whether the client, and util-linux's script (which Start runs it with), are installed, so tests
+// can be skipped where they aren't.
+func (c ExternalClient) Available() bool {
+	for _, name := range []string{c.Command("", "")[0], "script"} {
+		if _, err := exec.LookPath(name); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Start runs the client connected to the server at 'addr' (host and port), in a pseudo-terminal 'width' characters
+// wide and 'height' high, which it'll tell the server if asked (NAWS). The terminal is provided by util-linux's script.
+// The caller should call Close when finished.
+func (c ExternalClient) Start(addr string, width int, height int) (*Terminal, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	args := c.Command(host, port)
+	for i, arg := range args {
+		args[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+
+	cmd := exec.Command("script", "-qfec", fmt.Sprintf("stty cols %d rows %d && exec %s", width, height, strings.Join(args, " ")), "/dev/null")
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", c.Name, err)
+	}
+
+	terminal := &Terminal{cmd: cmd, stdin: stdin, changed: make(chan struct{}), exited: make(chan struct{})}
+	go terminal.read(stdout)
+
+	return terminal, nil
+}
+
+// Expect waits for the client to show 'text', returning everything shown up to it, which later calls won't see
+// again. It fails if the client doesn't show it within the Timeout, or exits first.
+func (t *Terminal) Expect(text string) (string, error) {
+	timer := time.NewTimer(t.timeout())
+	defer timer.Stop()
+
+	for {
+		t.mu.Lock()
+
+		if i := bytes.Index(t.output, []byte(text)); i >= 0 {
+			output := string(t.output[:i+len(text)])
+			t.output = t.output[i+len(text):]
+			t.mu.Unlock()
+
+			return output, nil
+		}
+
+		output, err, changed := string(t.output), t.err, t.changed
+		t.mu.Unlock()
+
+		if err != nil {
+			return "", fmt.Errorf("expected %q, but got %q before: %w", text, output, err)
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return "", fmt.Errorf("expected %q, but got %q after %s", text, output, t.timeout())
+		}
+	}
+}
+
+// Send types 'text' into the terminal, e.g. "root\r" to enter a username.
+func (t *Terminal) Send(text string) error {
+	_, err := io.WriteString(t.stdin, text)
+	return err
+}
+
+// Wait waits for the client to exit (e.g. once the server closes the connection), within the Timeout, returning the
+// output Expect hasn't consumed.
+func (t *Terminal) Wait() (string, error) {
+	select {
+	case <-t.exited:
+	case <-time.After(t.timeout()):
+		return "", fmt.Errorf("expected the client to exit, but it's still running after %s", t.timeout())
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.output), nil
+}
+
+// Close stops the client, if it's still running.
+func (t *Terminal) Close() error {
+	_ = t.stdin.Close()
+	_ = t.cmd.Process.Kill()
+	<-t.exited
+
+	return nil
+}
+
+// read collects the client's output, until it exits.
+func (t *Terminal) read(stdout io.Reader) {
+	buffer := make([]byte, 1024)
+
+	for {
+		n, err := stdout.Read(buffer)
+
+		t.mu.Lock()
+		t.output = append(t.output, buffer[:n]...)
+		t.err = err
+		close(t.changed)
+		t.changed = make(chan struct{})
+		t.mu.Unlock()
+
+		if err != nil {
+			_ = t.cmd.Wait()
+			close(t.exited)
+
+			return
+		}
+	}
+}
+
+// timeout returns how long Expect and Wait wait.
+func (t *Terminal) timeout() time.Duration {
+	if t.Timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return t.Timeout
+}
@@ -0,0 +1,40 @@
+package telnettest
+
+import (
+	"testing"
+)
+
+func TestExternalClient(t *testing.T) {
+	// A shell stands in for a TELNET client, showing its terminal's size and echoing what's typed.
+	client := ExternalClient{Name: "sh", Command: func(host string, port string) []string {
+		return []string{"sh", "-c", `echo "$0:$1"; stty size; read line; echo "got $line"`, host, port}
+	}}
+
+	if !client.Available() {
+		t.Skip("util-linux's script isn't installed.")
+	}
+
+	terminal, err := client.Start("127.0.0.1:23", 100, 40)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer terminal.Close()
+
+	for _, text := range []string{"127.0.0.1:23", "40 100"} {
+		if _, err = terminal.Expect(text); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}
+
+	if err = terminal.Send("it's an apple\r"); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if _, err = terminal.Expect("got it's an apple"); err != nil {
+		t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if _, err = terminal.Wait(); err != nil {
+		t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+}
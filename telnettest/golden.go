@@ -0,0 +1,218 @@
+package telnettest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the actual transcripts")
+
+var (
+	// commandNames are the names of TELNET commands, for FormatTranscript.
+	commandNames = map[byte]string{
+		telnet.SE:   "SE",
+		telnet.NOP:  "NOP",
+		telnet.DM:   "DM",
+		telnet.BRK:  "BRK",
+		telnet.IP:   "IP",
+		telnet.AO:   "AO",
+		telnet.AYT:  "AYT",
+		telnet.EC:   "EC",
+		telnet.EL:   "EL",
+		telnet.GA:   "GA",
+		telnet.SB:   "SB",
+		telnet.WILL: "WILL",
+		telnet.WONT: "WONT",
+		telnet.DO:   "DO",
+		telnet.DONT: "DONT",
+		telnet.IAC:  "IAC",
+	}
+
+	// optionNames are the names of TELNET options, for FormatTranscript.
+	optionNames = map[byte]string{
+		telnet.BINARY:     "BINARY",
+		telnet.ECHO:       "ECHO",
+		telnet.SGA:        "SGA",
+		telnet.TM:         "TM",
+		telnet.TTYPE:      "TTYPE",
+		telnet.NAWS:       "NAWS",
+		telnet.LINEMODE:   "LINEMODE",
+		telnet.NEWENVIRON: "NEW-ENVIRON",
+	}
+)
+
+// Golden runs 'handler' on an in-memory server, with a Client running 'steps' then waiting for the handler to return,
+// and compares the transcript of everything the server sent (see FormatTranscript) with the golden file at 'path'.
+// Running the tests with -update rewrites the golden files instead.
+func Golden(t testing.TB, path string, handler telnet.HandlerFunc, steps ...Step) {
+	t.Helper()
+
+	server := NewMemoryServer(handler)
+	defer server.Close()
+
+	client := server.Client()
+	defer client.Close()
+
+	if err := client.Run(steps...); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if _, err := client.ExpectEOF(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	actual := FormatTranscript(client.Transcript())
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if err := os.WriteFile(path, []byte(actual), 0o644); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected the golden file %s, but it doesn't exist (run the tests with -update to create it).", path)
+	} else if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expected := string(data)
+	if expected == actual {
+		return
+	}
+
+	expectedLines, actualLines := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	for i := 0; ; i++ {
+		var expectedLine, actualLine string
+		if i < len(expectedLines) {
+			expectedLine = expectedLines[i]
+		}
+
+		if i < len(actualLines) {
+			actualLine = actualLines[i]
+		}
+
+		if expectedLine != actualLine {
+			t.Errorf("Expected the transcript to match %s, but line %d differs: expected %q, but actually got %q.", path, i+1, expectedLine, actualLine)
+			return
+		}
+	}
+}
+
+// FormatTranscript formats data sent over a TELNET connection as readable text, for golden files. Commands are written
+// by name in angle brackets (e.g. "<IAC WILL ECHO>"), and control characters and invalid UTF-8 escaped like Go strings
+// (e.g. "\r"). Lines are broken after each "\n".
+func FormatTranscript(data []byte) string {
+	var builder strings.Builder
+
+	for len(data) > 0 {
+		if data[0] != telnet.IAC {
+			r, size := utf8.DecodeRune(data)
+			writeData(&builder, r, data[:size])
+			data = data[size:]
+
+			continue
+		}
+
+		if len(data) > 1 && data[1] == telnet.IAC {
+			builder.WriteString(`\xff`)
+			data = data[2:]
+
+			continue
+		}
+
+		length := commandLength(data)
+		builder.WriteString("<" + formatCommand(data[:length]) + ">")
+		data = data[length:]
+	}
+
+	return builder.String()
+}
+
+// writeData writes the rune 'r', decoded from 'data', escaping it if needed.
+func writeData(builder *strings.Builder, r rune, data []byte) {
+	switch {
+	case r == '\n':
+		builder.WriteString("\\n\n")
+	case r == '\r':
+		builder.WriteString(`\r`)
+	case r == '\t':
+		builder.WriteString(`\t`)
+	case r == '\\':
+		builder.WriteString(`\\`)
+	case r == '<':
+		builder.WriteString(`\<`)
+	case r == utf8.RuneError && len(data) == 1, r < 0x20, r == 0x7f:
+		fmt.Fprintf(builder, `\x%02x`, data[0])
+	default:
+		builder.Write(data)
+	}
+}
+
+// commandLength returns the length of the command at the start of 'data', or of 'data' if it's cut off.
+func commandLength(data []byte) int {
+	if len(data) < 2 {
+		return len(data)
+	}
+
+	switch data[1] {
+	case telnet.WILL, telnet.WONT, telnet.DO, telnet.DONT:
+		return min(3, len(data))
+	case telnet.SB:
+		for i := 2; i < len(data)-1; i++ {
+			if data[i] != telnet.IAC {
+				continue
+			}
+
+			if data[i+1] == telnet.SE {
+				return i + 2
+			}
+
+			i++ // skip the escaped IAC
+		}
+
+		return len(data)
+	default:
+		return 2
+	}
+}
+
+// formatCommand names the bytes of 'command', e.g. "IAC DO NAWS". Subnegotiation payloads are written in hex.
+func formatCommand(command []byte) string {
+	words := make([]string, len(command))
+
+	for i, b := range command {
+		var name string
+		var ok bool
+
+		switch {
+		case i == 2 && len(command) > 2:
+			name, ok = optionNames[b]
+		case i < 2, i == len(command)-2 && b == telnet.IAC, i == len(command)-1 && b == telnet.SE:
+			name, ok = commandNames[b]
+		}
+
+		if !ok {
+			name = fmt.Sprintf("%02x", b)
+		}
+
+		words[i] = name
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,50 @@
+package telnettest
+
+import (
+	"testing"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+func TestFormatTranscript(t *testing.T) {
+	tests := []struct {
+		Data     []byte
+		Expected string
+	}{
+		{Data: []byte("Login: "), Expected: "Login: "},
+		{Data: []byte("a\r\nb\r\n"), Expected: "a\\r\\n\nb\\r\\n\n"},
+		{Data: []byte("<tab>\t\\"), Expected: `\<tab>\t\\`},
+		{Data: []byte("\x1b[31m\x00"), Expected: `\x1b[31m\x00`},
+		{Data: []byte("héllo \xc3"), Expected: `héllo \xc3`},
+		{Data: []byte{telnet.IAC, telnet.IAC}, Expected: `\xff`},
+		{Data: []byte{telnet.IAC, telnet.WILL, telnet.ECHO, 'a'}, Expected: "<IAC WILL ECHO>a"},
+		{Data: []byte{telnet.IAC, telnet.DO, 200}, Expected: "<IAC DO c8>"},
+		{Data: []byte{telnet.IAC, telnet.GA}, Expected: "<IAC GA>"},
+		{Data: []byte{telnet.IAC, telnet.SB, telnet.TTYPE, telnet.SEND, telnet.IAC, telnet.SE}, Expected: "<IAC SB TTYPE 01 IAC SE>"},
+		{Data: []byte{telnet.IAC, telnet.SB, telnet.NAWS, 0, telnet.IAC, telnet.IAC, 0, 24, telnet.IAC, telnet.SE}, Expected: "<IAC SB NAWS 00 ff ff 00 18 IAC SE>"},
+		{Data: []byte{telnet.IAC, telnet.SB, telnet.NAWS, 0}, Expected: "<IAC SB NAWS 00>"},
+		{Data: []byte{telnet.IAC}, Expected: "<IAC>"},
+	}
+
+	for testNumber, test := range tests {
+		if expected, actual := test.Expected, FormatTranscript(test.Data); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	handler := func(session *telnet.Session) {
+		_ = session.WriteString("Login: ")
+
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+
+		_, _ = session.WriteCommand(telnet.IAC, telnet.WILL, telnet.ECHO)
+		_ = session.WriteLine("Welcome, ", line)
+	}
+
+	Golden(t, "testdata/welcome.golden", handler, Expect("Login: "), Send("root\r\n"))
+}
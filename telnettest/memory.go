@@ -0,0 +1,251 @@
+package telnettest
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// memoryListener is a net.Listener accepting connections dialled in memory.
+	memoryListener struct {
+		addr   memoryAddr
+		conns  chan net.Conn
+		closed chan struct{}
+		once   sync.Once
+		dialed atomic.Int64 // the number of connections dialled, to give each an address
+	}
+
+	// memoryConn is one end of a connection held in memory. Unlike net.Pipe, writes are buffered rather than waiting
+	// for the other end to read them, like TCP, so both ends can write before reading.
+	memoryConn struct {
+		local  memoryAddr
+		remote memoryAddr
+		input  *buffer // written by the other end
+		output *buffer // read by the other end
+	}
+
+	// memoryAddr is the address of a memoryListener or memoryConn.
+	memoryAddr string
+
+	// buffer is the data sent one way through a memoryConn.
+	buffer struct {
+		mu       sync.Mutex
+		data     []byte
+		closed   bool      // whether the writing end closed, so reads return io.EOF once the data runs out
+		detached bool      // whether the reading end closed, so reads and writes fail
+		deadline time.Time // the read deadline
+		changed  chan struct{}
+	}
+)
+
+var listeners atomic.Int64
+
+func newMemoryListener() *memoryListener {
+	return &memoryListener{
+		addr:   memoryAddr("memory:" + strconv.FormatInt(listeners.Add(1), 10)),
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// Accept waits for the next connection to be dialled.
+func (l *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops the listener accepting connections.
+func (l *memoryListener) Close() error {
+	err := net.ErrClosed
+	l.once.Do(func() {
+		close(l.closed)
+		err = nil
+	})
+
+	return err
+}
+
+// Addr returns the listener's address.
+func (l *memoryListener) Addr() net.Addr {
+	return l.addr
+}
+
+// dial connects to the listener, returning the client's end of the connection.
+func (l *memoryListener) dial() (net.Conn, error) {
+	toServer, toClient := newBuffer(), newBuffer()
+	clientAddr := l.nextAddr()
+
+	client := &memoryConn{local: clientAddr, remote: l.addr, input: toClient, output: toServer}
+	server := &memoryConn{local: l.addr, remote: clientAddr, input: toServer, output: toClient}
+
+	return l.connect(client, server)
+}
+
+// nextAddr returns the address of the next client to dial the listener.
+func (l *memoryListener) nextAddr() memoryAddr {
+	return memoryAddr(string(l.addr) + "/" + strconv.FormatInt(l.dialed.Add(1), 10))
+}
+
+// connect hands the server's end of a new connection to Accept, returning the client's end.
+func (l *memoryListener) connect(client net.Conn, server net.Conn) (net.Conn, error) {
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, &net.OpError{Op: "dial", Net: "memory", Addr: l.addr, Err: net.ErrClosed}
+	}
+}
+
+func (c *memoryConn) Read(p []byte) (int, error) {
+	return c.input.read(p)
+}
+
+func (c *memoryConn) Write(p []byte) (int, error) {
+	return c.output.write(p)
+}
+
+// Close stops the connection reading and writing. The other end can still read what was already written, before
+// getting io.EOF.
+func (c *memoryConn) Close() error {
+	c.input.detach()
+	c.output.close()
+
+	return nil
+}
+
+// CloseWrite stops the connection writing, so the other end gets io.EOF once it's read what was already written. The
+// connection can still read.
+func (c *memoryConn) CloseWrite() error {
+	c.output.close()
+	return nil
+}
+
+func (c *memoryConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *memoryConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *memoryConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *memoryConn) SetReadDeadline(t time.Time) error {
+	c.input.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline does nothing, as writes never block.
+func (c *memoryConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (a memoryAddr) Network() string {
+	return "memory"
+}
+
+func (a memoryAddr) String() string {
+	return string(a)
+}
+
+func newBuffer() *buffer {
+	return &buffer{changed: make(chan struct{})}
+}
+
+// read reads the buffered data, waiting for some to be written if there's none.
+func (b *buffer) read(p []byte) (int, error) {
+	for {
+		b.mu.Lock()
+
+		switch {
+		case b.detached:
+			b.mu.Unlock()
+			return 0, net.ErrClosed
+		case len(b.data) > 0:
+			n := copy(p, b.data)
+			b.data = b.data[n:]
+			b.mu.Unlock()
+
+			return n, nil
+		case b.closed:
+			b.mu.Unlock()
+			return 0, io.EOF
+		case !b.deadline.IsZero() && !time.Now().Before(b.deadline):
+			b.mu.Unlock()
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		changed, deadline := b.changed, b.deadline
+		b.mu.Unlock()
+
+		if deadline.IsZero() {
+			<-changed
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case <-changed:
+		case <-timer.C:
+		}
+
+		timer.Stop()
+	}
+}
+
+// write appends 'p' to the buffered data, failing if the reading end has closed.
+func (b *buffer) write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || b.detached {
+		return 0, io.ErrClosedPipe
+	}
+
+	b.data = append(b.data, p...)
+	b.notify()
+
+	return len(p), nil
+}
+
+func (b *buffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.notify()
+}
+
+func (b *buffer) detach() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.detached = true
+	b.data = nil
+	b.notify()
+}
+
+func (b *buffer) setDeadline(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.deadline = t
+	b.notify()
+}
+
+// notify wakes any reads waiting for the buffer to change. b.mu must be held.
+func (b *buffer) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
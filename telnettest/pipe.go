@@ -0,0 +1,70 @@
+package telnettest
+
+import (
+	"net"
+)
+
+type (
+	// PipeListener is a net.Listener for telnet.Server.Serve whose connections are dialled in process over net.Pipe,
+	// so whole client and server flows run without the network. Unlike the connections of a memory server (see
+	// NewMemoryServer), each write blocks until the other end has read it, so the order of every exchange (e.g. a
+	// negotiation and its answer) is deterministic; but both ends mustn't write at once.
+	PipeListener struct {
+		listener *memoryListener
+	}
+
+	// pipeConn is one end of a net.Pipe, addressed like the memory server's connections (e.g. "memory:2") rather than
+	// net.Pipe's "pipe", so each client's address, and so its session's ID and logs, identify it.
+	pipeConn struct {
+		net.Conn
+		local  memoryAddr
+		remote memoryAddr
+	}
+)
+
+// NewLocalPipeListener returns a PipeListener, with an address like "memory:1". The caller should Close it when
+// finished.
+func NewLocalPipeListener() *PipeListener {
+	return &PipeListener{listener: newMemoryListener()}
+}
+
+// Accept waits for the next connection to be dialled.
+func (l *PipeListener) Accept() (net.Conn, error) {
+	return l.listener.Accept()
+}
+
+// Close stops the listener accepting connections. Connections already accepted are left open.
+func (l *PipeListener) Close() error {
+	return l.listener.Close()
+}
+
+// Addr returns the listener's address.
+func (l *PipeListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// Dial connects to the listener, returning the client's end of the connection once it's been accepted (e.g. for
+// telnet.NewConn).
+func (l *PipeListener) Dial() (net.Conn, error) {
+	client, server := net.Pipe()
+	clientAddr := l.listener.nextAddr()
+
+	conn, err := l.listener.connect(
+		&pipeConn{Conn: client, local: clientAddr, remote: l.listener.addr},
+		&pipeConn{Conn: server, local: l.listener.addr, remote: clientAddr},
+	)
+	if err != nil {
+		_ = client.Close()
+		_ = server.Close()
+	}
+
+	return conn, err
+}
+
+func (c *pipeConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr {
+	return c.remote
+}
@@ -0,0 +1,122 @@
+// Package telnettest provides utilities for testing TELNET handlers, like net/http/httptest does for HTTP handlers.
+package telnettest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// Server is a TELNET server listening on a local port or in memory, with a client already connected to it, for use in
+// tests.
+type Server struct {
+	// Addr is the address the server listens on, e.g. "127.0.0.1:54321", or "memory:1" for in-memory servers.
+	Addr string
+
+	// Listener is the server's listener.
+	Listener net.Listener
+
+	// Config is the server, configured by the options it was created with. Its exported fields may be changed after
+	// NewUnstartedServer or NewUnstartedMemoryServer, and before Start (e.g. to set a ConnCallback).
+	Config *telnet.Server
+
+	// Conn is a client connected to the server as it started. It's closed by Close.
+	Conn *telnet.Conn
+
+	dial func() (net.Conn, error)
+}
+
+// NewServer starts a server running 'handler' on a local TCP port, configured by 'opts' (e.g. telnet.WithClock), and
+// connects a client to it. The caller should call Close when finished, to shut it down.
+func NewServer(handler telnet.HandlerFunc, opts ...telnet.Option) *Server {
+	server := NewUnstartedServer(handler, opts...)
+	server.Start()
+
+	return server
+}
+
+// NewMemoryServer is like NewServer, but connects the server and its clients in memory, without using the network.
+func NewMemoryServer(handler telnet.HandlerFunc, opts ...telnet.Option) *Server {
+	server := NewUnstartedMemoryServer(handler, opts...)
+	server.Start()
+
+	return server
+}
+
+// NewUnstartedServer returns a server running 'handler' on a local TCP port, configured by 'opts', without starting
+// it, so its Config can be changed first. The caller should call Start, then Close when finished.
+func NewUnstartedServer(handler telnet.HandlerFunc, opts ...telnet.Option) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("telnettest: failed to listen on a port: %v", err))
+	}
+
+	return newServer(listener, handler, opts, func() (net.Conn, error) {
+		return net.Dial("tcp", listener.Addr().String())
+	})
+}
+
+// NewUnstartedMemoryServer is like NewUnstartedServer, but connects the server and its clients in memory, without using
+// the network.
+func NewUnstartedMemoryServer(handler telnet.HandlerFunc, opts ...telnet.Option) *Server {
+	listener := newMemoryListener()
+	return newServer(listener, handler, opts, listener.dial)
+}
+
+// newServer returns a server running 'handler' on 'listener', configured by 'opts', connecting clients with 'dial'.
+func newServer(listener net.Listener, handler telnet.HandlerFunc, opts []telnet.Option, dial func() (net.Conn, error)) *Server {
+	config := telnet.NewServer(append([]telnet.Option{telnet.WithHandler(handler)}, opts...)...)
+
+	return &Server{
+		Addr:     listener.Addr().String(),
+		Listener: listener,
+		Config:   config,
+		dial:     dial,
+	}
+}
+
+// Start starts the server, and connects Conn to it.
+func (s *Server) Start() {
+	if s.Conn != nil {
+		panic("telnettest: server already started")
+	}
+
+	go func() {
+		_ = s.Config.Serve(s.Listener)
+	}()
+
+	conn, err := s.Dial()
+	if err != nil {
+		_ = s.Listener.Close()
+		panic(fmt.Sprintf("telnettest: failed to connect to the server: %v", err))
+	}
+
+	s.Conn = conn
+}
+
+// Dial connects another client to the server.
+func (s *Server) Dial() (*telnet.Conn, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	return telnet.NewConn(conn), nil
+}
+
+// Close disconnects Conn and shuts the server down, cancelling the contexts of any sessions still running.
+func (s *Server) Close() {
+	if s.Conn != nil {
+		_ = s.Conn.Close()
+	}
+
+	// The listener may not have been handed to the server yet.
+	if err := s.Listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Error("failed to close telnettest listener", "err", err)
+	}
+
+	_ = s.Config.Shutdown()
+}
@@ -0,0 +1,276 @@
+package telnettest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+func TestServer(t *testing.T) {
+	handler := func(session *telnet.Session) {
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+
+		_ = session.WriteString("Hello, ", strings.TrimSpace(line), "!\r\n")
+	}
+
+	tests := []struct {
+		Name      string
+		NewServer func(telnet.HandlerFunc, ...telnet.Option) *Server
+	}{
+		{Name: "NewServer", NewServer: NewServer},
+		{Name: "NewMemoryServer", NewServer: NewMemoryServer},
+	}
+
+	for _, test := range tests {
+		server := test.NewServer(handler)
+
+		for i, conn := range []*telnet.Conn{server.Conn, dial(t, server)} {
+			if _, err := conn.Write([]byte("world\r\n")); err != nil {
+				t.Fatalf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+			}
+
+			// The session ends after replying, so the client reads until EOF.
+			output, err := io.ReadAll(conn)
+			if err != nil {
+				t.Errorf("For %s client #%d, did not expect an error, but actually got one: (%T) %v.", test.Name, i, err, err)
+			}
+
+			if expected, actual := "Hello, world!\r\n", string(output); expected != actual {
+				t.Errorf("For %s client #%d, expected %q, but actually got %q.", test.Name, i, expected, actual)
+			}
+		}
+
+		server.Close()
+
+		if _, err := server.Dial(); err == nil {
+			t.Errorf("For %s, expected an error dialling a closed server, but didn't get one.", test.Name)
+		}
+	}
+}
+
+func TestSession_CloseWrite(t *testing.T) {
+	tests := []struct {
+		Name      string
+		NewServer func(telnet.HandlerFunc, ...telnet.Option) *Server
+	}{
+		{Name: "NewServer", NewServer: NewServer},
+		{Name: "NewMemoryServer", NewServer: NewMemoryServer},
+	}
+
+	for _, test := range tests {
+		received := make(chan string, 1)
+
+		server := test.NewServer(func(session *telnet.Session) {
+			_ = session.WriteLine("bye")
+
+			if err := session.CloseWrite(); err != nil {
+				received <- err.Error()
+				return
+			}
+
+			// The client can still send once the session has stopped writing, until it half-closes too.
+			input, _ := io.ReadAll(session)
+			received <- string(input)
+		})
+
+		output, err := io.ReadAll(server.Conn)
+		if err != nil {
+			t.Errorf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+		}
+
+		if expected, actual := "bye\r\n", string(output); expected != actual {
+			t.Errorf("For %s, expected %q, but actually got %q.", test.Name, expected, actual)
+		}
+
+		if _, err := server.Conn.Write([]byte("ok")); err != nil {
+			t.Fatalf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+		}
+
+		if err := server.Conn.CloseWrite(); err != nil {
+			t.Fatalf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+		}
+
+		if expected, actual := "ok", <-received; expected != actual {
+			t.Errorf("For %s, expected %q, but actually got %q.", test.Name, expected, actual)
+		}
+
+		server.Close()
+	}
+}
+
+func TestSession_Hijack(t *testing.T) {
+	tests := []struct {
+		Name      string
+		NewServer func(telnet.HandlerFunc, ...telnet.Option) *Server
+	}{
+		{Name: "NewServer", NewServer: NewServer},
+		{Name: "NewMemoryServer", NewServer: NewMemoryServer},
+	}
+
+	for _, test := range tests {
+		hijacked := make(chan error, 1)
+
+		server := test.NewServer(func(session *telnet.Session) {
+			if _, err := session.ReadLine(); err != nil {
+				hijacked <- err
+				return
+			}
+
+			conn, reader, err := session.Hijack()
+			if err != nil {
+				hijacked <- err
+				return
+			}
+
+			_, err = session.Write([]byte("a"))
+			hijacked <- err
+
+			// The connection stays open after the handler returns, for as long as the hijacker needs it.
+			go func() {
+				defer conn.Close()
+
+				var buffer [4]byte
+				if _, err := io.ReadFull(reader, buffer[:]); err == nil {
+					_, _ = conn.Write(buffer[:])
+				}
+			}()
+		})
+
+		conn, err := server.dial()
+		if err != nil {
+			t.Fatalf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+		}
+
+		// The raw data after the line is read by the session before it's hijacked, so must be handed over.
+		if _, err := conn.Write([]byte("switch\r\nRAW\xff")); err != nil {
+			t.Fatalf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+		}
+
+		if err := <-hijacked; !errors.Is(err, telnet.ErrHijacked) {
+			t.Errorf("For %s, expected %v, but actually got: (%T) %v.", test.Name, telnet.ErrHijacked, err, err)
+		}
+
+		output, err := io.ReadAll(conn)
+		if err != nil {
+			t.Errorf("For %s, did not expect an error, but actually got one: (%T) %v.", test.Name, err, err)
+		}
+
+		if expected := []byte("RAW\xff"); !bytes.HasSuffix(output, expected) {
+			t.Errorf("For %s, expected output ending %q, but actually got %q.", test.Name, expected, output)
+		}
+
+		_ = conn.Close()
+		server.Close()
+	}
+}
+
+func TestMemoryConn_Deadline(t *testing.T) {
+	listener := newMemoryListener()
+	defer listener.Close()
+
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			_, _ = conn.Write([]byte("a"))
+		}
+	}()
+
+	conn, err := listener.dial()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	var buffer [2]byte
+
+	n, err := conn.Read(buffer[:])
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := []byte("a"), buffer[:n]; !bytes.Equal(expected, actual) {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	var netErr net.Error
+	if _, err = conn.Read(buffer[:]); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected a timeout, but actually got: (%T) %v.", err, err)
+	}
+}
+
+// dial connects another client to 'server'.
+func dial(t *testing.T, server *Server) *telnet.Conn {
+	conn, err := server.Dial()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	return conn
+}
+
+func TestPipeListener(t *testing.T) {
+	listener := NewLocalPipeListener()
+
+	// With a pipe, the server mustn't be writing while the client does, so it only writes when replying.
+	server := telnet.NewServer(telnet.WithHandler(func(session *telnet.Session) {
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+
+		_ = session.WriteString("Hello, ", line, " from ", session.ID(), "!\r\n")
+	}), telnet.WithInitialNegotiation(), telnet.WithoutGoAhead())
+
+	go server.Serve(listener)
+
+	ids := make(map[string]bool)
+
+	for i := 1; i <= 2; i++ {
+		conn, err := listener.Dial()
+		if err != nil {
+			t.Fatalf("For client #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		client := telnet.NewConn(conn)
+
+		if err = telnet.WriteLine(client, "world"); err != nil {
+			t.Fatalf("For client #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		output, err := io.ReadAll(client)
+		if err != nil {
+			t.Errorf("For client #%d, did not expect an error, but actually got one: (%T) %v.", i, err, err)
+		}
+
+		// Clients share the pipe's address, but the sessions' IDs are numbered to tell them apart.
+		if expected, actual := "Hello, world from "+conn.LocalAddr().String()+"#", string(output); !strings.HasPrefix(actual, expected) {
+			t.Errorf("For client #%d, expected %q..., but actually got %q.", i, expected, actual)
+		}
+
+		if ids[string(output)] {
+			t.Errorf("For client #%d, expected a new session ID, but actually got %q again.", i, output)
+		}
+
+		ids[string(output)] = true
+
+		client.Close()
+	}
+
+	if err := listener.Close(); err != nil {
+		t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if _, err := listener.Dial(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected %v dialling a closed listener, but actually got: (%T) %v.", net.ErrClosed, err, err)
+	}
+}
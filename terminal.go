@@ -0,0 +1,170 @@
+package telnet
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Subnegotiation commands shared by TTYPE and other options.
+const (
+	IS   byte = 0
+	SEND byte = 1
+)
+
+// maxTerminalTypes caps how many terminal types are requested from a client, in case it never repeats itself.
+const maxTerminalTypes = 8
+
+// MTTS is the bitmask of terminal capabilities advertised by MUD clients using the Mud Terminal Type Standard, sent as
+// their third terminal type (e.g. "MTTS 137").
+type MTTS int
+
+const (
+	MTTSANSI MTTS = 1 << iota
+	MTTSVT100
+	MTTSUTF8
+	MTTS256Colors
+	MTTSMouseTracking
+	MTTSOSCColorPalette
+	MTTSScreenReader
+	MTTSProxy
+	MTTSTrueColor
+	MTTSMNES
+	MTTSMSLP
+	MTTSSSL
+)
+
+// Has reports whether all the capabilities in 'flag' are set.
+func (m MTTS) Has(flag MTTS) bool {
+	return m&flag == flag
+}
+
+// terminalTypes tracks a TTYPE negotiation with the client.
+type terminalTypes struct {
+	names   []string
+	mtts    MTTS
+	hasMTTS bool
+	done    bool
+}
+
+// RequestTerminalTypes asks the client to send its terminal types. Replies are processed as the session reads from
+// the client; use AwaitTerminalTypes to wait for them.
+func (s *Session) RequestTerminalTypes() error {
+	if s.options.requested(TTYPE) {
+		return nil
+	}
+
+	_, err := s.WriteCommand(IAC, DO, TTYPE)
+	return err
+}
+
+// AwaitTerminalTypes requests the client's terminal types (if they haven't been already), and waits until the client
+// has sent them all, refused, or 'ctx' is done. Any data the client sends in the meantime is kept for the next Read.
+func (s *Session) AwaitTerminalTypes(ctx context.Context) ([]string, error) {
+	if err := s.RequestTerminalTypes(); err != nil {
+		return nil, err
+	}
+
+	if err := s.await(ctx, func() bool { return s.ttype.done }); err != nil {
+		return s.TerminalTypes(), err
+	}
+
+	return s.TerminalTypes(), nil
+}
+
+// TerminalTypes returns the terminal types the client has sent so far, in the order they were received.
+func (s *Session) TerminalTypes() []string {
+	return append([]string(nil), s.ttype.names...)
+}
+
+// MTTS returns the capabilities the client advertised using MTTS, if it did.
+func (s *Session) MTTS() (MTTS, bool) {
+	return s.ttype.mtts, s.ttype.hasMTTS
+}
+
+// ansiTerminals are the prefixes of terminal type names known to support ANSI escape codes.
+var ansiTerminals = []string{"ANSI", "CYGWIN", "KONSOLE", "LINUX", "PUTTY", "RXVT", "SCREEN", "TMUX", "VT100", "VT102",
+	"VT220", "VT320", "XTERM"}
+
+// SupportsANSI reports whether the client advertised support for ANSI escape codes (e.g. colors), either with MTTS or
+// with a terminal type known to support them (e.g. "XTERM-256COLOR"). Terminal types must have been requested first
+// (see AwaitTerminalTypes).
+func (s *Session) SupportsANSI() bool {
+	if mtts, ok := s.MTTS(); ok {
+		return mtts.Has(MTTSANSI)
+	}
+
+	for _, name := range s.ttype.names {
+		name = strings.ToUpper(name)
+
+		for _, prefix := range ansiTerminals {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// receivedTerminalTypeOption handles the client's response to DO TTYPE.
+func (s *Session) receivedTerminalTypeOption(verb byte) {
+	if !s.options.requested(TTYPE) || s.ttype.done {
+		return
+	}
+
+	switch verb {
+	case WILL:
+		s.sendTerminalTypeRequest()
+	case WONT:
+		s.ttype.done = true
+	}
+}
+
+// receivedTerminalType handles a TTYPE IS subnegotiation from the client.
+//
+// Clients cycle through their terminal types each time they're asked, and signal the end of the list by repeating
+// the last (or first) one.
+func (s *Session) receivedTerminalType(payload []byte) {
+	if len(payload) == 0 || payload[0] != IS || s.ttype.done {
+		return
+	}
+
+	name := string(payload[1:])
+	names := s.ttype.names
+
+	if len(names) > 0 && (name == names[len(names)-1] || name == names[0]) {
+		s.ttype.done = true
+		return
+	}
+
+	s.ttype.names = append(s.ttype.names, name)
+
+	if value, ok := strings.CutPrefix(name, "MTTS "); ok {
+		if bits, err := strconv.Atoi(value); err == nil {
+			s.ttype.mtts = MTTS(bits)
+			s.ttype.hasMTTS = true
+		}
+
+		// MTTS is always the last terminal type in the cycle.
+		s.ttype.done = true
+		return
+	}
+
+	if len(s.ttype.names) >= maxTerminalTypes {
+		s.ttype.done = true
+		return
+	}
+
+	s.sendTerminalTypeRequest()
+}
+
+// sendTerminalTypeRequest asks the client for its next terminal type.
+func (s *Session) sendTerminalTypeRequest() {
+	if _, err := s.Write(append(commandSignature(), IAC, SB, TTYPE, SEND, IAC, SE)); err != nil {
+		s.ttype.done = true
+		return
+	}
+
+	_ = s.Flush()
+}
@@ -0,0 +1,148 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestSession returns a session for the server end of a pipe, and the client end.
+func newTestSession(t *testing.T, server *Server) (*Session, net.Conn) {
+	t.Helper()
+
+	client, conn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+		conn.Close()
+	})
+
+	return server.newSession(serverConn{Conn: conn, ctx: ctx, cancel: cancel, hijacked: new(atomic.Bool)}), client
+}
+
+// expect reads len(expected) bytes from 'conn', failing the test if they don't match, and reports whether they did.
+// It's safe to call from goroutines other than the test's, which should return if it fails.
+func expect(t *testing.T, conn net.Conn, expected []byte) bool {
+	t.Helper()
+
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, actual); err != nil {
+		t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		return false
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Expected %v, but actually got %v.", expected, actual)
+		return false
+	}
+
+	return true
+}
+
+func TestSession_AwaitTerminalTypes(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	go func() {
+		if !expect(t, client, []byte{IAC, DO, TTYPE}) {
+			return
+		}
+
+		client.Write([]byte{IAC, WILL, TTYPE, 'a', 'b'})
+
+		for _, name := range []string{"MUDLET", "XTERM-256COLOR", "MTTS 261"} {
+			if !expect(t, client, []byte{IAC, SB, TTYPE, SEND, IAC, SE}) {
+				return
+			}
+
+			client.Write(append(append([]byte{IAC, SB, TTYPE, IS}, name...), IAC, SE))
+		}
+
+		client.Write([]byte("c"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	names, err := session.AwaitTerminalTypes(ctx)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := 3, len(names); expected != actual {
+		t.Fatalf("Expected %d terminal types, but actually got %d: %q.", expected, actual, names)
+	}
+
+	mtts, ok := session.MTTS()
+	if !ok || !mtts.Has(MTTSANSI|MTTSUTF8|MTTSTrueColor) || mtts.Has(MTTSVT100) {
+		t.Errorf("Expected MTTS 261, but actually got %d (%t).", mtts, ok)
+	}
+
+	// Data sent during the negotiation should still be readable.
+	p := make([]byte, 3)
+	if _, err = io.ReadFull(session, p); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := "abc", string(p); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func TestSession_AwaitTerminalTypesTimeout(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	go io.Copy(io.Discard, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := session.AwaitTerminalTypes(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", context.DeadlineExceeded, err, err)
+	}
+}
+
+func TestSession_SupportsANSI(t *testing.T) {
+	tests := []struct {
+		TerminalTypes terminalTypes
+		Expected      bool
+	}{
+		{
+			TerminalTypes: terminalTypes{},
+			Expected:      false,
+		},
+		{
+			TerminalTypes: terminalTypes{names: []string{"dumb"}},
+			Expected:      false,
+		},
+		{
+			TerminalTypes: terminalTypes{names: []string{"xterm-256color"}},
+			Expected:      true,
+		},
+		{
+			TerminalTypes: terminalTypes{names: []string{"UNKNOWN", "VT100"}},
+			Expected:      true,
+		},
+		{
+			TerminalTypes: terminalTypes{names: []string{"MUDLET", "XTERM", "MTTS 9"}, mtts: MTTSANSI | MTTSUTF8, hasMTTS: true},
+			Expected:      true,
+		},
+		{
+			TerminalTypes: terminalTypes{names: []string{"MUDLET", "XTERM", "MTTS 0"}, hasMTTS: true},
+			Expected:      false,
+		},
+	}
+
+	for testNumber, test := range tests {
+		session := &Session{ttype: test.TerminalTypes}
+
+		if expected, actual := test.Expected, session.SupportsANSI(); expected != actual {
+			t.Errorf("For test #%d, expected %t, but actually got %t.", testNumber, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package telnet
+
+import (
+	"context"
+	"io"
+)
+
+// transferBufferSize is the size of the chunks files are sent and received in.
+const transferBufferSize = 32 * 1024
+
+// TransferOptions configures Session.SendFile and Session.ReceiveFile. The zero value transfers files of any size.
+type TransferOptions struct {
+	// MaxSize, if set, is the largest file that may be transferred, in bytes, beyond which the transfer fails with
+	// ErrTransferTooLarge.
+	MaxSize int64
+
+	// Progress, if set, is called with the number of bytes transferred so far after each chunk.
+	Progress func(transferred int64)
+}
+
+// SendFile streams what's read from 'r' to the client until EOF, in a binary window (see StartBinaryWindow) so the
+// data reaches the client untouched, returning the number of bytes sent. It waits for the client to agree to BINARY
+// first, failing with ErrOptionRefused if it won't, and ends the window afterwards, even if sending fails. Cancelling
+// 'ctx' stops the transfer.
+func (s *Session) SendFile(ctx context.Context, r io.Reader, opts *TransferOptions) (n int64, err error) {
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+
+	if err = s.openBinaryWindow(ctx, s.options.local); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if endErr := s.EndBinaryWindow(); err == nil {
+			err = endErr
+		}
+	}()
+
+	buffer := make([]byte, transferBufferSize)
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return n, err
+		}
+
+		read, readErr := r.Read(buffer)
+		if read > 0 {
+			if opts.MaxSize > 0 && n+int64(read) > opts.MaxSize {
+				return n, ErrTransferTooLarge
+			}
+
+			written, err := s.Write(buffer[:read])
+			if n += int64(written); err != nil {
+				return n, err
+			}
+
+			if opts.Progress != nil {
+				opts.Progress(n)
+			}
+		}
+
+		if readErr == io.EOF {
+			return n, s.Flush()
+		}
+
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
+
+// ReceiveFile reads 'size' bytes from the client (e.g. as announced by the client beforehand) in a binary window (see
+// StartBinaryWindow), writing them to 'w', and returns the number of bytes received. It fails straight away with
+// ErrTransferTooLarge if 'size' exceeds the options' MaxSize, or with ErrOptionRefused if the client won't agree to
+// BINARY, and ends the window afterwards, even if receiving fails. Cancelling 'ctx' stops the transfer.
+func (s *Session) ReceiveFile(ctx context.Context, w io.Writer, size int64, opts *TransferOptions) (n int64, err error) {
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return 0, ErrTransferTooLarge
+	}
+
+	if err = s.openBinaryWindow(ctx, s.options.remote); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if endErr := s.EndBinaryWindow(); err == nil {
+			err = endErr
+		}
+	}()
+
+	buffer := make([]byte, transferBufferSize)
+
+	for n < size {
+		chunk := buffer
+		if remaining := size - n; remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+
+		read, err := s.ReadContext(ctx, chunk)
+		if read > 0 {
+			written, writeErr := w.Write(chunk[:read])
+			if n += int64(written); writeErr != nil {
+				return n, writeErr
+			}
+
+			if opts.Progress != nil {
+				opts.Progress(n)
+			}
+		}
+
+		if err == io.EOF {
+			return n, io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// openBinaryWindow starts a binary window, and waits for the client to answer, checking with 'agreed' (the options'
+// local or remote) that BINARY is in effect in the direction the transfer needs. If it isn't, the window is ended.
+func (s *Session) openBinaryWindow(ctx context.Context, agreed func(option byte) bool) error {
+	if err := s.StartBinaryWindow(); err != nil {
+		return err
+	}
+
+	if err := s.await(ctx, func() bool { return !s.options.pending(BINARY) }); err != nil {
+		_ = s.EndBinaryWindow()
+		return err
+	}
+
+	if !agreed(BINARY) {
+		_ = s.EndBinaryWindow()
+		return ErrOptionRefused
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSession_SendFile(t *testing.T) {
+	session, client := newTestSession(t, NewServer(WithNewlinePolicy(NewlineCRLF)))
+
+	file := []byte{'a', '\n', IAC, 'b'}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var progress []int64
+	sent := make(chan error, 1)
+
+	go func() {
+		n, err := session.SendFile(ctx, bytes.NewReader(file), &TransferOptions{Progress: func(transferred int64) {
+			progress = append(progress, transferred)
+		}})
+		if err == nil && n != int64(len(file)) {
+			t.Errorf("Expected %d bytes to be sent, but actually got %d.", len(file), n)
+		}
+
+		sent <- err
+	}()
+
+	expect(t, client, []byte{IAC, WILL, BINARY, IAC, DO, BINARY})
+	client.Write([]byte{IAC, DO, BINARY, IAC, WILL, BINARY})
+
+	// The file arrives untouched (apart from IAC being doubled), and the window is ended afterwards.
+	expect(t, client, []byte{'a', '\n', IAC, IAC, 'b', IAC, WONT, BINARY, IAC, DONT, BINARY})
+
+	if err := <-sent; err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if len(progress) != 1 || progress[0] != int64(len(file)) {
+		t.Errorf("Expected progress of %d bytes, but actually got %v.", len(file), progress)
+	}
+}
+
+func TestSession_ReceiveFile(t *testing.T) {
+	tests := []struct {
+		Reply    []byte
+		Size     int64
+		Options  *TransferOptions
+		Expected []byte
+		Err      error
+	}{
+		{
+			Reply:    []byte{IAC, DO, BINARY, IAC, WILL, BINARY, '\r', IAC, IAC, 'x', 'y'},
+			Size:     3,
+			Expected: []byte{'\r', IAC, 'x'},
+		},
+		{
+			Size:    3,
+			Options: &TransferOptions{MaxSize: 2},
+			Err:     ErrTransferTooLarge,
+		},
+		{
+			Reply: []byte{IAC, DO, BINARY, IAC, WONT, BINARY},
+			Size:  3,
+			Err:   ErrOptionRefused,
+		},
+	}
+
+	for testNumber, test := range tests {
+		session, client := newTestSession(t, NewServer(WithParseMode(ParseStrict)))
+
+		if test.Reply != nil {
+			go func() {
+				if !expect(t, client, []byte{IAC, WILL, BINARY, IAC, DO, BINARY}) {
+					return
+				}
+
+				client.Write(test.Reply)
+				expect(t, client, []byte{IAC, WONT, BINARY, IAC, DONT, BINARY})
+			}()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+		var received bytes.Buffer
+		_, err := session.ReceiveFile(ctx, &received, test.Size, test.Options)
+		cancel()
+
+		if !errors.Is(err, test.Err) {
+			t.Errorf("For test #%d, expected %v, but actually got: (%T) %v.", testNumber, test.Err, err, err)
+			continue
+		}
+
+		if expected, actual := string(test.Expected), received.String(); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
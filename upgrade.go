@@ -0,0 +1,241 @@
+package telnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+)
+
+// ListenerEnv is the environment variable Server.Upgrade uses to tell the process it starts which file descriptor its
+// listener was handed over on (see InheritedListener).
+const ListenerEnv = "TELNET_LISTENER_FD"
+
+// ReadyEnv is the environment variable Server.UpgradeAndWait uses to tell the process it starts which file descriptor
+// to report it's ready on (see Ready).
+const ReadyEnv = "TELNET_READY_FD"
+
+// ListenerFile returns a copy of the server's listening socket as a file, to hand over to another process (e.g. a new
+// version of the program), which can serve it with ServeFile. The server keeps accepting connections on its own copy
+// until it's shut down (see Drain). The caller should close the file once it's been handed over.
+func (server *Server) ListenerFile() (*os.File, error) {
+	listener := server.currentListener()
+	if listener == nil {
+		return nil, ErrNotListening
+	}
+
+	fileListener, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("failed to hand over %T listener: it has no file", listener)
+	}
+
+	return fileListener.File()
+}
+
+// ServeFile is like Serve, accepting connections on the listening socket 'file', e.g. one inherited from the process
+// that started this one (see InheritedListener). The file is closed once it's been turned into a listener.
+func (server *Server) ServeFile(file *os.File) error {
+	listener, err := net.FileListener(file)
+	_ = file.Close()
+
+	if err != nil {
+		return fmt.Errorf("failed to listen on inherited socket: %w", err)
+	}
+
+	return server.Serve(listener)
+}
+
+// Upgrade starts 'cmd' (e.g. a new version of the program, typically on SIGUSR2) with a copy of the server's listening
+// socket, for it to serve with InheritedListener and ServeFile, so the service is upgraded without clients being
+// turned away while it restarts. Both processes accept connections until the caller stops this one, usually with Drain
+// once the new process is ready (see UpgradeAndWait), letting existing sessions finish.
+func (server *Server) Upgrade(cmd *exec.Cmd) error {
+	file, err := server.ListenerFile()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// ExtraFiles start at file descriptor 3, after stdin, stdout and stderr.
+	cmd.ExtraFiles = append(cmd.ExtraFiles, file)
+	fd := 2 + len(cmd.ExtraFiles)
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, ListenerEnv+"="+strconv.Itoa(fd))
+
+	return cmd.Start()
+}
+
+// UpgradeAndWait is like Upgrade, but waits for 'cmd' to call Ready, so the caller knows it's safe to stop this
+// process. If 'cmd' exits first, ErrNotReady is returned, and if 'ctx' is done first, 'cmd' is killed and ctx.Err() is
+// returned. Either way, this process should carry on serving.
+func (server *Server) UpgradeAndWait(ctx context.Context, cmd *exec.Cmd) error {
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create readiness pipe: %w", err)
+	}
+	defer readyReader.Close()
+
+	cmd.ExtraFiles = append(cmd.ExtraFiles, readyWriter)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, ReadyEnv+"="+strconv.Itoa(2+len(cmd.ExtraFiles)))
+
+	err = server.Upgrade(cmd)
+
+	// Only the new process keeps the write end open, so reading it ends once it's ready or exits.
+	_ = readyWriter.Close()
+
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan bool, 1)
+	go func() {
+		n, _ := readyReader.Read(make([]byte, 1))
+		ready <- n == 1
+	}()
+
+	select {
+	case ok := <-ready:
+		if !ok {
+			_ = cmd.Wait()
+			return ErrNotReady
+		}
+
+		return nil
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return ctx.Err()
+	}
+}
+
+// UpgradeOnSignal waits for one of 'signals' (typically syscall.SIGUSR2), then upgrades the server to the process
+// returned by 'newCmd' with UpgradeAndWait, and drains it with Drain once the new process is ready. If 'newCmd' is nil,
+// the program re-executes itself with the same arguments. If an upgrade fails, it's logged and the server carries on
+// serving, waiting for the next signal. It returns once the server's drained, or 'ctx' is done.
+func (server *Server) UpgradeOnSignal(ctx context.Context, newCmd func() *exec.Cmd, signals ...os.Signal) error {
+	signalled := make(chan os.Signal, 1)
+	signal.Notify(signalled, signals...)
+	defer signal.Stop(signalled)
+
+	return server.upgradeOn(ctx, signalled, newCmd)
+}
+
+// upgradeOn is UpgradeOnSignal, upgrading each time 'signalled' receives.
+func (server *Server) upgradeOn(ctx context.Context, signalled <-chan os.Signal, newCmd func() *exec.Cmd) error {
+	if newCmd == nil {
+		newCmd = reexec
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sig := <-signalled:
+			logger := server.serverLogger()
+			logger.Info("upgrading server", "signal", sig.String())
+
+			if err := server.UpgradeAndWait(ctx, newCmd()); err != nil {
+				logger.Error("failed to upgrade server", "err", err)
+				continue
+			}
+
+			return server.Drain(ctx)
+		}
+	}
+}
+
+// reexec returns a command re-executing the program with the same arguments, sharing its standard streams.
+func reexec() *exec.Cmd {
+	path, err := os.Executable()
+	if err != nil {
+		path = os.Args[0]
+	}
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	return cmd
+}
+
+// Ready tells the process that started this one with Server.UpgradeAndWait that it's ready to take over, e.g. once
+// it's called InheritedListener (connections queue on the inherited socket until ServeFile accepts them). It does
+// nothing if this process wasn't started that way. Like InheritedListener, it removes ReadyEnv from the environment.
+func Ready() error {
+	value, ok := os.LookupEnv(ReadyEnv)
+	if !ok {
+		return nil
+	}
+
+	_ = os.Unsetenv(ReadyEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 3 {
+		return fmt.Errorf("failed to parse %s %q: not a file descriptor", ReadyEnv, value)
+	}
+
+	file := os.NewFile(uintptr(fd), "telnet-ready")
+	defer file.Close()
+
+	if _, err = file.Write([]byte{1}); err != nil {
+		return fmt.Errorf("failed to report readiness: %w", err)
+	}
+
+	return nil
+}
+
+// InheritedListener returns the listening socket handed over to this process by Server.Upgrade, for ServeFile, or nil
+// if there isn't one (e.g. on first start, when the program should listen itself). It removes ListenerEnv from the
+// environment, so the socket isn't mistakenly claimed again by processes this one starts.
+func InheritedListener() (*os.File, error) {
+	value, ok := os.LookupEnv(ListenerEnv)
+	if !ok {
+		return nil, nil
+	}
+
+	_ = os.Unsetenv(ListenerEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 3 {
+		return nil, fmt.Errorf("failed to parse %s %q: not a file descriptor", ListenerEnv, value)
+	}
+
+	return os.NewFile(uintptr(fd), "telnet-listener"), nil
+}
+
+// Drain stops the server accepting connections, and waits for the sessions it's serving to finish, e.g. after handing
+// its listener over to a new process with Upgrade. If 'ctx' is done first, the remaining sessions are ended as with
+// Shutdown (without waiting for their handlers to return), and ctx.Err() is returned.
+func (server *Server) Drain(ctx context.Context) error {
+	if err := server.closeListener(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.handlesMu.Lock()
+		for _, cancel := range server.handles {
+			cancel()
+		}
+		server.handlesMu.Unlock()
+
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,297 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// upgradeHelperEnv is set for the process TestServer_Upgrade starts, to run TestUpgradeHelperProcess.
+const upgradeHelperEnv = "TELNET_TEST_UPGRADE_HELPER"
+
+func TestServer_Drain(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	server := NewServer(WithHandler(func(session *Session) {
+		line, _ := session.ReadLine()
+		_ = session.WriteLine("bye ", line)
+	}), WithInitialNegotiation(), WithoutGoAhead())
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	// Make sure the connection's been accepted before draining.
+	if _, err = conn.Write([]byte("a")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	for server.Stats().BytesRead == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- server.Drain(context.Background())
+	}()
+
+	select {
+	case err = <-drained:
+		t.Fatalf("Expected Drain to wait for the session, but it returned: %v.", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err = net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Errorf("Expected new connections to be refused while draining, but one wasn't.")
+	}
+
+	if _, err = conn.Write([]byte("pple\r\n")); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	expect(t, conn, []byte("bye apple\r\n"))
+
+	select {
+	case err = <-drained:
+		if err != nil {
+			t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected Drain to return once the session finished, but it didn't.")
+	}
+}
+
+func TestServer_ServeFile(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	old := serveOld(t, listener)
+
+	file, err := old.ListenerFile()
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	served := make(chan struct{}, 1)
+	upgraded := NewServer(WithHandler(func(session *Session) {
+		_ = session.WriteLine("new")
+		served <- struct{}{}
+	}), WithInitialNegotiation())
+
+	go upgraded.ServeFile(file)
+	defer upgraded.Shutdown()
+
+	if err = old.Drain(context.Background()); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	expect(t, conn, []byte("new\r\n"))
+	<-served
+}
+
+func TestServer_Upgrade(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Listening sockets can't be handed to other processes on Windows.")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if err = NewServer().Upgrade(exec.Command(os.Args[0])); err != ErrNotListening {
+		t.Errorf("Expected %v, but actually got: (%T) %v.", ErrNotListening, err, err)
+	}
+
+	server := serveOld(t, listener)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeHelperProcess$")
+	cmd.Env = append(os.Environ(), upgradeHelperEnv+"=1")
+
+	if err = server.Upgrade(cmd); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	if err = server.Drain(context.Background()); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	// The listener is still open in the new process, which serves the connection.
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	expect(t, conn, []byte("new\r\n"))
+}
+
+func TestServer_UpgradeOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Listening sockets can't be handed to other processes on Windows.")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	server := serveOld(t, listener)
+
+	// The first new process exits without calling Ready, so the server carries on until the second is ready.
+	var cmds []*exec.Cmd
+	newCmd := func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		if len(cmds) > 0 {
+			cmd = exec.Command(os.Args[0], "-test.run=^TestUpgradeHelperProcess$")
+			cmd.Env = append(os.Environ(), upgradeHelperEnv+"=ready")
+		}
+
+		cmds = append(cmds, cmd)
+		return cmd
+	}
+
+	defer func() {
+		for _, cmd := range cmds[1:] {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		}
+	}()
+
+	signalled := make(chan os.Signal)
+	upgraded := make(chan error, 1)
+	go func() {
+		upgraded <- server.upgradeOn(context.Background(), signalled, newCmd)
+	}()
+
+	signalled <- os.Interrupt
+	signalled <- os.Interrupt
+
+	select {
+	case err = <-upgraded:
+		if err != nil {
+			t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected the server to be upgraded, but it wasn't.")
+	}
+
+	if expected, actual := 2, len(cmds); expected != actual {
+		t.Errorf("Expected %d upgrades, but actually got %d.", expected, actual)
+	}
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	expect(t, conn, []byte("new\r\n"))
+}
+
+func TestServer_UpgradeOnSignal_Literal(t *testing.T) {
+	// A Server literal has no logger, and isn't serving, so the upgrade fails and is logged to slog.Default().
+	server := &Server{Handler: EchoHandler}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signalled := make(chan os.Signal)
+	upgraded := make(chan error, 1)
+	go func() {
+		upgraded <- server.upgradeOn(ctx, signalled, func() *exec.Cmd {
+			return exec.Command(os.Args[0], "-test.run=^$")
+		})
+	}()
+
+	// Once the second signal's received, the first upgrade has failed without ending upgradeOn.
+	signalled <- os.Interrupt
+	signalled <- os.Interrupt
+	cancel()
+
+	select {
+	case err := <-upgraded:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected %v, but actually got: (%T) %v.", context.Canceled, err, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected upgradeOn to return once the context was done, but it didn't.")
+	}
+}
+
+// serveOld serves 'listener' with a server answering "old", returning once it's served a connection, so Serve has
+// started.
+func serveOld(t *testing.T, listener net.Listener) *Server {
+	t.Helper()
+
+	served := make(chan struct{}, 1)
+	server := NewServer(WithHandler(func(session *Session) {
+		_ = session.WriteLine("old")
+		served <- struct{}{}
+	}), WithInitialNegotiation())
+
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+	defer conn.Close()
+
+	expect(t, conn, []byte("old\r\n"))
+	<-served
+
+	return server
+}
+
+// TestUpgradeHelperProcess is the new process started by TestServer_Upgrade, serving the listener it inherited.
+func TestUpgradeHelperProcess(t *testing.T) {
+	if os.Getenv(upgradeHelperEnv) == "" {
+		return
+	}
+
+	file, err := InheritedListener()
+	if err != nil || file == nil {
+		os.Exit(1)
+	}
+
+	if os.Getenv(upgradeHelperEnv) == "ready" {
+		if err = Ready(); err != nil {
+			os.Exit(1)
+		}
+	}
+
+	server := NewServer(WithHandler(func(session *Session) {
+		_ = session.WriteLine("new")
+	}), WithInitialNegotiation())
+
+	_ = server.ServeFile(file)
+	os.Exit(0)
+}
@@ -0,0 +1,104 @@
+package telnet
+
+import (
+	"context"
+	"encoding/binary"
+)
+
+// NAWS is the TELNET option clients use to send their window size (RFC 1073).
+const NAWS byte = 31
+
+// windowSize tracks the window size the client has sent with NAWS; it's guarded by Session.windowMu. Whether it's been
+// asked for is tracked by the session's options.
+type windowSize struct {
+	width    int
+	height   int
+	received bool
+	done     bool // set once the client has sent its window size, or refused to
+	onChange func(width int, height int)
+}
+
+// RequestWindowSize asks the client to send its window size, which it then sends again whenever the window is
+// resized. It can be called at any time, including from another goroutine while the handler is reading: replies are
+// processed as the session reads from the client without disturbing its other input, and AwaitWindowSize waits for
+// them.
+func (s *Session) RequestWindowSize() error {
+	if s.options.requested(NAWS) {
+		return nil
+	}
+
+	_, err := s.WriteCommand(IAC, DO, NAWS)
+	if err == nil {
+		err = s.Flush()
+	}
+
+	return err
+}
+
+// AwaitWindowSize requests the client's window size (if it hasn't been already), and waits until the client has sent
+// it, refused, or 'ctx' is done. Any data the client sends in the meantime is kept for the next Read.
+func (s *Session) AwaitWindowSize(ctx context.Context) (width int, height int, err error) {
+	if err = s.RequestWindowSize(); err != nil {
+		return 0, 0, err
+	}
+
+	err = s.await(ctx, func() bool {
+		s.windowMu.Lock()
+		defer s.windowMu.Unlock()
+
+		return s.window.done
+	})
+	width, height, _ = s.WindowSize()
+
+	return width, height, err
+}
+
+// WindowSize returns the latest window size the client has sent, in characters, and whether it has sent one. Either
+// dimension may be 0 if the client doesn't know it.
+func (s *Session) WindowSize() (width int, height int, ok bool) {
+	s.windowMu.Lock()
+	defer s.windowMu.Unlock()
+
+	return s.window.width, s.window.height, s.window.received
+}
+
+// OnWindowSize sets 'f' to be called with each window size the client sends (see RequestWindowSize), e.g. to pass
+// resizes on to a program the session is relaying. It's called while the session is reading from the client.
+func (s *Session) OnWindowSize(f func(width int, height int)) {
+	s.windowMu.Lock()
+	defer s.windowMu.Unlock()
+
+	s.window.onChange = f
+}
+
+// receivedWindowSizeOption handles the client's response to DO NAWS.
+func (s *Session) receivedWindowSizeOption(verb byte) {
+	if verb == WONT {
+		s.windowMu.Lock()
+		s.window.done = true
+		s.windowMu.Unlock()
+	}
+}
+
+// receivedWindowSize handles a NAWS subnegotiation from the client: its width and height, as 16-bit big-endian
+// integers.
+func (s *Session) receivedWindowSize(payload []byte) {
+	if len(payload) != 4 {
+		return
+	}
+
+	width, height := int(binary.BigEndian.Uint16(payload)), int(binary.BigEndian.Uint16(payload[2:]))
+
+	s.windowMu.Lock()
+	s.window.width, s.window.height = width, height
+	s.window.received = true
+	s.window.done = true
+	onChange := s.window.onChange
+	s.windowMu.Unlock()
+
+	if onChange != nil {
+		onChange(width, height)
+	}
+
+	s.signal(Signal{Command: NAWS, Width: width, Height: height})
+}
@@ -0,0 +1,96 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSession_AwaitWindowSize(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	go func() {
+		if !expect(t, client, []byte{IAC, DO, NAWS}) {
+			return
+		}
+
+		// A width of 511 has an IAC byte, which is escaped.
+		client.Write([]byte{IAC, WILL, NAWS, IAC, SB, NAWS, 1, IAC, IAC, 0, 50, IAC, SE, 'a'})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	width, height, err := session.AwaitWindowSize(ctx)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if width != 511 || height != 50 {
+		t.Errorf("Expected 511x50, but actually got %dx%d.", width, height)
+	}
+
+	if _, _, ok := session.WindowSize(); !ok {
+		t.Errorf("Expected the window size to have been received, but it wasn't.")
+	}
+
+	// Data sent alongside the negotiation is kept for the next read.
+	buffer := make([]byte, 1)
+	if _, err = session.Read(buffer); err != nil || buffer[0] != 'a' {
+		t.Errorf("Expected %q, but actually got %q (%v).", "a", buffer, err)
+	}
+}
+
+func TestSession_OnWindowSize(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	var sizes [][2]int
+	session.OnWindowSize(func(width int, height int) {
+		sizes = append(sizes, [2]int{width, height})
+	})
+
+	go client.Write([]byte{IAC, SB, NAWS, 0, 80, 0, 24, IAC, SE, IAC, SB, NAWS, 0, 100, 0, 40, IAC, SE, 'a'})
+
+	buffer := make([]byte, 1)
+	if _, err := session.Read(buffer); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := [][2]int{{80, 24}, {100, 40}}, sizes; len(actual) != 2 || expected[0] != actual[0] || expected[1] != actual[1] {
+		t.Errorf("Expected %v, but actually got %v.", expected, actual)
+	}
+}
+
+func TestSession_RequestWindowSizeWhileReading(t *testing.T) {
+	session, client := newTestSession(t, &Server{})
+
+	read := make(chan string, 1)
+	go func() {
+		buffer := make([]byte, 2)
+		n, _ := session.Read(buffer)
+		read <- string(buffer[:n])
+	}()
+
+	// The request is sent straight away, even though the handler is blocked reading, and doesn't disturb its input.
+	go func() {
+		if err := session.RequestWindowSize(); err != nil {
+			t.Errorf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}()
+
+	expect(t, client, []byte{IAC, DO, NAWS})
+
+	if err := session.RequestWindowSize(); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	client.Write([]byte{IAC, WILL, NAWS, IAC, SB, NAWS, 0, 80, 0, 24, IAC, SE, 'a', 'b'})
+
+	if expected, actual := "ab", <-read; expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	if width, height, ok := session.WindowSize(); !ok || width != 80 || height != 24 {
+		t.Errorf("Expected 80x24, but actually got %dx%d (%t).", width, height, ok)
+	}
+}
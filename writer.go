@@ -2,9 +2,11 @@ package telnet
 
 import (
 	"bytes"
-	"errors"
+	"context"
+	"fmt"
 	"io"
 	"strings"
+	"sync"
 )
 
 // writer handles escaping data according to the TELNET and TELNETS protocols.
@@ -27,9 +29,39 @@ import (
 //
 // writer automatically handles this escaping process for you.
 type writer struct {
-	writer io.Writer
+	ctx      context.Context // optional; cancels blocked writes when done
+	writer   io.Writer
+	newline  NewlinePolicy
+	binary   bool // Set while we're transmitting in BINARY mode; disables newline translation.
+	linemode bool // Set while the client is in LINEMODE; bare LFs are translated even under NewlineRaw.
+	last     byte // The last data byte written, used to detect CR LF pairs split across writes.
 }
 
+// maxPooledBufferSize caps the size of escape buffers returned to writeBufferPool.
+const maxPooledBufferSize = 64 * 1024
+
+// writeBufferPool holds the buffers writers escape data into, so escaping doesn't allocate on every write.
+var writeBufferPool = sync.Pool{
+	New: func() any {
+		buffer := make([]byte, 0, 512)
+		return &buffer
+	},
+}
+
+// NewlinePolicy controls how line endings in outgoing data are translated before being escaped.
+type NewlinePolicy int
+
+const (
+	// NewlineRaw writes data as-is, without translating line endings.
+	NewlineRaw NewlinePolicy = iota
+
+	// NewlineLFToCRLF translates every LF into CR LF.
+	NewlineLFToCRLF
+
+	// NewlineCRLF translates bare LFs into CR LF, and passes existing CR LF sequences through untouched.
+	NewlineCRLF
+)
+
 // newWriter creates a new writer that writes to 'w'.
 //
 // 'w' will receive the data written to the writer, but escaped according to
@@ -53,65 +85,141 @@ func newWriter(w io.Writer) *writer {
 }
 
 // Write writes the TELNET (and TELNETS) escaped data for of the data in 'data' to the writer io.Writer.
+//
+// The returned count is the number of bytes from 'data' that were fully written, regardless of how many bytes the
+// escaping and newline translation added.
 func (w *writer) Write(data []byte) (n int, err error) {
-	var buffer bytes.Buffer
-
 	// Workaround for commands.
-	if len(data) > 5 && bytes.Equal(data[0:4], commandSignature()) {
-		numWritten, err := LongWrite(w.writer, data[4:])
+	if isCommand(data) {
+		numWritten, err := w.longWrite(data[4:])
 		return int(numWritten), err
 	}
 
-	for _, value := range data {
-		if value != IAC {
-			buffer.WriteByte(value)
-			continue
-		}
+	if len(data) == 0 {
+		return 0, nil
+	}
 
-		// Write buffered data first if there's any.
-		if buffer.Len() > 0 {
-			numWritten, err := LongWrite(w.writer, buffer.Bytes())
-			n += int(numWritten)
-			if err != nil {
-				return n, err
-			}
-			buffer.Reset()
-		}
+	policy := w.policy()
 
-		// Write escape IAC sequence.
-		numWritten, err := LongWrite(w.writer, w.escapeIAC())
+	// Fast path: nothing needs escaping or translating, so the data can be handed over untouched.
+	if bytes.IndexByte(data, IAC) < 0 && (policy == NewlineRaw || bytes.IndexByte(data, NL) < 0) {
+		numWritten, err := w.longWrite(data)
 		if err != nil {
+			n = int(numWritten)
+			if n > 0 {
+				w.last = data[n-1]
+			}
+
 			return n, err
 		}
 
-		if int(numWritten) != len(w.escapeIAC()) {
-			return n, errors.New("partial IAC IAC write")
+		w.last = data[len(data)-1]
+
+		return len(data), nil
+	}
+
+	bufferPtr := writeBufferPool.Get().(*[]byte)
+	buffer := (*bufferPtr)[:0]
+
+	last := w.last
+	for _, value := range data {
+		switch {
+		case value == IAC:
+			buffer = append(buffer, IAC, IAC)
+		case value == NL && (policy == NewlineLFToCRLF || (policy == NewlineCRLF && last != CR)):
+			buffer = append(buffer, CR, NL)
+		default:
+			buffer = append(buffer, value)
 		}
 
-		n++
+		last = value
 	}
 
-	// Write any remaining buffered data
-	if buffer.Len() > 0 {
-		numWritten, err := LongWrite(w.writer, buffer.Bytes())
-		n += int(numWritten)
-		if err != nil {
-			return n, err
+	numWritten, err := w.longWrite(buffer)
+
+	// Don't hold on to unusually large buffers.
+	if cap(buffer) <= maxPooledBufferSize {
+		*bufferPtr = buffer
+		writeBufferPool.Put(bufferPtr)
+	}
+
+	if err != nil {
+		n = w.consumed(data, policy, int(numWritten))
+		if n > 0 {
+			w.last = data[n-1]
 		}
+
+		return n, err
+	}
+
+	w.last = last
+
+	return len(data), nil
+}
+
+// longWrite writes 'p' to the underlying writer, observing the writer's context if it has one.
+func (w *writer) longWrite(p []byte) (int64, error) {
+	if w.ctx != nil {
+		return LongWriteContext(w.ctx, w.writer, p)
+	}
+
+	return LongWrite(w.writer, p)
+}
+
+// consumed returns how many bytes of 'data' were fully written, given that 'written' bytes of its translated and
+// escaped form made it to the underlying writer.
+func (w *writer) consumed(data []byte, policy NewlinePolicy, written int) int {
+	last := w.last
+	for i, value := range data {
+		size := 1
+		switch {
+		case value == IAC, value == NL && (policy == NewlineLFToCRLF || (policy == NewlineCRLF && last != CR)):
+			size = 2
+		}
+
+		if written < size {
+			return i
+		}
+
+		written -= size
+		last = value
 	}
 
-	return n, nil
+	return len(data)
 }
 
-func (w *writer) escapeIAC() []byte {
-	return []byte{IAC, IAC}
+// policy returns the newline policy currently in effect.
+func (w *writer) policy() NewlinePolicy {
+	if w.binary {
+		return NewlineRaw
+	}
+
+	// LINEMODE clients edit locally and expect NVT line endings, so never send them bare LFs.
+	if w.linemode && w.newline == NewlineRaw {
+		return NewlineCRLF
+	}
+
+	return w.newline
 }
 
+// WriteLine writes 'text' to 'writer' as a line, ending it with CR LF (the TELNET end of line).
 func WriteLine(writer io.Writer, text ...string) error {
+	_, err := writer.Write([]byte(strings.Join(text, "") + "\r\n"))
+	return err
+}
+
+// WriteString writes 'text' to 'writer' as is, e.g. for a prompt, or output that already ends its lines.
+func WriteString(writer io.Writer, text ...string) error {
 	_, err := writer.Write([]byte(strings.Join(text, "")))
 	return err
 }
 
+// Writef writes the text formatted from 'format' and 'args' (see fmt.Sprintf) to 'writer' as is.
+func Writef(writer io.Writer, format string, args ...any) error {
+	_, err := fmt.Fprintf(writer, format, args...)
+	return err
+}
+
 // WriteCommand is a dirty workaround to write Telnet commands directly to the client. The internal wrapper satisfies
 // io.Write, preventing us from including custom logic to handle commands (without risking bodging real data). Instead,
 // this submits a signature (IAC x4) the underlying Write function knows to look for, and to treat as a command.
@@ -122,3 +230,8 @@ func WriteCommand(writer io.Writer, command byte, option byte, action byte) (n i
 func commandSignature() []byte {
 	return []byte{IAC, IAC, IAC, IAC}
 }
+
+// isCommand reports whether 'data' starts with the command signature, and carries a command after it.
+func isCommand(data []byte) bool {
+	return len(data) > 5 && data[0] == IAC && data[1] == IAC && data[2] == IAC && data[3] == IAC
+}
@@ -0,0 +1,84 @@
+package telnet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func BenchmarkWriter_Write(b *testing.B) {
+	benchmarks := []struct {
+		Name    string
+		Data    []byte
+		Newline NewlinePolicy
+	}{
+		{
+			Name: "Interactive",
+			Data: []byte("$ "),
+		},
+		{
+			Name:    "InteractiveLine",
+			Data:    []byte("uname: command not found\n"),
+			Newline: NewlineCRLF,
+		},
+		{
+			Name: "Plain",
+			Data: bytes.Repeat([]byte("apple banana cherry "), 50),
+		},
+		{
+			Name: "IAC",
+			Data: bytes.Repeat([]byte("apple\xffbanana\xffcherry "), 50),
+		},
+		{
+			Name: "IACDense",
+			Data: bytes.Repeat([]byte("a\xff"), 512),
+		},
+		{
+			Name:    "Bulk",
+			Data:    bytes.Repeat([]byte("apple banana cherry\n"), 64*1024/20),
+			Newline: NewlineCRLF,
+		},
+		{
+			Name: "BulkBinary",
+			Data: benchmarkBinaryData(64 * 1024),
+		},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.Name, func(b *testing.B) {
+			telnetWriter := newWriter(io.Discard)
+			telnetWriter.newline = benchmark.Newline
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(benchmark.Data)))
+
+			for i := 0; i < b.N; i++ {
+				if _, err := telnetWriter.Write(benchmark.Data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWriteCommand(b *testing.B) {
+	telnetWriter := newWriter(io.Discard)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := WriteCommand(telnetWriter, IAC, WILL, ECHO); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkBinaryData returns 'size' bytes cycling through every byte value, so 1 in 256 bytes is an IAC.
+func benchmarkBinaryData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	return data
+}
@@ -2,6 +2,7 @@ package telnet
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -131,3 +132,274 @@ func TestWriter_WriteCommand(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		Write    func(w io.Writer) error
+		Expected string
+	}{
+		{
+			Write:    func(w io.Writer) error { return WriteLine(w, "Hello, ", "world!") },
+			Expected: "Hello, world!\r\n",
+		},
+		{
+			Write:    func(w io.Writer) error { return WriteLine(w) },
+			Expected: "\r\n",
+		},
+		{
+			Write:    func(w io.Writer) error { return WriteString(w, "login", ": ") },
+			Expected: "login: ",
+		},
+		{
+			Write:    func(w io.Writer) error { return Writef(w, "%dx%d\r\n", 80, 24) },
+			Expected: "80x24\r\n",
+		},
+	}
+
+	for testNumber, test := range tests {
+		subWriter := new(bytes.Buffer)
+
+		if err := test.Write(newWriter(subWriter)); err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v.", testNumber, err, err)
+			continue
+		}
+
+		if expected, actual := test.Expected, subWriter.String(); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestWriter_NewlinePolicy(t *testing.T) {
+	tests := []struct {
+		Policy   NewlinePolicy
+		Bytes    []byte
+		Expected []byte
+	}{
+		{
+			Policy:   NewlineRaw,
+			Bytes:    []byte("apple\nbanana\r\ncherry"),
+			Expected: []byte("apple\nbanana\r\ncherry"),
+		},
+		{
+			Policy:   NewlineLFToCRLF,
+			Bytes:    []byte("apple\nbanana\ncherry\n"),
+			Expected: []byte("apple\r\nbanana\r\ncherry\r\n"),
+		},
+		{
+			Policy:   NewlineLFToCRLF,
+			Bytes:    []byte("apple\r\nbanana"),
+			Expected: []byte("apple\r\r\nbanana"),
+		},
+		{
+			Policy:   NewlineCRLF,
+			Bytes:    []byte("apple\nbanana\r\ncherry\n"),
+			Expected: []byte("apple\r\nbanana\r\ncherry\r\n"),
+		},
+		{
+			Policy:   NewlineCRLF,
+			Bytes:    []byte("\xff\n"),
+			Expected: []byte("\xff\xff\r\n"),
+		},
+	}
+
+	for testNumber, test := range tests {
+		subWriter := new(bytes.Buffer)
+		telnetWriter := newWriter(subWriter)
+		telnetWriter.newline = test.Policy
+
+		n, err := telnetWriter.Write(test.Bytes)
+		if err != nil {
+			t.Errorf("For test #%d, did not expect an error, but actually got one: (%T) %v; for %q -> %q.", testNumber, err, err, string(test.Bytes), string(test.Expected))
+			continue
+		}
+
+		if expected, actual := len(test.Bytes), n; expected != actual {
+			t.Errorf("For test #%d, expected %d, but actually got %d; for %q -> %q.", testNumber, expected, actual, string(test.Bytes), string(test.Expected))
+			continue
+		}
+
+		if expected, actual := string(test.Expected), subWriter.String(); expected != actual {
+			t.Errorf("For test #%d, expected %q, but actually got %q; for %q -> %q.", testNumber, expected, actual, string(test.Bytes), string(test.Expected))
+			continue
+		}
+	}
+}
+
+func TestWriter_NewlinePolicySplitCRLF(t *testing.T) {
+	subWriter := new(bytes.Buffer)
+	telnetWriter := newWriter(subWriter)
+	telnetWriter.newline = NewlineCRLF
+
+	for _, data := range []string{"apple\r", "\nbanana\n"} {
+		if _, err := telnetWriter.Write([]byte(data)); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+	}
+
+	if expected, actual := "apple\r\nbanana\r\n", subWriter.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+// limitedWriter accepts up to 'limit' bytes, then fails.
+type limitedWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		n, _ := w.Buffer.Write(p[:w.limit-w.Len()])
+		return n, io.ErrClosedPipe
+	}
+
+	return w.Buffer.Write(p)
+}
+
+func TestWriter_WritePartialCount(t *testing.T) {
+	tests := []struct {
+		Policy   NewlinePolicy
+		Bytes    []byte
+		Limit    int
+		Expected int
+	}{
+		{
+			Policy:   NewlineRaw,
+			Bytes:    []byte("apple"),
+			Limit:    3,
+			Expected: 3,
+		},
+		{
+			Policy:   NewlineRaw,
+			Bytes:    []byte("ab\xffcd"),
+			Limit:    3,
+			Expected: 2,
+		},
+		{
+			Policy:   NewlineRaw,
+			Bytes:    []byte("ab\xffcd"),
+			Limit:    4,
+			Expected: 3,
+		},
+		{
+			Policy:   NewlineLFToCRLF,
+			Bytes:    []byte("a\nb\nc"),
+			Limit:    5,
+			Expected: 3,
+		},
+		{
+			Policy:   NewlineLFToCRLF,
+			Bytes:    []byte("a\nb\nc"),
+			Limit:    6,
+			Expected: 4,
+		},
+	}
+
+	for testNumber, test := range tests {
+		subWriter := &limitedWriter{limit: test.Limit}
+		telnetWriter := newWriter(subWriter)
+		telnetWriter.newline = test.Policy
+
+		n, err := telnetWriter.Write(test.Bytes)
+		if err == nil {
+			t.Errorf("For test #%d, expected an error, but did not actually get one.", testNumber)
+			continue
+		}
+
+		if expected, actual := test.Expected, n; expected != actual {
+			t.Errorf("For test #%d, expected %d, but actually got %d; for %q.", testNumber, expected, actual, string(test.Bytes))
+			continue
+		}
+	}
+}
+
+func TestWriter_WriteAllocations(t *testing.T) {
+	tests := []struct {
+		Policy NewlinePolicy
+		Bytes  []byte
+	}{
+		{
+			Policy: NewlineRaw,
+			Bytes:  []byte("apple banana cherry\n"),
+		},
+		{
+			Policy: NewlineCRLF,
+			Bytes:  []byte("apple banana cherry"),
+		},
+		{
+			Policy: NewlineCRLF,
+			Bytes:  []byte("apple\xffbanana\ncherry"),
+		},
+	}
+
+	for testNumber, test := range tests {
+		telnetWriter := newWriter(io.Discard)
+		telnetWriter.newline = test.Policy
+
+		allocations := testing.AllocsPerRun(100, func() {
+			if _, err := telnetWriter.Write(test.Bytes); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		if allocations != 0 {
+			t.Errorf("For test #%d, expected no allocations, but actually got %v; for %q.", testNumber, allocations, string(test.Bytes))
+		}
+	}
+}
+
+func TestSession_TeeOutputCommands(t *testing.T) {
+	var output, tee bytes.Buffer
+
+	session := &Session{writer: newWriter(&output)}
+
+	_ = session.WriteString("before")
+	stop := session.TeeOutput(&tee)
+	_ = session.WriteString("during", "\xff")
+	_, _ = session.WriteCommand(IAC, WILL, ECHO)
+	stop()
+	_ = session.WriteString("after")
+
+	if expected, actual := "during\xff", tee.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+
+	expected := "beforeduring\xff\xff" + string([]byte{IAC, WILL, ECHO}) + "after"
+	if actual := output.String(); expected != actual {
+		t.Errorf("Expected %q, but actually got %q.", expected, actual)
+	}
+}
+
+func FuzzWriterWrite(f *testing.F) {
+	f.Add([]byte("Login: "))
+	f.Add([]byte{'a', IAC, 'b', IAC, IAC, '\r', '\n'})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Writes starting with the command signature are commands, not data.
+		if isCommand(data) {
+			t.Skip()
+		}
+
+		var output bytes.Buffer
+
+		n, err := newWriter(&output).Write(data)
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if expected, actual := len(data), n; expected != actual {
+			t.Errorf("Expected to write %d bytes, but actually wrote %d.", expected, actual)
+		}
+
+		// Reading back what was written should give the original data.
+		actual, err := io.ReadAll(newReader(&output))
+		if err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if !bytes.Equal(data, actual) {
+			t.Errorf("Expected to read back %v, but actually got %v.", data, actual)
+		}
+	})
+}
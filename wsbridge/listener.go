@@ -0,0 +1,87 @@
+package wsbridge
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+type (
+	// listener is a net.Listener accepting the connections bridged from WebSocket clients, for a telnet.Server to
+	// serve.
+	listener struct {
+		conns  chan net.Conn
+		closed chan struct{}
+		once   sync.Once
+	}
+
+	// bridgedConn is the server's end of a connection bridged from a WebSocket client, reporting the client's address
+	// as its own.
+	bridgedConn struct {
+		net.Conn
+		remote net.Addr
+	}
+
+	// websocketAddr is the address of a WebSocket client, e.g. "203.0.113.1:54321".
+	websocketAddr string
+)
+
+func newListener() *listener {
+	return &listener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// Accept waits for the next WebSocket client to be bridged.
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops the listener accepting connections.
+func (l *listener) Close() error {
+	err := net.ErrClosed
+	l.once.Do(func() {
+		close(l.closed)
+		err = nil
+	})
+
+	return err
+}
+
+// Addr returns the listener's address.
+func (l *listener) Addr() net.Addr {
+	return websocketAddr("wsbridge")
+}
+
+// dial connects the WebSocket client making 'r' to the listener, returning the bridge's end of the connection.
+func (l *listener) dial(r *http.Request) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case l.conns <- bridgedConn{Conn: server, remote: websocketAddr(r.RemoteAddr)}:
+		return client, nil
+	case <-l.closed:
+		_ = client.Close()
+		_ = server.Close()
+
+		return nil, &net.OpError{Op: "dial", Net: "websocket", Addr: l.Addr(), Err: net.ErrClosed}
+	}
+}
+
+func (c bridgedConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (a websocketAddr) Network() string {
+	return "websocket"
+}
+
+func (a websocketAddr) String() string {
+	return string(a)
+}
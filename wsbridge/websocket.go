@@ -0,0 +1,301 @@
+package wsbridge
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client's key to compute Sec-WebSocket-Accept (RFC 6455, section 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize caps the size of a message read from a WebSocket client, so it can't exhaust memory.
+const maxMessageSize = 1 << 20
+
+// WebSocket opcodes (RFC 6455, section 5.2).
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xa
+)
+
+// WebSocket close status codes (RFC 6455, section 7.4.1).
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooLarge      = 1009
+)
+
+var (
+	errProtocol        = errors.New("wsbridge: websocket protocol error")
+	errMessageTooLarge = errors.New("wsbridge: websocket message too large")
+)
+
+// websocket is the server end of a WebSocket connection (RFC 6455), carrying whole messages.
+type websocket struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	mu     sync.Mutex // serialises writes, as pongs are written by the reader
+	closed bool
+}
+
+// checkHandshake checks that 'r' is a WebSocket handshake 'checkOrigin' accepts, returning the client's key. If it
+// isn't, the error is written to 'w'.
+func checkHandshake(w http.ResponseWriter, r *http.Request, checkOrigin func(*http.Request) bool) (string, bool) {
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Expected a WebSocket handshake.", http.StatusBadRequest)
+		return "", false
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version.", http.StatusUpgradeRequired)
+
+		return "", false
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid WebSocket key.", http.StatusBadRequest)
+		return "", false
+	}
+
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+
+	if !checkOrigin(r) {
+		http.Error(w, "Origin not allowed.", http.StatusForbidden)
+		return "", false
+	}
+
+	return key, true
+}
+
+// accept completes the handshake checked by checkHandshake, taking over the connection from the HTTP server.
+func accept(w http.ResponseWriter, key string) (*websocket, error) {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "WebSockets aren't supported by this server.", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	// Clear any deadlines the HTTP server set, as the connection now lives as long as the terminal.
+	_ = conn.SetDeadline(time.Time{})
+
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n"
+
+	if _, err = conn.Write([]byte(response)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &websocket{conn: conn, reader: rw.Reader}, nil
+}
+
+// sameOrigin accepts requests without an Origin (i.e. not from a browser), or from a page on the same host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContains reports whether the comma separated header 'name' contains 'token', ignoring case.
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// readMessage reads the next text or binary message from the client, answering pings along the way. It returns
+// io.EOF once the client closes the connection.
+func (ws *websocket) readMessage() (opcode byte, message []byte, err error) {
+	for {
+		fin, frameOpcode, payload, err := ws.readFrame()
+		if err != nil {
+			switch {
+			case errors.Is(err, errMessageTooLarge):
+				_ = ws.close(closeTooLarge)
+			case errors.Is(err, errProtocol):
+				_ = ws.close(closeProtocolError)
+			}
+
+			return 0, nil, err
+		}
+
+		switch frameOpcode {
+		case opPing:
+			if err = ws.writeMessage(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+
+			continue
+		case opPong:
+			continue
+		case opClose:
+			_ = ws.close(closeNormal)
+			return 0, nil, io.EOF
+		case opContinuation:
+			if opcode == 0 {
+				_ = ws.close(closeProtocolError)
+				return 0, nil, errProtocol
+			}
+		case opText, opBinary:
+			if opcode != 0 {
+				_ = ws.close(closeProtocolError)
+				return 0, nil, errProtocol
+			}
+
+			opcode = frameOpcode
+		default:
+			_ = ws.close(closeProtocolError)
+			return 0, nil, errProtocol
+		}
+
+		if len(message)+len(payload) > maxMessageSize {
+			_ = ws.close(closeTooLarge)
+			return 0, nil, errMessageTooLarge
+		}
+
+		message = append(message, payload...)
+
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+// readFrame reads and unmasks the next frame from the client.
+func (ws *websocket) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0f
+	masked, length := header[1]&0x80 != 0, uint64(header[1]&0x7f)
+
+	// Extensions aren't negotiated, so the reserved bits must be clear, and clients must mask their frames.
+	if header[0]&0x70 != 0 || !masked {
+		return false, 0, nil, errProtocol
+	}
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(ws.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(ws.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	// Control frames can't be fragmented, and carry at most 125 bytes.
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, errProtocol
+	}
+
+	if length > maxMessageSize {
+		return false, 0, nil, errMessageTooLarge
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeMessage writes 'payload' to the client as a single frame.
+func (ws *websocket) writeMessage(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.closed {
+		return net.ErrClosed
+	}
+
+	return ws.writeFrame(opcode, payload)
+}
+
+// writeFrame writes an unmasked frame to the client. ws.mu must be held.
+func (ws *websocket) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|opcode)
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	_, err := ws.conn.Write(append(frame, payload...))
+	return err
+}
+
+// close sends the client a close frame with 'status' (unless it's already been sent), and closes the connection.
+func (ws *websocket) close(status uint16) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.closed {
+		return nil
+	}
+
+	ws.closed = true
+
+	// Give up on the close frame if the client has stopped reading.
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_ = ws.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, status))
+
+	return ws.conn.Close()
+}
@@ -0,0 +1,404 @@
+// Package wsbridge bridges WebSocket clients, such as xterm.js terminals in a browser, to TELNET: either to sessions
+// served by a telnet.Server in the same process, or to a remote TELNET server.
+package wsbridge
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/globalcyberalliance/telnet-go"
+)
+
+// DefaultTerminalType is the terminal type sent to TELNET servers asking for it (TTYPE), unless configured otherwise.
+const DefaultTerminalType = "xterm-256color"
+
+// maxQueuedReplies caps the negotiation replies waiting to be written to a TELNET server, which is disconnected if it
+// keeps negotiating without reading the replies.
+const maxQueuedReplies = 1024
+
+var (
+	// localOptions are the options the bridge agrees to perform when the TELNET server asks (DO).
+	localOptions = map[byte]bool{telnet.NAWS: true, telnet.TTYPE: true, telnet.SGA: true}
+
+	// remoteOptions are the options the bridge agrees to the TELNET server performing when it offers (WILL).
+	remoteOptions = map[byte]bool{telnet.ECHO: true, telnet.SGA: true, telnet.BINARY: true}
+)
+
+type (
+	// Handler is an http.Handler bridging each WebSocket client to TELNET, acting as the TELNET client on its behalf
+	// (answering the server's negotiations as a terminal would).
+	//
+	// Messages from the WebSocket client are sent to the TELNET server as the terminal's input, except for text
+	// messages holding a JSON resize message, which are sent to the server as the terminal's window size (NAWS):
+	//
+	//	{"type": "resize", "cols": 80, "rows": 24}
+	//
+	// The TELNET server's output is sent to the WebSocket client as binary messages, with commands filtered out.
+	Handler struct {
+		// TerminalType is sent to TELNET servers asking for the terminal type (TTYPE); DefaultTerminalType if unset.
+		TerminalType string
+
+		// CheckOrigin reports whether to accept a WebSocket request. By default, only requests without an Origin
+		// header, or from a page on the same host, are accepted.
+		CheckOrigin func(r *http.Request) bool
+
+		// Logger logs failures to connect to the TELNET server, or accept WebSocket connections; slog.Default() if
+		// unset.
+		Logger *slog.Logger
+
+		dial     func(r *http.Request) (net.Conn, error)
+		listener *listener // the listener sessions are served from by NewServerHandler, if any
+	}
+
+	// resizeMessage is a message from the WebSocket client resizing its terminal.
+	resizeMessage struct {
+		Type string `json:"type"`
+		Cols int    `json:"cols"`
+		Rows int    `json:"rows"`
+	}
+
+	// bridge relays one WebSocket client to a TELNET server.
+	bridge struct {
+		ws           *websocket
+		conn         net.Conn
+		telnet       *telnet.Conn
+		terminalType string
+
+		writes  chan []byte   // the client's input to write to the TELNET server, already escaped
+		replied chan struct{} // signalled when replies are queued
+		done    chan struct{}
+		stop    sync.Once
+
+		mu      sync.Mutex
+		replies [][]byte  // negotiation replies to write to the TELNET server, queued without waiting for it
+		local   [256]bool // the options we've agreed to perform
+		remote  [256]bool // the options we've agreed to the server performing
+		width   int
+		height  int
+	}
+)
+
+// NewServerHandler returns a Handler serving each WebSocket client a Session of 'server', as if it had connected over
+// TELNET (with the client's address as its RemoteAddr). 'server' is served straight away, until Close is called, so
+// must be configured first (including its logger, see telnet.Server.SetLogger).
+func NewServerHandler(server *telnet.Server) *Handler {
+	listener := newListener()
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return &Handler{dial: listener.dial, listener: listener}
+}
+
+// NewClientHandler returns a Handler relaying each WebSocket client to the TELNET server at 'addr' (e.g.
+// "example.com:23").
+func NewClientHandler(addr string) *Handler {
+	var dialer net.Dialer
+
+	return &Handler{
+		dial: func(r *http.Request) (net.Conn, error) {
+			return dialer.DialContext(r.Context(), "tcp", addr)
+		},
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection, and bridges it to TELNET until either end closes.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := checkHandshake(w, r, h.CheckOrigin)
+	if !ok {
+		return
+	}
+
+	logger := h.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	conn, err := h.dial(r)
+	if err != nil {
+		logger.Error("failed to connect to the telnet server", "err", err)
+		http.Error(w, "Failed to connect to the TELNET server.", http.StatusBadGateway)
+
+		return
+	}
+
+	ws, err := accept(w, key)
+	if err != nil {
+		logger.Error("failed to accept websocket connection", "from", r.RemoteAddr, "err", err)
+		_ = conn.Close()
+
+		return
+	}
+
+	terminalType := h.TerminalType
+	if terminalType == "" {
+		terminalType = DefaultTerminalType
+	}
+
+	newBridge(ws, conn, terminalType).run()
+}
+
+// Close stops serving the telnet.Server of a Handler returned by NewServerHandler, so new WebSocket clients are turned
+// away. Sessions already running carry on until they end.
+func (h *Handler) Close() error {
+	if h.listener == nil {
+		return nil
+	}
+
+	return h.listener.Close()
+}
+
+func newBridge(ws *websocket, conn net.Conn, terminalType string) *bridge {
+	b := &bridge{
+		ws:           ws,
+		conn:         conn,
+		telnet:       telnet.NewConn(conn),
+		terminalType: terminalType,
+		writes:       make(chan []byte, 64),
+		replied:      make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+
+	b.telnet.OnOption(b.receivedOption)
+	b.telnet.OnSubnegotiation(b.receivedSubnegotiation)
+
+	return b
+}
+
+// run relays between the WebSocket client and the TELNET server until either closes.
+func (b *bridge) run() {
+	var group sync.WaitGroup
+	group.Add(2)
+
+	go func() {
+		defer group.Done()
+		b.writeTELNET()
+	}()
+
+	go func() {
+		defer group.Done()
+		defer b.close()
+
+		b.readTELNET()
+	}()
+
+	b.readWebSocket()
+	b.close()
+	group.Wait()
+}
+
+// close closes both connections, stopping the bridge.
+func (b *bridge) close() {
+	b.stop.Do(func() {
+		close(b.done)
+		_ = b.ws.close(closeNormal)
+		_ = b.conn.Close()
+	})
+}
+
+// readWebSocket sends the WebSocket client's input and window size to the TELNET server.
+func (b *bridge) readWebSocket() {
+	for {
+		opcode, message, err := b.ws.readMessage()
+		if err != nil {
+			return
+		}
+
+		var resize resizeMessage
+		if opcode == opText && json.Unmarshal(message, &resize) == nil && resize.Type == "resize" {
+			b.resize(resize.Cols, resize.Rows)
+			continue
+		}
+
+		b.send(escapeInput(message))
+	}
+}
+
+// readTELNET sends the TELNET server's output to the WebSocket client.
+func (b *bridge) readTELNET() {
+	var buffer [4096]byte
+
+	for {
+		n, err := b.telnet.Read(buffer[:])
+		if n > 0 {
+			if err := b.ws.writeMessage(opBinary, buffer[:n]); err != nil {
+				return
+			}
+		}
+
+		// Skip any commands the server sends that we don't understand.
+		var protocolErr *telnet.ProtocolError
+		if errors.As(err, &protocolErr) && !protocolErr.Fatal {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeTELNET writes the input queued by send, and the replies queued by reply, to the TELNET server, so reading from
+// it never waits on it reading.
+func (b *bridge) writeTELNET() {
+	for {
+		var pending [][]byte
+
+		select {
+		case data := <-b.writes:
+			pending = [][]byte{data}
+		case <-b.replied:
+			b.mu.Lock()
+			pending, b.replies = b.replies, nil
+			b.mu.Unlock()
+		case <-b.done:
+			return
+		}
+
+		for _, data := range pending {
+			if _, err := b.conn.Write(data); err != nil {
+				b.close()
+				return
+			}
+		}
+	}
+}
+
+// send queues the client's input 'data' to be written to the TELNET server as is, waiting for room in the queue.
+func (b *bridge) send(data []byte) {
+	select {
+	case b.writes <- data:
+	case <-b.done:
+	}
+}
+
+// reply queues the negotiation 'data' to be written to the TELNET server as is, without waiting, as it's called while
+// reading from it. b.mu must be held.
+func (b *bridge) reply(data []byte) {
+	if len(b.replies) >= maxQueuedReplies {
+		b.close()
+		return
+	}
+
+	b.replies = append(b.replies, data)
+
+	select {
+	case b.replied <- struct{}{}:
+	default:
+	}
+}
+
+// receivedOption answers a negotiation from the TELNET server, only replying when it changes what's been agreed, so
+// negotiations can't loop (RFC 1143).
+func (b *bridge) receivedOption(verb byte, option byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch verb {
+	case telnet.DO:
+		if !localOptions[option] {
+			b.reply([]byte{telnet.IAC, telnet.WONT, option})
+			return
+		}
+
+		if b.local[option] {
+			return
+		}
+
+		b.local[option] = true
+		b.reply([]byte{telnet.IAC, telnet.WILL, option})
+
+		if option == telnet.NAWS && b.width > 0 {
+			b.reply(b.windowSize())
+		}
+	case telnet.DONT:
+		if b.local[option] {
+			b.local[option] = false
+			b.reply([]byte{telnet.IAC, telnet.WONT, option})
+		}
+	case telnet.WILL:
+		if !remoteOptions[option] {
+			b.reply([]byte{telnet.IAC, telnet.DONT, option})
+			return
+		}
+
+		if !b.remote[option] {
+			b.remote[option] = true
+			b.reply([]byte{telnet.IAC, telnet.DO, option})
+		}
+	case telnet.WONT:
+		if b.remote[option] {
+			b.remote[option] = false
+			b.reply([]byte{telnet.IAC, telnet.DONT, option})
+		}
+	}
+}
+
+// receivedSubnegotiation answers the TELNET server asking for the terminal type.
+func (b *bridge) receivedSubnegotiation(option byte, payload []byte) {
+	if option != telnet.TTYPE || len(payload) == 0 || payload[0] != telnet.SEND {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reply(subnegotiation(telnet.TTYPE, append([]byte{telnet.IS}, b.terminalType...)))
+}
+
+// resize records the terminal's window size, sending it to the TELNET server if it's asked for it.
+func (b *bridge) resize(width int, height int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.width, b.height = min(max(width, 0), 0xffff), min(max(height, 0), 0xffff)
+
+	if b.local[telnet.NAWS] {
+		b.reply(b.windowSize())
+	}
+}
+
+// windowSize returns the NAWS subnegotiation sending the terminal's window size. b.mu must be held.
+func (b *bridge) windowSize() []byte {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(b.width))
+	return subnegotiation(telnet.NAWS, binary.BigEndian.AppendUint16(payload, uint16(b.height)))
+}
+
+// subnegotiation returns IAC SB 'option' 'payload' IAC SE, escaping any IAC bytes in 'payload'.
+func subnegotiation(option byte, payload []byte) []byte {
+	command := []byte{telnet.IAC, telnet.SB, option}
+
+	for _, b := range payload {
+		command = append(command, b)
+		if b == telnet.IAC {
+			command = append(command, telnet.IAC)
+		}
+	}
+
+	return append(command, telnet.IAC, telnet.SE)
+}
+
+// escapeInput escapes the terminal's input for TELNET: IAC is doubled, and a CR not followed by LF (as terminals send
+// for Enter) becomes CR LF, the TELNET end of line (RFC 854), as other TELNET clients send.
+func escapeInput(data []byte) []byte {
+	escaped := make([]byte, 0, len(data)+1)
+
+	for i, b := range data {
+		escaped = append(escaped, b)
+
+		switch {
+		case b == telnet.IAC:
+			escaped = append(escaped, telnet.IAC)
+		case b == telnet.CR && (i+1 == len(data) || data[i+1] != telnet.NL):
+			escaped = append(escaped, telnet.NL)
+		}
+	}
+
+	return escaped
+}
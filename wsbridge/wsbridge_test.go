@@ -0,0 +1,292 @@
+package wsbridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalcyberalliance/telnet-go"
+	"github.com/globalcyberalliance/telnet-go/telnettest"
+)
+
+// testClient is a minimal WebSocket client, for talking to a Handler.
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	output []byte // the output received, up to what expect has consumed
+}
+
+func TestNewServerHandler(t *testing.T) {
+	server := &telnet.Server{
+		Handler: func(session *telnet.Session) {
+			ctx, cancel := context.WithTimeout(session.Context(), time.Second)
+			defer cancel()
+
+			width, height, _ := session.AwaitWindowSize(ctx)
+			_ = session.Writef("%s %dx%d\r\n", session.RemoteAddr().Network(), width, height)
+
+			line, err := session.ReadLine()
+			if err != nil {
+				return
+			}
+
+			_ = session.WriteLine("Hello, ", line, "!")
+		},
+	}
+	server.SetLogger(slog.Default())
+
+	handler := NewServerHandler(server)
+	defer handler.Close()
+
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	client := dialTestClient(t, httpServer.URL)
+	defer client.conn.Close()
+
+	client.write(t, opText, []byte(`{"type": "resize", "cols": 100, "rows": 40}`))
+	client.expect(t, "websocket 100x40\r\n")
+
+	// Terminals send CR for Enter, which must reach the server as CR LF.
+	client.write(t, opText, []byte("world\r"))
+	client.expect(t, "Hello, world!\r\n")
+}
+
+func TestNewClientHandler(t *testing.T) {
+	server := telnettest.NewServer(func(session *telnet.Session) {
+		ctx, cancel := context.WithTimeout(session.Context(), time.Second)
+		defer cancel()
+
+		types, err := session.AwaitTerminalTypes(ctx)
+		if err != nil || len(types) == 0 {
+			return
+		}
+
+		_ = session.WriteLine("Terminal: ", types[0])
+	})
+	defer server.Close()
+
+	httpServer := httptest.NewServer(NewClientHandler(server.Addr))
+	defer httpServer.Close()
+
+	client := dialTestClient(t, httpServer.URL)
+	defer client.conn.Close()
+
+	client.expect(t, "Terminal: "+DefaultTerminalType+"\r\n")
+}
+
+func TestHandler_Handshake(t *testing.T) {
+	tests := []struct {
+		Header   http.Header
+		Expected int
+	}{
+		{
+			Header:   http.Header{},
+			Expected: http.StatusBadRequest,
+		},
+		{
+			Header: http.Header{
+				"Connection":            {"Upgrade"},
+				"Upgrade":               {"websocket"},
+				"Sec-Websocket-Version": {"8"},
+			},
+			Expected: http.StatusUpgradeRequired,
+		},
+		{
+			Header: http.Header{
+				"Connection":            {"keep-alive, Upgrade"},
+				"Upgrade":               {"websocket"},
+				"Sec-Websocket-Version": {"13"},
+				"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+				"Origin":                {"https://example.net"},
+			},
+			Expected: http.StatusForbidden,
+		},
+	}
+
+	handler := NewClientHandler("127.0.0.1:0")
+
+	for testNumber, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header = test.Header
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if expected, actual := test.Expected, recorder.Code; expected != actual {
+			t.Errorf("For test #%d, expected %d, but actually got %d.", testNumber, expected, actual)
+		}
+	}
+}
+
+func TestHandler_NegotiationFlood(t *testing.T) {
+	serverConn, bridgeConn := net.Pipe()
+	defer serverConn.Close()
+
+	handler := &Handler{dial: func(r *http.Request) (net.Conn, error) {
+		return bridgeConn, nil
+	}}
+
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	client := dialTestClient(t, httpServer.URL)
+	defer client.conn.Close()
+
+	// The server negotiates without reading the replies until it's done, which mustn't stop its output being read.
+	go func() {
+		for i := 0; i < 200; i++ {
+			if _, err := serverConn.Write([]byte{telnet.IAC, telnet.DO, telnet.NAWS, telnet.IAC, telnet.DONT, telnet.NAWS}); err != nil {
+				return
+			}
+		}
+
+		if _, err := serverConn.Write([]byte("ready\r\n")); err != nil {
+			return
+		}
+
+		_, _ = io.Copy(io.Discard, serverConn)
+	}()
+
+	client.expect(t, "ready\r\n")
+}
+
+func TestHandler_Logger(t *testing.T) {
+	var logs bytes.Buffer
+
+	handler := &Handler{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+		dial: func(r *http.Request) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header = http.Header{
+		"Connection":            {"Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if expected, actual := http.StatusBadGateway, recorder.Code; expected != actual {
+		t.Errorf("Expected %d, but actually got %d.", expected, actual)
+	}
+
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("Expected the error to be logged, but actually got %q.", logs.String())
+	}
+}
+
+// dialTestClient connects a testClient to the Handler served at 'rawURL'.
+func dialTestClient(t *testing.T, rawURL string) *testClient {
+	t.Helper()
+
+	host := strings.TrimPrefix(rawURL, "http://")
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+
+	request := "GET / HTTP/1.1\r\n" +
+		"Host: " + host + "\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + base64.StdEncoding.EncodeToString(nonce[:]) + "\r\n\r\n"
+
+	if _, err = conn.Write([]byte(request)); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+
+	if expected, actual := http.StatusSwitchingProtocols, response.StatusCode; expected != actual {
+		t.Fatalf("Expected %d, but actually got %d.", expected, actual)
+	}
+
+	return &testClient{conn: conn, reader: reader}
+}
+
+// write sends 'payload' to the server as a masked frame.
+func (c *testClient) write(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(len(payload)))
+	}
+
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+	}
+}
+
+// expect reads messages from the server until its output includes 'text'.
+func (c *testClient) expect(t *testing.T, text string) {
+	t.Helper()
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for !bytes.Contains(c.output, []byte(text)) {
+		var header [2]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			t.Fatalf("Expected %q, but got %q before: (%T) %v.", text, c.output, err, err)
+		}
+
+		length := int(header[1] & 0x7f)
+		if length == 126 {
+			var extended [2]byte
+			if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+				t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+			}
+
+			length = int(binary.BigEndian.Uint16(extended[:]))
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			t.Fatalf("Did not expect an error, but actually got one: (%T) %v.", err, err)
+		}
+
+		if header[0]&0x0f == opBinary {
+			c.output = append(c.output, payload...)
+		}
+	}
+
+	c.output = c.output[bytes.Index(c.output, []byte(text))+len(text):]
+}